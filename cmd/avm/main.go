@@ -11,8 +11,11 @@ import (
 	"github.com/elys-network/avm/internal/avm"
 	"github.com/elys-network/avm/internal/config"
 	datafetcher "github.com/elys-network/avm/internal/datafetcher"
+	"github.com/elys-network/avm/internal/events"
+	"github.com/elys-network/avm/internal/fixtures"
 	"github.com/elys-network/avm/internal/logger"
 	"github.com/elys-network/avm/internal/state"
+	"github.com/elys-network/avm/internal/types"
 	"github.com/elys-network/avm/internal/vault"
 	"github.com/elys-network/avm/internal/web"
 
@@ -42,31 +45,60 @@ func main() {
 	logger.Initialize(os.Getenv("LOG_LEVEL"))
 	log.Info().Msg("AVM Core Logic Starting...")
 
-	// Initialize Database Connection (for ScoringParameters only)
-	dbCfg := state.DBConfig{
-		Host: os.Getenv("DB_HOST"), Port: mustAtoi(os.Getenv("DB_PORT"), 5432),
-		User: os.Getenv("DB_USER"), Password: os.Getenv("DB_PASSWORD"),
-		DBName: os.Getenv("DB_NAME"), SSLMode: os.Getenv("DB_SSLMODE"),
+	// Record or replay external I/O; this must happen before any connection is made
+	if err := fixtures.Start(config.FixturesMode, config.FixturesFile); err != nil {
+		log.Fatal().Err(err).Msg("Failed to start fixtures")
 	}
-	if err := state.InitDB(dbCfg); err != nil {
-		log.Fatal().Err(err).Msg("Failed to initialize database")
+
+	// Open the storage backend: PostgreSQL (default) or a local SQLite file
+	storeCfg := state.StoreConfig{
+		Backend: os.Getenv("STORAGE_BACKEND"),
+		Postgres: state.DBConfig{
+			Host: os.Getenv("DB_HOST"), Port: mustAtoi(os.Getenv("DB_PORT"), 5432),
+			User: os.Getenv("DB_USER"), Password: os.Getenv("DB_PASSWORD"),
+			DBName: os.Getenv("DB_NAME"), SSLMode: os.Getenv("DB_SSLMODE"),
+		},
+		SQLitePath: os.Getenv("SQLITE_PATH"),
 	}
-	defer state.CloseDB()
-	if err := state.EnsureSchema(); err != nil {
-		log.Fatal().Err(err).Msg("Failed to ensure database schema")
+	store, err := state.OpenStore(storeCfg)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to open storage backend")
+	}
+	defer store.Close()
+	// The SQLite store migrates itself on open; PostgreSQL uses the versioned migrations
+	if state.DB != nil {
+		if err := state.EnsureSchema(); err != nil {
+			log.Fatal().Err(err).Msg("Failed to ensure database schema")
+		}
+	} else {
+		// The on-chain volume sources read the swap volume index, which only exists in PostgreSQL
+		if config.VolumeSource != config.VolumeSourceSupplyAPI {
+			log.Fatal().Str("volumeSource", config.VolumeSource).Msg("VOLUME_SOURCE must be supply_api with the SQLite store")
+		}
+		log.Info().Str("path", storeCfg.SQLitePath).Msg("Using SQLite store; price history cache and swap volume index are disabled")
 	}
 
-	// Load Scoring Parameters
-	scoringParams, err := state.LoadActiveScoringParameters(avm.DEFAULT_SCORING_CONFIG_NAME)
+	// Load Scoring Parameters. Versions activated later are picked up at the start of each cycle.
+	var scoringParams *types.ScoringParameters
+	var scoringParamsID *int64
+	activeParams, err := store.LoadActiveScoringParametersVersion(avm.DEFAULT_SCORING_CONFIG_NAME)
 	if err != nil {
 		log.Warn().Err(err).Msg("Failed to load active scoring parameters, using defaults and saving.")
 		defaultParams := config.DefaultScoringParameters
-		if _, err := state.SaveScoringParameters(defaultParams, avm.DEFAULT_SCORING_CONFIG_NAME, avm.DEFAULT_SCORING_CONFIG_VERSION, true); err != nil {
+		defaultParamsID, err := store.SaveScoringParameters(defaultParams, avm.DEFAULT_SCORING_CONFIG_NAME, avm.DEFAULT_SCORING_CONFIG_VERSION, true, types.AuditInfo{
+			Actor:  types.AuditActorAVM,
+			Reason: "No active scoring parameters at startup, saved the defaults",
+		})
+		if err != nil {
 			log.Fatal().Err(err).Msg("Failed to save initial default scoring parameters.")
 		}
 		scoringParams = &defaultParams
+		scoringParamsID = &defaultParamsID
+	} else {
+		scoringParams = &activeParams.Parameters
+		scoringParamsID = &activeParams.ParamsID
 	}
-	log.Info().Msg("Scoring parameters loaded successfully.")
+	log.Info().Int64("paramsID", *scoringParamsID).Msg("Scoring parameters loaded successfully.")
 
 	// --- Start Web Server ---
 	webPort := os.Getenv("WEB_PORT")
@@ -74,7 +106,13 @@ func main() {
 		webPort = "8080"
 	}
 
-	webServer := web.NewWebServer(webPort)
+	// Cycle progress, published by the AVM and streamed by the web server
+	eventBus := events.NewBus()
+
+	webServer := web.NewWebServer(webPort, store, web.AuthConfig{
+		AnonymousRole:  config.WebAnonymousRole,
+		AllowedOrigins: config.WebCORSAllowedOrigins,
+	}, eventBus)
 	go func() {
 		log.Info().Str("port", webPort).Str("url", "http://localhost:"+webPort).Msg("Starting AVM web dashboard")
 		if err := webServer.Start(); err != nil {
@@ -90,7 +128,7 @@ func main() {
 	} else {
 		creds = grpc.WithTransportCredentials(insecure.NewCredentials())
 	}
-	grpcClient, err := grpc.Dial(grpcEndpoint, creds)
+	grpcClient, err := grpc.Dial(grpcEndpoint, creds, fixtures.GRPCDialOption())
 	if err != nil {
 		log.Fatal().Err(err).Msg("gRPC connection error")
 	}
@@ -103,10 +141,13 @@ func main() {
 
 	if avmMode == "live" {
 		log.Warn().Msg("Initializing AVM in LIVE mode. Real transactions will be broadcast.")
-		tokenData, err := datafetcher.GetTokens(grpcClient)
+		tokenData, quarantinedTokens, err := datafetcher.GetTokens(context.Background(), grpcClient, nil)
 		if err != nil {
 			log.Fatal().Err(err).Msg("Cannot start without initial token data")
 		}
+		if len(quarantinedTokens) > 0 {
+			log.Warn().Int("quarantinedTokens", len(quarantinedTokens)).Msg("Starting without quarantined tokens")
+		}
 		liveVault, err := vault.NewVaultClient(config.VaultID, grpcClient, tokenData)
 		if err != nil {
 			log.Fatal().Err(err).Msg("Failed to initialize live vault manager")
@@ -120,11 +161,14 @@ func main() {
 	log.Info().Msg("Creating AVM instance with dependency injection...")
 	
 	avmConfig := avm.Config{
-		GRPCClient:    grpcClient,
-		VaultManager:  vm,
-		ScoringParams: scoringParams,
-		ConfigName:    avm.DEFAULT_SCORING_CONFIG_NAME,
-		ConfigVersion: avm.DEFAULT_SCORING_CONFIG_VERSION,
+		GRPCClient:      grpcClient,
+		VaultManager:    vm,
+		Store:           store,
+		ScoringParams:   scoringParams,
+		ScoringParamsID: scoringParamsID,
+		Events:          eventBus,
+		ConfigName:      avm.DEFAULT_SCORING_CONFIG_NAME,
+		ConfigVersion:   avm.DEFAULT_SCORING_CONFIG_VERSION,
 	}
 
 	avmInstance, err := avm.NewAVM(avmConfig)
@@ -133,12 +177,24 @@ func main() {
 	}
 
 	log.Info().Msg("AVM instance created successfully")
+	webServer.SetController(avmInstance)
+	webServer.SetPoolExplorer(avmInstance)
 
 	// --- 4. Start AVM Main Loop ---
 	log.Info().Str("interval", LOOP_INTERVAL.String()).Msg("Starting AVM main loop")
 	
 	// Create context for graceful shutdown
 	ctx := context.Background()
+
+	// A recording or replay covers startup and a single cycle
+	if fixtures.Enabled() {
+		log.Info().Str("mode", config.FixturesMode).Msg("Running a single AVM cycle with fixtures")
+		avmInstance.RunCycle(ctx)
+		if err := fixtures.Save(config.FixturesFile); err != nil {
+			log.Fatal().Err(err).Msg("Failed to save fixture bundle")
+		}
+		return
+	}
 	
 	// Start the AVM loop (this will run indefinitely)
 	avmInstance.RunLoop(ctx, LOOP_INTERVAL)
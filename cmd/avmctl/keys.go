@@ -0,0 +1,153 @@
+/*
+
+This file implements "avmctl keys", which manages the API keys of the web API. It is how the
+first admin key is created; admins can then manage keys through the API as well.
+
+*/
+
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/elys-network/avm/internal/auth"
+	"github.com/elys-network/avm/internal/state"
+	"github.com/elys-network/avm/internal/types"
+)
+
+const keysUsage = `Usage: avmctl keys <command> [flags] [arguments]
+
+Commands:
+  list                  List all API keys, revoked ones included
+  create NAME           Create an API key and print it; it is not shown again
+  revoke NAME           Revoke an API key
+
+Flags (before the arguments):
+  -role ROLE            create: viewer, operator or admin (default: viewer)
+  -reason TEXT          create, revoke: why the change is made (required)
+  -actor NAME           create, revoke: who makes the change (default: AVM_ACTOR or the OS user)
+
+Roles include the ones below them: viewers read vault data, operators also run operator actions
+and read the audit log, and admins also manage API keys.`
+
+// runKeys dispatches an "avmctl keys" command
+func runKeys(args []string) error {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, keysUsage)
+		os.Exit(2)
+	}
+	command := args[0]
+
+	flags := flag.NewFlagSet("keys "+command, flag.ExitOnError)
+	flags.Usage = func() { fmt.Fprintln(os.Stderr, keysUsage) }
+	roleName := flags.String("role", string(types.APIRoleViewer), "role of the new key")
+	reason := flags.String("reason", "", "why the change is made")
+	actor := flags.String("actor", defaultActor(), "who makes the change")
+	flags.Parse(args[1:])
+	rest := flags.Args()
+
+	expectArgs := func(n int) {
+		if len(rest) != n {
+			fmt.Fprintln(os.Stderr, keysUsage)
+			os.Exit(2)
+		}
+	}
+
+	switch command {
+	case "list":
+		expectArgs(0)
+		return keysList()
+	case "create":
+		expectArgs(1)
+		role, err := types.ParseAPIRole(*roleName)
+		if err != nil {
+			return err
+		}
+		audit, err := auditInfo(*actor, *reason)
+		if err != nil {
+			return err
+		}
+		return keysCreate(rest[0], role, audit)
+	case "revoke":
+		expectArgs(1)
+		audit, err := auditInfo(*actor, *reason)
+		if err != nil {
+			return err
+		}
+		return keysRevoke(rest[0], audit)
+	default:
+		fmt.Fprintln(os.Stderr, keysUsage)
+		os.Exit(2)
+	}
+	return nil
+}
+
+// keysList prints every API key
+func keysList() error {
+	if err := connectDB(); err != nil {
+		return err
+	}
+
+	keys, err := store.ListAPIKeys()
+	if err != nil {
+		return err
+	}
+	if len(keys) == 0 {
+		fmt.Println("No API keys")
+		return nil
+	}
+
+	formatTime := func(t *time.Time) string {
+		if t == nil {
+			return "-"
+		}
+		return t.UTC().Format("2006-01-02 15:04:05")
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tROLE\tPREFIX\tCREATED AT\tCREATED BY\tLAST USED AT\tREVOKED AT")
+	for _, k := range keys {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n", k.Name, k.Role, k.Prefix,
+			formatTime(&k.CreatedAt), k.CreatedBy, formatTime(k.LastUsedAt), formatTime(k.RevokedAt))
+	}
+	return w.Flush()
+}
+
+// keysCreate creates an API key and prints it
+func keysCreate(name string, role types.APIRole, audit types.AuditInfo) error {
+	if err := connectDB(); err != nil {
+		return err
+	}
+
+	key, err := auth.GenerateAPIKey()
+	if err != nil {
+		return err
+	}
+	if _, err := store.CreateAPIKey(name, role, auth.HashAPIKey(key), auth.DisplayPrefix(key), audit); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(os.Stderr, "Created %s key %q. Store it now, it is not shown again:\n", role, name)
+	fmt.Println(key)
+	return nil
+}
+
+// keysRevoke revokes an API key
+func keysRevoke(name string, audit types.AuditInfo) error {
+	if err := connectDB(); err != nil {
+		return err
+	}
+
+	if _, err := store.RevokeAPIKey(name, audit); err != nil {
+		if errors.Is(err, state.ErrAPIKeyNotFound) {
+			return fmt.Errorf("no API key named %q", name)
+		}
+		return err
+	}
+	fmt.Printf("Revoked API key %q\n", name)
+	return nil
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/user"
+	"strconv"
+	"strings"
+
+	"github.com/elys-network/avm/internal/logger"
+	"github.com/elys-network/avm/internal/state"
+	"github.com/elys-network/avm/internal/types"
+	"github.com/joho/godotenv"
+	"github.com/rs/zerolog/log"
+)
+
+const usage = `Usage: avmctl <command> [arguments]
+
+Commands:
+  params    Manage scoring parameter versions (run "avmctl params" for details)
+  snapshots Export and archive cycle snapshots (run "avmctl snapshots" for details)
+  keys      Manage web API keys (run "avmctl keys" for details)
+
+The storage backend is selected with STORAGE_BACKEND ("postgres" or "sqlite"). PostgreSQL is
+configured with DB_HOST, DB_PORT, DB_USER, DB_PASSWORD, DB_NAME and DB_SSLMODE, SQLite with SQLITE_PATH.`
+
+// store is opened by the commands that need it
+var store state.Store
+
+// main is the entry point for the AVM operator command line.
+func main() {
+	if err := godotenv.Load(); err != nil {
+		log.Warn().Msg("Warning: .env file not found. Relying on OS environment variables.")
+	}
+	logLevel := os.Getenv("LOG_LEVEL")
+	if logLevel == "" {
+		logLevel = "warn" // Keep command output readable
+	}
+	logger.Initialize(logLevel)
+
+	if len(os.Args) < 2 {
+		fmt.Fprintln(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	defer closeStore()
+
+	var err error
+	switch os.Args[1] {
+	case "params":
+		err = runParams(os.Args[2:])
+	case "snapshots":
+		err = runSnapshots(os.Args[2:])
+	case "keys":
+		err = runKeys(os.Args[2:])
+	default:
+		fmt.Fprintln(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	if err != nil {
+		closeStore()
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		os.Exit(1)
+	}
+}
+
+// connectDB opens the store on first use and checks that its schema matches this build
+func connectDB() error {
+	if store != nil {
+		return nil
+	}
+	opened, err := state.OpenStore(state.StoreConfig{
+		Backend: os.Getenv("STORAGE_BACKEND"),
+		Postgres: state.DBConfig{
+			Host: os.Getenv("DB_HOST"), Port: mustAtoi(os.Getenv("DB_PORT"), 5432),
+			User: os.Getenv("DB_USER"), Password: os.Getenv("DB_PASSWORD"),
+			DBName: os.Getenv("DB_NAME"), SSLMode: os.Getenv("DB_SSLMODE"),
+		},
+		SQLitePath: os.Getenv("SQLITE_PATH"),
+	})
+	if err != nil {
+		return err
+	}
+	store = opened
+	// The SQLite store migrates itself on open; PostgreSQL is migrated with cmd/migrate
+	if state.DB == nil {
+		return nil
+	}
+
+	if err := state.CheckSchemaCompatibility(); err != nil {
+		return err
+	}
+
+	statuses, err := state.GetMigrationStatus()
+	if err != nil {
+		return err
+	}
+	for _, s := range statuses {
+		if !s.Applied {
+			return fmt.Errorf("database schema is behind this build (migration %04d_%s is pending), run \"migrate up\" first", s.Version, s.Name)
+		}
+	}
+	return nil
+}
+
+// closeStore closes the store if it was opened
+func closeStore() {
+	if store != nil {
+		store.Close()
+		store = nil
+	}
+}
+
+// defaultActor names the operator in the audit log: AVM_ACTOR, or else the OS user
+func defaultActor() string {
+	if actor := os.Getenv("AVM_ACTOR"); actor != "" {
+		return actor
+	}
+	if u, err := user.Current(); err == nil {
+		return u.Username
+	}
+	return ""
+}
+
+// auditInfo checks the actor and reason of a change recorded in the audit log
+func auditInfo(actor, reason string) (types.AuditInfo, error) {
+	actor, reason = strings.TrimSpace(actor), strings.TrimSpace(reason)
+	if actor == "" {
+		return types.AuditInfo{}, fmt.Errorf("-actor or AVM_ACTOR is required for changes recorded in the audit log")
+	}
+	if reason == "" {
+		return types.AuditInfo{}, fmt.Errorf("-reason is required for changes recorded in the audit log")
+	}
+	return types.AuditInfo{Actor: actor, Reason: reason}, nil
+}
+
+// Helper to convert string to int with a default value
+func mustAtoi(s string, defaultValue int) int {
+	i, err := strconv.Atoi(s)
+	if err != nil {
+		return defaultValue
+	}
+	return i
+}
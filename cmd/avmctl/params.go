@@ -0,0 +1,407 @@
+/*
+
+This file implements "avmctl params", which manages the stored versions of the scoring parameters.
+
+Parameter files hold a single JSON or YAML object with every field of types.ScoringParameters,
+as written by "params export". Wherever a command takes a reference to a set of parameters, it
+accepts "active", a version number, or the path of a parameter file.
+
+*/
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/elys-network/avm/internal/analyzer"
+	"github.com/elys-network/avm/internal/avm"
+	"github.com/elys-network/avm/internal/state"
+	"github.com/elys-network/avm/internal/types"
+	"sigs.k8s.io/yaml"
+)
+
+const paramsUsage = `Usage: avmctl params <command> [flags] [arguments]
+
+Commands:
+  list                  List all versions and show which one is active
+  export [REF]          Write a set of parameters (default: active) as JSON or YAML
+  import FILE           Validate a parameter file and save it as a new version
+  diff REF_A REF_B      Show the parameters that differ between two sets, field by field
+  validate REF          Check a set of parameters with the analyzer's validation
+  activate VERSION      Make a stored version the active one
+  rollback              Reactivate the version that was active before the current one
+
+REF is "active", a version number, or the path of a JSON or YAML parameter file.
+
+Flags (before the arguments):
+  -config NAME          Scoring config name (default: ` + avm.DEFAULT_SCORING_CONFIG_NAME + `)
+  -format json|yaml     export: output format (default: json)
+  -out FILE             export: write to FILE instead of stdout
+  -activate             import: make the new version active
+  -reason TEXT          import, activate, rollback: why the change is made (required)
+  -actor NAME           import, activate, rollback: who makes the change (default: AVM_ACTOR or the OS user)
+
+import, activate and rollback are recorded in the audit log with the actor, the reason and the
+changed parameters. The running AVM picks up a newly activated version at the start of its next cycle.`
+
+// runParams dispatches an "avmctl params" command
+func runParams(args []string) error {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, paramsUsage)
+		os.Exit(2)
+	}
+	command := args[0]
+
+	flags := flag.NewFlagSet("params "+command, flag.ExitOnError)
+	flags.Usage = func() { fmt.Fprintln(os.Stderr, paramsUsage) }
+	configName := flags.String("config", avm.DEFAULT_SCORING_CONFIG_NAME, "scoring config name")
+	format := flags.String("format", "json", "export format: json or yaml")
+	out := flags.String("out", "", "export output file")
+	activate := flags.Bool("activate", false, "activate the imported version")
+	reason := flags.String("reason", "", "why the change is made")
+	actor := flags.String("actor", defaultActor(), "who makes the change")
+	flags.Parse(args[1:])
+	rest := flags.Args()
+
+	expectArgs := func(n int) {
+		if len(rest) != n {
+			fmt.Fprintln(os.Stderr, paramsUsage)
+			os.Exit(2)
+		}
+	}
+
+	switch command {
+	case "list":
+		expectArgs(0)
+		return paramsList(*configName)
+	case "export":
+		ref := "active"
+		if len(rest) == 1 {
+			ref = rest[0]
+		} else {
+			expectArgs(0)
+		}
+		return paramsExport(*configName, ref, *format, *out)
+	case "import":
+		expectArgs(1)
+		audit, err := auditInfo(*actor, *reason)
+		if err != nil {
+			return err
+		}
+		return paramsImport(*configName, rest[0], *activate, audit)
+	case "diff":
+		expectArgs(2)
+		return paramsDiff(*configName, rest[0], rest[1])
+	case "validate":
+		expectArgs(1)
+		return paramsValidate(*configName, rest[0])
+	case "activate":
+		expectArgs(1)
+		version, err := strconv.Atoi(rest[0])
+		if err != nil {
+			return fmt.Errorf("VERSION must be a number, got %q", rest[0])
+		}
+		audit, err := auditInfo(*actor, *reason)
+		if err != nil {
+			return err
+		}
+		return paramsActivate(*configName, version, audit)
+	case "rollback":
+		expectArgs(0)
+		audit, err := auditInfo(*actor, *reason)
+		if err != nil {
+			return err
+		}
+		return paramsRollback(*configName, audit)
+	default:
+		fmt.Fprintln(os.Stderr, paramsUsage)
+		os.Exit(2)
+	}
+	return nil
+}
+
+// paramsList prints every version of the config
+func paramsList(configName string) error {
+	if err := connectDB(); err != nil {
+		return err
+	}
+
+	versions, err := store.ListScoringParameters(configName)
+	if err != nil {
+		return err
+	}
+	if len(versions) == 0 {
+		fmt.Printf("No scoring parameters stored for config %q\n", configName)
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "VERSION\tPARAMS ID\tACTIVE\tCREATED AT\tLAST ACTIVATED AT")
+	for _, v := range versions {
+		active := ""
+		if v.IsActive {
+			active = "*"
+		}
+		fmt.Fprintf(w, "%d\t%d\t%s\t%s\t%s\n", v.Version, v.ParamsID, active,
+			v.CreatedAt.UTC().Format("2006-01-02 15:04:05"), v.ActivatedAt.UTC().Format("2006-01-02 15:04:05"))
+	}
+	return w.Flush()
+}
+
+// paramsExport writes a set of parameters as JSON or YAML
+func paramsExport(configName, ref, format, out string) error {
+	params, _, err := resolveParams(configName, ref)
+	if err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(params, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode parameters: %w", err)
+	}
+	switch format {
+	case "json":
+		data = append(data, '\n')
+	case "yaml":
+		data, err = yaml.JSONToYAML(data)
+		if err != nil {
+			return fmt.Errorf("failed to encode parameters as YAML: %w", err)
+		}
+	default:
+		return fmt.Errorf("unsupported format %q, must be json or yaml", format)
+	}
+
+	if out == "" {
+		_, err = os.Stdout.Write(data)
+		return err
+	}
+	if err := os.WriteFile(out, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", out, err)
+	}
+	fmt.Fprintf(os.Stderr, "Wrote %s\n", out)
+	return nil
+}
+
+// paramsImport saves a validated parameter file as the config's next version
+func paramsImport(configName, path string, activate bool, audit types.AuditInfo) error {
+	params, err := readParamsFile(path)
+	if err != nil {
+		return err
+	}
+	if err := analyzer.ValidateScoringParameters(params); err != nil {
+		return fmt.Errorf("%s is invalid: %w", path, err)
+	}
+
+	if err := connectDB(); err != nil {
+		return err
+	}
+
+	version, err := store.NextScoringParametersVersion(configName)
+	if err != nil {
+		return err
+	}
+	if _, err := store.SaveScoringParameters(params, configName, version, activate, audit); err != nil {
+		return err
+	}
+
+	fmt.Printf("Saved %s as version %d of config %q\n", path, version, configName)
+	if activate {
+		fmt.Println("Version", version, "is now active. The AVM applies it at the start of its next cycle.")
+	}
+	return nil
+}
+
+// paramsDiff prints the parameters that differ between two sets
+func paramsDiff(configName, refA, refB string) error {
+	paramsA, labelA, err := resolveParams(configName, refA)
+	if err != nil {
+		return err
+	}
+	paramsB, labelB, err := resolveParams(configName, refB)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("--- %s\n+++ %s\n", labelA, labelB)
+	return printChanges(types.DiffScoringParameters(paramsA, paramsB))
+}
+
+// paramsValidate checks a set of parameters with the analyzer's validation
+func paramsValidate(configName, ref string) error {
+	params, label, err := resolveParams(configName, ref)
+	if err != nil {
+		return err
+	}
+	if err := analyzer.ValidateScoringParameters(params); err != nil {
+		return fmt.Errorf("%s is invalid: %w", label, err)
+	}
+	fmt.Printf("%s is valid\n", label)
+	return nil
+}
+
+// paramsActivate makes a stored version active and prints what changed
+func paramsActivate(configName string, version int, audit types.AuditInfo) error {
+	if err := connectDB(); err != nil {
+		return err
+	}
+
+	target, err := store.LoadScoringParametersVersion(configName, version)
+	if err != nil {
+		return err
+	}
+	if err := analyzer.ValidateScoringParameters(target.Parameters); err != nil {
+		return fmt.Errorf("version %d is invalid and cannot be activated: %w", version, err)
+	}
+
+	previous, err := store.LoadActiveScoringParametersVersion(configName)
+	if err != nil && !errors.Is(err, state.ErrScoringParametersNotFound) {
+		return err
+	}
+
+	if _, err := store.ActivateScoringParameters(configName, version, audit); err != nil {
+		return err
+	}
+
+	fmt.Printf("Version %d of config %q is now active. The AVM applies it at the start of its next cycle.\n", version, configName)
+	if previous != nil {
+		fmt.Printf("--- version %d\n+++ version %d\n", previous.Version, version)
+		return printChanges(types.DiffScoringParameters(previous.Parameters, target.Parameters))
+	}
+	return nil
+}
+
+// paramsRollback reactivates the previously active version and prints what changed
+func paramsRollback(configName string, audit types.AuditInfo) error {
+	if err := connectDB(); err != nil {
+		return err
+	}
+
+	current, err := store.LoadActiveScoringParametersVersion(configName)
+	if err != nil {
+		return err
+	}
+
+	restored, err := store.RollbackScoringParameters(configName, audit)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Rolled back config %q from version %d to version %d. The AVM applies it at the start of its next cycle.\n",
+		configName, current.Version, restored.Version)
+	fmt.Printf("--- version %d\n+++ version %d\n", current.Version, restored.Version)
+	return printChanges(types.DiffScoringParameters(current.Parameters, restored.Parameters))
+}
+
+// resolveParams loads the parameters a reference points to, with a label describing them.
+// A reference is "active", a version number or a file path.
+func resolveParams(configName, ref string) (types.ScoringParameters, string, error) {
+	version, versionErr := strconv.Atoi(ref)
+	if ref != "active" && versionErr != nil {
+		params, err := readParamsFile(ref)
+		return params, ref, err
+	}
+
+	if err := connectDB(); err != nil {
+		return types.ScoringParameters{}, "", err
+	}
+
+	var v *types.ScoringParametersVersion
+	var err error
+	if ref == "active" {
+		v, err = store.LoadActiveScoringParametersVersion(configName)
+	} else {
+		v, err = store.LoadScoringParametersVersion(configName, version)
+	}
+	if err != nil {
+		return types.ScoringParameters{}, "", err
+	}
+	return v.Parameters, fmt.Sprintf("version %d", v.Version), nil
+}
+
+// readParamsFile reads a JSON or YAML parameter file. YAML is detected by a .yaml or .yml
+// extension. Every parameter must be present and unknown fields are rejected, so a typo
+// cannot silently leave a parameter at zero.
+func readParamsFile(path string) (types.ScoringParameters, error) {
+	var params types.ScoringParameters
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return params, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		data, err = yaml.YAMLToJSON(data)
+		if err != nil {
+			return params, fmt.Errorf("failed to parse YAML in %s: %w", path, err)
+		}
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return params, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	missing := make([]string, 0)
+	for _, field := range types.ScoringParameterFields() {
+		if _, ok := fields[field]; !ok {
+			missing = append(missing, field)
+		}
+	}
+	if len(missing) > 0 {
+		sort.Strings(missing)
+		return params, fmt.Errorf("%s is missing parameters: %s", path, strings.Join(missing, ", "))
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&params); err != nil {
+		return params, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	if _, err := decoder.Token(); err != io.EOF {
+		return params, fmt.Errorf("%s must contain a single object", path)
+	}
+
+	return params, nil
+}
+
+// printChanges prints parameter changes as a table
+func printChanges(changes []types.ScoringParameterChange) error {
+	if len(changes) == 0 {
+		fmt.Println("No differences")
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "PARAMETER\tFROM\tTO\tCHANGE")
+	for _, c := range changes {
+		change := "-"
+		if c.From != 0 {
+			change = fmt.Sprintf("%+.2f%%", (c.To-c.From)/abs(c.From)*100)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", c.Field, formatParam(c.From), formatParam(c.To), change)
+	}
+	return w.Flush()
+}
+
+// formatParam formats a parameter value without trailing zeros
+func formatParam(value float64) string {
+	return strconv.FormatFloat(value, 'f', -1, 64)
+}
+
+func abs(x float64) float64 {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
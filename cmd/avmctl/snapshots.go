@@ -0,0 +1,205 @@
+/*
+
+This file implements "avmctl snapshots", which exports cycle snapshots to files and runs the
+snapshot retention job by hand.
+
+*/
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/elys-network/avm/internal/export"
+)
+
+const snapshotsUsage = `Usage: avmctl snapshots <command> [flags]
+
+Commands:
+  export                Write the snapshots taken within a date range as flattened tables
+  archive               Archive snapshots older than the retention period and delete them
+
+Flags:
+  -from DATE            export: start of the range, inclusive (default: 7 days before -to)
+  -to DATE              export: end of the range, exclusive (default: now)
+  -format FORMAT        csv, jsonl or parquet (default: csv, or SNAPSHOT_ARCHIVE_FORMAT for archive)
+  -table TABLE          export: write only snapshots, positions, allocations or receipts
+  -out PATH             export: output directory, or output file with -table (default: stdout)
+  -dir DIR              archive: archive directory (default: SNAPSHOT_ARCHIVE_DIR)
+  -older-than-days N    archive: retention period in days (default: SNAPSHOT_RETENTION_DAYS)
+
+DATE is an RFC 3339 timestamp or a YYYY-MM-DD day (UTC). Without -table, export writes every
+table and cycles.jsonl, the complete snapshots, to the -out directory.`
+
+// runSnapshots dispatches an "avmctl snapshots" command
+func runSnapshots(args []string) error {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, snapshotsUsage)
+		os.Exit(2)
+	}
+	command := args[0]
+
+	flags := flag.NewFlagSet("snapshots "+command, flag.ExitOnError)
+	flags.Usage = func() { fmt.Fprintln(os.Stderr, snapshotsUsage) }
+	from := flags.String("from", "", "start of the export range")
+	to := flags.String("to", "", "end of the export range")
+	format := flags.String("format", "", "csv, jsonl or parquet")
+	table := flags.String("table", "", "single table to export")
+	out := flags.String("out", "", "export output directory or file")
+	dir := flags.String("dir", os.Getenv("SNAPSHOT_ARCHIVE_DIR"), "archive directory")
+	olderThanDays := flags.Int("older-than-days", mustAtoi(os.Getenv("SNAPSHOT_RETENTION_DAYS"), 0), "retention period in days")
+	flags.Parse(args[1:])
+	if flags.NArg() != 0 {
+		fmt.Fprintln(os.Stderr, snapshotsUsage)
+		os.Exit(2)
+	}
+
+	switch command {
+	case "export":
+		if *format == "" {
+			*format = string(export.FormatCSV)
+		}
+		return snapshotsExport(*from, *to, *format, *table, *out)
+	case "archive":
+		if *format == "" {
+			*format = os.Getenv("SNAPSHOT_ARCHIVE_FORMAT")
+		}
+		return snapshotsArchive(*dir, *format, *olderThanDays)
+	default:
+		fmt.Fprintln(os.Stderr, snapshotsUsage)
+		os.Exit(2)
+	}
+	return nil
+}
+
+// snapshotsExport writes the snapshots taken within [from, to) as one table or a directory of files
+func snapshotsExport(fromStr, toStr, formatName, tableName, out string) error {
+	format, err := export.ParseFormat(formatName)
+	if err != nil {
+		return err
+	}
+
+	to := time.Now().UTC()
+	if toStr != "" {
+		if to, err = parseDate(toStr); err != nil {
+			return fmt.Errorf("invalid -to: %w", err)
+		}
+	}
+	from := to.Add(-7 * 24 * time.Hour)
+	if fromStr != "" {
+		if from, err = parseDate(fromStr); err != nil {
+			return fmt.Errorf("invalid -from: %w", err)
+		}
+	}
+	if !from.Before(to) {
+		return fmt.Errorf("-from must be before -to")
+	}
+
+	if err := connectDB(); err != nil {
+		return err
+	}
+	snapshots, err := store.GetCyclesInRange(from, to)
+	if err != nil {
+		return err
+	}
+	dataset := export.Flatten(snapshots)
+
+	if tableName != "" {
+		table, err := export.ParseTable(tableName)
+		if err != nil {
+			return err
+		}
+		if out == "" {
+			return dataset.WriteTable(os.Stdout, table, format)
+		}
+		f, err := os.Create(out)
+		if err != nil {
+			return fmt.Errorf("failed to create %s: %w", out, err)
+		}
+		defer f.Close()
+		if err := dataset.WriteTable(f, table, format); err != nil {
+			return err
+		}
+		if err := f.Close(); err != nil {
+			return fmt.Errorf("failed to write %s: %w", out, err)
+		}
+		fmt.Fprintf(os.Stderr, "Wrote %d snapshots to %s\n", len(snapshots), out)
+		return nil
+	}
+
+	if out == "" {
+		return fmt.Errorf("-out DIR is required when exporting every table")
+	}
+	paths, err := dataset.WriteFiles(out, format)
+	if err != nil {
+		return err
+	}
+	fullPath := filepath.Join(out, export.FullSnapshotsFile)
+	f, err := os.Create(fullPath)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", fullPath, err)
+	}
+	defer f.Close()
+	if err := export.WriteSnapshotsJSONL(f, snapshots); err != nil {
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("failed to write %s: %w", fullPath, err)
+	}
+
+	for _, path := range append(paths, fullPath) {
+		fmt.Fprintf(os.Stderr, "Wrote %s\n", path)
+	}
+	fmt.Fprintf(os.Stderr, "Exported %d snapshots from %s to %s\n", len(snapshots), from.Format(time.RFC3339), to.Format(time.RFC3339))
+	return nil
+}
+
+// snapshotsArchive runs the retention job once with the given settings
+func snapshotsArchive(dir, formatName string, olderThanDays int) error {
+	if dir == "" {
+		return fmt.Errorf("-dir or SNAPSHOT_ARCHIVE_DIR is required")
+	}
+	if olderThanDays <= 0 {
+		return fmt.Errorf("-older-than-days or SNAPSHOT_RETENTION_DAYS must be a positive number of days")
+	}
+	format, err := export.ParseFormat(formatName)
+	if err != nil {
+		return err
+	}
+
+	if err := connectDB(); err != nil {
+		return err
+	}
+	before := time.Now().Add(-time.Duration(olderThanDays) * 24 * time.Hour)
+	result, err := export.ArchiveSnapshots(store, dir, format, before)
+	if result != nil {
+		for i, day := range result.Days {
+			fmt.Printf("Archived %s to %s\n", day, result.Directories[i])
+		}
+	}
+	if err != nil {
+		return err
+	}
+	if len(result.Days) == 0 {
+		fmt.Println("No snapshots to archive")
+		return nil
+	}
+	fmt.Printf("Archived %d snapshots over %d days, deleted %d rows\n", result.Snapshots, len(result.Days), result.Deleted)
+	return nil
+}
+
+// parseDate reads an RFC 3339 timestamp or a YYYY-MM-DD day, taken as midnight UTC
+func parseDate(s string) (time.Time, error) {
+	if t, err := time.Parse(time.DateOnly, s); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%q is neither an RFC 3339 timestamp nor a YYYY-MM-DD day", s)
+	}
+	return t.UTC(), nil
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"text/tabwriter"
+
+	"github.com/elys-network/avm/internal/logger"
+	"github.com/elys-network/avm/internal/state"
+	"github.com/joho/godotenv"
+	"github.com/rs/zerolog/log"
+)
+
+const usage = `Usage: migrate <command>
+
+Commands:
+  up          Apply all pending migrations
+  down [N]    Roll back the last N applied migrations (default 1)
+  status      List migrations and whether they are applied
+
+The database is configured with DB_HOST, DB_PORT, DB_USER, DB_PASSWORD, DB_NAME and DB_SSLMODE.`
+
+// main applies, rolls back or lists the database schema migrations.
+func main() {
+	if err := godotenv.Load(); err != nil {
+		log.Warn().Msg("Warning: .env file not found. Relying on OS environment variables.")
+	}
+	logger.Initialize(os.Getenv("LOG_LEVEL"))
+
+	if len(os.Args) < 2 {
+		fmt.Fprintln(os.Stderr, usage)
+		os.Exit(2)
+	}
+	command := os.Args[1]
+
+	steps := 1
+	switch command {
+	case "up", "status":
+		if len(os.Args) > 2 {
+			fmt.Fprintln(os.Stderr, usage)
+			os.Exit(2)
+		}
+	case "down":
+		if len(os.Args) > 3 {
+			fmt.Fprintln(os.Stderr, usage)
+			os.Exit(2)
+		}
+		if len(os.Args) == 3 {
+			n, err := strconv.Atoi(os.Args[2])
+			if err != nil || n <= 0 {
+				fmt.Fprintf(os.Stderr, "N must be a positive integer, got %q\n", os.Args[2])
+				os.Exit(2)
+			}
+			steps = n
+		}
+	default:
+		fmt.Fprintln(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	dbCfg := state.DBConfig{
+		Host: os.Getenv("DB_HOST"), Port: mustAtoi(os.Getenv("DB_PORT"), 5432),
+		User: os.Getenv("DB_USER"), Password: os.Getenv("DB_PASSWORD"),
+		DBName: os.Getenv("DB_NAME"), SSLMode: os.Getenv("DB_SSLMODE"),
+	}
+	if err := state.InitDB(dbCfg); err != nil {
+		log.Fatal().Err(err).Msg("Failed to initialize database")
+	}
+	defer state.CloseDB()
+
+	switch command {
+	case "up":
+		applied, err := state.MigrateUp()
+		if err != nil {
+			log.Fatal().Err(err).Int("applied", applied).Msg("Migration failed")
+		}
+		log.Info().Int("applied", applied).Msg("Database is up to date")
+	case "down":
+		rolledBack, err := state.MigrateDown(steps)
+		if err != nil {
+			log.Fatal().Err(err).Int("rolledBack", rolledBack).Msg("Rollback failed")
+		}
+		log.Info().Int("rolledBack", rolledBack).Msg("Rollback complete")
+	case "status":
+		if err := printStatus(); err != nil {
+			log.Fatal().Err(err).Msg("Failed to get migration status")
+		}
+	}
+}
+
+// printStatus writes a table of all migrations to stdout
+func printStatus() error {
+	statuses, err := state.GetMigrationStatus()
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "VERSION\tNAME\tSTATUS\tAPPLIED AT")
+	for _, s := range statuses {
+		status := "pending"
+		switch {
+		case s.Unknown:
+			status = "applied (unknown to this build)"
+		case s.Modified:
+			status = "applied (modified since)"
+		case s.Applied:
+			status = "applied"
+		}
+		appliedAt := "-"
+		if s.AppliedAt != nil {
+			appliedAt = s.AppliedAt.UTC().Format("2006-01-02 15:04:05 UTC")
+		}
+		fmt.Fprintf(w, "%04d\t%s\t%s\t%s\n", s.Version, s.Name, status, appliedAt)
+	}
+	return w.Flush()
+}
+
+// Helper to convert string to int with a default value
+func mustAtoi(s string, defaultValue int) int {
+	i, err := strconv.Atoi(s)
+	if err != nil {
+		return defaultValue
+	}
+	return i
+}
@@ -51,10 +51,14 @@ func CalculatePoolScore(pool types.Pool, params types.ScoringParameters) (types.
 
 	scoreLogger.Debug().
 		Uint64("poolID", uint64(pool.ID)).
-		Str("tokenA", pool.TokenA.Symbol).
-		Str("tokenB", pool.TokenB.Symbol).
+		Str("assets", pool.Symbols()).
 		Msg("Pool and parameter validation passed, proceeding with score calculation")
 
+	poolVolatility, err := CalculatePoolVolatility(pool)
+	if err != nil {
+		return types.PoolScoreResult{}, errors.Join(errors.New("pool volatility calculation failed"), err)
+	}
+
 	//  Initialize Result Struct
 	// Pre-populate known values like ID and volatility
 	result := types.PoolScoreResult{
@@ -69,7 +73,7 @@ func CalculatePoolScore(pool types.Pool, params types.ScoringParameters) (types.
 			BonusScoreComponent  float64 `json:"bonus_score_component"`
 			SentimentAdjustment  float64 `json:"sentiment_adjustment,omitempty"`
 		}{
-			AnnualizedVolatility: pool.TokenA.Volatility, // Store the base volatility
+			AnnualizedVolatility: poolVolatility, // Store the base volatility
 		},
 	}
 
@@ -98,7 +102,13 @@ func CalculatePoolScore(pool types.Pool, params types.ScoringParameters) (types.
 	}
 	result.Components.SentimentAdjustment = sentimentAdjustment
 
-	ilRisk, err := CalculateILRisk(pool.TokenA.Volatility, pool.IsSmartShielded, params)
+	// Impermanent loss comes from the pool's asset prices moving relative to each other
+	divergenceVolatility, err := CalculateDivergenceVolatility(pool)
+	if err != nil {
+		return types.PoolScoreResult{}, errors.Join(errors.New("divergence volatility calculation failed"), err)
+	}
+
+	ilRisk, err := CalculateILRisk(divergenceVolatility, pool.IsSmartShielded, params)
 	if err != nil {
 		return types.PoolScoreResult{}, errors.Join(errors.New("IL risk calculation failed"), err)
 	}
@@ -236,8 +246,7 @@ func CalculateRewardScore(weightedAPR float64, pool types.Pool, params types.Sco
 
 		scoreLogger.Debug().
 			Uint64("poolID", uint64(pool.ID)).
-			Str("tokenA", pool.TokenA.Symbol).
-			Str("tokenB", pool.TokenB.Symbol).
+			Str("assets", pool.Symbols()).
 			Msg("Pool has zero volume - using only APR component for reward score")
 	} else {
 		// Calculate volume component for non-zero volume
@@ -259,8 +268,7 @@ func CalculateRewardScore(weightedAPR float64, pool types.Pool, params types.Sco
 
 	scoreLogger.Debug().
 		Uint64("poolID", uint64(pool.ID)).
-		Str("tokenA", pool.TokenA.Symbol).
-		Str("tokenB", pool.TokenB.Symbol).
+		Str("assets", pool.Symbols()).
 		Float64("inputWeightedAPR", weightedAPR).
 		Float64("aprCoefficient", params.AprCoefficient).
 		Float64("aprScoreComponent", aprScorePart).
@@ -411,8 +419,7 @@ func CalculateWeightedAPR(pool types.Pool, params types.ScoringParameters) (floa
 	//  Optional Debugging
 	scoreLogger.Debug().
 		Uint64("poolID", uint64(pool.ID)).
-		Str("tokenA", pool.TokenA.Symbol).
-		Str("tokenB", pool.TokenB.Symbol).
+		Str("assets", pool.Symbols()).
 		Float64("rawEdenAPR", pool.EdenRewardsAPR).
 		Float64("edenWeight", params.EdenWeight).
 		Float64("weightedEdenComponent", edenComponent).
@@ -462,7 +469,12 @@ func CalculateRiskScore(ilRisk float64, agePenalty float64, sentimentAdjustment
 		return 0, errors.New("IL penalty calculation resulted in non-finite value")
 	}
 
-	volatilityPenalty := params.VolatilityCoefficient * pool.TokenA.Volatility
+	poolVolatility, err := CalculatePoolVolatility(pool)
+	if err != nil {
+		return 0, err
+	}
+
+	volatilityPenalty := params.VolatilityCoefficient * poolVolatility
 	if math.IsNaN(volatilityPenalty) || math.IsInf(volatilityPenalty, 0) {
 		return 0, errors.New("volatility penalty calculation resulted in non-finite value")
 	}
@@ -474,12 +486,11 @@ func CalculateRiskScore(ilRisk float64, agePenalty float64, sentimentAdjustment
 
 	scoreLogger.Debug().
 		Uint64("poolID", uint64(pool.ID)).
-		Str("tokenA", pool.TokenA.Symbol).
-		Str("tokenB", pool.TokenB.Symbol).
+		Str("assets", pool.Symbols()).
 		Float64("inputIlRisk", ilRisk).
 		Float64("ilRiskCoefficient", params.IlRiskCoefficient).
 		Float64("ilPenaltyComponent", ilPenalty).
-		Float64("inputTokenAVolatility", pool.TokenA.Volatility).
+		Float64("inputPoolVolatility", poolVolatility).
 		Float64("volatilityCoefficient", params.VolatilityCoefficient).
 		Float64("volatilityPenaltyComponent", volatilityPenalty).
 		Float64("inputAgePenalty", agePenalty).
@@ -538,8 +549,7 @@ func CalculateLiquidityScore(pool types.Pool, params types.ScoringParameters) (f
 	//  Optional Debugging
 	scoreLogger.Debug().
 		Uint64("poolID", uint64(pool.ID)).
-		Str("tokenA", pool.TokenA.Symbol).
-		Str("tokenB", pool.TokenB.Symbol).
+		Str("assets", pool.Symbols()).
 		Float64("rawTvlUSD", pool.TvlUSD).
 		Float64("effectiveMinTvlThreshold", params.MinTVLThreshold).
 		Float64("log10InputForTvl", logInput).
@@ -665,8 +675,7 @@ func CalculateAgePenalty(pool types.Pool, params types.ScoringParameters) (float
 	if params.PoolMaturityDays == 0 {
 		scoreLogger.Debug().
 			Uint64("poolID", uint64(pool.ID)).
-			Str("tokenA", pool.TokenA.Symbol).
-			Str("tokenB", pool.TokenB.Symbol).
+			Str("assets", pool.Symbols()).
 			Msg("PoolMaturityDays is zero, no age penalty applied")
 		return 0.0, nil
 	}
@@ -680,8 +689,7 @@ func CalculateAgePenalty(pool types.Pool, params types.ScoringParameters) (float
 	if pool.AgeInDays >= params.PoolMaturityDays {
 		scoreLogger.Debug().
 			Uint64("poolID", uint64(pool.ID)).
-			Str("tokenA", pool.TokenA.Symbol).
-			Str("tokenB", pool.TokenB.Symbol).
+			Str("assets", pool.Symbols()).
 			Int("currentPoolAgeDays", pool.AgeInDays).
 			Int("maturityThresholdDays", params.PoolMaturityDays).
 			Msg("Pool is mature, no age penalty applied")
@@ -706,8 +714,7 @@ func CalculateAgePenalty(pool types.Pool, params types.ScoringParameters) (float
 
 	scoreLogger.Debug().
 		Uint64("poolID", uint64(pool.ID)).
-		Str("tokenA", pool.TokenA.Symbol).
-		Str("tokenB", pool.TokenB.Symbol).
+		Str("assets", pool.Symbols()).
 		Int("currentPoolAgeDays", pool.AgeInDays).
 		Int("maturityThresholdDays", params.PoolMaturityDays).
 		Float64("maturityCompletionScale", maturityScale).
@@ -739,8 +746,7 @@ func CalculateSentimentAdjustment(pool types.Pool, params types.ScoringParameter
 	if pool.SentimentScore == 0 || params.SentimentImpactFactor == 0 {
 		scoreLogger.Debug().
 			Uint64("poolID", uint64(pool.ID)).
-			Str("tokenA", pool.TokenA.Symbol).
-			Str("tokenB", pool.TokenB.Symbol).
+			Str("assets", pool.Symbols()).
 			Float64("rawSentimentScore", pool.SentimentScore).
 			Float64("sentimentImpactFactor", params.SentimentImpactFactor).
 			Msg("Sentiment adjustment is zero due to zero score or zero factor")
@@ -754,8 +760,7 @@ func CalculateSentimentAdjustment(pool types.Pool, params types.ScoringParameter
 
 	scoreLogger.Debug().
 		Uint64("poolID", uint64(pool.ID)).
-		Str("tokenA", pool.TokenA.Symbol).
-		Str("tokenB", pool.TokenB.Symbol).
+		Str("assets", pool.Symbols()).
 		Float64("rawSentimentScore", pool.SentimentScore).
 		Float64("sentimentImpactFactor", params.SentimentImpactFactor).
 		Float64("finalSentimentAdjustment", sentimentAdjustment).
@@ -772,11 +777,13 @@ func ValidatePoolData(pool types.Pool) error {
 	}
 
 	// Validate token information
-	if pool.TokenA.Symbol == "" {
-		return errors.New("TokenA symbol cannot be empty")
+	if len(pool.Assets) < 2 {
+		return errors.New("pool must have at least 2 assets")
 	}
-	if pool.TokenB.Symbol == "" {
-		return errors.New("TokenB symbol cannot be empty")
+	for i, asset := range pool.Assets {
+		if asset.Token.Symbol == "" {
+			return fmt.Errorf("asset %d symbol cannot be empty", i)
+		}
 	}
 
 	// Validate financial data - must be non-negative
@@ -799,11 +806,10 @@ func ValidatePoolData(pool types.Pool) error {
 	}
 
 	// Validate volatility data
-	if pool.TokenA.Volatility < 0 {
-		return errors.New("TokenA volatility cannot be negative")
-	}
-	if math.IsNaN(pool.TokenA.Volatility) || math.IsInf(pool.TokenA.Volatility, 0) {
-		return errors.New("TokenA volatility must be finite")
+	for _, asset := range pool.Assets {
+		if err := validateAssetVolatility(asset); err != nil {
+			return err
+		}
 	}
 
 	// Validate pool age
@@ -812,7 +818,10 @@ func ValidatePoolData(pool types.Pool) error {
 	}
 
 	// Validate weights (should sum to approximately 1.0)
-	totalWeight := pool.WeightA + pool.WeightB
+	totalWeight := 0.0
+	for _, asset := range pool.Assets {
+		totalWeight += asset.Weight
+	}
 	if totalWeight <= 0 || math.Abs(totalWeight-1.0) > 0.01 {
 		return errors.New("pool weights must be positive and sum to approximately 1.0")
 	}
@@ -931,8 +940,7 @@ func CalculatePoolScores(pools []types.Pool, params types.ScoringParameters) ([]
 		scoreLogger.Debug().
 			Int("poolIndex", i).
 			Uint64("poolID", uint64(pool.ID)).
-			Str("tokenA", pool.TokenA.Symbol).
-			Str("tokenB", pool.TokenB.Symbol).
+			Str("assets", pool.Symbols()).
 			Msg("Processing pool in batch")
 
 		// Calculate score for individual pool using existing function
@@ -0,0 +1,196 @@
+/*
+
+This file contains the volatility measures of pools with two or more assets.
+
+The pool volatility is the price risk of holding the pool's non-USDC assets, and the divergence
+volatility drives impermanent loss, which comes from the assets' prices moving apart. Both reduce
+to the volatility of the other asset for a pool of one token against USDC.
+
+*/
+
+package analyzer
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/elys-network/avm/internal/types"
+)
+
+// MIN_CORRELATION_RETURNS is the number of overlapping returns needed to estimate the correlation of two assets
+const MIN_CORRELATION_RETURNS = 24
+
+// CalculatePoolVolatility returns the annualized volatility of the pool's non-USDC assets, weighted by
+// their share of the pool's value. A pool whose only weighted assets are USDC gets USDC's own volatility.
+func CalculatePoolVolatility(pool types.Pool) (float64, error) {
+	if len(pool.Assets) == 0 {
+		return 0, errors.New("pool has no assets")
+	}
+
+	weightedVolatility, totalWeight := 0.0, 0.0
+	usdcWeightedVolatility, usdcWeight := 0.0, 0.0
+	for _, asset := range pool.Assets {
+		if err := validateAssetVolatility(asset); err != nil {
+			return 0, err
+		}
+		if asset.Token.IsUSDC() {
+			usdcWeightedVolatility += asset.Weight * asset.Token.Volatility
+			usdcWeight += asset.Weight
+			continue
+		}
+		weightedVolatility += asset.Weight * asset.Token.Volatility
+		totalWeight += asset.Weight
+	}
+
+	if totalWeight <= 0 {
+		if usdcWeight <= 0 {
+			return 0, errors.New("pool has no assets with positive weight")
+		}
+		weightedVolatility, totalWeight = usdcWeightedVolatility, usdcWeight
+	}
+
+	volatility := weightedVolatility / totalWeight
+	if math.IsNaN(volatility) || math.IsInf(volatility, 0) {
+		return 0, errors.New("pool volatility calculation resulted in non-finite value")
+	}
+	return volatility, nil
+}
+
+// CalculateDivergenceVolatility returns the annualized volatility of the pool's asset prices relative to
+// each other, which is what causes impermanent loss.
+//
+// For weights w and the covariance C of the assets' log returns, the loss of a weighted pool against
+// holding grows with D = Σ wᵢCᵢᵢ - ΣΣ wᵢwⱼCᵢⱼ. The result is sqrt(4D), scaled so that a 50/50 pool
+// of a token against USDC gets the token's volatility, as the IL risk calculation expects. Correlations
+// are estimated from the tokens' hourly price data; assets without enough overlapping data are treated
+// as uncorrelated. Highly correlated assets, such as stablecoins in the same pool, get a low result.
+func CalculateDivergenceVolatility(pool types.Pool) (float64, error) {
+	n := len(pool.Assets)
+	if n < 2 {
+		return 0, fmt.Errorf("pool has %d assets, at least 2 are required", n)
+	}
+
+	returns := make([]map[time.Time]float64, n)
+	for i, asset := range pool.Assets {
+		if err := validateAssetVolatility(asset); err != nil {
+			return 0, err
+		}
+		returns[i] = hourlyLogReturns(asset.Token.PriceData)
+	}
+
+	divergence := 0.0
+	for i, assetI := range pool.Assets {
+		varianceI := assetI.Token.Volatility * assetI.Token.Volatility
+		divergence += assetI.Weight * varianceI
+
+		for j, assetJ := range pool.Assets {
+			covariance := varianceI
+			if i != j {
+				correlation, ok := returnCorrelation(returns[i], returns[j])
+				if !ok {
+					correlation = 0
+				}
+				covariance = correlation * assetI.Token.Volatility * assetJ.Token.Volatility
+			}
+			divergence -= assetI.Weight * assetJ.Weight * covariance
+		}
+	}
+
+	// Rounding can leave a tiny negative value for perfectly correlated assets
+	if divergence < 0 {
+		divergence = 0
+	}
+
+	divergenceVolatility := math.Sqrt(4 * divergence)
+	if math.IsNaN(divergenceVolatility) || math.IsInf(divergenceVolatility, 0) {
+		return 0, errors.New("divergence volatility calculation resulted in non-finite value")
+	}
+
+	scoreLogger.Debug().
+		Uint64("poolID", uint64(pool.ID)).
+		Str("assets", pool.Symbols()).
+		Float64("divergenceVolatility", divergenceVolatility).
+		Msg("Divergence volatility calculated")
+
+	return divergenceVolatility, nil
+}
+
+// validateAssetVolatility checks the weight and volatility of a pool asset
+func validateAssetVolatility(asset types.PoolAsset) error {
+	if math.IsNaN(asset.Weight) || math.IsInf(asset.Weight, 0) || asset.Weight < 0 || asset.Weight > 1 {
+		return fmt.Errorf("%s has invalid weight: %f", asset.Token.Symbol, asset.Weight)
+	}
+	if math.IsNaN(asset.Token.Volatility) || math.IsInf(asset.Token.Volatility, 0) {
+		return fmt.Errorf("%s volatility must be finite", asset.Token.Symbol)
+	}
+	if asset.Token.Volatility < 0 {
+		return fmt.Errorf("%s volatility cannot be negative", asset.Token.Symbol)
+	}
+	return nil
+}
+
+// hourlyLogReturns returns the log return ending at each hour of the price data,
+// skipping pairs that are not one hour apart or have non-positive prices
+func hourlyLogReturns(prices []types.PriceData) map[time.Time]float64 {
+	sorted := make([]types.PriceData, len(prices))
+	copy(sorted, prices)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Timestamp.Before(sorted[j].Timestamp)
+	})
+
+	returns := make(map[time.Time]float64, len(sorted))
+	for i := 1; i < len(sorted); i++ {
+		previous, current := sorted[i-1], sorted[i]
+		if previous.Price <= 0 || current.Price <= 0 || current.Timestamp.Sub(previous.Timestamp) != time.Hour {
+			continue
+		}
+		returns[current.Timestamp.UTC()] = math.Log(current.Price / previous.Price)
+	}
+	return returns
+}
+
+// returnCorrelation returns the Pearson correlation of two return series over their common hours.
+// It reports false if they overlap by fewer than MIN_CORRELATION_RETURNS hours or either is constant.
+func returnCorrelation(a, b map[time.Time]float64) (float64, bool) {
+	var pairsA, pairsB []float64
+	for hour, returnA := range a {
+		if returnB, ok := b[hour]; ok {
+			pairsA = append(pairsA, returnA)
+			pairsB = append(pairsB, returnB)
+		}
+	}
+
+	count := len(pairsA)
+	if count < MIN_CORRELATION_RETURNS {
+		return 0, false
+	}
+
+	var meanA, meanB float64
+	for i := 0; i < count; i++ {
+		meanA += pairsA[i]
+		meanB += pairsB[i]
+	}
+	meanA /= float64(count)
+	meanB /= float64(count)
+
+	var covariance, varianceA, varianceB float64
+	for i := 0; i < count; i++ {
+		deltaA := pairsA[i] - meanA
+		deltaB := pairsB[i] - meanB
+		covariance += deltaA * deltaB
+		varianceA += deltaA * deltaA
+		varianceB += deltaB * deltaB
+	}
+	if varianceA == 0 || varianceB == 0 {
+		return 0, false
+	}
+
+	correlation := covariance / math.Sqrt(varianceA*varianceB)
+	if math.IsNaN(correlation) || math.IsInf(correlation, 0) {
+		return 0, false
+	}
+	return math.Max(-1, math.Min(1, correlation)), true
+}
@@ -0,0 +1,221 @@
+/*
+
+This file contains the functions for aggregating token-level exposure across pools and
+enforcing the per-token exposure limit on a set of target allocations.
+
+*/
+
+package analyzer
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/elys-network/avm/internal/types"
+)
+
+// CalculateTokenExposures aggregates the vault's exposure to each underlying token.
+// A pool contributes Weight × allocation for each of its tokens, so a 50/50 ATOM/USDC pool
+// holding 20% of the vault adds 10% ATOM exposure and 10% USDC exposure.
+// The result is keyed by token denom.
+func CalculateTokenExposures(
+	allocations map[types.PoolID]float64,
+	poolsDataMap map[types.PoolID]types.Pool,
+) (map[string]types.TokenExposure, error) {
+	exposures := make(map[string]types.TokenExposure)
+
+	// Iterate in a stable order so the pool lists are deterministic
+	poolIDs := make([]types.PoolID, 0, len(allocations))
+	for id := range allocations {
+		poolIDs = append(poolIDs, id)
+	}
+	sort.Slice(poolIDs, func(i, j int) bool { return poolIDs[i] < poolIDs[j] })
+
+	for _, id := range poolIDs {
+		allocation := allocations[id]
+		if math.IsNaN(allocation) || math.IsInf(allocation, 0) {
+			return nil, fmt.Errorf("allocation for pool %d is not finite: %f", id, allocation)
+		}
+		if allocation <= 0 {
+			continue
+		}
+
+		pool, exists := poolsDataMap[id]
+		if !exists {
+			return nil, fmt.Errorf("pool data not found for pool %d", id)
+		}
+
+		for _, asset := range pool.Assets {
+			if math.IsNaN(asset.Weight) || math.IsInf(asset.Weight, 0) || asset.Weight < 0 || asset.Weight > 1 {
+				return nil, fmt.Errorf("pool %d has invalid weight for %s: %f", id, asset.Token.Symbol, asset.Weight)
+			}
+			if asset.Token.Denom == "" {
+				return nil, fmt.Errorf("pool %d has a token with an empty denom", id)
+			}
+
+			exposure := exposures[asset.Token.Denom]
+			exposure.Denom = asset.Token.Denom
+			exposure.Symbol = asset.Token.Symbol
+			exposure.ExposurePercent += asset.Weight * allocation
+			exposure.Pools = append(exposure.Pools, id)
+			exposures[asset.Token.Denom] = exposure
+		}
+	}
+
+	return exposures, nil
+}
+
+// enforceTokenExposureLimits reduces the allocations of pools that share an over-exposed token
+// until the token's aggregate exposure is within params.MaxTokenExposure, and redistributes the
+// freed allocation to the remaining pools in proportion to their scores.
+// Pools that were reduced are frozen so later redistributions cannot push them back up.
+//
+// The cap takes precedence over the other constraints. When the pools holding the token are all at
+// their minimum allocation, the lowest scored of them is dropped; freed allocation that no other
+// pool has room for is left unallocated, to be held as liquid USDC. The returned allocations then
+// sum to less than 1. The forced ELYS pool (elysPoolID, 0 if none) is never dropped; if it is the
+// only pool left to drop, ErrAllocationImpossible is returned.
+func enforceTokenExposureLimits(
+	allocations map[types.PoolID]float64,
+	elysPoolID types.PoolID,
+	poolScores map[types.PoolID]float64,
+	minAllocations map[types.PoolID]float64,
+	params types.ScoringParameters,
+	poolsDataMap map[types.PoolID]types.Pool,
+) (map[types.PoolID]float64, error) {
+	adjusted := make(map[types.PoolID]float64, len(allocations))
+	for id, alloc := range allocations {
+		adjusted[id] = alloc
+	}
+
+	frozen := make(map[types.PoolID]bool)
+
+	for iteration := 1; iteration <= maxAllocationIterations; iteration++ {
+		exposures, err := CalculateTokenExposures(adjusted, poolsDataMap)
+		if err != nil {
+			return nil, err
+		}
+
+		// Find the most over-exposed non-USDC token; exposure to the numeraire is reported but never capped
+		var worst types.TokenExposure
+		found := false
+		for _, exposure := range exposures {
+			if exposure.Denom == types.USDCDenom {
+				continue
+			}
+			if exposure.ExposurePercent > params.MaxTokenExposure+0.00001 &&
+				(!found || exposure.ExposurePercent > worst.ExposurePercent) {
+				worst = exposure
+				found = true
+			}
+		}
+		if !found {
+			return adjusted, nil
+		}
+
+		poolSelectorLogger.Debug().
+			Int("iteration", iteration).
+			Str("token", worst.Symbol).
+			Float64("exposure", worst.ExposurePercent).
+			Float64("maxExposure", params.MaxTokenExposure).
+			Msg("Token exposure above limit. Scaling down pools holding it")
+
+		// Scale every pool holding the token by the same factor, never below its minimum
+		scaleFactor := params.MaxTokenExposure / worst.ExposurePercent
+		freed := 0.0
+		for _, id := range worst.Pools {
+			reduced := math.Max(adjusted[id]*scaleFactor, minAllocations[id])
+			if reduced < adjusted[id] {
+				freed += adjusted[id] - reduced
+				adjusted[id] = reduced
+			}
+			frozen[id] = true
+		}
+
+		if freed <= 0.00001 {
+			// Every pool holding the token is at its minimum: drop the lowest scored one,
+			// keeping the forced ELYS pool
+			var dropped types.PoolID
+			droppable := false
+			for _, id := range worst.Pools {
+				if id == elysPoolID && elysPoolID != 0 {
+					continue
+				}
+				if !droppable || poolScores[id] < poolScores[dropped] {
+					dropped = id
+					droppable = true
+				}
+			}
+			if !droppable {
+				return nil, fmt.Errorf("%w: exposure to %s (%.6f) exceeds MaxTokenExposure (%.4f) with only the forced ELYS pool %d left to drop",
+					ErrAllocationImpossible, worst.Symbol, worst.ExposurePercent, params.MaxTokenExposure, elysPoolID)
+			}
+			freed = adjusted[dropped]
+			delete(adjusted, dropped)
+
+			poolSelectorLogger.Warn().
+				Uint64("poolID", uint64(dropped)).
+				Str("token", worst.Symbol).
+				Float64("exposure", worst.ExposurePercent).
+				Float64("maxExposure", params.MaxTokenExposure).
+				Msg("Token exposure above limit with all its pools at minimum allocation. Dropping the lowest scored pool")
+		}
+
+		unplaced := redistributeAllocation(adjusted, freed, poolScores, frozen, params.MaxAllocation)
+		if unplaced > 0.00001 {
+			poolSelectorLogger.Warn().
+				Str("token", worst.Symbol).
+				Float64("freed", freed).
+				Float64("unallocated", unplaced).
+				Msg("No pool has room for the allocation freed by the token exposure limit. Leaving it in liquid USDC")
+		}
+	}
+
+	return nil, fmt.Errorf("token exposure enforcement failed to converge after %d iterations", maxAllocationIterations)
+}
+
+// redistributeAllocation hands out the given amount to non-frozen pools in proportion to their
+// scores, water-filling up to maxAllocation. Returns the amount no pool had room for.
+func redistributeAllocation(
+	allocations map[types.PoolID]float64,
+	amount float64,
+	poolScores map[types.PoolID]float64,
+	frozen map[types.PoolID]bool,
+	maxAllocation float64,
+) float64 {
+	remaining := amount
+
+	for remaining > 0.00001 {
+		totalScore := 0.0
+		for id, score := range poolScores {
+			if _, selected := allocations[id]; selected && !frozen[id] && allocations[id] < maxAllocation-0.00001 {
+				totalScore += score
+			}
+		}
+		if totalScore <= 0 {
+			return remaining
+		}
+
+		distributed := 0.0
+		for id, score := range poolScores {
+			if _, selected := allocations[id]; !selected || frozen[id] || allocations[id] >= maxAllocation-0.00001 {
+				continue
+			}
+			share := remaining * score / totalScore
+			room := maxAllocation - allocations[id]
+			if share > room {
+				share = room
+			}
+			allocations[id] += share
+			distributed += share
+		}
+
+		if distributed <= 0 {
+			return remaining
+		}
+		remaining -= distributed
+	}
+
+	return 0
+}
@@ -0,0 +1,187 @@
+/*
+
+This file contains the function for explaining a pool's score: each component of CalculatePoolScore
+broken down into its terms, with the pool data and scoring parameters each term was derived from.
+
+*/
+
+package analyzer
+
+import (
+	"errors"
+	"math"
+
+	"github.com/elys-network/avm/internal/types"
+)
+
+// ExplainPoolScore scores the pool like CalculatePoolScore and breaks each score component down into
+// its terms. The terms are computed with the same functions as the score, so they add up to it.
+func ExplainPoolScore(pool types.Pool, params types.ScoringParameters) (types.PoolScoreExplanation, error) {
+	result, err := CalculatePoolScore(pool, params)
+	if err != nil {
+		return types.PoolScoreExplanation{}, err
+	}
+
+	// --- Reward ---
+	weightedAPR, err := CalculateWeightedAPR(pool, params)
+	if err != nil {
+		return types.PoolScoreExplanation{}, errors.Join(errors.New("weighted APR calculation failed"), err)
+	}
+	volumeTerm := 0.0
+	if pool.Volume7dUSD > 0 {
+		volumeTerm = params.TradingVolumeCoefficient * math.Log10(pool.Volume7dUSD)
+	}
+	reward := types.ScoreComponentExplanation{
+		Name:  "reward",
+		Value: result.Components.RewardScoreComponent,
+		Terms: []types.ScoreTerm{
+			{
+				Name:    "apr",
+				Formula: "apr_coefficient × weighted_apr, where weighted_apr = (eden_rewards_apr × eden_weight + usdc_fees_apr × usdc_fee_weight + price_impact_apr × price_impact_weight) / (eden_weight + usdc_fee_weight + price_impact_weight)",
+				Inputs: map[string]interface{}{
+					"apr_coefficient":     params.AprCoefficient,
+					"weighted_apr":        weightedAPR,
+					"eden_rewards_apr":    pool.EdenRewardsAPR,
+					"eden_weight":         params.EdenWeight,
+					"usdc_fees_apr":       pool.UsdcFeesAPR,
+					"usdc_fee_weight":     params.UsdcFeeWeight,
+					"price_impact_apr":    pool.PriceImpactAPR,
+					"price_impact_weight": params.PriceImpactWeight,
+				},
+				Value: params.AprCoefficient * weightedAPR,
+			},
+			{
+				Name:    "volume",
+				Formula: "trading_volume_coefficient × log10(volume_7d_usd), 0 without volume",
+				Inputs: map[string]interface{}{
+					"trading_volume_coefficient": params.TradingVolumeCoefficient,
+					"volume_7d_usd":              pool.Volume7dUSD,
+				},
+				Value: volumeTerm,
+			},
+		},
+	}
+
+	// --- Risk ---
+	divergenceVolatility, err := CalculateDivergenceVolatility(pool)
+	if err != nil {
+		return types.PoolScoreExplanation{}, errors.Join(errors.New("divergence volatility calculation failed"), err)
+	}
+	agePenalty, err := CalculateAgePenalty(pool, params)
+	if err != nil {
+		return types.PoolScoreExplanation{}, errors.Join(errors.New("age penalty calculation failed"), err)
+	}
+	risk := types.ScoreComponentExplanation{
+		Name:  "risk",
+		Value: result.Components.RiskScoreComponent,
+		Terms: []types.ScoreTerm{
+			{
+				Name:    "impermanent_loss",
+				Formula: "il_risk_coefficient × il_risk, where il_risk = il_confidence_factor × divergence_volatility² × il_holding_period_years × (1 - smart_shield_reduction_factor for smart shielded pools)",
+				Inputs: map[string]interface{}{
+					"il_risk_coefficient":           params.IlRiskCoefficient,
+					"il_risk":                       result.Components.ILRisk,
+					"divergence_volatility":         divergenceVolatility,
+					"il_confidence_factor":          params.IlConfidenceFactor,
+					"il_holding_period_years":       params.IlHoldingPeriodYears,
+					"is_smart_shielded":             pool.IsSmartShielded,
+					"smart_shield_reduction_factor": params.SmartShieldReductionFactor,
+				},
+				Value: params.IlRiskCoefficient * result.Components.ILRisk,
+			},
+			{
+				Name:    "volatility",
+				Formula: "volatility_coefficient × annualized_volatility",
+				Inputs: map[string]interface{}{
+					"volatility_coefficient": params.VolatilityCoefficient,
+					"annualized_volatility":  result.Components.AnnualizedVolatility,
+				},
+				Value: params.VolatilityCoefficient * result.Components.AnnualizedVolatility,
+			},
+			{
+				Name:    "new_pool",
+				Formula: "new_pool_coefficient × (1 - age_in_days / pool_maturity_days) until the pool is mature, otherwise 0",
+				Inputs: map[string]interface{}{
+					"new_pool_coefficient": params.NewPoolCoefficient,
+					"age_in_days":          pool.AgeInDays,
+					"pool_maturity_days":   params.PoolMaturityDays,
+				},
+				Value: agePenalty,
+			},
+			{
+				Name:    "sentiment",
+				Formula: "sentiment_score × sentiment_impact_factor",
+				Inputs: map[string]interface{}{
+					"sentiment_score":         pool.SentimentScore,
+					"sentiment_impact_factor": params.SentimentImpactFactor,
+				},
+				Value: result.Components.SentimentAdjustment,
+			},
+		},
+	}
+
+	// --- Liquidity ---
+	liquidity := types.ScoreComponentExplanation{
+		Name:  "liquidity",
+		Value: result.Components.TvlScoreComponent,
+		Terms: []types.ScoreTerm{
+			{
+				Name:    "tvl",
+				Formula: "tvl_coefficient × log10(max(min_tvl_threshold, tvl_usd))",
+				Inputs: map[string]interface{}{
+					"tvl_coefficient":   params.TvlCoefficient,
+					"min_tvl_threshold": params.MinTVLThreshold,
+					"tvl_usd":           pool.TvlUSD,
+				},
+				Value: result.Components.TvlScoreComponent,
+			},
+		},
+	}
+
+	// --- Bonus ---
+	shieldBonus, err := CalculateSmartShieldBonus(pool, params)
+	if err != nil {
+		return types.PoolScoreExplanation{}, errors.Join(errors.New("smart shield bonus calculation failed"), err)
+	}
+	continuityBonus, err := CalculateContinuityBonus(pool, params)
+	if err != nil {
+		return types.PoolScoreExplanation{}, errors.Join(errors.New("continuity bonus calculation failed"), err)
+	}
+	bonus := types.ScoreComponentExplanation{
+		Name:  "bonus",
+		Value: result.Components.BonusScoreComponent,
+		Terms: []types.ScoreTerm{
+			{
+				Name:    "smart_shield",
+				Formula: "smart_shield_bonus for smart shielded pools, otherwise 0",
+				Inputs: map[string]interface{}{
+					"smart_shield_bonus": params.SmartShieldBonus,
+					"is_smart_shielded":  pool.IsSmartShielded,
+				},
+				Value: shieldBonus,
+			},
+			{
+				Name:    "continuity",
+				Formula: "continuity_coefficient × min(1, position_age_days / continuity_lookback_days) with a current position, otherwise 0",
+				Inputs: map[string]interface{}{
+					"continuity_coefficient":   params.ContinuityCoefficient,
+					"has_current_position":     pool.HasCurrentPosition,
+					"position_age_days":        pool.CurrentPositionAgeDays,
+					"continuity_lookback_days": params.ContinuityLookbackDays,
+				},
+				Value: continuityBonus,
+			},
+		},
+	}
+
+	assets := make([]string, len(pool.Assets))
+	for i, asset := range pool.Assets {
+		assets[i] = asset.Token.Symbol
+	}
+	return types.PoolScoreExplanation{
+		PoolID:     pool.ID,
+		Assets:     assets,
+		Score:      result.Score,
+		Components: []types.ScoreComponentExplanation{reward, risk, liquidity, bonus},
+	}, nil
+}
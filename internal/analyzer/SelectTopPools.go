@@ -59,7 +59,7 @@ func SelectTopPools(scoredPools []types.PoolScoreResult, params types.ScoringPar
 
 		// Check if this pool contains ELYS (uelys denom)
 		if poolData, exists := poolsDataMap[poolScore.PoolID]; exists {
-			if poolData.TokenA.Denom == "uelys" || poolData.TokenB.Denom == "uelys" {
+			if poolData.HasDenom("uelys") {
 				if !elysPoolFound {
 					// First ELYS pool found
 					elysPoolID = poolScore.PoolID
@@ -143,14 +143,18 @@ func SelectTopPools(scoredPools []types.PoolScoreResult, params types.ScoringPar
 }
 
 // DetermineTargetAllocations calculates the target percentage allocation for each selected pool
-// based on their scores, respecting Min/Max allocation constraints and ensuring ELYS pools
-// receive at least the minimum forced allocation.
+// based on their scores, respecting Min/Max allocation constraints, ensuring ELYS pools
+// receive at least the minimum forced allocation, and capping the aggregate exposure to any
+// single non-USDC token across all selected pools at MaxTokenExposure.
+// The allocations sum to 1, unless the exposure cap could only be met by leaving part of the vault
+// in liquid USDC; see enforceTokenExposureLimits.
 // Returns error if allocation is impossible or constraints are invalid.
 func DetermineTargetAllocations(
 	selectedPoolIDs []types.PoolID,
 	scoredPoolsMap map[types.PoolID]types.PoolScoreResult,
 	params types.ScoringParameters,
 	elysPoolID types.PoolID, // ID of the ELYS pool that requires minimum allocation (0 if none)
+	poolsDataMap map[types.PoolID]types.Pool, // Pool data used to aggregate token exposure
 ) (map[types.PoolID]float64, error) {
 
 	// --- 1. Handle Edge Cases ---
@@ -185,6 +189,14 @@ func DetermineTargetAllocations(
 		return nil, fmt.Errorf("ElysForcedAllocationMinimum (%.4f) must be between 0 and 1", params.ElysForcedAllocationMinimum)
 	}
 
+	// Validate token exposure limit
+	if math.IsNaN(params.MaxTokenExposure) || math.IsInf(params.MaxTokenExposure, 0) {
+		return nil, errors.New("MaxTokenExposure is not finite")
+	}
+	if params.MaxTokenExposure <= 0 || params.MaxTokenExposure > 1 {
+		return nil, fmt.Errorf("MaxTokenExposure (%.4f) must be greater than 0 and at most 1", params.MaxTokenExposure)
+	}
+
 	// Check if minimum constraints can be satisfied with ELYS forced allocation
 	elysPoolInSelection := false
 	for _, poolID := range selectedPoolIDs {
@@ -380,7 +392,27 @@ func DetermineTargetAllocations(
 		return nil, errors.New("final allocation sum is zero")
 	}
 
+	// --- 6. Enforce Token-Level Exposure Limits ---
+	poolScores := make(map[types.PoolID]float64, len(validPools))
+	minAllocations := make(map[types.PoolID]float64, len(validPools))
+	for _, p := range validPools {
+		poolScores[p.ID] = p.Score
+		minAllocations[p.ID] = params.MinAllocation
+		if p.ID == elysPoolID && elysPoolID != 0 {
+			minAllocations[p.ID] = params.ElysForcedAllocationMinimum
+		}
+	}
+	targetAllocations, err := enforceTokenExposureLimits(targetAllocations, elysPoolID, poolScores, minAllocations, params, poolsDataMap)
+	if err != nil {
+		return nil, err
+	}
+
 	// Final validation - check all constraints are satisfied including ELYS minimum
+	if elysPoolInSelection && elysPoolID != 0 {
+		if _, ok := targetAllocations[elysPoolID]; !ok {
+			return nil, fmt.Errorf("forced ELYS pool %d was dropped from the final allocations", elysPoolID)
+		}
+	}
 	for id, alloc := range targetAllocations {
 		minRequired := params.MinAllocation
 		if id == elysPoolID && elysPoolID != 0 {
@@ -393,6 +425,18 @@ func DetermineTargetAllocations(
 		}
 	}
 
+	// Final validation - token exposure limits
+	exposures, err := CalculateTokenExposures(targetAllocations, poolsDataMap)
+	if err != nil {
+		return nil, fmt.Errorf("failed to calculate token exposures: %w", err)
+	}
+	for _, exposure := range exposures {
+		if exposure.Denom != types.USDCDenom && exposure.ExposurePercent > params.MaxTokenExposure+0.00001 {
+			return nil, fmt.Errorf("final exposure to %s (%.6f) exceeds MaxTokenExposure (%.4f)",
+				exposure.Symbol, exposure.ExposurePercent, params.MaxTokenExposure)
+		}
+	}
+
 	// Log final allocations with ELYS pool highlighting
 	poolSelectorLogger.Info().Msg("Final target allocations calculated")
 	for id, alloc := range targetAllocations {
@@ -403,6 +447,13 @@ func DetermineTargetAllocations(
 			Bool("isElysPool", isElysPpool).
 			Msg("Pool allocation percentage")
 	}
+	for _, exposure := range exposures {
+		poolSelectorLogger.Info().
+			Str("token", exposure.Symbol).
+			Float64("exposure", exposure.ExposurePercent*100).
+			Int("pools", len(exposure.Pools)).
+			Msg("Token exposure percentage")
+	}
 
 	return targetAllocations, nil
 }
@@ -0,0 +1,225 @@
+/*
+
+This file implements the per-pool PnL attribution. It compares two consecutive cycle snapshots
+and splits each pool's PnL into the sources below.
+
+For the holding period, from the end of the previous cycle to the start of the current one, the
+change in value of the LP shares held throughout is split into:
+  - LP fees: accrued to the pool, estimated from its price impact APR
+  - price return: the change in value of the pool's tokens, had the vault held them instead
+  - impermanent loss: the rest, the LP position versus holding the tokens
+The Eden and USDC rewards are paid outside the pool, so they are estimated from their APRs on
+top of the change in value.
+
+The execution costs of the current cycle are added from its receipts: the realized slippage
+(TotalSlippageUSD) is split between the pools in proportion to each action's expected slippage,
+and the gas (TotalGasFeeUSD) evenly between the actions.
+
+Prices, APRs and pool balances come from the pool metrics points at each end of the period.
+
+*/
+
+package attribution
+
+import (
+	"math"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/elys-network/avm/internal/types"
+)
+
+const year = 365 * 24 * time.Hour
+
+// Attribute returns the PnL attribution of every pool the vault held or acted on in current.
+// previous is the snapshot saved before current, or nil if there is none; startMetrics and
+// endMetrics are the pool metrics at the start of previous and current.
+func Attribute(previous *types.CycleSnapshot, current types.CycleSnapshot, startMetrics, endMetrics map[types.PoolID]types.PoolMetricsPoint) []types.PoolAttribution {
+	rows := make(map[types.PoolID]*types.PoolAttribution)
+	var period time.Duration
+	if previous != nil && current.Timestamp.After(previous.Timestamp) {
+		period = current.Timestamp.Sub(previous.Timestamp)
+	}
+	row := func(poolID types.PoolID) *types.PoolAttribution {
+		if existing, ok := rows[poolID]; ok {
+			return existing
+		}
+		created := &types.PoolAttribution{
+			CycleNumber:   current.CycleNumber,
+			Timestamp:     current.Timestamp.UTC(),
+			PoolID:        poolID,
+			PeriodSeconds: period.Seconds(),
+		}
+		rows[poolID] = created
+		return created
+	}
+
+	if previous != nil && period > 0 {
+		attributeHolding(previous.FinalPositions, current.InitialPositions, period, startMetrics, endMetrics, row)
+	}
+	attributeExecution(current, row)
+
+	attributions := make([]types.PoolAttribution, 0, len(rows))
+	for _, attribution := range rows {
+		attribution.TotalPnLUSD = attribution.LPFeesUSD + attribution.EdenRewardsUSD + attribution.UsdcRewardsUSD +
+			attribution.PriceReturnUSD + attribution.ImpermanentLossUSD - attribution.SlippageUSD - attribution.GasUSD
+		attributions = append(attributions, *attribution)
+	}
+	sort.Slice(attributions, func(i, j int) bool { return attributions[i].PoolID < attributions[j].PoolID })
+	return attributions
+}
+
+// attributeHolding splits the change in value of the positions held from the end of the previous
+// cycle to the start of the current one. Positions opened or closed outside the AVM in between
+// are skipped.
+func attributeHolding(
+	startPositions, endPositions []types.PositionSnapshot,
+	period time.Duration,
+	startMetrics, endMetrics map[types.PoolID]types.PoolMetricsPoint,
+	row func(types.PoolID) *types.PoolAttribution,
+) {
+	endByPool := make(map[types.PoolID]types.PositionSnapshot, len(endPositions))
+	for _, position := range endPositions {
+		endByPool[position.PoolID] = position
+	}
+	years := float64(period) / float64(year)
+
+	for _, start := range startPositions {
+		end, held := endByPool[start.PoolID]
+		startShares := parseAmount(start.LPShares)
+		endShares := parseAmount(end.LPShares)
+		if !held || startShares <= 0 || endShares <= 0 {
+			continue
+		}
+
+		// Value the shares held at the start, in case the position changed in between
+		startValue := start.EstimatedValueUSD
+		endValue := end.EstimatedValueUSD * startShares / endShares
+		attribution := row(start.PoolID)
+		attribution.StartValueUSD = startValue
+		attribution.EndValueUSD = endValue
+
+		startPoint, hasStart := startMetrics[start.PoolID]
+		endPoint, hasEnd := endMetrics[start.PoolID]
+		if hasStart {
+			edenAPR, usdcAPR, priceImpactAPR := startPoint.EdenRewardsAPR, startPoint.UsdcFeesAPR, startPoint.PriceImpactAPR
+			if hasEnd {
+				// Average the rates in force at each end of the period
+				edenAPR = (edenAPR + endPoint.EdenRewardsAPR) / 2
+				usdcAPR = (usdcAPR + endPoint.UsdcFeesAPR) / 2
+				priceImpactAPR = (priceImpactAPR + endPoint.PriceImpactAPR) / 2
+			}
+			attribution.LPFeesUSD = startValue * priceImpactAPR * years
+			attribution.EdenRewardsUSD = startValue * edenAPR * years
+			attribution.UsdcRewardsUSD = startValue * usdcAPR * years
+		}
+
+		priceReturn, complete := 0.0, false
+		if hasStart && hasEnd {
+			priceReturn, complete = holdingPriceReturn(startShares, startPoint, endPoint)
+		}
+		attribution.PriceReturnUSD = priceReturn
+		attribution.ImpermanentLossUSD = endValue - startValue - attribution.LPFeesUSD - priceReturn
+		attribution.MarketDataComplete = complete
+	}
+}
+
+// holdingPriceReturn returns the change in value, from the start to the end point, of the pool
+// tokens the LP shares were worth at the start. It reports false if the points lack the
+// balances or prices to compute it.
+func holdingPriceReturn(shares float64, start, end types.PoolMetricsPoint) (float64, bool) {
+	totalShares := parseAmount(start.TotalShares)
+	if totalShares <= 0 {
+		return 0, false
+	}
+	fraction := shares / totalShares
+	endPrices := assetPrices(end)
+
+	priceReturn := 0.0
+	for denom, startPrice := range assetPrices(start) {
+		endPrice, ok := endPrices[denom]
+		if !ok {
+			return 0, false
+		}
+		var amount float64
+		for _, asset := range start.Assets {
+			if asset.Denom == denom {
+				amount = parseAmount(asset.Amount) * fraction
+				break
+			}
+		}
+		priceReturn += amount * (endPrice - startPrice)
+	}
+	if len(endPrices) == 0 || math.IsNaN(priceReturn) || math.IsInf(priceReturn, 0) {
+		return 0, false
+	}
+	return priceReturn, true
+}
+
+// assetPrices returns the USD price of one raw unit of each asset of a metrics point, derived
+// from the asset's share of the pool's TVL
+func assetPrices(point types.PoolMetricsPoint) map[string]float64 {
+	prices := make(map[string]float64, len(point.Assets))
+	for _, asset := range point.Assets {
+		amount := parseAmount(asset.Amount)
+		if amount > 0 {
+			prices[asset.Denom] = asset.Weight * point.TvlUSD / amount
+		}
+	}
+	return prices
+}
+
+// attributeExecution splits the current cycle's slippage and gas between the pools acted on
+func attributeExecution(current types.CycleSnapshot, row func(types.PoolID) *types.PoolAttribution) {
+	if len(current.ActionReceipts) == 0 {
+		if current.TotalSlippageUSD != 0 || current.TotalGasFeeUSD != 0 {
+			attribution := row(0)
+			attribution.SlippageUSD = current.TotalSlippageUSD
+			attribution.GasUSD = current.TotalGasFeeUSD
+		}
+		return
+	}
+
+	// Weigh each pool's share of the slippage by its actions' expected slippage, or by the
+	// amounts moved if no action expected any
+	expectedWeights := make(map[types.PoolID]float64)
+	amountWeights := make(map[types.PoolID]float64)
+	var expectedTotal, amountTotal float64
+	for _, receipt := range current.ActionReceipts {
+		poolID := receipt.OriginalSubAction.PoolID()
+		row(poolID).GasUSD += current.TotalGasFeeUSD / float64(len(current.ActionReceipts))
+		if !receipt.Success {
+			continue
+		}
+		amount := math.Abs(receipt.ActualAmountUSD)
+		expectedWeights[poolID] += amount * receipt.OriginalSubAction.ExpectedSlippage
+		expectedTotal += amount * receipt.OriginalSubAction.ExpectedSlippage
+		amountWeights[poolID] += amount
+		amountTotal += amount
+	}
+
+	if current.TotalSlippageUSD == 0 {
+		return
+	}
+	weights, total := expectedWeights, expectedTotal
+	if total <= 0 {
+		weights, total = amountWeights, amountTotal
+	}
+	if total <= 0 {
+		row(0).SlippageUSD += current.TotalSlippageUSD
+		return
+	}
+	for poolID, weight := range weights {
+		row(poolID).SlippageUSD += current.TotalSlippageUSD * weight / total
+	}
+}
+
+// parseAmount parses an arbitrary precision integer string as a float; 0 if invalid
+func parseAmount(amount string) float64 {
+	value, err := strconv.ParseFloat(amount, 64)
+	if err != nil || math.IsNaN(value) || math.IsInf(value, 0) {
+		return 0
+	}
+	return value
+}
@@ -0,0 +1,49 @@
+/*
+
+This file generates and hashes the web API's keys.
+
+Keys are 32 random bytes, so a single unsalted SHA-256 is enough to store them: unlike passwords
+they cannot be guessed, and an unsalted hash can be looked up with an index.
+
+*/
+
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+)
+
+const (
+	// KeyPrefix starts every key, so leaked keys are easy to recognize
+	KeyPrefix = "avm_"
+	// displayPrefixLength is how much of a key is stored in clear to recognize it
+	displayPrefixLength = len(KeyPrefix) + 8
+	keyBytes            = 32
+)
+
+// GenerateAPIKey returns a new random API key
+func GenerateAPIKey() (string, error) {
+	buf := make([]byte, keyBytes)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate API key: %w", err)
+	}
+	return KeyPrefix + base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// HashAPIKey returns the hash stored for a key
+func HashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// DisplayPrefix returns the start of a key, which is stored in clear to recognize it
+func DisplayPrefix(key string) string {
+	if len(key) <= displayPrefixLength {
+		return key
+	}
+	return key[:displayPrefixLength]
+}
@@ -5,15 +5,25 @@ import (
 	"encoding/json"
 	"fmt"
 	"math"
+	"sort"
+	"sync"
 	"time"
 
+	sdkmath "cosmossdk.io/math"
 	"github.com/elys-network/avm/internal/analyzer"
+	"github.com/elys-network/avm/internal/attribution"
 	"github.com/elys-network/avm/internal/config"
 	datafetcher "github.com/elys-network/avm/internal/datafetcher"
+	"github.com/elys-network/avm/internal/events"
+	"github.com/elys-network/avm/internal/export"
 	"github.com/elys-network/avm/internal/logger"
+	"github.com/elys-network/avm/internal/metrics"
+	"github.com/elys-network/avm/internal/performance"
 	"github.com/elys-network/avm/internal/planner"
+	"github.com/elys-network/avm/internal/priceguard"
 	"github.com/elys-network/avm/internal/state"
 	"github.com/elys-network/avm/internal/types"
+	"github.com/elys-network/avm/internal/utils"
 	"github.com/elys-network/avm/internal/vault"
 
 	"github.com/google/uuid"
@@ -25,31 +35,53 @@ const (
 	// Export constants for use in main.go
 	DEFAULT_SCORING_CONFIG_NAME    = "default_avm_strategy"
 	DEFAULT_SCORING_CONFIG_VERSION = 1
+
+	// Oldest pool metrics point used as a pool's prices and APRs at a cycle start. Downsampled
+	// points start up to a day before the cycles they cover.
+	attributionMetricsMaxAge = 24 * time.Hour
 )
 
 // AVM represents the Autonomous Vault Manager with all its dependencies
 type AVM struct {
 	// Core dependencies
-	logger       zerolog.Logger
-	vault        vault.VaultManager
-	grpcClient   *grpc.ClientConn
-	scoringParams *types.ScoringParameters
+	logger          zerolog.Logger
+	vault           vault.VaultManager
+	grpcClient      *grpc.ClientConn
+	store           state.Store
+	scoringParams   *types.ScoringParameters
+	scoringParamsID *int64      // params_id of scoringParams; nil if unknown
+	events          *events.Bus // Receives the cycle events; nil publishes nothing
 	
 	// Configuration
 	configName    string
 	configVersion int
 	
 	// Runtime state
-	cycleCount int
+	cycleCount       int
+	rejectedParamsID *int64 // Active params_id that failed validation; not retried until another version is activated
+
+	// Operator controls, see control.go
+	controlMu      sync.Mutex
+	controlState   types.ControlState
+	cycleCancel    context.CancelFunc // Cancels the running cycle; nil between cycles
+	cycleStartedAt *time.Time         // Start of the running cycle; nil between cycles
+	triggerCh      chan struct{}      // Holds at most one triggered cycle for RunLoop
+
+	// Pools of the latest scoring cycle, see pools.go
+	poolsMu        sync.RWMutex
+	scoredUniverse *scoredUniverse // nil until a cycle has scored the pools
 }
 
 // Config holds the configuration for creating a new AVM instance
 type Config struct {
-	GRPCClient     *grpc.ClientConn
-	VaultManager   vault.VaultManager
-	ScoringParams  *types.ScoringParameters
-	ConfigName     string
-	ConfigVersion  int
+	GRPCClient      *grpc.ClientConn
+	VaultManager    vault.VaultManager
+	Store           state.Store
+	ScoringParams   *types.ScoringParameters
+	ScoringParamsID *int64      // params_id of ScoringParams; nil if unknown
+	Events          *events.Bus // Optional, receives the progress of each cycle
+	ConfigName      string
+	ConfigVersion   int
 }
 
 // NewAVM creates a new AVM instance with dependency injection
@@ -61,13 +93,31 @@ func NewAVM(cfg Config) (*AVM, error) {
 
 	// Create AVM instance
 	avm := &AVM{
-		logger:        logger.GetForComponent("avm_core"),
-		vault:         cfg.VaultManager,
-		grpcClient:    cfg.GRPCClient,
-		scoringParams: cfg.ScoringParams,
-		configName:    cfg.ConfigName,
-		configVersion: cfg.ConfigVersion,
-		cycleCount:    0,
+		logger:          logger.GetForComponent("avm_core"),
+		vault:           cfg.VaultManager,
+		grpcClient:      cfg.GRPCClient,
+		store:           cfg.Store,
+		scoringParams:   cfg.ScoringParams,
+		scoringParamsID: cfg.ScoringParamsID,
+		events:          cfg.Events,
+		configName:      cfg.ConfigName,
+		configVersion:   cfg.ConfigVersion,
+		cycleCount:      0,
+		triggerCh:       make(chan struct{}, 1),
+	}
+
+	// A pause or emergency stop set before a restart stays in effect
+	controlState, err := cfg.Store.LoadControlState()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load control state: %w", err)
+	}
+	avm.controlState = *controlState
+	if controlState.Mode != types.ControlModeRunning {
+		avm.logger.Warn().
+			Str("mode", string(controlState.Mode)).
+			Str("updatedBy", controlState.Actor).
+			Str("reason", controlState.Reason).
+			Msg("AVM starts with a persisted control mode, resume it to execute plans")
 	}
 
 	avm.logger.Info().
@@ -86,6 +136,9 @@ func validateAVMConfig(cfg Config) error {
 	if cfg.VaultManager == nil {
 		return fmt.Errorf("vault manager cannot be nil")
 	}
+	if cfg.Store == nil {
+		return fmt.Errorf("store cannot be nil")
+	}
 	if cfg.ScoringParams == nil {
 		return fmt.Errorf("scoring parameters cannot be nil")
 	}
@@ -108,43 +161,75 @@ func (a *AVM) RunLoop(ctx context.Context, interval time.Duration) {
 	defer ticker.Stop()
 
 	// Run first cycle immediately
-	a.cycleCount++
-	a.logger.Info().Int("cycle", a.cycleCount).Msg("Initiating AVM cycle")
-	a.RunCycle(ctx)
-	a.logger.Info().Int("cycle", a.cycleCount).Msg("AVM cycle completed")
+	a.runLoopCycle(ctx)
 
-	// Continue with ticker
+	// Continue with ticker, or earlier when an operator triggers a cycle
 	for {
 		select {
 		case <-ctx.Done():
 			a.logger.Info().Msg("AVM loop stopped due to context cancellation")
 			return
 		case <-ticker.C:
-			a.cycleCount++
-			a.logger.Info().Int("cycle", a.cycleCount).Msg("Initiating AVM cycle")
-			a.RunCycle(ctx)
-			a.logger.Info().Int("cycle", a.cycleCount).Msg("AVM cycle completed")
+			a.runLoopCycle(ctx)
+		case <-a.triggerCh:
+			a.logger.Info().Msg("Running triggered AVM cycle")
+			a.runLoopCycle(ctx)
+			// The next scheduled cycle is a full interval after the triggered one
+			ticker.Reset(interval)
 		}
 	}
 }
 
-// RunCycle executes a complete AVM rebalancing cycle
+// runLoopCycle runs one cycle of RunLoop, unless an emergency stop is active
+func (a *AVM) runLoopCycle(ctx context.Context) {
+	if a.ControlStatus().Mode == types.ControlModeEmergencyStopped {
+		a.logger.Warn().Msg("Skipping AVM cycle: emergency stop is active")
+		return
+	}
+	a.cycleCount++
+	a.logger.Info().Int("cycle", a.cycleCount).Msg("Initiating AVM cycle")
+	a.RunCycle(ctx)
+	a.logger.Info().Int("cycle", a.cycleCount).Msg("AVM cycle completed")
+}
+
+// RunCycle executes a complete AVM rebalancing cycle. It does nothing during an emergency stop, and
+// skips execution while paused.
 func (a *AVM) RunCycle(ctx context.Context) {
+	// cycleCtx is cancelled by an emergency stop. It covers steps 1 to 4 and is checked before each
+	// broadcast; execution and the final state use ctx, so they can record what already happened.
+	cycleCtx, finishCycle, ok := a.startCycle(ctx)
+	if !ok {
+		a.logger.Warn().Msg("Cycle skipped: emergency stop is active")
+		metrics.ObserveCycle(metrics.CycleOutcomeEmergencyStopped, 0)
+		return
+	}
+	defer finishCycle()
+
 	cycleStartTime := time.Now()
 
+	// Every return before the end of execution sets the cycle's outcome
+	outcome := metrics.CycleOutcomeAborted
+	defer func() { metrics.ObserveCycle(outcome, time.Since(cycleStartTime)) }()
+
 	// Generate unique cycle ID for tracing logs across the entire cycle
 	cycleID := uuid.New().String()
 	cycleLogger := a.logger.With().Str("cycle_id", cycleID).Logger()
 	
 	cycleLogger.Info().Msg("--- Starting AVM Cycle ---")
 
+	// Pick up a newly activated version of the scoring parameters. The whole set is swapped
+	// between cycles, and this cycle uses its own copy throughout.
+	paramsReload := a.reloadScoringParams(cycleLogger)
+	scoringParams := *a.scoringParams
+
 	// --- Initialize Cycle Snapshot ---
 	cycleSnapshot := types.CycleSnapshot{
-		CycleNumber:       a.getCycleNumber(), // Global cycle counter
-		Timestamp:         cycleStartTime,
-		ScoringParamsID:   a.getScoringParamsID(), // Helper to get current params ID
-		TransactionHashes: make([]string, 0),
-		ActionReceipts:    make([]types.ActionReceipt, 0),
+		CycleNumber:         a.getCycleNumber(), // Global cycle counter
+		Timestamp:           cycleStartTime,
+		ScoringParamsID:     a.scoringParamsID,
+		ScoringParamsReload: paramsReload,
+		TransactionHashes:   make([]string, 0),
+		ActionReceipts:      make([]types.ActionReceipt, 0),
 	}
 
 	cycleLogger.Info().
@@ -152,11 +237,34 @@ func (a *AVM) RunCycle(ctx context.Context) {
 		Time("timestamp", cycleStartTime).
 		Msg("Cycle snapshot initialized")
 
+	// Step transitions, the plan and the transactions for live subscribers
+	cycleEvents := a.startCycleEvents(cycleID, cycleSnapshot)
+	defer func() { cycleEvents.finish(outcome, &cycleSnapshot, time.Since(cycleStartTime)) }()
+
+	// Per-step durations, recorded on the snapshot
+	timer := utils.NewStepTimer()
+
+	// Pin every chain query up to planning to one block height, so pools, prices, vault
+	// positions and simulations all describe the same chain state
+	cycleEvents.startStep(events.StepFetch)
+	blockHeight, err := datafetcher.GetLatestBlockHeight(cycleCtx)
+	if err != nil {
+		cycleLogger.Error().Err(err).Msg("Cycle aborted: Failed to get the latest block height.")
+		return
+	}
+	queryCtx := utils.WithBlockHeight(cycleCtx, blockHeight)
+	cycleSnapshot.BlockHeight = blockHeight
+	cycleLogger = cycleLogger.With().Int64("block_height", blockHeight).Logger()
+	cycleLogger.Info().Msg("Pinned cycle queries to block height")
+
 	// --- Step 1: Data Fetching ---
 	cycleLogger.Info().Msg("Step 1: Fetching live on-chain data...")
+	stopStep := timer.Track("data_fetching")
 	
 	// Get supported tokens from vault before fetching pools
-	supportedTokens, err := a.vault.GetTradableDenoms()
+	stopSupportedTokens := timer.Track("supported_tokens")
+	supportedTokens, err := a.vault.GetTradableDenoms(queryCtx)
+	stopSupportedTokens()
 	if err != nil {
 		cycleLogger.Error().Err(err).Msg("Cycle aborted: Failed to get supported tokens from vault.")
 		return
@@ -164,68 +272,145 @@ func (a *AVM) RunCycle(ctx context.Context) {
 	
 	cycleLogger.Info().Int("supportedTokenCount", len(supportedTokens)).Msg("Retrieved supported tokens from vault")
 	
-	pools, err := datafetcher.GetPools(a.grpcClient, supportedTokens)
+	// Pools, volume, APRs and the token data referenced by the pools are fetched concurrently
+	// Tokens and pools with bad data are quarantined instead of aborting the cycle, if enabled
+	pools, tokenDataMap, quarantine, err := datafetcher.GetPools(queryCtx, a.grpcClient, supportedTokens, timer)
 	if err != nil {
 		cycleLogger.Error().Err(err).Msg("Cycle aborted: Failed to fetch pools.")
 		return
 	}
+	cycleSnapshot.Quarantine = quarantine
+	if len(quarantine.Tokens) > 0 || len(quarantine.Pools) > 0 {
+		cycleLogger.Warn().
+			Int("quarantinedTokens", len(quarantine.Tokens)).
+			Int("quarantinedPools", len(quarantine.Pools)).
+			Msg("Continuing without quarantined tokens and pools")
+	}
 	poolsDataMap := make(map[types.PoolID]types.Pool)
 	for _, p := range pools {
 		poolsDataMap[p.ID] = p
 	}
-
-	tokenDataMap, err := datafetcher.GetTokens(a.grpcClient)
-	if err != nil {
-		cycleLogger.Error().Err(err).Msg("Cycle aborted: Failed to fetch token data.")
-		return
-	}
+	stopStep()
+	cycleEvents.finishStep(map[string]interface{}{
+		"block_height":       blockHeight,
+		"pools":              len(poolsDataMap),
+		"tokens":             len(tokenDataMap),
+		"quarantined_tokens": len(quarantine.Tokens),
+		"quarantined_pools":  len(quarantine.Pools),
+	})
 	cycleLogger.Info().Int("pools", len(poolsDataMap)).Int("tokens", len(tokenDataMap)).Msg("Step 1: Data fetching complete.")
 
+	// Keep the fetched pool data as a time series; failures do not affect the cycle
+	a.recordPoolMetrics(cycleSnapshot, pools)
+
 	// --- Step 2: Vault State Assessment & Initial Snapshot Data ---
 	cycleLogger.Info().Msg("Step 2: Assessing current vault state...")
-	currentPositions, err := a.vault.GetPoolPositions()
+	cycleEvents.startStep(events.StepAssess)
+	stopStep = timer.Track("vault_state")
+	currentPositions, err := a.vault.GetPoolPositions(queryCtx)
 	if err != nil {
 		cycleLogger.Error().Err(err).Msg("Cycle aborted: Failed to get current positions.")
 		return
 	}
-	liquidUSDC, err := a.vault.GetLiquidUSDC()
+	liquidUSDC, err := a.vault.GetLiquidUSDC(queryCtx)
 	if err != nil {
 		cycleLogger.Error().Err(err).Msg("Cycle aborted: Failed to get liquid USDC.")
 		return
 	}
-	totalVaultValue, err := a.vault.GetTotalVaultValue()
+	totalVaultValue, err := a.vault.GetTotalVaultValue(queryCtx)
 	if err != nil {
 		cycleLogger.Error().Err(err).Msg("Cycle aborted: Failed to get total vault value.")
 		return
 	}
+	// The share supply only feeds the NAV per share, so the cycle continues without it
+	shareSupply, shareSupplyErr := a.vault.GetShareSupply(queryCtx)
+	if shareSupplyErr != nil {
+		cycleLogger.Warn().Err(shareSupplyErr).Msg("Failed to get vault share supply, NAV per share will not be recorded this cycle.")
+	}
 
 	// Populate initial snapshot state
 	cycleSnapshot.InitialVaultValueUSD = totalVaultValue
 	cycleSnapshot.InitialLiquidUSDC = liquidUSDC
 	cycleSnapshot.InitialPositions = a.convertToPositionSnapshots(currentPositions, poolsDataMap, totalVaultValue)
 
+	// Positions in quarantined pools are frozen: planning only sees the remaining positions and value
+	activePositions, frozenValueUSD := a.freezeQuarantinedPositions(currentPositions, quarantine)
+	plannableValueUSD := totalVaultValue - frozenValueUSD
+
+	stopStep()
 	cycleLogger.Info().Int("positions", len(currentPositions)).Float64("liquidUSDC", liquidUSDC).Float64("totalValue", totalVaultValue).Msg("Step 2: Vault state assessed.")
 
+	// --- Price Integrity Guard ---
+	// Cross-check oracle, AMM and reference prices before any decision is based on them
+	stopStep = timer.Track("price_integrity")
+	priceIntegrity := priceguard.CheckPriceIntegrity(tokenDataMap, pools, currentPositions)
+	cycleSnapshot.PriceIntegrity = &priceIntegrity
+	stopStep()
+	cycleEvents.finishStep(map[string]interface{}{
+		"positions":       len(currentPositions),
+		"liquid_usdc":     liquidUSDC,
+		"total_value_usd": totalVaultValue,
+		"halted":          priceIntegrity.HaltExecution,
+	})
+	if priceIntegrity.HaltExecution {
+		cycleLogger.Error().Str("reason", priceIntegrity.HaltReason).Msg("Cycle halted: Price integrity check failed.")
+		outcome = metrics.CycleOutcomeHalted
+		// Complete snapshot with no changes
+		cycleSnapshot.TargetAllocations = make(map[types.PoolID]float64)
+		cycleSnapshot.ActionPlan = types.ActionPlan{
+			GoalDescription:       "Execution halted - " + priceIntegrity.HaltReason,
+			SubActions:            []types.SubAction{},
+			EstimatedNetUSDChange: 0.0,
+		}
+		cycleSnapshot.FinalVaultValueUSD = totalVaultValue
+		cycleSnapshot.FinalLiquidUSDC = liquidUSDC
+		cycleSnapshot.FinalPositions = cycleSnapshot.InitialPositions
+		stopStep()
+		a.finalizeCycleSnapshot(&cycleSnapshot, poolsDataMap, timer)
+		a.saveCycleSnapshot(cycleSnapshot)
+		a.logEndOfCycleState(ctx, cycleStartTime, cycleLogger)
+		return
+	}
+	// Only record the NAV once the prices behind the vault value have passed the guard
+	if shareSupplyErr == nil {
+		a.recordVaultPerformance(cycleSnapshot, shareSupply)
+	}
+	if len(priceIntegrity.ExcludedPools) > 0 {
+		pools = priceguard.ExcludeFlaggedPools(pools, priceIntegrity)
+		cycleLogger.Warn().
+			Int("excludedPools", len(priceIntegrity.ExcludedPools)).
+			Int("remainingPools", len(pools)).
+			Msg("Excluded pools holding tokens that failed the price integrity check")
+	}
+
 	// --- Step 3: Analysis & Scoring ---
 	cycleLogger.Info().Msg("Step 3: Analyzing and scoring pools...")
-	scoredPools, err := analyzer.CalculatePoolScores(pools, *a.scoringParams)
+	cycleEvents.startStep(events.StepScore)
+	stopStep = timer.Track("analysis")
+	scoredPools, err := analyzer.CalculatePoolScores(pools, scoringParams)
 	if err != nil {
 		cycleLogger.Error().Err(err).Msg("Cycle aborted: Failed to score pools.")
 		return
 	}
-	selectedPoolIDs, elysPoolID, err := analyzer.SelectTopPools(scoredPools, *a.scoringParams, poolsDataMap)
+	selectedPoolIDs, elysPoolID, err := analyzer.SelectTopPools(scoredPools, scoringParams, poolsDataMap)
 	if err != nil {
 		cycleLogger.Error().Err(err).Msg("Cycle aborted: Failed to select top pools.")
 		return
 	}
 
+	// Keep every score in the snapshot, so cycles can be compared
+	cycleSnapshot.PoolScores = make([]types.PoolScoreResult, len(scoredPools))
+	copy(cycleSnapshot.PoolScores, scoredPools)
+	sort.Slice(cycleSnapshot.PoolScores, func(i, j int) bool {
+		return cycleSnapshot.PoolScores[i].PoolID < cycleSnapshot.PoolScores[j].PoolID
+	})
+
 	// Log ELYS pool information
 	if elysPoolID != 0 {
 		if poolData, exists := poolsDataMap[elysPoolID]; exists {
 			cycleLogger.Info().
 				Uint64("elysPoolID", uint64(elysPoolID)).
-				Str("tokenA", poolData.TokenA.Symbol).
-				Str("tokenB", poolData.TokenB.Symbol).
+				Str("assets", poolData.Symbols()).
 				Msg("ELYS pool identified and included in selection")
 		}
 	} else {
@@ -234,6 +419,7 @@ func (a *AVM) RunCycle(ctx context.Context) {
 	
 	if len(selectedPoolIDs) == 0 {
 		cycleLogger.Info().Msg("No pools selected for investment. No rebalancing needed.")
+		outcome = metrics.CycleOutcomeNoPoolsSelected
 		// Complete snapshot with no changes
 		cycleSnapshot.TargetAllocations = make(map[types.PoolID]float64)
 		cycleSnapshot.ActionPlan = types.ActionPlan{
@@ -248,15 +434,19 @@ func (a *AVM) RunCycle(ctx context.Context) {
 		cycleSnapshot.NetReturnUSD = 0.0
 		cycleSnapshot.TotalSlippageUSD = 0.0
 		cycleSnapshot.TotalGasFeeUSD = 0.0
+		stopStep()
+		a.recordPoolUniverse(cycleSnapshot, poolsDataMap, scoredPools, selectedPoolIDs, elysPoolID, scoringParams)
+		cycleEvents.finishStep(map[string]interface{}{"scored_pools": len(scoredPools), "selected_pools": 0})
+		a.finalizeCycleSnapshot(&cycleSnapshot, poolsDataMap, timer)
 		a.saveCycleSnapshot(cycleSnapshot)
-		a.logEndOfCycleState(cycleStartTime, cycleLogger)
+		a.logEndOfCycleState(ctx, cycleStartTime, cycleLogger)
 		return
 	}
 	scoredPoolsMap := make(map[types.PoolID]types.PoolScoreResult)
 	for _, sp := range scoredPools {
 		scoredPoolsMap[sp.PoolID] = sp
 	}
-	targetAllocations, err := analyzer.DetermineTargetAllocations(selectedPoolIDs, scoredPoolsMap, *a.scoringParams, elysPoolID)
+	targetAllocations, err := analyzer.DetermineTargetAllocations(selectedPoolIDs, scoredPoolsMap, scoringParams, elysPoolID, poolsDataMap)
 	if err != nil {
 		cycleLogger.Error().Err(err).Msg("Cycle aborted: Failed to determine target allocations.")
 		return
@@ -264,14 +454,29 @@ func (a *AVM) RunCycle(ctx context.Context) {
 
 	// Capture target allocations in snapshot
 	cycleSnapshot.TargetAllocations = targetAllocations
+	totalTarget := 0.0
+	for _, allocation := range targetAllocations {
+		totalTarget += allocation
+	}
+	if unallocated := 1.0 - totalTarget; unallocated > 0.00001 {
+		cycleSnapshot.UnallocatedTarget = unallocated
+		cycleLogger.Warn().
+			Float64("unallocated", unallocated).
+			Msg("Token exposure cap leaves part of the vault in liquid USDC")
+	}
+	stopStep()
+	a.recordPoolUniverse(cycleSnapshot, poolsDataMap, scoredPools, selectedPoolIDs, elysPoolID, scoringParams)
+	cycleEvents.finishStep(map[string]interface{}{"scored_pools": len(scoredPools), "selected_pools": len(selectedPoolIDs), "unallocated_target": cycleSnapshot.UnallocatedTarget})
 
 	cycleLogger.Info().Int("selectedPools", len(selectedPoolIDs)).Msg("Step 3: Pool analysis complete.")
 
 	// --- Step 4: Action Planning ---
 	cycleLogger.Info().Msg("Step 4: Generating action plan...")
-	withdrawalActions, depositActions, err := planner.GenerateActionPlan(
-		currentPositions, liquidUSDC, targetAllocations, totalVaultValue,
-		poolsDataMap, tokenDataMap, *a.scoringParams, config.NodeRPC,
+	cycleEvents.startStep(events.StepPlan)
+	stopStep = timer.Track("planning")
+	withdrawalActions, depositActions, err := planner.GenerateActionPlan(queryCtx,
+		activePositions, liquidUSDC, targetAllocations, plannableValueUSD,
+		poolsDataMap, tokenDataMap, scoringParams, config.NodeRPC,
 	)
 	if err != nil {
 		cycleLogger.Error().Err(err).Msg("Cycle aborted: Failed to generate action plan.")
@@ -284,9 +489,13 @@ func (a *AVM) RunCycle(ctx context.Context) {
 		SubActions:            append(withdrawalActions, depositActions...),
 		EstimatedNetUSDChange: 0.0, // Would be calculated based on expected value changes
 	}
+	stopStep()
+	cycleEvents.finishStep(map[string]interface{}{"withdrawals": len(withdrawalActions), "deposits": len(depositActions)})
+	cycleEvents.plan(withdrawalActions, depositActions, targetAllocations)
 
 	if len(withdrawalActions) == 0 && len(depositActions) == 0 {
 		cycleLogger.Info().Msg("No rebalancing actions required.")
+		outcome = metrics.CycleOutcomeNoActions
 		// Complete snapshot with no changes
 		cycleSnapshot.FinalVaultValueUSD = totalVaultValue
 		cycleSnapshot.FinalLiquidUSDC = liquidUSDC
@@ -295,8 +504,10 @@ func (a *AVM) RunCycle(ctx context.Context) {
 		cycleSnapshot.NetReturnUSD = 0.0
 		cycleSnapshot.TotalSlippageUSD = 0.0
 		cycleSnapshot.TotalGasFeeUSD = 0.0
+		stopStep()
+		a.finalizeCycleSnapshot(&cycleSnapshot, poolsDataMap, timer)
 		a.saveCycleSnapshot(cycleSnapshot)
-		a.logEndOfCycleState(cycleStartTime, cycleLogger)
+		a.logEndOfCycleState(ctx, cycleStartTime, cycleLogger)
 		return
 	}
 	cycleLogger.Info().Int("withdrawalActions", len(withdrawalActions)).Int("depositActions", len(depositActions)).Msg("Step 4: Action plan generated.")
@@ -306,17 +517,37 @@ func (a *AVM) RunCycle(ctx context.Context) {
 	}{withdrawalActions, depositActions}, "", "  ")
 	cycleLogger.Info().Str("actionPlan", string(planJSON)).Msg("--- Detailed Action Plan ---")
 
+	// Paused or emergency stopped since the cycle started: keep the plan, skip execution
+	if reason := a.executionBlocked(cycleCtx); reason != "" {
+		cycleLogger.Warn().Str("reason", reason).Msg("Step 5: Execution skipped.")
+		outcome = metrics.CycleOutcomeExecutionSkipped
+		cycleSnapshot.ActionPlan.GoalDescription = "Execution skipped - " + reason
+		cycleSnapshot.FinalVaultValueUSD = totalVaultValue
+		cycleSnapshot.FinalLiquidUSDC = liquidUSDC
+		cycleSnapshot.FinalPositions = cycleSnapshot.InitialPositions
+		cycleSnapshot.AllocationEfficiencyPercent = a.calculateAllocationEfficiency(cycleSnapshot.InitialPositions, targetAllocations)
+		cycleSnapshot.NetReturnUSD = 0.0
+		cycleSnapshot.TotalSlippageUSD = 0.0
+		cycleSnapshot.TotalGasFeeUSD = 0.0
+		a.finalizeCycleSnapshot(&cycleSnapshot, poolsDataMap, timer)
+		a.saveCycleSnapshot(cycleSnapshot)
+		a.logEndOfCycleState(ctx, cycleStartTime, cycleLogger)
+		return
+	}
+
 	// --- Step 5: Action Execution (Two-Phase) ---
 	cycleLogger.Info().Msg("Step 5: Executing action plan...")
+	stopStep = timer.Track("execution")
 
 	// Track total gas fees (slippage will be calculated from final values)
 	var totalGasFeeUSD float64
 
 	if len(withdrawalActions) > 0 {
 		cycleLogger.Info().Msg("Executing withdrawal/consolidation phase...")
+		cycleEvents.startStep(events.StepWithdraw)
 
 		// Capture vault state before withdrawal
-		preWithdrawPositions, preWithdrawUSDC, err := a.captureVaultState()
+		preWithdrawPositions, preWithdrawUSDC, err := a.captureVaultState(ctx)
 		if err != nil {
 			cycleLogger.Error().Err(err).Msg("Failed to capture vault state before withdrawal")
 			// Continue with execution but use current state
@@ -327,20 +558,25 @@ func (a *AVM) RunCycle(ctx context.Context) {
 		txResult, err := a.vault.ExecuteActionPlan(withdrawalActions)
 		if err != nil {
 			cycleLogger.Error().Err(err).Msg("Withdrawal/consolidation transaction failed.")
+			outcome = metrics.CycleOutcomeExecutionFailed
+			cycleEvents.failStep(err)
 			// Save snapshot even on failure, marking final state as current state
 			a.finalizeFailedSnapshot(&cycleSnapshot, totalVaultValue, liquidUSDC, currentPositions, poolsDataMap)
+			stopStep()
+			a.finalizeCycleSnapshot(&cycleSnapshot, poolsDataMap, timer)
 			a.saveCycleSnapshot(cycleSnapshot)
-			a.logEndOfCycleState(cycleStartTime, cycleLogger)
+			a.logEndOfCycleState(ctx, cycleStartTime, cycleLogger)
 			return
 		}
 		cycleLogger.Info().Str("txHash", txResult.TxHash).Msg("Withdrawal/consolidation transaction completed successfully.")
+		cycleEvents.transaction(txResult.TxHash, txResult.GasFeeUSD)
 		cycleSnapshot.TransactionHashes = append(cycleSnapshot.TransactionHashes, txResult.TxHash)
 
 		// Accumulate gas fees from transaction result
 		totalGasFeeUSD += txResult.GasFeeUSD
 
 		// Capture vault state after withdrawal
-		postWithdrawPositions, postWithdrawUSDC, err := a.captureVaultState()
+		postWithdrawPositions, postWithdrawUSDC, err := a.captureVaultState(ctx)
 		if err != nil {
 			cycleLogger.Error().Err(err).Msg("Failed to capture vault state after withdrawal")
 			// Use current state as fallback
@@ -366,13 +602,22 @@ func (a *AVM) RunCycle(ctx context.Context) {
 				Float64("actualAmountUSD", actualAmountUSD).
 				Msg("Generated withdrawal action receipt")
 		}
+		cycleEvents.finishStep(nil)
+	}
+
+	// The withdrawals already landed, so a pause or emergency stop only skips the deposits
+	if reason := a.executionBlocked(cycleCtx); len(depositActions) > 0 && reason != "" {
+		cycleLogger.Warn().Str("reason", reason).Msg("Deposit phase skipped.")
+		cycleSnapshot.ActionPlan.GoalDescription += " - deposits skipped: " + reason
+		depositActions = nil
 	}
 
 	if len(depositActions) > 0 {
 		cycleLogger.Info().Msg("Executing deposit phase...")
+		cycleEvents.startStep(events.StepDeposit)
 
 		// Capture vault state before deposit
-		preDepositPositions, preDepositUSDC, err := a.captureVaultState()
+		preDepositPositions, preDepositUSDC, err := a.captureVaultState(ctx)
 		if err != nil {
 			cycleLogger.Error().Err(err).Msg("Failed to capture vault state before deposit")
 			// Continue with execution but use current state
@@ -383,20 +628,25 @@ func (a *AVM) RunCycle(ctx context.Context) {
 		txResult, err := a.vault.ExecuteActionPlan(depositActions)
 		if err != nil {
 			cycleLogger.Error().Err(err).Msg("Deposit transaction failed.")
+			outcome = metrics.CycleOutcomeExecutionFailed
+			cycleEvents.failStep(err)
 			// Save snapshot even on failure
 			a.finalizeFailedSnapshot(&cycleSnapshot, totalVaultValue, liquidUSDC, currentPositions, poolsDataMap)
+			stopStep()
+			a.finalizeCycleSnapshot(&cycleSnapshot, poolsDataMap, timer)
 			a.saveCycleSnapshot(cycleSnapshot)
-			a.logEndOfCycleState(cycleStartTime, cycleLogger)
+			a.logEndOfCycleState(ctx, cycleStartTime, cycleLogger)
 			return
 		}
 		cycleLogger.Info().Str("txHash", txResult.TxHash).Msg("Deposit transaction completed successfully.")
+		cycleEvents.transaction(txResult.TxHash, txResult.GasFeeUSD)
 		cycleSnapshot.TransactionHashes = append(cycleSnapshot.TransactionHashes, txResult.TxHash)
 
 		// Accumulate gas fees from transaction result
 		totalGasFeeUSD += txResult.GasFeeUSD
 
 		// Capture vault state after deposit
-		postDepositPositions, postDepositUSDC, err := a.captureVaultState()
+		postDepositPositions, postDepositUSDC, err := a.captureVaultState(ctx)
 		if err != nil {
 			cycleLogger.Error().Err(err).Msg("Failed to capture vault state after deposit")
 			// Use current state as fallback
@@ -422,24 +672,30 @@ func (a *AVM) RunCycle(ctx context.Context) {
 				Float64("actualAmountUSD", actualAmountUSD).
 				Msg("Generated deposit action receipt")
 		}
+		cycleEvents.finishStep(nil)
 	}
 
+	stopStep()
+	outcome = metrics.CycleOutcomeExecuted
+
 	// --- Step 6: Capture Final State & Calculate Performance Metrics ---
 	cycleLogger.Info().Msg("Step 6: Capturing final state and calculating performance metrics...")
+	stopStep = timer.Track("final_state")
+	cycleEvents.startStep(events.StepFinalize)
 
-	finalLiquidUSDC, err := a.vault.GetLiquidUSDC()
+	finalLiquidUSDC, err := a.vault.GetLiquidUSDC(ctx)
 	if err != nil {
 		cycleLogger.Error().Err(err).Msg("Failed to get final liquid USDC.")
 		finalLiquidUSDC = liquidUSDC // Use initial value as fallback
 	}
 
-	finalPositions, err := a.vault.GetPoolPositions()
+	finalPositions, err := a.vault.GetPoolPositions(ctx)
 	if err != nil {
 		cycleLogger.Error().Err(err).Msg("Failed to get final positions.")
 		finalPositions = currentPositions // Use initial positions as fallback
 	}
 
-	finalTotalValue, err := a.vault.GetTotalVaultValue()
+	finalTotalValue, err := a.vault.GetTotalVaultValue(ctx)
 	if err != nil {
 		cycleLogger.Error().Err(err).Msg("Failed to get final total value.")
 		finalTotalValue = totalVaultValue // Use initial value as fallback
@@ -466,7 +722,16 @@ func (a *AVM) RunCycle(ctx context.Context) {
 	cycleSnapshot.TotalSlippageUSD = actualSlippageUSD
 	cycleSnapshot.TotalGasFeeUSD = totalGasFeeUSD
 
+	stopStep()
+	cycleEvents.finishStep(map[string]interface{}{
+		"final_value_usd": finalTotalValue,
+		"net_return_usd":  netValueChange,
+		"slippage_usd":    actualSlippageUSD,
+		"gas_fee_usd":     totalGasFeeUSD,
+	})
+
 	// Save the complete cycle snapshot
+	a.finalizeCycleSnapshot(&cycleSnapshot, poolsDataMap, timer)
 	a.saveCycleSnapshot(cycleSnapshot)
 
 	cycleLogger.Info().
@@ -488,7 +753,7 @@ func (a *AVM) RunCycle(ctx context.Context) {
 
 // getCycleNumber increments and returns the persistent cycle counter from database
 func (a *AVM) getCycleNumber() int {
-	cycleNumber, err := state.IncrementCycleNumber()
+	cycleNumber, err := a.store.IncrementCycleNumber()
 	if err != nil {
 		a.logger.Error().Err(err).Msg("Failed to increment cycle number, using fallback")
 		// Fallback to a simple counter if database fails
@@ -497,14 +762,65 @@ func (a *AVM) getCycleNumber() int {
 	return cycleNumber
 }
 
-// getScoringParamsID retrieves the current active scoring parameters ID from database
-func (a *AVM) getScoringParamsID() *int64 {
-	paramsID, err := state.GetActiveScoringParametersID(a.configName)
+// reloadScoringParams checks whether another version of the scoring parameters has been activated
+// and, if it passes validation, swaps it in. It returns what happened for the cycle snapshot, or nil
+// if the active version is unchanged. On any failure the AVM keeps the parameters it is using.
+func (a *AVM) reloadScoringParams(cycleLogger zerolog.Logger) *types.ScoringParamsReload {
+	active, err := a.store.LoadActiveScoringParametersVersion(a.configName)
 	if err != nil {
-		a.logger.Error().Err(err).Str("configName", a.configName).Msg("Failed to get active scoring parameters ID")
+		cycleLogger.Warn().Err(err).Str("configName", a.configName).Msg("Failed to check for newly activated scoring parameters, keeping current ones")
+		return nil
+	}
+	if a.scoringParamsID != nil && *a.scoringParamsID == active.ParamsID {
+		return nil
+	}
+	if a.rejectedParamsID != nil && *a.rejectedParamsID == active.ParamsID {
+		cycleLogger.Warn().
+			Int64("activeParamsID", active.ParamsID).
+			Msg("Active scoring parameters were rejected, still using the previous ones")
 		return nil
 	}
-	return paramsID
+
+	reload := &types.ScoringParamsReload{
+		FromParamsID: a.scoringParamsID,
+		ToParamsID:   active.ParamsID,
+		ToVersion:    active.Version,
+		Changes:      types.DiffScoringParameters(*a.scoringParams, active.Parameters),
+	}
+
+	if err := analyzer.ValidateScoringParameters(active.Parameters); err != nil {
+		reload.RejectReason = err.Error()
+		a.rejectedParamsID = &active.ParamsID
+		cycleLogger.Error().Err(err).
+			Int64("activeParamsID", active.ParamsID).
+			Int("version", active.Version).
+			Msg("Rejected newly activated scoring parameters, keeping current ones")
+		return reload
+	}
+
+	params := active.Parameters
+	a.scoringParams = &params
+	a.scoringParamsID = &active.ParamsID
+	a.rejectedParamsID = nil
+	reload.Applied = true
+
+	event := cycleLogger.Info().
+		Int64("paramsID", active.ParamsID).
+		Int("version", active.Version).
+		Int("changedParameters", len(reload.Changes))
+	if reload.FromParamsID != nil {
+		event = event.Int64("previousParamsID", *reload.FromParamsID)
+	}
+	event.Msg("Reloaded scoring parameters")
+	for _, change := range reload.Changes {
+		cycleLogger.Info().
+			Str("parameter", change.Field).
+			Float64("from", change.From).
+			Float64("to", change.To).
+			Msg("Scoring parameter changed")
+	}
+
+	return reload
 }
 
 // calculateActualAmountUSD calculates the actual USD amount for an action by comparing vault states
@@ -718,14 +1034,15 @@ func (a *AVM) calculateSwapActualUSD(
 	}
 }
 
-// captureVaultState captures the current vault state for comparison
-func (a *AVM) captureVaultState() ([]types.Position, float64, error) {
-	positions, err := a.vault.GetPoolPositions()
+// captureVaultState captures the current vault state for comparison.
+// It is called around execution, so ctx must not be pinned to the cycle's block height.
+func (a *AVM) captureVaultState(ctx context.Context) ([]types.Position, float64, error) {
+	positions, err := a.vault.GetPoolPositions(ctx)
 	if err != nil {
 		return nil, 0, fmt.Errorf("failed to get pool positions: %w", err)
 	}
 
-	liquidUSDC, err := a.vault.GetLiquidUSDC()
+	liquidUSDC, err := a.vault.GetLiquidUSDC(ctx)
 	if err != nil {
 		return nil, 0, fmt.Errorf("failed to get liquid USDC: %w", err)
 	}
@@ -738,13 +1055,14 @@ func (a *AVM) convertToPositionSnapshots(positions []types.Position, poolsDataMa
 	snapshots := make([]types.PositionSnapshot, len(positions))
 	for i, pos := range positions {
 		pool, exists := poolsDataMap[pos.PoolID]
-		var tokenA, tokenB string
+		var poolAssets []string
 		var poolTVL, poolScore float64
 
 		if exists {
 			// Get token symbols from pool tokens
-			tokenA = pool.TokenA.Symbol
-			tokenB = pool.TokenB.Symbol
+			for _, asset := range pool.Assets {
+				poolAssets = append(poolAssets, asset.Token.Symbol)
+			}
 			poolTVL = pool.TvlUSD
 			// Use the pool's score result if available
 			poolScore = pool.Score.Score
@@ -761,8 +1079,7 @@ func (a *AVM) convertToPositionSnapshots(positions []types.Position, poolsDataMa
 			EstimatedValueUSD: pos.EstimatedValue,
 			AllocationPercent: allocationPercent,
 			AgeDays:           pos.AgeDays,
-			PoolTokenA:        tokenA,
-			PoolTokenB:        tokenB,
+			PoolAssets:        poolAssets,
 			PoolTVL:           poolTVL,
 			PoolScore:         poolScore,
 		}
@@ -801,6 +1118,90 @@ func (a *AVM) calculateAllocationEfficiency(finalPositions []types.PositionSnaps
 	return efficiency
 }
 
+// recordTokenExposures populates the snapshot's per-token exposure for both the target allocations
+// and the final positions, sorted from largest to smallest exposure
+func (a *AVM) recordTokenExposures(snapshot *types.CycleSnapshot, poolsDataMap map[types.PoolID]types.Pool) {
+	snapshot.TargetTokenExposures = a.calculateTokenExposures(snapshot.TargetAllocations, poolsDataMap)
+
+	finalAllocations := make(map[types.PoolID]float64)
+	for _, pos := range snapshot.FinalPositions {
+		if _, exists := poolsDataMap[pos.PoolID]; !exists {
+			a.logger.Warn().Uint64("poolID", uint64(pos.PoolID)).Msg("Position pool missing from pool data, excluding from token exposure")
+			continue
+		}
+		finalAllocations[pos.PoolID] += pos.AllocationPercent / 100.0 // Convert to fraction
+	}
+	snapshot.TokenExposures = a.calculateTokenExposures(finalAllocations, poolsDataMap)
+}
+
+// calculateTokenExposures converts allocations into a sorted list of token exposures
+func (a *AVM) calculateTokenExposures(allocations map[types.PoolID]float64, poolsDataMap map[types.PoolID]types.Pool) []types.TokenExposure {
+	exposureMap, err := analyzer.CalculateTokenExposures(allocations, poolsDataMap)
+	if err != nil {
+		a.logger.Error().Err(err).Msg("Failed to calculate token exposures")
+		return make([]types.TokenExposure, 0)
+	}
+
+	exposures := make([]types.TokenExposure, 0, len(exposureMap))
+	for _, exposure := range exposureMap {
+		exposures = append(exposures, exposure)
+	}
+	sort.Slice(exposures, func(i, j int) bool {
+		return exposures[i].ExposurePercent > exposures[j].ExposurePercent
+	})
+	return exposures
+}
+
+// freezeQuarantinedPositions separates positions in quarantined pools from the rest and records them
+// in the quarantine report. Returns the positions that may be rebalanced and the total frozen value.
+func (a *AVM) freezeQuarantinedPositions(positions []types.Position, quarantine *types.QuarantineReport) ([]types.Position, float64) {
+	if quarantine == nil || len(quarantine.Pools) == 0 {
+		return positions, 0
+	}
+
+	quarantinedPools := make(map[types.PoolID]bool)
+	for _, pool := range quarantine.Pools {
+		quarantinedPools[pool.PoolID] = true
+	}
+
+	activePositions := make([]types.Position, 0, len(positions))
+	frozenValueUSD := 0.0
+	for _, pos := range positions {
+		if !quarantinedPools[pos.PoolID] {
+			activePositions = append(activePositions, pos)
+			continue
+		}
+
+		quarantine.FrozenPositions = append(quarantine.FrozenPositions, types.FrozenPosition{
+			PoolID:            pos.PoolID,
+			EstimatedValueUSD: pos.EstimatedValue,
+		})
+		frozenValueUSD += pos.EstimatedValue
+		a.logger.Warn().
+			Uint64("poolID", uint64(pos.PoolID)).
+			Float64("estimatedValueUSD", pos.EstimatedValue).
+			Msg("Freezing position in quarantined pool")
+	}
+
+	return activePositions, frozenValueUSD
+}
+
+// finalizeCycleSnapshot records the token exposures and step timings on the snapshot before it is
+// saved, and exports the snapshot's metrics
+func (a *AVM) finalizeCycleSnapshot(snapshot *types.CycleSnapshot, poolsDataMap map[types.PoolID]types.Pool, timer *utils.StepTimer) {
+	a.recordTokenExposures(snapshot, poolsDataMap)
+
+	snapshot.StepTimings = timer.Timings()
+	for _, timing := range snapshot.StepTimings {
+		a.logger.Info().
+			Int("cycleNumber", snapshot.CycleNumber).
+			Str("step", timing.Step).
+			Float64("durationMs", timing.DurationMs).
+			Msg("Cycle step timing")
+	}
+	metrics.RecordCycleSnapshot(*snapshot)
+}
+
 // finalizeFailedSnapshot marks final state as same as initial state since transaction failed
 func (a *AVM) finalizeFailedSnapshot(snapshot *types.CycleSnapshot, totalVaultValue, liquidUSDC float64, positions []types.Position, poolsDataMap map[types.PoolID]types.Pool) {
 	snapshot.FinalVaultValueUSD = totalVaultValue
@@ -812,32 +1213,150 @@ func (a *AVM) finalizeFailedSnapshot(snapshot *types.CycleSnapshot, totalVaultVa
 	snapshot.TotalGasFeeUSD = 0.0
 }
 
+// recordVaultPerformance records the vault's NAV per share at the start of the cycle and the
+// returns up to it. Failures are logged and do not affect the cycle.
+func (a *AVM) recordVaultPerformance(snapshot types.CycleSnapshot, shareSupply sdkmath.Int) {
+	history, err := a.store.GetNAVHistory()
+	if err != nil {
+		a.logger.Warn().Err(err).Msg("Failed to load NAV history")
+		return
+	}
+	var previous *types.NAVPoint
+	if len(history) > 0 {
+		previous = &history[len(history)-1]
+	}
+
+	point, err := performance.NewNAVPoint(snapshot.CycleNumber, snapshot.Timestamp, snapshot.BlockHeight, snapshot.InitialVaultValueUSD, shareSupply, previous)
+	if err != nil {
+		a.logger.Warn().Err(err).Msg("Failed to compute NAV per share")
+		return
+	}
+	returns := performance.CalculateReturns(append(history, point))
+
+	if err := a.store.SaveVaultPerformance(types.VaultPerformance{NAVPoint: point, VaultReturns: returns}); err != nil {
+		a.logger.Warn().Err(err).Msg("Failed to save vault performance")
+		return
+	}
+	metrics.SetNAVPerShare(point.NAVPerShare)
+	a.logger.Info().
+		Float64("navPerShare", point.NAVPerShare).
+		Float64("netFlowUSD", point.NetFlowUSD).
+		Float64("twrInception", returns.TWRInception).
+		Msg("Recorded vault NAV per share")
+}
+
+// recordPoolMetrics saves the cycle's pool metrics and applies the metrics retention policy
+func (a *AVM) recordPoolMetrics(snapshot types.CycleSnapshot, pools []types.Pool) {
+	if err := a.store.SavePoolMetrics(snapshot.CycleNumber, snapshot.BlockHeight, snapshot.Timestamp, pools); err != nil {
+		a.logger.Warn().Err(err).Msg("Failed to save pool metrics")
+		return
+	}
+	if err := a.store.DownsamplePoolMetrics(config.PoolMetricsRawRetention, config.PoolMetricsHourlyRetention, config.PoolMetricsDailyRetention); err != nil {
+		a.logger.Warn().Err(err).Msg("Failed to downsample pool metrics")
+	}
+}
+
 // saveCycleSnapshot saves the cycle snapshot to database
 func (a *AVM) saveCycleSnapshot(snapshot types.CycleSnapshot) {
-	snapshotID, err := state.SaveCycleSnapshot(snapshot)
+	snapshotID, err := a.store.SaveCycleSnapshot(snapshot)
 	if err != nil {
 		a.logger.Error().Err(err).Msg("Failed to save cycle snapshot to database")
 		return
 	}
 	a.logger.Info().Int64("snapshot_id", snapshotID).Msg("Cycle snapshot saved successfully")
+
+	a.recordPnLAttribution(snapshotID, snapshot)
+	a.applySnapshotRetention()
+}
+
+// recordPnLAttribution attributes each pool's PnL since the previous snapshot and saves it.
+// Failures are logged and do not affect the cycle.
+func (a *AVM) recordPnLAttribution(snapshotID int64, snapshot types.CycleSnapshot) {
+	recent, err := a.store.GetRecentCycles(2)
+	if err != nil {
+		a.logger.Warn().Err(err).Msg("Failed to load the previous snapshot for PnL attribution")
+		return
+	}
+	var previous *types.CycleSnapshot
+	for i := range recent {
+		if recent[i].SnapshotID != snapshotID && recent[i].Timestamp.Before(snapshot.Timestamp) {
+			previous = &recent[i]
+			break
+		}
+	}
+
+	endMetrics, err := a.store.GetPoolMetricsAt(snapshot.Timestamp, attributionMetricsMaxAge)
+	if err != nil {
+		a.logger.Warn().Err(err).Msg("Failed to load pool metrics for PnL attribution")
+		return
+	}
+	var startMetrics map[types.PoolID]types.PoolMetricsPoint
+	if previous != nil {
+		startMetrics, err = a.store.GetPoolMetricsAt(previous.Timestamp, attributionMetricsMaxAge)
+		if err != nil {
+			a.logger.Warn().Err(err).Msg("Failed to load pool metrics for PnL attribution")
+			return
+		}
+	}
+
+	attributions := attribution.Attribute(previous, snapshot, startMetrics, endMetrics)
+	if len(attributions) == 0 {
+		return
+	}
+	if err := a.store.SavePoolAttributions(snapshot.CycleNumber, attributions); err != nil {
+		a.logger.Warn().Err(err).Msg("Failed to save PnL attribution")
+		return
+	}
+
+	totalPnL := 0.0
+	for _, poolAttribution := range attributions {
+		totalPnL += poolAttribution.TotalPnLUSD
+	}
+	a.logger.Info().Int("pools", len(attributions)).Float64("totalPnLUSD", totalPnL).Msg("Recorded PnL attribution")
+}
+
+// applySnapshotRetention archives and deletes the snapshots older than the retention period, if one is set
+func (a *AVM) applySnapshotRetention() {
+	if config.SnapshotRetention <= 0 {
+		return
+	}
+	format, err := export.ParseFormat(config.SnapshotArchiveFormat)
+	if err != nil {
+		a.logger.Warn().Err(err).Msg("Invalid snapshot archive format, skipping snapshot retention")
+		return
+	}
+
+	result, err := export.ArchiveSnapshots(a.store, config.SnapshotArchiveDir, format, time.Now().Add(-config.SnapshotRetention))
+	if err != nil {
+		a.logger.Warn().Err(err).Msg("Failed to archive old cycle snapshots")
+		return
+	}
+	if result.Snapshots > 0 {
+		a.logger.Info().
+			Strs("days", result.Days).
+			Int("snapshots", result.Snapshots).
+			Int64("deleted", result.Deleted).
+			Str("dir", config.SnapshotArchiveDir).
+			Msg("Archived old cycle snapshots")
+	}
 }
 
 // logEndOfCycleState fetches and logs the final state of the vault for the cycle
-func (a *AVM) logEndOfCycleState(cycleStartTime time.Time, cycleLogger zerolog.Logger) {
+func (a *AVM) logEndOfCycleState(ctx context.Context, cycleStartTime time.Time, cycleLogger zerolog.Logger) {
 	cycleLogger.Info().Msg("Step 6: Logging final vault state...")
 
-	finalLiquidUSDC, err := a.vault.GetLiquidUSDC()
+	finalLiquidUSDC, err := a.vault.GetLiquidUSDC(ctx)
 	if err != nil {
 		cycleLogger.Error().Err(err).Msg("Failed to get final liquid USDC for logging.")
 		finalLiquidUSDC = -1 // Indicate error
 	}
 
-	finalPositions, err := a.vault.GetPoolPositions()
+	finalPositions, err := a.vault.GetPoolPositions(ctx)
 	if err != nil {
 		cycleLogger.Error().Err(err).Msg("Failed to get final positions for logging.")
 	}
 
-	finalTotalValue, err := a.vault.GetTotalVaultValue()
+	finalTotalValue, err := a.vault.GetTotalVaultValue(ctx)
 	if err != nil {
 		cycleLogger.Error().Err(err).Msg("Failed to get final total value for logging.")
 		finalTotalValue = -1 // Indicate error
@@ -0,0 +1,172 @@
+/*
+
+This file implements the operator controls of the AVM, used by the web server's /api/control
+endpoints:
+
+  - Pause: cycles keep fetching, scoring and planning, but skip execution.
+  - Resume: cycles execute their plans again, also after an emergency stop.
+  - EmergencyStop: cancels the running cycle before its next broadcast and skips every cycle
+    until resumed. A transaction that is already being broadcast is not interrupted.
+  - TriggerCycle: runs a cycle now instead of waiting for the loop interval, or right after the
+    running cycle.
+
+The mode is persisted through the Store, so a pause or an emergency stop survives restarts, and
+each action is recorded in the audit log.
+
+*/
+
+package avm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/elys-network/avm/internal/state"
+	"github.com/elys-network/avm/internal/types"
+)
+
+// ControlStatus returns the control mode and whether a cycle is running or triggered
+func (a *AVM) ControlStatus() types.ControlStatus {
+	a.controlMu.Lock()
+	defer a.controlMu.Unlock()
+	return a.controlStatusLocked()
+}
+
+// Pause makes cycles skip execution until Resume
+func (a *AVM) Pause(audit types.AuditInfo) (types.ControlStatus, error) {
+	return a.setControlMode(types.ControlModePaused, types.AuditActionControlPause, audit)
+}
+
+// Resume makes cycles execute their plans again after a pause or an emergency stop
+func (a *AVM) Resume(audit types.AuditInfo) (types.ControlStatus, error) {
+	return a.setControlMode(types.ControlModeRunning, types.AuditActionControlResume, audit)
+}
+
+// EmergencyStop cancels the running cycle, drops a triggered one, and skips cycles until Resume
+func (a *AVM) EmergencyStop(audit types.AuditInfo) (types.ControlStatus, error) {
+	return a.setControlMode(types.ControlModeEmergencyStopped, types.AuditActionControlEmergencyStop, audit)
+}
+
+// TriggerCycle makes RunLoop run a cycle now, or right after the running cycle. Returns
+// types.ErrEmergencyStopped during an emergency stop and types.ErrCycleAlreadyTriggered if a
+// triggered cycle has not started yet.
+func (a *AVM) TriggerCycle(audit types.AuditInfo) (types.ControlStatus, error) {
+	a.controlMu.Lock()
+	defer a.controlMu.Unlock()
+
+	if a.controlState.Mode == types.ControlModeEmergencyStopped {
+		return a.controlStatusLocked(), types.ErrEmergencyStopped
+	}
+	if len(a.triggerCh) > 0 {
+		return a.controlStatusLocked(), types.ErrCycleAlreadyTriggered
+	}
+	diff, err := json.Marshal(map[string]interface{}{
+		"mode":          a.controlState.Mode,
+		"cycle_running": a.cycleStartedAt != nil,
+	})
+	if err != nil {
+		return a.controlStatusLocked(), fmt.Errorf("failed to encode trigger details: %w", err)
+	}
+	_, err = a.store.RecordAuditEvent(types.AuditEvent{
+		Action:    types.AuditActionControlTrigger,
+		Target:    state.ControlAuditTarget,
+		AuditInfo: audit,
+		Diff:      diff,
+	})
+	if err != nil {
+		return a.controlStatusLocked(), err
+	}
+	a.triggerCh <- struct{}{}
+
+	a.logger.Info().Str("actor", audit.Actor).Str("reason", audit.Reason).Msg("AVM cycle triggered")
+	return a.controlStatusLocked(), nil
+}
+
+// setControlMode persists a new control mode and applies it
+func (a *AVM) setControlMode(mode types.ControlMode, action types.AuditAction, audit types.AuditInfo) (types.ControlStatus, error) {
+	a.controlMu.Lock()
+	defer a.controlMu.Unlock()
+
+	if a.controlState.Mode == mode {
+		return a.controlStatusLocked(), fmt.Errorf("%w: %s", types.ErrControlModeUnchanged, mode)
+	}
+	// The mode only changes once it is stored, so the AVM never runs in a mode it would lose on restart
+	controlState, err := a.store.SaveControlState(mode, action, audit)
+	if err != nil {
+		return a.controlStatusLocked(), err
+	}
+	a.controlState = *controlState
+
+	if mode == types.ControlModeEmergencyStopped {
+		select {
+		case <-a.triggerCh:
+		default:
+		}
+		if a.cycleCancel != nil {
+			a.cycleCancel()
+		}
+	}
+
+	a.logger.Warn().
+		Str("mode", string(mode)).
+		Str("actor", audit.Actor).
+		Str("reason", audit.Reason).
+		Msg("AVM control mode changed")
+	return a.controlStatusLocked(), nil
+}
+
+// controlStatusLocked returns the control status; the caller holds controlMu
+func (a *AVM) controlStatusLocked() types.ControlStatus {
+	status := types.ControlStatus{
+		ControlState:   a.controlState,
+		CycleRunning:   a.cycleStartedAt != nil,
+		TriggerPending: len(a.triggerCh) > 0,
+	}
+	if a.cycleStartedAt != nil {
+		startedAt := *a.cycleStartedAt
+		status.CycleStartedAt = &startedAt
+	}
+	return status
+}
+
+// startCycle registers a new cycle and returns its context, which an emergency stop cancels,
+// and the function to call when it ends. Reports false during an emergency stop.
+func (a *AVM) startCycle(ctx context.Context) (context.Context, func(), bool) {
+	a.controlMu.Lock()
+	defer a.controlMu.Unlock()
+
+	if a.controlState.Mode == types.ControlModeEmergencyStopped {
+		return nil, nil, false
+	}
+	cycleCtx, cancel := context.WithCancel(ctx)
+	startedAt := time.Now().UTC()
+	a.cycleCancel = cancel
+	a.cycleStartedAt = &startedAt
+
+	finish := func() {
+		a.controlMu.Lock()
+		defer a.controlMu.Unlock()
+		cancel()
+		a.cycleCancel = nil
+		a.cycleStartedAt = nil
+	}
+	return cycleCtx, finish, true
+}
+
+// executionBlocked returns why the cycle must not broadcast its next transaction, or "" if it may
+func (a *AVM) executionBlocked(cycleCtx context.Context) string {
+	a.controlMu.Lock()
+	defer a.controlMu.Unlock()
+
+	switch {
+	case a.controlState.Mode == types.ControlModeEmergencyStopped:
+		return "emergency stop by " + a.controlState.Actor
+	case cycleCtx.Err() != nil:
+		return "cycle cancelled"
+	case a.controlState.Mode == types.ControlModePaused:
+		return "paused by " + a.controlState.Actor
+	}
+	return ""
+}
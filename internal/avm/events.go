@@ -0,0 +1,107 @@
+/*
+
+This file publishes the progress of a cycle to the AVM's event bus (see internal/events): the start
+and end of each step, the action plan, the transactions and the outcome. The web server streams
+these events at /api/events.
+
+*/
+
+package avm
+
+import (
+	"time"
+
+	"github.com/elys-network/avm/internal/events"
+	"github.com/elys-network/avm/internal/types"
+)
+
+// cycleEvents publishes the events of one cycle. Without an event bus it publishes nothing.
+type cycleEvents struct {
+	bus         *events.Bus
+	cycleID     string
+	cycleNumber int
+	step        string // Running step; "" between steps
+	stepStart   time.Time
+}
+
+// startCycleEvents publishes the start of the cycle of snapshot
+func (a *AVM) startCycleEvents(cycleID string, snapshot types.CycleSnapshot) *cycleEvents {
+	c := &cycleEvents{bus: a.events, cycleID: cycleID, cycleNumber: snapshot.CycleNumber}
+	data := map[string]interface{}{"started_at": snapshot.Timestamp.UTC()}
+	if snapshot.ScoringParamsID != nil {
+		data["scoring_params_id"] = *snapshot.ScoringParamsID
+	}
+	c.publish(events.TypeCycleStarted, "", data)
+	return c
+}
+
+// startStep publishes the start of step
+func (c *cycleEvents) startStep(step string) {
+	c.step = step
+	c.stepStart = time.Now()
+	c.publish(events.TypeStepStarted, step, nil)
+}
+
+// finishStep publishes the completion of the running step, with what it found in data
+func (c *cycleEvents) finishStep(data map[string]interface{}) {
+	c.endStep(events.StepCompleted, data)
+}
+
+// failStep publishes the failure of the running step
+func (c *cycleEvents) failStep(err error) {
+	c.endStep(events.StepFailed, map[string]interface{}{"error": err.Error()})
+}
+
+// endStep publishes the end of the running step with its status and duration
+func (c *cycleEvents) endStep(status string, data map[string]interface{}) {
+	if c.step == "" {
+		return
+	}
+	if data == nil {
+		data = make(map[string]interface{})
+	}
+	data["status"] = status
+	data["duration_ms"] = float64(time.Since(c.stepStart).Microseconds()) / 1000.0
+	c.publish(events.TypeStepFinished, c.step, data)
+	c.step = ""
+}
+
+// plan publishes the action plan and the target allocations it moves the vault to
+func (c *cycleEvents) plan(withdrawals, deposits []types.SubAction, targetAllocations map[types.PoolID]float64) {
+	c.publish(events.TypePlan, events.StepPlan, map[string]interface{}{
+		"withdrawals":        withdrawals,
+		"deposits":           deposits,
+		"target_allocations": targetAllocations,
+	})
+}
+
+// transaction publishes an included transaction of the running step
+func (c *cycleEvents) transaction(txHash string, gasFeeUSD float64) {
+	c.publish(events.TypeTransaction, c.step, map[string]interface{}{
+		"tx_hash":     txHash,
+		"gas_fee_usd": gasFeeUSD,
+	})
+}
+
+// finish publishes the end of the cycle with its outcome (see metrics.CycleOutcome*). A step that is
+// still running ended the cycle, so it is published as failed first.
+func (c *cycleEvents) finish(outcome string, snapshot *types.CycleSnapshot, duration time.Duration) {
+	c.endStep(events.StepFailed, nil)
+	c.publish(events.TypeCycleFinished, "", map[string]interface{}{
+		"outcome":            outcome,
+		"goal":               snapshot.ActionPlan.GoalDescription,
+		"transaction_hashes": snapshot.TransactionHashes,
+		"duration_ms":        float64(duration.Microseconds()) / 1000.0,
+	})
+}
+
+// publish sends an event of the cycle to the bus
+func (c *cycleEvents) publish(eventType events.Type, step string, data map[string]interface{}) {
+	c.bus.Publish(events.Event{
+		Type:        eventType,
+		CycleID:     c.cycleID,
+		CycleNumber: c.cycleNumber,
+		Step:        step,
+		Data:        data,
+	})
+}
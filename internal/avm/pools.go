@@ -0,0 +1,169 @@
+/*
+
+This file keeps the pools of the latest cycle that scored them for the web server's pool explorer
+(/api/pools): every pool the cycle considered with its score, rank, whether it was selected and
+why, its target allocation and the vault's holding. ExplainPool breaks a pool's score down with
+the pool data and scoring parameters of that cycle.
+
+*/
+
+package avm
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/elys-network/avm/internal/analyzer"
+	"github.com/elys-network/avm/internal/types"
+)
+
+// scoredUniverse is the pool universe of a scoring cycle, with what ExplainPool rescores pools with
+type scoredUniverse struct {
+	universe types.PoolUniverse
+	pools    map[types.PoolID]types.Pool
+	params   types.ScoringParameters
+}
+
+// PoolUniverse returns the pools of the latest cycle that scored them, best ranked first. Returns
+// types.ErrPoolUniverseUnavailable until a cycle has scored the pools.
+func (a *AVM) PoolUniverse() (*types.PoolUniverse, error) {
+	a.poolsMu.RLock()
+	defer a.poolsMu.RUnlock()
+
+	if a.scoredUniverse == nil {
+		return nil, types.ErrPoolUniverseUnavailable
+	}
+	universe := a.scoredUniverse.universe
+	return &universe, nil
+}
+
+// ExplainPool shows how a pool's score in the latest scoring cycle was derived from its scoring
+// parameters. Returns types.ErrPoolNotFound for a pool the cycle did not consider, and
+// types.ErrPoolNotScored for one it excluded before scoring.
+func (a *AVM) ExplainPool(poolID types.PoolID) (*types.PoolScoreExplanation, error) {
+	a.poolsMu.RLock()
+	latest := a.scoredUniverse
+	a.poolsMu.RUnlock()
+
+	if latest == nil {
+		return nil, types.ErrPoolUniverseUnavailable
+	}
+	var entry *types.PoolExplorerEntry
+	for i := range latest.universe.Pools {
+		if latest.universe.Pools[i].PoolID == poolID {
+			entry = &latest.universe.Pools[i]
+			break
+		}
+	}
+	if entry == nil {
+		return nil, fmt.Errorf("%w: %d", types.ErrPoolNotFound, poolID)
+	}
+	if entry.Score == nil {
+		return nil, fmt.Errorf("%w: pool %d is %s", types.ErrPoolNotScored, poolID, entry.Reason)
+	}
+
+	explanation, err := analyzer.ExplainPoolScore(latest.pools[poolID], latest.params)
+	if err != nil {
+		return nil, fmt.Errorf("failed to explain the score of pool %d: %w", poolID, err)
+	}
+	explanation.CycleNumber = latest.universe.CycleNumber
+	explanation.ScoringParamsID = latest.universe.ScoringParamsID
+	return &explanation, nil
+}
+
+// recordPoolUniverse keeps the pools a cycle considered once it has scored and selected them:
+// the scored pools by rank, then those excluded by the price integrity guard and quarantined.
+// poolsDataMap holds the pools before the price integrity exclusions.
+func (a *AVM) recordPoolUniverse(snapshot types.CycleSnapshot, poolsDataMap map[types.PoolID]types.Pool, scoredPools []types.PoolScoreResult, selectedPoolIDs []types.PoolID, elysPoolID types.PoolID, params types.ScoringParameters) {
+	universe := types.PoolUniverse{
+		CycleNumber:     snapshot.CycleNumber,
+		Timestamp:       snapshot.Timestamp,
+		BlockHeight:     snapshot.BlockHeight,
+		ScoringParamsID: snapshot.ScoringParamsID,
+		MaxPools:        params.MaxPools,
+		ElysPoolID:      elysPoolID,
+		Pools:           make([]types.PoolExplorerEntry, 0, len(poolsDataMap)),
+	}
+
+	holdings := make(map[types.PoolID]types.PositionSnapshot, len(snapshot.InitialPositions))
+	for _, position := range snapshot.InitialPositions {
+		holdings[position.PoolID] = position
+	}
+	newEntry := func(poolID types.PoolID) types.PoolExplorerEntry {
+		entry := types.PoolExplorerEntry{
+			PoolID:                 poolID,
+			TargetAllocation:       snapshot.TargetAllocations[poolID],
+			HoldingUSD:             holdings[poolID].EstimatedValueUSD,
+			HoldingAllocationRatio: holdings[poolID].AllocationPercent / 100,
+		}
+		if pool, exists := poolsDataMap[poolID]; exists {
+			entry.Assets = make([]string, len(pool.Assets))
+			for i, asset := range pool.Assets {
+				entry.Assets[i] = asset.Token.Symbol
+			}
+			entry.TvlUSD = pool.TvlUSD
+			entry.Volume7dUSD = pool.Volume7dUSD
+			entry.EdenRewardsAPR = pool.EdenRewardsAPR
+			entry.UsdcFeesAPR = pool.UsdcFeesAPR
+			entry.PriceImpactAPR = pool.PriceImpactAPR
+			entry.IsSmartShielded = pool.IsSmartShielded
+			entry.AgeInDays = pool.AgeInDays
+		}
+		return entry
+	}
+
+	// Ranked as SelectTopPools ranks them; the ELYS pool replaces the lowest selected pool
+	ranked := make([]types.PoolScoreResult, len(scoredPools))
+	copy(ranked, scoredPools)
+	sort.SliceStable(ranked, func(i, j int) bool { return ranked[i].Score > ranked[j].Score })
+	selected := make(map[types.PoolID]bool, len(selectedPoolIDs))
+	for _, poolID := range selectedPoolIDs {
+		selected[poolID] = true
+	}
+	for i, score := range ranked {
+		entry := newEntry(score.PoolID)
+		entry.Score = &score
+		entry.Rank = i + 1
+		entry.Selected = selected[score.PoolID]
+		switch {
+		case entry.Selected && entry.Rank > params.MaxPools:
+			entry.Reason = types.PoolSelectionForcedElys
+		case entry.Selected:
+			entry.Reason = types.PoolSelectionTopScore
+		case entry.Rank <= params.MaxPools:
+			entry.Reason = types.PoolSelectionReplacedByElys
+		default:
+			entry.Reason = types.PoolSelectionBelowMaxPools
+		}
+		universe.Pools = append(universe.Pools, entry)
+	}
+
+	if snapshot.PriceIntegrity != nil {
+		for _, poolID := range snapshot.PriceIntegrity.ExcludedPools {
+			entry := newEntry(poolID)
+			entry.Reason = types.PoolSelectionPriceFlagged
+			var flagged []string
+			for _, flag := range snapshot.PriceIntegrity.Flags {
+				pool := poolsDataMap[poolID]
+				if pool.HasDenom(flag.Denom) || (flag.IBCDenom != "" && pool.HasDenom(flag.IBCDenom)) {
+					flagged = append(flagged, flag.Symbol+": "+flag.Reason)
+				}
+			}
+			entry.ReasonDetail = strings.Join(flagged, "; ")
+			universe.Pools = append(universe.Pools, entry)
+		}
+	}
+	if snapshot.Quarantine != nil {
+		for _, quarantined := range snapshot.Quarantine.Pools {
+			entry := newEntry(quarantined.PoolID)
+			entry.Reason = types.PoolSelectionInvalidData
+			entry.ReasonDetail = quarantined.Reason
+			universe.Pools = append(universe.Pools, entry)
+		}
+	}
+
+	a.poolsMu.Lock()
+	defer a.poolsMu.Unlock()
+	a.scoredUniverse = &scoredUniverse{universe: universe, pools: poolsDataMap, params: params}
+}
@@ -0,0 +1,85 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+)
+
+// Data fetching concurrency and rate limit configuration loaded from environment variables.
+// These are populated at startup by the LoadConfig function.
+var (
+	// FetchMaxConcurrency is the maximum number of tokens whose price history is fetched at once.
+	FetchMaxConcurrency int
+	// FetchDefaultRateLimit is the maximum requests per second sent to any single HTTP host.
+	FetchDefaultRateLimit float64
+	// FetchHostRateLimits overrides FetchDefaultRateLimit for specific hosts (host -> requests per second).
+	FetchHostRateLimits map[string]float64
+)
+
+// loadFetchingConfig loads the data fetching configuration from environment variables.
+// This function is called by LoadConfig() in General.go.
+func loadFetchingConfig() error {
+	log.Info().Msg("Loading data fetching configuration from environment variables...")
+
+	maxConcurrency, err := getEnvAsUint64("FETCH_MAX_CONCURRENCY")
+	if err != nil {
+		return err
+	}
+	if maxConcurrency == 0 || maxConcurrency > 64 {
+		return fmt.Errorf("FETCH_MAX_CONCURRENCY must be between 1 and 64, got %d", maxConcurrency)
+	}
+	FetchMaxConcurrency = int(maxConcurrency)
+
+	FetchDefaultRateLimit, err = getEnvAsFloat64("FETCH_DEFAULT_RATE_LIMIT")
+	if err != nil {
+		return err
+	}
+	if FetchDefaultRateLimit <= 0 {
+		return errors.New("FETCH_DEFAULT_RATE_LIMIT must be positive")
+	}
+
+	hostLimitsStr, err := getEnv("FETCH_HOST_RATE_LIMITS")
+	if err != nil {
+		return err
+	}
+	FetchHostRateLimits, err = parseHostRateLimits(hostLimitsStr)
+	if err != nil {
+		return err
+	}
+
+	log.Debug().
+		Int("FetchMaxConcurrency", FetchMaxConcurrency).
+		Float64("FetchDefaultRateLimit", FetchDefaultRateLimit).
+		Int("FetchHostRateLimits", len(FetchHostRateLimits)).
+		Msg("Data fetching configuration loaded successfully.")
+
+	return nil
+}
+
+// parseHostRateLimits parses "host=rate,host=rate" into a map. An empty string yields an empty map.
+func parseHostRateLimits(value string) (map[string]float64, error) {
+	limits := make(map[string]float64)
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		host, rateStr, found := strings.Cut(entry, "=")
+		host = strings.ToLower(strings.TrimSpace(host))
+		if !found || host == "" {
+			return nil, fmt.Errorf("FETCH_HOST_RATE_LIMITS entry %q must be in host=rate form", entry)
+		}
+
+		rate, err := strconv.ParseFloat(strings.TrimSpace(rateStr), 64)
+		if err != nil || rate <= 0 {
+			return nil, fmt.Errorf("FETCH_HOST_RATE_LIMITS rate for %s must be a positive number, got: %s", host, rateStr)
+		}
+		limits[host] = rate
+	}
+	return limits, nil
+}
@@ -0,0 +1,64 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+)
+
+// Fixture modes, as used in FIXTURES_MODE.
+const (
+	// FixturesModeOff talks to the real node and APIs.
+	FixturesModeOff = "off"
+	// FixturesModeRecord talks to the real node and APIs and saves every response to FixturesFile.
+	FixturesModeRecord = "record"
+	// FixturesModeReplay serves every response from FixturesFile without any network access.
+	FixturesModeReplay = "replay"
+)
+
+// Fixture configuration loaded from environment variables.
+// These are populated at startup by the LoadConfig function.
+var (
+	// FixturesMode selects whether external I/O is recorded, replayed or neither.
+	FixturesMode string
+	// FixturesFile is the path of the fixture bundle written when recording and read when replaying.
+	FixturesFile string
+)
+
+// loadFixturesConfig loads the record/replay configuration from environment variables.
+// FIXTURES_FILE is only required when recording or replaying.
+// This function is called by LoadConfig() in General.go.
+func loadFixturesConfig() error {
+	log.Info().Msg("Loading fixture configuration from environment variables...")
+
+	mode, err := getEnv("FIXTURES_MODE")
+	if err != nil {
+		return err
+	}
+	FixturesMode = strings.ToLower(strings.TrimSpace(mode))
+	switch FixturesMode {
+	case FixturesModeOff:
+		log.Debug().Str("FixturesMode", FixturesMode).Msg("Fixture configuration loaded successfully.")
+		return nil
+	case FixturesModeRecord, FixturesModeReplay:
+	default:
+		return fmt.Errorf("FIXTURES_MODE must be one of %s, %s or %s, got %q",
+			FixturesModeOff, FixturesModeRecord, FixturesModeReplay, mode)
+	}
+
+	FixturesFile, err = getEnv("FIXTURES_FILE")
+	if err != nil {
+		return err
+	}
+	if strings.TrimSpace(FixturesFile) == "" {
+		return fmt.Errorf("FIXTURES_FILE must be set when FIXTURES_MODE is %s", FixturesMode)
+	}
+
+	log.Debug().
+		Str("FixturesMode", FixturesMode).
+		Str("FixturesFile", FixturesFile).
+		Msg("Fixture configuration loaded successfully.")
+
+	return nil
+}
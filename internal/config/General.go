@@ -93,6 +93,51 @@ func LoadConfig() error {
 		return err
 	}
 
+	// Load price integrity thresholds
+	if err := loadPriceIntegrityConfig(); err != nil {
+		return err
+	}
+
+	// Load historical price provider configuration
+	if err := loadPriceHistoryConfig(); err != nil {
+		return err
+	}
+
+	// Load data fetching concurrency and rate limits
+	if err := loadFetchingConfig(); err != nil {
+		return err
+	}
+
+	// Load data quarantine settings
+	if err := loadQuarantineConfig(); err != nil {
+		return err
+	}
+
+	// Load pool volume source and indexer settings
+	if err := loadVolumeConfig(); err != nil {
+		return err
+	}
+
+	// Load pool metrics retention settings
+	if err := loadPoolMetricsConfig(); err != nil {
+		return err
+	}
+
+	// Load cycle snapshot retention settings
+	if err := loadSnapshotRetentionConfig(); err != nil {
+		return err
+	}
+
+	// Load web API access settings
+	if err := loadWebConfig(); err != nil {
+		return err
+	}
+
+	// Load record/replay settings
+	if err := loadFixturesConfig(); err != nil {
+		return err
+	}
+
 	// Expand the tilde (~) in the keyring directory path to the user's home directory.
 	if strings.HasPrefix(KeyringDir, "~/") {
 		home, err := os.UserHomeDir()
@@ -132,6 +177,19 @@ func getEnvAsUint64(key string) (uint64, error) {
 	return value, nil
 }
 
+// getEnvAsBool retrieves an environment variable as a bool. Returns error if not set or invalid.
+func getEnvAsBool(key string) (bool, error) {
+	valueStr, err := getEnv(key)
+	if err != nil {
+		return false, err
+	}
+	value, err := strconv.ParseBool(valueStr)
+	if err != nil {
+		return false, errors.New("environment variable " + key + " must be a valid bool, got: " + valueStr)
+	}
+	return value, nil
+}
+
 // getEnvAsFloat64 retrieves an environment variable as a float64. Returns error if not set or invalid.
 func getEnvAsFloat64(key string) (float64, error) {
 	valueStr, err := getEnv(key)
@@ -63,6 +63,11 @@ var DefaultScoringParameters = types.ScoringParameters{
 	// Rationale: Deposits typically require more conservative sizing due to multi-token complexity.
 	// 80% provides meaningful reduction while maintaining substantial position entry.
 
+	MaxTokenExposure: 0.30, // Hold at most 30% of the vault in any single non-USDC token.
+	// Rationale: Several top pools often share the same volatile asset. Per-pool caps alone
+	// allow the vault to become, say, 60% ATOM across three pools. Capping aggregate exposure
+	// keeps a single token crash from dominating the vault's drawdown.
+
 	// --- APR Weights (Adjusted for Risk-Adjusted Returns) ---
 	EdenWeight: 0.8, // Weight for EDEN rewards component.
 	// Rationale: EDEN rewards are paid in a volatile token, not stable value.
@@ -0,0 +1,62 @@
+package config
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// Pool metrics retention configuration loaded from environment variables.
+// These are populated at startup by the LoadConfig function.
+var (
+	// PoolMetricsRawRetention is how long one point per cycle is kept before points are averaged per hour.
+	PoolMetricsRawRetention time.Duration
+	// PoolMetricsHourlyRetention is how long hourly points are kept before they are averaged per day.
+	PoolMetricsHourlyRetention time.Duration
+	// PoolMetricsDailyRetention is how long daily points are kept; 0 keeps them forever.
+	PoolMetricsDailyRetention time.Duration
+)
+
+// loadPoolMetricsConfig loads the pool metrics retention configuration from environment variables.
+// This function is called by LoadConfig() in General.go.
+func loadPoolMetricsConfig() error {
+	log.Info().Msg("Loading pool metrics retention configuration from environment variables...")
+
+	rawDays, err := getEnvAsUint64("POOL_METRICS_RAW_RETENTION_DAYS")
+	if err != nil {
+		return err
+	}
+	if rawDays == 0 {
+		return fmt.Errorf("POOL_METRICS_RAW_RETENTION_DAYS must be positive")
+	}
+
+	hourlyDays, err := getEnvAsUint64("POOL_METRICS_HOURLY_RETENTION_DAYS")
+	if err != nil {
+		return err
+	}
+	if hourlyDays < rawDays {
+		return fmt.Errorf("POOL_METRICS_HOURLY_RETENTION_DAYS (%d) must not be less than POOL_METRICS_RAW_RETENTION_DAYS (%d)", hourlyDays, rawDays)
+	}
+
+	dailyDays, err := getEnvAsUint64("POOL_METRICS_DAILY_RETENTION_DAYS")
+	if err != nil {
+		return err
+	}
+	if dailyDays != 0 && dailyDays < hourlyDays {
+		return fmt.Errorf("POOL_METRICS_DAILY_RETENTION_DAYS (%d) must be 0 or not less than POOL_METRICS_HOURLY_RETENTION_DAYS (%d)", dailyDays, hourlyDays)
+	}
+
+	day := 24 * time.Hour
+	PoolMetricsRawRetention = time.Duration(rawDays) * day
+	PoolMetricsHourlyRetention = time.Duration(hourlyDays) * day
+	PoolMetricsDailyRetention = time.Duration(dailyDays) * day
+
+	log.Debug().
+		Dur("PoolMetricsRawRetention", PoolMetricsRawRetention).
+		Dur("PoolMetricsHourlyRetention", PoolMetricsHourlyRetention).
+		Dur("PoolMetricsDailyRetention", PoolMetricsDailyRetention).
+		Msg("Pool metrics retention configuration loaded successfully.")
+
+	return nil
+}
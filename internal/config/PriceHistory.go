@@ -0,0 +1,88 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+)
+
+// Names of the supported historical price providers, as used in PRICE_HISTORY_PROVIDERS
+// and in the provider column of the token_symbol_mappings table.
+const (
+	PriceProviderCryptoCompare = "cryptocompare"
+	PriceProviderCoinGecko     = "coingecko"
+	PriceProviderFile          = "file"
+)
+
+// Historical price provider configuration loaded from environment variables.
+// These are populated at startup by the LoadConfig function.
+var (
+	// PriceHistoryProviders is the order in which providers are tried; later providers are fallbacks.
+	PriceHistoryProviders []string
+	// CoinGeckoAPIURL is the base URL of a CoinGecko-compatible API (e.g., "https://api.coingecko.com/api/v3").
+	CoinGeckoAPIURL string
+	// CoinGeckoAPIKey is the optional API key sent with CoinGecko requests.
+	CoinGeckoAPIKey string
+	// PriceHistoryFileDir is the directory holding <SYMBOL>.csv or <SYMBOL>.json price history files.
+	PriceHistoryFileDir string
+)
+
+// loadPriceHistoryConfig loads the historical price provider configuration from environment variables.
+// Provider-specific settings are only required when that provider is enabled.
+// This function is called by LoadConfig() in General.go.
+func loadPriceHistoryConfig() error {
+	log.Info().Msg("Loading price history provider configuration from environment variables...")
+
+	providersStr, err := getEnv("PRICE_HISTORY_PROVIDERS")
+	if err != nil {
+		return err
+	}
+
+	PriceHistoryProviders = make([]string, 0)
+	seen := make(map[string]bool)
+	for _, name := range strings.Split(providersStr, ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		switch name {
+		case PriceProviderCryptoCompare, PriceProviderCoinGecko, PriceProviderFile:
+		default:
+			return fmt.Errorf("PRICE_HISTORY_PROVIDERS contains unknown provider %q", name)
+		}
+		if seen[name] {
+			return fmt.Errorf("PRICE_HISTORY_PROVIDERS lists provider %q more than once", name)
+		}
+		seen[name] = true
+		PriceHistoryProviders = append(PriceHistoryProviders, name)
+	}
+	if len(PriceHistoryProviders) == 0 {
+		return errors.New("PRICE_HISTORY_PROVIDERS must list at least one provider")
+	}
+
+	if seen[PriceProviderCoinGecko] {
+		CoinGeckoAPIURL, err = getEnv("COINGECKO_API_URL")
+		if err != nil {
+			return err
+		}
+		CoinGeckoAPIURL = strings.TrimRight(CoinGeckoAPIURL, "/")
+		CoinGeckoAPIKey, _ = getEnv("COINGECKO_API_KEY") // Optional: public endpoints work without a key
+	}
+
+	if seen[PriceProviderFile] {
+		PriceHistoryFileDir, err = getEnv("PRICE_HISTORY_FILE_DIR")
+		if err != nil {
+			return err
+		}
+	}
+
+	log.Debug().
+		Strs("PriceHistoryProviders", PriceHistoryProviders).
+		Str("CoinGeckoAPIURL", CoinGeckoAPIURL).
+		Str("PriceHistoryFileDir", PriceHistoryFileDir).
+		Msg("Price history provider configuration loaded successfully.")
+
+	return nil
+}
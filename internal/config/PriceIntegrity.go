@@ -0,0 +1,53 @@
+package config
+
+import (
+	"errors"
+
+	"github.com/rs/zerolog/log"
+)
+
+// Price integrity thresholds loaded from environment variables.
+// These are populated at startup by the LoadConfig function.
+var (
+	// MaxOracleAmmDivergencePercent is the largest allowed gap between a token's oracle and AMM price (e.g., 5.0 for 5%).
+	MaxOracleAmmDivergencePercent float64
+	// MaxReferenceDivergencePercent is the largest allowed gap between the on-chain price and the CryptoCompare spot price.
+	MaxReferenceDivergencePercent float64
+	// MaxUSDCDepegPercent is the largest allowed deviation of USDC from $1 across any price source.
+	MaxUSDCDepegPercent float64
+)
+
+// loadPriceIntegrityConfig loads the price integrity thresholds from environment variables.
+// This function is called by LoadConfig() in General.go.
+func loadPriceIntegrityConfig() error {
+	log.Info().Msg("Loading price integrity configuration from environment variables...")
+
+	var err error
+
+	MaxOracleAmmDivergencePercent, err = getEnvAsFloat64("PRICE_MAX_ORACLE_AMM_DIVERGENCE_PERCENT")
+	if err != nil {
+		return err
+	}
+
+	MaxReferenceDivergencePercent, err = getEnvAsFloat64("PRICE_MAX_REFERENCE_DIVERGENCE_PERCENT")
+	if err != nil {
+		return err
+	}
+
+	MaxUSDCDepegPercent, err = getEnvAsFloat64("PRICE_MAX_USDC_DEPEG_PERCENT")
+	if err != nil {
+		return err
+	}
+
+	if MaxOracleAmmDivergencePercent <= 0 || MaxReferenceDivergencePercent <= 0 || MaxUSDCDepegPercent <= 0 {
+		return errors.New("price integrity thresholds must be positive")
+	}
+
+	log.Debug().
+		Float64("MaxOracleAmmDivergencePercent", MaxOracleAmmDivergencePercent).
+		Float64("MaxReferenceDivergencePercent", MaxReferenceDivergencePercent).
+		Float64("MaxUSDCDepegPercent", MaxUSDCDepegPercent).
+		Msg("Price integrity configuration loaded successfully.")
+
+	return nil
+}
@@ -0,0 +1,45 @@
+package config
+
+import (
+	"fmt"
+
+	"github.com/rs/zerolog/log"
+)
+
+// Data quarantine configuration loaded from environment variables.
+// These are populated at startup by the LoadConfig function.
+var (
+	// QuarantineEnabled makes tokens and pools whose data fails to fetch or validate be skipped
+	// for the cycle instead of aborting it.
+	QuarantineEnabled bool
+	// QuarantineMaxFailureRatio is the largest fraction of tokens or pools that may be quarantined
+	// before the cycle is aborted anyway (e.g., 0.25 for 25%).
+	QuarantineMaxFailureRatio float64
+)
+
+// loadQuarantineConfig loads the data quarantine configuration from environment variables.
+// This function is called by LoadConfig() in General.go.
+func loadQuarantineConfig() error {
+	log.Info().Msg("Loading data quarantine configuration from environment variables...")
+
+	var err error
+	QuarantineEnabled, err = getEnvAsBool("QUARANTINE_ENABLED")
+	if err != nil {
+		return err
+	}
+
+	QuarantineMaxFailureRatio, err = getEnvAsFloat64("QUARANTINE_MAX_FAILURE_RATIO")
+	if err != nil {
+		return err
+	}
+	if QuarantineMaxFailureRatio < 0 || QuarantineMaxFailureRatio >= 1 {
+		return fmt.Errorf("QUARANTINE_MAX_FAILURE_RATIO must be in [0, 1), got %f", QuarantineMaxFailureRatio)
+	}
+
+	log.Debug().
+		Bool("QuarantineEnabled", QuarantineEnabled).
+		Float64("QuarantineMaxFailureRatio", QuarantineMaxFailureRatio).
+		Msg("Data quarantine configuration loaded successfully.")
+
+	return nil
+}
@@ -0,0 +1,65 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// Cycle snapshot retention configuration loaded from environment variables.
+// These are populated at startup by the LoadConfig function.
+var (
+	// SnapshotRetention is how long cycle snapshots are kept in the database before they are
+	// archived to files and deleted; 0 keeps them forever.
+	SnapshotRetention time.Duration
+	// SnapshotArchiveDir is the directory archived snapshots are written to, one subdirectory per day.
+	SnapshotArchiveDir string
+	// SnapshotArchiveFormat is the file format of the archived tables: "csv", "jsonl" or "parquet".
+	SnapshotArchiveFormat string
+)
+
+// loadSnapshotRetentionConfig loads the cycle snapshot retention configuration from environment
+// variables. The archive settings are only required when a retention period is set.
+// This function is called by LoadConfig() in General.go.
+func loadSnapshotRetentionConfig() error {
+	log.Info().Msg("Loading snapshot retention configuration from environment variables...")
+
+	retentionDays, err := getEnvAsUint64("SNAPSHOT_RETENTION_DAYS")
+	if err != nil {
+		return err
+	}
+	SnapshotRetention = time.Duration(retentionDays) * 24 * time.Hour
+	if SnapshotRetention == 0 {
+		log.Debug().Msg("Snapshot retention disabled, snapshots are kept forever.")
+		return nil
+	}
+
+	SnapshotArchiveDir, err = getEnv("SNAPSHOT_ARCHIVE_DIR")
+	if err != nil {
+		return err
+	}
+	if strings.TrimSpace(SnapshotArchiveDir) == "" {
+		return fmt.Errorf("SNAPSHOT_ARCHIVE_DIR must not be empty when SNAPSHOT_RETENTION_DAYS is set")
+	}
+
+	format, err := getEnv("SNAPSHOT_ARCHIVE_FORMAT")
+	if err != nil {
+		return err
+	}
+	SnapshotArchiveFormat = strings.ToLower(strings.TrimSpace(format))
+	switch SnapshotArchiveFormat {
+	case "csv", "jsonl", "parquet":
+	default:
+		return fmt.Errorf("SNAPSHOT_ARCHIVE_FORMAT must be one of csv, jsonl or parquet, got %q", format)
+	}
+
+	log.Debug().
+		Dur("SnapshotRetention", SnapshotRetention).
+		Str("SnapshotArchiveDir", SnapshotArchiveDir).
+		Str("SnapshotArchiveFormat", SnapshotArchiveFormat).
+		Msg("Snapshot retention configuration loaded successfully.")
+
+	return nil
+}
@@ -0,0 +1,88 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+)
+
+// Sources of the 7-day pool volume, as used in VOLUME_SOURCE.
+const (
+	// VolumeSourceSupplyAPI reads volume from the Elys Supply API only.
+	VolumeSourceSupplyAPI = "supply_api"
+	// VolumeSourceOnChain reads volume from the on-chain swap event index, falling back to the
+	// Supply API while the index does not yet cover the full window.
+	VolumeSourceOnChain = "onchain"
+	// VolumeSourceCrossCheck uses the Supply API and logs pools whose on-chain volume disagrees.
+	VolumeSourceCrossCheck = "crosscheck"
+)
+
+// Pool volume configuration loaded from environment variables.
+// These are populated at startup by the LoadConfig function.
+var (
+	// VolumeSource selects where Pool.Volume7dUSD comes from.
+	VolumeSource string
+	// VolumeIndexerBackfillBlocks is how many blocks behind the chain head the indexer starts when it has no state.
+	VolumeIndexerBackfillBlocks uint64
+	// VolumeIndexerMaxBlocksPerRun caps how many blocks are indexed per cycle, so a backfill is spread over several cycles.
+	VolumeIndexerMaxBlocksPerRun uint64
+	// VolumeCrossCheckMaxDivergencePercent is the largest gap between the two volume sources before a pool is reported.
+	VolumeCrossCheckMaxDivergencePercent float64
+)
+
+// loadVolumeConfig loads the pool volume configuration from environment variables.
+// Indexer settings are only required when the on-chain index is used.
+// This function is called by LoadConfig() in General.go.
+func loadVolumeConfig() error {
+	log.Info().Msg("Loading pool volume configuration from environment variables...")
+
+	source, err := getEnv("VOLUME_SOURCE")
+	if err != nil {
+		return err
+	}
+	VolumeSource = strings.ToLower(strings.TrimSpace(source))
+	switch VolumeSource {
+	case VolumeSourceSupplyAPI:
+		log.Debug().Str("VolumeSource", VolumeSource).Msg("Pool volume configuration loaded successfully.")
+		return nil
+	case VolumeSourceOnChain, VolumeSourceCrossCheck:
+	default:
+		return fmt.Errorf("VOLUME_SOURCE must be one of %s, %s or %s, got %q",
+			VolumeSourceSupplyAPI, VolumeSourceOnChain, VolumeSourceCrossCheck, source)
+	}
+
+	VolumeIndexerBackfillBlocks, err = getEnvAsUint64("VOLUME_INDEXER_BACKFILL_BLOCKS")
+	if err != nil {
+		return err
+	}
+	if VolumeIndexerBackfillBlocks == 0 {
+		return errors.New("VOLUME_INDEXER_BACKFILL_BLOCKS must be positive")
+	}
+
+	VolumeIndexerMaxBlocksPerRun, err = getEnvAsUint64("VOLUME_INDEXER_MAX_BLOCKS_PER_RUN")
+	if err != nil {
+		return err
+	}
+	if VolumeIndexerMaxBlocksPerRun == 0 {
+		return errors.New("VOLUME_INDEXER_MAX_BLOCKS_PER_RUN must be positive")
+	}
+
+	VolumeCrossCheckMaxDivergencePercent, err = getEnvAsFloat64("VOLUME_CROSSCHECK_MAX_DIVERGENCE_PERCENT")
+	if err != nil {
+		return err
+	}
+	if VolumeCrossCheckMaxDivergencePercent <= 0 {
+		return errors.New("VOLUME_CROSSCHECK_MAX_DIVERGENCE_PERCENT must be positive")
+	}
+
+	log.Debug().
+		Str("VolumeSource", VolumeSource).
+		Uint64("VolumeIndexerBackfillBlocks", VolumeIndexerBackfillBlocks).
+		Uint64("VolumeIndexerMaxBlocksPerRun", VolumeIndexerMaxBlocksPerRun).
+		Float64("VolumeCrossCheckMaxDivergencePercent", VolumeCrossCheckMaxDivergencePercent).
+		Msg("Pool volume configuration loaded successfully.")
+
+	return nil
+}
@@ -0,0 +1,78 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/elys-network/avm/internal/types"
+	"github.com/rs/zerolog/log"
+)
+
+// Web API access configuration loaded from environment variables.
+// These are populated at startup by the LoadConfig function.
+var (
+	// WebAnonymousRole is the role of requests without an API key; empty requires a key for
+	// every API route.
+	WebAnonymousRole types.APIRole
+	// WebCORSAllowedOrigins are the browser origins allowed to call the API from another site.
+	// "*" allows every origin; empty allows none.
+	WebCORSAllowedOrigins []string
+)
+
+// loadWebConfig loads the web API access configuration from environment variables.
+// This function is called by LoadConfig() in General.go.
+func loadWebConfig() error {
+	log.Info().Msg("Loading web API access configuration from environment variables...")
+
+	anonymousRole, err := getEnv("WEB_ANONYMOUS_ROLE")
+	if err != nil {
+		return err
+	}
+	switch strings.ToLower(strings.TrimSpace(anonymousRole)) {
+	case "", "none":
+		WebAnonymousRole = ""
+	case string(types.APIRoleViewer):
+		WebAnonymousRole = types.APIRoleViewer
+	default:
+		// Operator and admin actions always need a key
+		return fmt.Errorf("WEB_ANONYMOUS_ROLE must be none or viewer, got %q", anonymousRole)
+	}
+
+	originsStr, err := getEnv("WEB_CORS_ALLOWED_ORIGINS")
+	if err != nil {
+		return err
+	}
+	WebCORSAllowedOrigins, err = parseAllowedOrigins(originsStr)
+	if err != nil {
+		return err
+	}
+
+	log.Debug().
+		Str("WebAnonymousRole", string(WebAnonymousRole)).
+		Strs("WebCORSAllowedOrigins", WebCORSAllowedOrigins).
+		Msg("Web API access configuration loaded successfully.")
+
+	return nil
+}
+
+// parseAllowedOrigins parses a comma-separated list of origins such as "https://app.elys.network".
+// An empty string yields an empty list.
+func parseAllowedOrigins(value string) ([]string, error) {
+	origins := make([]string, 0)
+	for _, origin := range strings.Split(value, ",") {
+		origin = strings.TrimRight(strings.TrimSpace(origin), "/")
+		if origin == "" {
+			continue
+		}
+		if origin != "*" {
+			u, err := url.Parse(origin)
+			if err != nil || u.Scheme == "" || u.Host == "" || (u.Path != "" && u.Path != "/") {
+				return nil, fmt.Errorf("WEB_CORS_ALLOWED_ORIGINS entry %q must be an origin such as https://example.com, or *", origin)
+			}
+			origin = strings.ToLower(u.Scheme + "://" + u.Host)
+		}
+		origins = append(origins, origin)
+	}
+	return origins, nil
+}
@@ -0,0 +1,226 @@
+/*
+
+This file implements the cycle diff. It compares two cycle snapshots, usually an earlier and a
+later one, to show why the allocations shifted between them:
+  - the target allocations the analyzer set in each cycle
+  - the positions each cycle left the vault with (FinalPositions)
+  - the versions of the scoring parameters used, with the parameters whose values differ
+  - each pool's score, rank and score components, from the snapshots' PoolScores
+
+Snapshots saved before pool scores were kept have no PoolScores; the score section is then empty.
+
+*/
+
+package cyclediff
+
+import (
+	"math"
+	"sort"
+
+	"github.com/elys-network/avm/internal/types"
+)
+
+// epsilon is the smallest difference reported as a change, so floating point noise is not
+const epsilon = 1e-9
+
+// Compare returns the diff from one snapshot to another. fromParams and toParams are the
+// scoring parameters the snapshots reference, or nil if they are unknown; the parameter
+// changes are only listed when both are known.
+func Compare(from, to types.CycleSnapshot, fromParams, toParams *types.ScoringParametersVersion) types.CycleDiff {
+	return types.CycleDiff{
+		From:               side(from),
+		To:                 side(to),
+		VaultValueDeltaUSD: to.FinalVaultValueUSD - from.FinalVaultValueUSD,
+		ScoringParams:      compareScoringParams(from.ScoringParamsID, to.ScoringParamsID, fromParams, toParams),
+		TargetAllocations:  compareAllocations(from.TargetAllocations, to.TargetAllocations),
+		Positions:          comparePositions(from.FinalPositions, to.FinalPositions),
+		PoolScores:         compareScores(from.PoolScores, to.PoolScores),
+	}
+}
+
+// side identifies a snapshot in the diff
+func side(snapshot types.CycleSnapshot) types.CycleDiffSide {
+	return types.CycleDiffSide{
+		SnapshotID:         snapshot.SnapshotID,
+		CycleNumber:        snapshot.CycleNumber,
+		Timestamp:          snapshot.Timestamp,
+		BlockHeight:        snapshot.BlockHeight,
+		ScoringParamsID:    snapshot.ScoringParamsID,
+		FinalVaultValueUSD: snapshot.FinalVaultValueUSD,
+		FinalLiquidUSDC:    snapshot.FinalLiquidUSDC,
+		ScoresRecorded:     snapshot.PoolScores != nil,
+	}
+}
+
+// compareScoringParams compares the parameter versions of the two snapshots
+func compareScoringParams(fromID, toID *int64, fromParams, toParams *types.ScoringParametersVersion) types.ScoringParamsDiff {
+	diff := types.ScoringParamsDiff{
+		FromParamsID: fromID,
+		ToParamsID:   toID,
+		Changed:      (fromID == nil) != (toID == nil) || (fromID != nil && toID != nil && *fromID != *toID),
+		Changes:      make([]types.ScoringParameterChange, 0),
+	}
+	if fromParams != nil {
+		diff.FromVersion = fromParams.Version
+	}
+	if toParams != nil {
+		diff.ToVersion = toParams.Version
+	}
+	if diff.Changed && fromParams != nil && toParams != nil {
+		diff.Changes = types.DiffScoringParameters(fromParams.Parameters, toParams.Parameters)
+	}
+	return diff
+}
+
+// compareAllocations compares the target allocations of every pool in either snapshot
+func compareAllocations(from, to map[types.PoolID]float64) []types.AllocationDiff {
+	poolIDs := make(map[types.PoolID]bool, len(from)+len(to))
+	for poolID := range from {
+		poolIDs[poolID] = true
+	}
+	for poolID := range to {
+		poolIDs[poolID] = true
+	}
+
+	diffs := make([]types.AllocationDiff, 0, len(poolIDs))
+	for _, poolID := range sortedPoolIDs(poolIDs) {
+		fromAllocation, inFrom := from[poolID]
+		toAllocation, inTo := to[poolID]
+		diffs = append(diffs, types.AllocationDiff{
+			PoolID: poolID,
+			From:   fromAllocation,
+			To:     toAllocation,
+			Delta:  toAllocation - fromAllocation,
+			Change: change(inFrom, inTo, math.Abs(toAllocation-fromAllocation) > epsilon),
+		})
+	}
+	return diffs
+}
+
+// comparePositions compares the positions of every pool held at the end of either snapshot
+func comparePositions(from, to []types.PositionSnapshot) []types.PositionDiff {
+	fromPositions := make(map[types.PoolID]types.PositionSnapshot, len(from))
+	toPositions := make(map[types.PoolID]types.PositionSnapshot, len(to))
+	poolIDs := make(map[types.PoolID]bool, len(from)+len(to))
+	for _, position := range from {
+		fromPositions[position.PoolID] = position
+		poolIDs[position.PoolID] = true
+	}
+	for _, position := range to {
+		toPositions[position.PoolID] = position
+		poolIDs[position.PoolID] = true
+	}
+
+	diffs := make([]types.PositionDiff, 0, len(poolIDs))
+	for _, poolID := range sortedPoolIDs(poolIDs) {
+		fromPosition, inFrom := fromPositions[poolID]
+		toPosition, inTo := toPositions[poolID]
+		assets := toPosition.PoolAssets
+		if !inTo {
+			assets = fromPosition.PoolAssets
+		}
+		diffs = append(diffs, types.PositionDiff{
+			PoolID:                 poolID,
+			PoolAssets:             assets,
+			FromLPShares:           fromPosition.LPShares,
+			ToLPShares:             toPosition.LPShares,
+			FromValueUSD:           fromPosition.EstimatedValueUSD,
+			ToValueUSD:             toPosition.EstimatedValueUSD,
+			ValueDeltaUSD:          toPosition.EstimatedValueUSD - fromPosition.EstimatedValueUSD,
+			FromAllocationPercent:  fromPosition.AllocationPercent,
+			ToAllocationPercent:    toPosition.AllocationPercent,
+			AllocationDeltaPercent: toPosition.AllocationPercent - fromPosition.AllocationPercent,
+			Change:                 change(inFrom, inTo, fromPosition.LPShares != toPosition.LPShares),
+		})
+	}
+	return diffs
+}
+
+// compareScores compares the score, rank and score components of every pool scored in either
+// snapshot. Returns no pools unless both snapshots recorded their scores.
+func compareScores(from, to []types.PoolScoreResult) []types.PoolScoreDiff {
+	if from == nil || to == nil {
+		return make([]types.PoolScoreDiff, 0)
+	}
+
+	fromScores, fromRanks := rankScores(from)
+	toScores, toRanks := rankScores(to)
+	poolIDs := make(map[types.PoolID]bool, len(fromScores)+len(toScores))
+	for poolID := range fromScores {
+		poolIDs[poolID] = true
+	}
+	for poolID := range toScores {
+		poolIDs[poolID] = true
+	}
+
+	diffs := make([]types.PoolScoreDiff, 0, len(poolIDs))
+	for _, poolID := range sortedPoolIDs(poolIDs) {
+		fromScore, inFrom := fromScores[poolID]
+		toScore, inTo := toScores[poolID]
+		diff := types.PoolScoreDiff{
+			PoolID:   poolID,
+			FromRank: fromRanks[poolID],
+			ToRank:   toRanks[poolID],
+		}
+		if inFrom {
+			diff.From = &fromScore
+		}
+		if inTo {
+			diff.To = &toScore
+		}
+		if inFrom && inTo {
+			diff.ScoreDelta = toScore.Score - fromScore.Score
+			diff.ComponentDeltas = &types.ScoreComponentDeltas{
+				WeightedAPR:          toScore.Components.WeightedAPR - fromScore.Components.WeightedAPR,
+				ILRisk:               toScore.Components.ILRisk - fromScore.Components.ILRisk,
+				AnnualizedVolatility: toScore.Components.AnnualizedVolatility - fromScore.Components.AnnualizedVolatility,
+				RewardScoreComponent: toScore.Components.RewardScoreComponent - fromScore.Components.RewardScoreComponent,
+				RiskScoreComponent:   toScore.Components.RiskScoreComponent - fromScore.Components.RiskScoreComponent,
+				TvlScoreComponent:    toScore.Components.TvlScoreComponent - fromScore.Components.TvlScoreComponent,
+				BonusScoreComponent:  toScore.Components.BonusScoreComponent - fromScore.Components.BonusScoreComponent,
+			}
+		}
+		diff.Change = change(inFrom, inTo, math.Abs(diff.ScoreDelta) > epsilon || diff.FromRank != diff.ToRank)
+		diffs = append(diffs, diff)
+	}
+	return diffs
+}
+
+// rankScores indexes the scores by pool and ranks them as SelectTopPools does, 1 for the best
+func rankScores(scores []types.PoolScoreResult) (map[types.PoolID]types.PoolScoreResult, map[types.PoolID]int) {
+	ranked := make([]types.PoolScoreResult, len(scores))
+	copy(ranked, scores)
+	sort.SliceStable(ranked, func(i, j int) bool { return ranked[i].Score > ranked[j].Score })
+
+	byPool := make(map[types.PoolID]types.PoolScoreResult, len(ranked))
+	ranks := make(map[types.PoolID]int, len(ranked))
+	for i, score := range ranked {
+		byPool[score.PoolID] = score
+		ranks[score.PoolID] = i + 1
+	}
+	return byPool, ranks
+}
+
+// change classifies a pool by the snapshots it is in and whether its value changed
+func change(inFrom, inTo, changed bool) types.PoolChange {
+	switch {
+	case !inFrom:
+		return types.PoolChangeAdded
+	case !inTo:
+		return types.PoolChangeRemoved
+	case changed:
+		return types.PoolChangeChanged
+	default:
+		return types.PoolChangeUnchanged
+	}
+}
+
+// sortedPoolIDs returns the pool IDs in ascending order
+func sortedPoolIDs(poolIDs map[types.PoolID]bool) []types.PoolID {
+	sorted := make([]types.PoolID, 0, len(poolIDs))
+	for poolID := range poolIDs {
+		sorted = append(sorted, poolID)
+	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	return sorted
+}
@@ -0,0 +1,145 @@
+/*
+This file is used to fetch historical price data from a CoinGecko-compatible API.
+
+CoinGecko returns raw price samples (5-minutely for ranges under a day, hourly for up to 90 days),
+which are bucketed into hourly close prices to match the CryptoCompare bars.
+*/
+
+package datafetcher
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/elys-network/avm/internal/config"
+	"github.com/elys-network/avm/internal/fixtures"
+	"github.com/elys-network/avm/internal/types"
+	"github.com/elys-network/avm/internal/utils"
+)
+
+// CoinGeckoProvider serves hourly price history from the CoinGecko market_chart/range API
+type CoinGeckoProvider struct {
+	baseURL string
+	apiKey  string
+	symbols map[string]string // Token symbol -> CoinGecko coin ID
+}
+
+type coinGeckoMarketChartResponse struct {
+	Prices [][2]float64 `json:"prices"` // [unix milliseconds, price]
+}
+
+// Name returns the provider name
+func (p *CoinGeckoProvider) Name() string {
+	return config.PriceProviderCoinGecko
+}
+
+// FetchHourlyPrices fetches price samples covering the requested hours and buckets them into hourly bars
+func (p *CoinGeckoProvider) FetchHourlyPrices(ctx context.Context, tokenSymbol string, hours int, to time.Time) ([]types.PriceData, error) {
+	if p.baseURL == "" {
+		return nil, fmt.Errorf("%w: CoinGecko API URL is not configured", ErrAPIConfiguration)
+	}
+	if hours <= 0 {
+		return nil, fmt.Errorf("number of hours to fetch must be positive: %d", hours)
+	}
+
+	// CoinGecko identifies coins by ID (e.g., "cosmos"), which is rarely the symbol itself
+	coinID := strings.ToLower(resolveProviderSymbol(p.symbols, tokenSymbol))
+
+	end := to.UTC().Truncate(time.Hour)
+	start := end.Add(-time.Duration(hours-1) * time.Hour)
+	requestURL := fmt.Sprintf("%s/coins/%s/market_chart/range?vs_currency=usd&from=%d&to=%d",
+		p.baseURL, url.PathEscape(coinID), start.Unix(), end.Add(time.Hour).Unix())
+
+	client := &http.Client{
+		Timeout:   TIMEOUT_SECONDS * time.Second,
+		Transport: fixtures.Transport(),
+	}
+
+	var lastErr error
+	for attempt := 1; attempt <= MAX_RETRIES; attempt++ {
+		samples, err := p.fetchSamples(ctx, client, requestURL)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, fmt.Errorf("CoinGecko fetch for %s cancelled: %w", coinID, ctx.Err())
+			}
+			lastErr = err
+			priceLogger.Warn().
+				Err(err).
+				Str("coinID", coinID).
+				Int("attempt", attempt).
+				Msg("CoinGecko request failed, will retry if attempts remain")
+			if attempt < MAX_RETRIES {
+				if err := utils.SleepContext(ctx, time.Duration(attempt)*time.Second); err != nil {
+					return nil, fmt.Errorf("CoinGecko fetch for %s cancelled: %w", coinID, err)
+				}
+			}
+			continue
+		}
+
+		bars, err := bucketHourly(samples, hours, end)
+		if err != nil {
+			return nil, fmt.Errorf("CoinGecko data for %s: %w", coinID, err)
+		}
+
+		priceLogger.Info().
+			Str("coinID", coinID).
+			Int("dataPoints", len(bars)).
+			Msg("Successfully retrieved price data from CoinGecko")
+		return bars, nil
+	}
+
+	return nil, fmt.Errorf("failed to fetch CoinGecko price data for %s after %d attempts: %w", coinID, MAX_RETRIES, lastErr)
+}
+
+// fetchSamples performs a single market_chart/range request and returns the raw price samples
+func (p *CoinGeckoProvider) fetchSamples(ctx context.Context, client *http.Client, requestURL string) ([]types.PriceData, error) {
+	headers := map[string]string{"Accept": "application/json"}
+	if p.apiKey != "" {
+		// The pro API and the public (demo) API expect different key headers
+		if strings.Contains(p.baseURL, "pro-api") {
+			headers["x-cg-pro-api-key"] = p.apiKey
+		} else {
+			headers["x-cg-demo-api-key"] = p.apiKey
+		}
+	}
+
+	resp, err := fetchHTTP(ctx, client, requestURL, headers)
+	if err != nil {
+		return nil, fmt.Errorf("HTTP request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("API returned status %d", resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+
+	var chart coinGeckoMarketChartResponse
+	if err := json.Unmarshal(body, &chart); err != nil {
+		return nil, fmt.Errorf("failed to parse JSON response: %w", err)
+	}
+	if len(chart.Prices) == 0 {
+		return nil, errors.New("no price data in response")
+	}
+
+	samples := make([]types.PriceData, 0, len(chart.Prices))
+	for _, point := range chart.Prices {
+		samples = append(samples, types.PriceData{
+			Timestamp: time.UnixMilli(int64(point[0])).UTC(),
+			Price:     point[1],
+		})
+	}
+
+	return samples, nil
+}
@@ -0,0 +1,65 @@
+/*
+This file contains the shared HTTP plumbing for the data fetchers.
+
+Every outgoing HTTP request goes through fetchHTTP, which honours context cancellation and
+the per-host rate limits from FETCH_DEFAULT_RATE_LIMIT and FETCH_HOST_RATE_LIMITS, so that
+parallel workers cannot exceed an API's quota between them. API keys in the URL are redacted from
+the errors it returns, so callers can log and wrap them freely.
+*/
+
+package datafetcher
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sync"
+
+	"github.com/elys-network/avm/internal/config"
+	"github.com/elys-network/avm/internal/fixtures"
+	"github.com/elys-network/avm/internal/utils"
+)
+
+var (
+	hostLimiterOnce sync.Once
+	hostLimiter     *utils.HostRateLimiter
+)
+
+// getHostLimiter returns the process-wide per-host rate limiter, built from config on first use
+func getHostLimiter() *utils.HostRateLimiter {
+	hostLimiterOnce.Do(func() {
+		hostLimiter = utils.NewHostRateLimiter(config.FetchDefaultRateLimit, config.FetchHostRateLimits)
+	})
+	return hostLimiter
+}
+
+// fetchHTTP waits for the host's rate limit and performs a GET request bound to ctx
+func fetchHTTP(ctx context.Context, client *http.Client, requestURL string, headers map[string]string) (*http.Response, error) {
+	if err := getHostLimiter().Wait(ctx, requestURL); err != nil {
+		return nil, fmt.Errorf("rate limit wait cancelled: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, requestURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build request: %w", redactURLError(err))
+	}
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, redactURLError(err)
+	}
+	return resp, nil
+}
+
+// redactURLError replaces the URL carried by a *url.Error with its redacted form,
+// since the URL may hold an API key
+func redactURLError(err error) error {
+	if urlErr, ok := err.(*url.Error); ok {
+		return &url.Error{Op: urlErr.Op, URL: fixtures.RedactURL(urlErr.URL), Err: urlErr.Err}
+	}
+	return err
+}
@@ -0,0 +1,151 @@
+/*
+This file is used to load historical price data from local files.
+
+Files are named after the provider symbol, e.g. ATOM.csv or ATOM.json, in PRICE_HISTORY_FILE_DIR.
+CSV files have "timestamp,price" rows, where timestamp is unix seconds or RFC3339; a header row is optional.
+JSON files hold an array of {"timestamp": "<RFC3339>", "price": <number>} objects.
+This is intended for backtests, offline development, and tokens no API covers.
+*/
+
+package datafetcher
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/elys-network/avm/internal/config"
+	"github.com/elys-network/avm/internal/types"
+)
+
+// FilePriceHistoryProvider serves hourly price history from local CSV or JSON files
+type FilePriceHistoryProvider struct {
+	dir     string
+	symbols map[string]string // Token symbol -> file name (without extension)
+}
+
+// Name returns the provider name
+func (p *FilePriceHistoryProvider) Name() string {
+	return config.PriceProviderFile
+}
+
+// FetchHourlyPrices loads the token's price file and buckets it into the requested hourly bars
+func (p *FilePriceHistoryProvider) FetchHourlyPrices(ctx context.Context, tokenSymbol string, hours int, to time.Time) ([]types.PriceData, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if p.dir == "" {
+		return nil, fmt.Errorf("%w: price history file directory is not configured", ErrAPIConfiguration)
+	}
+	if hours <= 0 {
+		return nil, fmt.Errorf("number of hours to fetch must be positive: %d", hours)
+	}
+
+	name := strings.ToUpper(resolveProviderSymbol(p.symbols, tokenSymbol))
+
+	var samples []types.PriceData
+	csvPath := filepath.Join(p.dir, name+".csv")
+	jsonPath := filepath.Join(p.dir, name+".json")
+	switch {
+	case fileExists(csvPath):
+		loaded, err := loadPriceCSV(csvPath)
+		if err != nil {
+			return nil, err
+		}
+		samples = loaded
+	case fileExists(jsonPath):
+		loaded, err := loadPriceJSON(jsonPath)
+		if err != nil {
+			return nil, err
+		}
+		samples = loaded
+	default:
+		return nil, fmt.Errorf("no price history file for %s in %s", name, p.dir)
+	}
+
+	bars, err := bucketHourly(samples, hours, to)
+	if err != nil {
+		return nil, fmt.Errorf("price history file for %s: %w", name, err)
+	}
+
+	priceLogger.Info().
+		Str("symbol", name).
+		Int("dataPoints", len(bars)).
+		Msg("Loaded price data from local file")
+	return bars, nil
+}
+
+// fileExists reports whether path exists and is a regular file
+func fileExists(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.Mode().IsRegular()
+}
+
+// loadPriceCSV reads "timestamp,price" rows from a CSV file
+func loadPriceCSV(path string) ([]types.PriceData, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	defer file.Close()
+
+	reader := csv.NewReader(file)
+	reader.FieldsPerRecord = 2
+	reader.TrimLeadingSpace = true
+
+	samples := make([]types.PriceData, 0)
+	for line := 1; ; line++ {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s line %d: %w", path, line, err)
+		}
+
+		timestamp, tsErr := parseFileTimestamp(record[0])
+		price, priceErr := strconv.ParseFloat(strings.TrimSpace(record[1]), 64)
+		if tsErr != nil || priceErr != nil {
+			if line == 1 {
+				continue // Header row
+			}
+			return nil, fmt.Errorf("invalid row in %s line %d: %q", path, line, strings.Join(record, ","))
+		}
+
+		samples = append(samples, types.PriceData{Timestamp: timestamp, Price: price})
+	}
+
+	return samples, nil
+}
+
+// loadPriceJSON reads an array of types.PriceData from a JSON file
+func loadPriceJSON(path string) ([]types.PriceData, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	var samples []types.PriceData
+	if err := json.Unmarshal(data, &samples); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+
+	return samples, nil
+}
+
+// parseFileTimestamp accepts unix seconds or RFC3339 timestamps
+func parseFileTimestamp(value string) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	if unix, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return time.Unix(unix, 0).UTC(), nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
@@ -8,6 +8,7 @@ to calculate volatility accurately for financial decisions involving millions of
 package datafetcher
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -18,8 +19,11 @@ import (
 	"strings"
 	"time"
 
+	"github.com/elys-network/avm/internal/config"
+	"github.com/elys-network/avm/internal/fixtures"
 	"github.com/elys-network/avm/internal/logger"
 	"github.com/elys-network/avm/internal/types"
+	"github.com/elys-network/avm/internal/utils"
 )
 
 var priceLogger = logger.GetForComponent("price_retriever")
@@ -146,27 +150,47 @@ func validatePriceDataPoint(data struct {
 
 // FetchHistoricalPriceData fetches exactly 30 days of hourly price data with strict validation
 func FetchHistoricalPriceData(coin string) ([]types.PriceData, error) {
+	return fetchHourlyPriceData(context.Background(), coin, REQUIRED_HOURS, time.Time{})
+}
+
+// fetchHourlyPriceData fetches the given number of hourly bars ending at the hour of toTime.
+// A zero toTime requests the most recent bars.
+func fetchHourlyPriceData(ctx context.Context, coin string, hours int, toTime time.Time) ([]types.PriceData, error) {
+	if hours <= 0 {
+		return nil, fmt.Errorf("number of hours to fetch must be positive: %d", hours)
+	}
+
 	// Normalize coin symbol
 	originalCoin := coin
 	coin = strings.TrimSpace(strings.ToUpper(coin))
 
-	fmt.Printf("DEBUG: Starting price data fetch for %s (normalized: %s)\n", originalCoin, coin)
+	priceLogger.Debug().
+		Str("coin", originalCoin).
+		Str("normalizedCoin", coin).
+		Msg("Starting price data fetch")
 
 	apiKey := os.Getenv("CRYPTOCOMPARE_API")
 	if apiKey == "" {
-		fmt.Printf("ERROR: CRYPTOCOMPARE_API environment variable not set for %s\n", coin)
+		priceLogger.Error().Str("coin", coin).Msg("CRYPTOCOMPARE_API environment variable not set")
 		return nil, errors.New("CRYPTOCOMPARE_API environment variable is required")
 	}
 
 	// Build request URL
 	url := fmt.Sprintf("%s?fsym=%s&tsym=USDT&limit=%d&api_key=%s",
-		BASE_URL, coin, REQUIRED_HOURS, apiKey)
+		BASE_URL, coin, hours, apiKey)
+	if !toTime.IsZero() {
+		url = fmt.Sprintf("%s&toTs=%d", url, toTime.Unix())
+	}
 
-	fmt.Printf("DEBUG: Fetching price data for %s from URL: %s\n", coin, url)
+	priceLogger.Debug().
+		Str("coin", coin).
+		Str("url", fixtures.RedactURL(url)).
+		Msg("Fetching price data")
 
 	// Create HTTP client with timeout
 	client := &http.Client{
-		Timeout: TIMEOUT_SECONDS * time.Second,
+		Timeout:   TIMEOUT_SECONDS * time.Second,
+		Transport: fixtures.Transport(),
 	}
 
 	var lastErr error
@@ -177,19 +201,24 @@ func FetchHistoricalPriceData(coin string) ([]types.PriceData, error) {
 			Int("maxRetries", MAX_RETRIES).
 			Msg("Making API request")
 
-		resp, err := client.Get(url)
+		resp, err := fetchHTTP(ctx, client, url, nil)
 
 		if err != nil {
+			if ctx.Err() != nil {
+				return nil, fmt.Errorf("price data fetch for %s cancelled: %w", coin, ctx.Err())
+			}
 			lastErr = fmt.Errorf("HTTP request failed on attempt %d: %w", attempt, err)
 			priceLogger.Warn().
 				Err(err).
 				Str("coin", coin).
 				Int("attempt", attempt).
-				Str("url", url).
+				Str("url", fixtures.RedactURL(url)).
 				Msg("HTTP request failed, will retry if attempts remain")
 
 			if attempt < MAX_RETRIES {
-				time.Sleep(time.Duration(attempt) * time.Second) // Exponential backoff
+				if err := utils.SleepContext(ctx, time.Duration(attempt)*time.Second); err != nil { // Exponential backoff
+					return nil, fmt.Errorf("price data fetch for %s cancelled: %w", coin, err)
+				}
 				continue
 			}
 			break
@@ -202,7 +231,7 @@ func FetchHistoricalPriceData(coin string) ([]types.PriceData, error) {
 			Msg("HTTP request successful")
 
 		// Process successful response
-		result, err := processAPIResponse(resp, coin)
+		result, err := processAPIResponse(resp, coin, hours)
 		if err != nil {
 			lastErr = err
 			resp.Body.Close()
@@ -212,7 +241,9 @@ func FetchHistoricalPriceData(coin string) ([]types.PriceData, error) {
 					Str("coin", coin).
 					Int("attempt", attempt).
 					Msg("API response processing failed, will retry if attempts remain")
-				time.Sleep(time.Duration(attempt) * time.Second)
+				if err := utils.SleepContext(ctx, time.Duration(attempt)*time.Second); err != nil {
+					return nil, fmt.Errorf("price data fetch for %s cancelled: %w", coin, err)
+				}
 				continue
 			}
 			break
@@ -230,8 +261,9 @@ func FetchHistoricalPriceData(coin string) ([]types.PriceData, error) {
 	return nil, fmt.Errorf("failed to fetch price data for %s after %d attempts: %w", coin, MAX_RETRIES, lastErr)
 }
 
-// processAPIResponse handles the API response with strict validation
-func processAPIResponse(resp *http.Response, coin string) ([]types.PriceData, error) {
+// processAPIResponse handles the API response with strict validation.
+// It returns exactly the requiredHours most recent data points.
+func processAPIResponse(resp *http.Response, coin string, requiredHours int) ([]types.PriceData, error) {
 	defer resp.Body.Close()
 
 	// Validate HTTP status
@@ -308,7 +340,6 @@ func processAPIResponse(resp *http.Response, coin string) ([]types.PriceData, er
 
 	// Log warning if API indicates a warning
 	if cryptoResp.HasWarning {
-		fmt.Printf("WARNING: API returned warning for %s (data points: %d)\n", coin, len(cryptoResp.Data.Data))
 		priceLogger.Warn().
 			Str("coin", coin).
 			Int("dataPointCount", len(cryptoResp.Data.Data)).
@@ -318,13 +349,13 @@ func processAPIResponse(resp *http.Response, coin string) ([]types.PriceData, er
 
 	// Validate we received the required amount of data
 	dataPoints := len(cryptoResp.Data.Data)
-	if dataPoints < REQUIRED_HOURS {
+	if dataPoints < requiredHours {
 		priceLogger.Error().
 			Str("coin", coin).
 			Int("received", dataPoints).
-			Int("required", REQUIRED_HOURS).
+			Int("required", requiredHours).
 			Msg("Insufficient data points received")
-		return nil, fmt.Errorf("insufficient data for %s: received %d hours, required %d", coin, dataPoints, REQUIRED_HOURS)
+		return nil, fmt.Errorf("insufficient data for %s: received %d hours, required %d", coin, dataPoints, requiredHours)
 	}
 
 	// Process and validate each data point
@@ -351,13 +382,13 @@ func processAPIResponse(resp *http.Response, coin string) ([]types.PriceData, er
 	}
 
 	// Final validation - ensure we have exactly what we need
-	if len(priceData) < REQUIRED_HOURS {
+	if len(priceData) < requiredHours {
 		priceLogger.Error().
 			Str("coin", coin).
 			Int("validDataPoints", len(priceData)).
-			Int("required", REQUIRED_HOURS).
+			Int("required", requiredHours).
 			Msg("Not enough valid data points after validation")
-		return nil, fmt.Errorf("insufficient valid data for %s: %d valid points, %d required", coin, len(priceData), REQUIRED_HOURS)
+		return nil, fmt.Errorf("insufficient valid data for %s: %d valid points, %d required", coin, len(priceData), requiredHours)
 	}
 
 	// Validate timestamps are in chronological order and contiguous
@@ -366,8 +397,8 @@ func processAPIResponse(resp *http.Response, coin string) ([]types.PriceData, er
 	}
 
 	// Take exactly the required number of most recent data points
-	if len(priceData) > REQUIRED_HOURS {
-		priceData = priceData[len(priceData)-REQUIRED_HOURS:]
+	if len(priceData) > requiredHours {
+		priceData = priceData[len(priceData)-requiredHours:]
 	}
 
 	priceLogger.Info().
@@ -406,3 +437,18 @@ func validateTimeSequence(priceData []types.PriceData, coin string) error {
 
 	return nil
 }
+
+// CryptoCompareProvider serves hourly price history from the CryptoCompare histohour API
+type CryptoCompareProvider struct {
+	symbols map[string]string // Token symbol -> CryptoCompare symbol
+}
+
+// Name returns the provider name
+func (p *CryptoCompareProvider) Name() string {
+	return config.PriceProviderCryptoCompare
+}
+
+// FetchHourlyPrices fetches the requested hourly bars from CryptoCompare
+func (p *CryptoCompareProvider) FetchHourlyPrices(ctx context.Context, tokenSymbol string, hours int, to time.Time) ([]types.PriceData, error) {
+	return fetchHourlyPriceData(ctx, resolveProviderSymbol(p.symbols, tokenSymbol), hours, to.UTC().Truncate(time.Hour))
+}
@@ -0,0 +1,102 @@
+/*
+This file contains the helpers for reading the node's CometBFT RPC (NODE_RPC) directly,
+for data that is not exposed over gRPC such as block times, block events and the latest height.
+*/
+
+package datafetcher
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/elys-network/avm/internal/config"
+	"github.com/elys-network/avm/internal/fixtures"
+	"github.com/elys-network/avm/internal/metrics"
+)
+
+const NODE_RPC_TIMEOUT = 30 * time.Second
+
+// rpcResponse is the JSON-RPC envelope returned by the CometBFT RPC
+type rpcResponse struct {
+	Result json.RawMessage `json:"result"`
+	Error  *struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+		Data    string `json:"data"`
+	} `json:"error"`
+}
+
+// rpcStatusResult is the part of the /status result the AVM reads
+type rpcStatusResult struct {
+	SyncInfo struct {
+		LatestBlockHeight string `json:"latest_block_height"`
+	} `json:"sync_info"`
+}
+
+// GetLatestBlockHeight returns the latest block height of the node at NODE_RPC.
+// The cycle pins all of its chain queries to this height.
+func GetLatestBlockHeight(ctx context.Context) (int64, error) {
+	client := &http.Client{
+		Timeout:   NODE_RPC_TIMEOUT,
+		Transport: fixtures.Transport(),
+	}
+	height, err := fetchLatestHeight(ctx, client)
+	return height, metrics.CountFetchError(metrics.SourceNodeRPC, err)
+}
+
+// fetchLatestHeight returns the node's latest block height
+func fetchLatestHeight(ctx context.Context, client *http.Client) (int64, error) {
+	var status rpcStatusResult
+	if err := queryNodeRPC(ctx, client, "/status", &status); err != nil {
+		return 0, fmt.Errorf("failed to fetch node status: %w", err)
+	}
+
+	height, err := strconv.ParseInt(status.SyncInfo.LatestBlockHeight, 10, 64)
+	if err != nil || height <= 0 {
+		return 0, fmt.Errorf("invalid latest block height %q from node status", status.SyncInfo.LatestBlockHeight)
+	}
+	return height, nil
+}
+
+// queryNodeRPC performs a GET request against NODE_RPC and decodes the JSON-RPC result into result
+func queryNodeRPC(ctx context.Context, client *http.Client, path string, result interface{}) error {
+	url := strings.TrimRight(config.NodeRPC, "/") + path
+
+	resp, err := fetchHTTP(ctx, client, url, nil)
+	if err != nil {
+		return fmt.Errorf("RPC request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("RPC returned non-200 status: %d", resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read RPC response: %w", err)
+	}
+
+	var envelope rpcResponse
+	if err := json.Unmarshal(body, &envelope); err != nil {
+		return fmt.Errorf("failed to parse RPC response: %w", err)
+	}
+	if envelope.Error != nil {
+		return fmt.Errorf("RPC error %d: %s %s", envelope.Error.Code, envelope.Error.Message, envelope.Error.Data)
+	}
+	if len(envelope.Result) == 0 {
+		return errors.New("RPC response has no result")
+	}
+
+	if err := json.Unmarshal(envelope.Result, result); err != nil {
+		return fmt.Errorf("failed to parse RPC result: %w", err)
+	}
+	return nil
+}
@@ -7,14 +7,17 @@ import (
 	"fmt"
 	"math"
 	"os"
+	"sort"
 	"strings"
 
 	sdkmath "cosmossdk.io/math"
 	"github.com/cosmos/cosmos-sdk/types/query"
+	"github.com/elys-network/avm/internal/config"
 	"github.com/elys-network/avm/internal/logger"
+	"github.com/elys-network/avm/internal/metrics"
 	"github.com/elys-network/avm/internal/types"
+	"github.com/elys-network/avm/internal/utils"
 	amm "github.com/elys-network/elys/v6/x/amm/types"
-	assetprofiletypes "github.com/elys-network/elys/v6/x/assetprofile/types"
 	masterchef "github.com/elys-network/elys/v6/x/masterchef/types"
 	"google.golang.org/grpc"
 )
@@ -25,40 +28,230 @@ var ErrMissingCriticalData = errors.New("missing critical pool data for financia
 var ErrPoolValidationFailed = errors.New("pool validation failed")
 
 
-// GetPools fetches pools for supported assets with strict validation - no partial results for financial calculations
-func GetPools(grpcClient *grpc.ClientConn, supportedTokens []string) ([]types.Pool, error) {
+// GetPools fetches pools for supported assets with strict validation - no partial results for financial calculations.
+// It also returns the validated token data for the assets of those pools, keyed by denom and IBC denom.
+// With quarantine enabled, tokens and pools that fail are skipped and listed in the returned report.
+// Sub-steps are recorded on timer, which may be nil.
+func GetPools(ctx context.Context, grpcClient *grpc.ClientConn, supportedTokens []string, timer *utils.StepTimer) ([]types.Pool, map[string]types.Token, *types.QuarantineReport, error) {
 	poolLogger.Info().Int("supportedTokenCount", len(supportedTokens)).Msg("Starting strict pool retrieval process for supported assets")
 
 	// Validate GRPC client
 	if grpcClient == nil {
-		return nil, errors.New("GRPC client cannot be nil")
+		return nil, nil, nil, errors.New("GRPC client cannot be nil")
 	}
 
 	// Validate supported tokens
 	if len(supportedTokens) == 0 {
-		return nil, errors.New("supported tokens list cannot be empty")
+		return nil, nil, nil, errors.New("supported tokens list cannot be empty")
 	}
 
 	// Create a map for fast token lookup
 	supportedTokenMap := make(map[string]bool)
 	for _, token := range supportedTokens {
 		if strings.TrimSpace(token) == "" {
-			return nil, errors.New("supported tokens cannot contain empty strings")
+			return nil, nil, nil, errors.New("supported tokens cannot contain empty strings")
 		}
 		supportedTokenMap[token] = true
 	}
 
 	poolLogger.Info().Int("uniqueSupportedTokens", len(supportedTokenMap)).Msg("Validated supported tokens")
 
-	// Fetch pools from AMM with strict validation and pagination
+	// Pool discovery (followed by token data for the pools' assets), volume and APRs are
+	// independent, so they are fetched concurrently. The first failure cancels the others.
+	var supportedPools []amm.Pool
+	var supportedExtraInfos []amm.PoolExtraInfo
+	var totalPoolCount int
+	var tokenMap map[string]types.Token
+	var weeklyVolume WeeklyVolume
+	quarantine := &types.QuarantineReport{
+		Tokens:          make([]types.QuarantinedToken, 0),
+		Pools:           make([]types.QuarantinedPool, 0),
+		FrozenPositions: make([]types.FrozenPosition, 0),
+	}
+	var poolAPRs map[uint64]masterchef.PoolApr
+
+	err := utils.RunConcurrently(ctx,
+		func(ctx context.Context) error {
+			stopPools := timer.Track("fetch_pools")
+			allPools, allExtraInfos, err := fetchAllAMMPools(ctx, grpcClient)
+			stopPools()
+			if err != nil {
+				return metrics.CountFetchError(metrics.SourceAMM, err)
+			}
+			totalPoolCount = len(allPools)
+
+			// Filter pools to only include those with supported tokens
+			supportedPools, supportedExtraInfos = filterSupportedPools(allPools, allExtraInfos, supportedTokenMap)
+
+			if len(supportedPools) == 0 {
+				poolLogger.Warn().
+					Int("totalPools", len(allPools)).
+					Int("supportedTokens", len(supportedTokenMap)).
+					Msg("No pools found with supported tokens")
+				return errors.New("no pools found with supported tokens")
+			}
+
+			poolLogger.Info().
+				Int("totalPools", len(allPools)).
+				Int("supportedPools", len(supportedPools)).
+				Msg("Filtered pools to supported tokens only")
+
+			// Fetch tokens with strict validation, scoped to the assets of the supported pools
+			defer timer.Track("fetch_tokens")()
+			var quarantinedTokens []types.QuarantinedToken
+			tokenMap, quarantinedTokens, err = GetTokens(ctx, grpcClient, poolAssetDenoms(supportedPools))
+			if err != nil {
+				poolLogger.Error().Err(err).Msg("Failed to fetch tokens")
+				return fmt.Errorf("token fetch failed: %w", err)
+			}
+			quarantine.Tokens = append(quarantine.Tokens, quarantinedTokens...)
+
+			if len(tokenMap) == 0 {
+				poolLogger.Error().Msg("No tokens available")
+				return errors.New("no tokens available for pool calculations")
+			}
+
+			poolLogger.Info().Int("tokenCount", len(tokenMap)).Msg("Successfully fetched tokens")
+			return nil
+		},
+		func(ctx context.Context) error {
+			// Fetch volume data from the configured source with strict validation
+			defer timer.Track("fetch_volume")()
+			var err error
+			weeklyVolume, err = fetchWeeklyVolume(ctx, grpcClient)
+			if err != nil {
+				poolLogger.Error().Err(err).Msg("Failed to fetch weekly volume data")
+				return fmt.Errorf("volume data fetch failed: %w", err)
+			}
+
+			poolLogger.Info().
+				Int("volumePoolCount", len(weeklyVolume.VolumeUSD)).
+				Str("volumeSource", weeklyVolume.Source).
+				Msg("Successfully fetched weekly volume data")
+			return nil
+		},
+		func(ctx context.Context) error {
+			// Fetch pool APRs with strict validation
+			defer timer.Track("fetch_pool_aprs")()
+			var err error
+			poolAPRs, err = getPoolAPRs(ctx, grpcClient)
+			if err != nil {
+				metrics.CountFetchError(metrics.SourceMasterchef, err)
+				poolLogger.Error().Err(err).Msg("Failed to fetch pool APRs")
+				return fmt.Errorf("pool APR fetch failed: %w", err)
+			}
+
+			poolLogger.Info().Int("poolAPRCount", len(poolAPRs)).Msg("Successfully fetched pool APRs")
+			return nil
+		},
+	)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	// Validate we have enough extra info for all supported pools
+	if len(supportedExtraInfos) != len(supportedPools) {
+		poolLogger.Error().
+			Int("supportedPoolCount", len(supportedPools)).
+			Int("supportedExtraInfoCount", len(supportedExtraInfos)).
+			Msg("Mismatch between supported pool count and extra info count")
+		return nil, nil, nil, errors.New("incomplete pool data: missing extra info for some supported pools")
+	}
+
+	var pools []types.Pool
+	processedCount := 0
+
+	// Tokens quarantined by GetTokens, keyed by denom and IBC denom, to explain why their pools are skipped
+	quarantinedTokens := make(map[string]types.QuarantinedToken)
+	for _, token := range quarantine.Tokens {
+		quarantinedTokens[token.Denom] = token
+		quarantinedTokens[token.IBCDenom] = token
+	}
+
+	for i, pool := range supportedPools {
+		poolLogger.Debug().
+			Uint64("poolID", pool.PoolId).
+			Int("poolIndex", i).
+			Msg("Processing pool")
+
+		newPool, err := buildPool(pool, supportedExtraInfos[i], tokenMap, poolAPRs, weeklyVolume)
+		if err != nil {
+			if !config.QuarantineEnabled {
+				return nil, nil, nil, err
+			}
+
+			// Report the underlying token failure rather than the missing token data
+			reason := err.Error()
+			for _, asset := range pool.PoolAssets {
+				if token, isQuarantined := quarantinedTokens[asset.Token.Denom]; isQuarantined {
+					reason = fmt.Sprintf("token %s quarantined: %s", token.Symbol, token.Reason)
+					break
+				}
+			}
+
+			poolLogger.Warn().
+				Err(err).
+				Uint64("poolID", pool.PoolId).
+				Msg("Quarantining pool for this cycle")
+			quarantine.Pools = append(quarantine.Pools, types.QuarantinedPool{
+				PoolID: types.PoolID(pool.PoolId),
+				Reason: reason,
+			})
+			continue
+		}
+
+		pools = append(pools, newPool)
+		processedCount++
+
+		poolLogger.Debug().
+			Uint64("poolID", pool.PoolId).
+			Str("poolType", string(newPool.Type)).
+			Str("assets", newPool.Symbols()).
+			Float64("tvl", newPool.TvlUSD).
+			Float64("volume", newPool.Volume7dUSD).
+			Msg("Successfully processed and validated pool")
+	}
+
+	if processedCount == 0 {
+		poolLogger.Error().Msg("No pools were successfully processed")
+		return nil, nil, nil, errors.New("no valid pools found for financial calculations")
+	}
+
+	// Too many failures point at a broken data source rather than a few bad pools
+	tokenCount := len(quarantine.Tokens)
+	for denom, token := range tokenMap {
+		if denom == token.Denom {
+			tokenCount++
+		}
+	}
+	quarantine.TokenFailureRatio, _ = checkFailureRatio("tokens", len(quarantine.Tokens), tokenCount)
+	quarantine.PoolFailureRatio, err = checkFailureRatio("pools", len(quarantine.Pools), len(supportedPools))
+	if err != nil {
+		poolLogger.Error().Err(err).Msg("Pool quarantine threshold exceeded")
+		return nil, nil, nil, err
+	}
+
+	poolLogger.Info().
+		Int("totalPoolsFromAMM", totalPoolCount).
+		Int("supportedPools", len(supportedPools)).
+		Int("processedPools", processedCount).
+		Int("quarantinedPools", len(quarantine.Pools)).
+		Int("quarantinedTokens", len(quarantine.Tokens)).
+		Msg("Pool retrieval complete with strict validation - only supported tokens processed")
+
+	return pools, tokenMap, quarantine, nil
+}
+
+// fetchAllAMMPools fetches every AMM pool and its extra info using pagination
+func fetchAllAMMPools(ctx context.Context, grpcClient *grpc.ClientConn) ([]amm.Pool, []amm.PoolExtraInfo, error) {
 	ammClient := amm.NewQueryClient(grpcClient)
-	
+
 	// Fetch all pools using pagination to handle large datasets
 	var allPools []amm.Pool
 	var allExtraInfos []amm.PoolExtraInfo
 	var nextKey []byte
 	pageLimit := uint64(1000) // Reasonable page size for memory efficiency
-	
+
 	for {
 		// Configure pagination for each request
 		paginationReq := &query.PageRequest{
@@ -66,30 +259,30 @@ func GetPools(grpcClient *grpc.ClientConn, supportedTokens []string) ([]types.Po
 			Limit:      pageLimit,
 			CountTotal: false, // We don't need total count for this use case
 		}
-		
-		queryPools, err := ammClient.PoolAll(context.Background(), &amm.QueryAllPoolRequest{
-			Days: 7,
+
+		queryPools, err := ammClient.PoolAll(ctx, &amm.QueryAllPoolRequest{
+			Days:       7,
 			Pagination: paginationReq,
 		})
 		if err != nil {
 			poolLogger.Error().Err(err).Msg("Failed to fetch pools from AMM")
-			return nil, fmt.Errorf("AMM pool query failed: %w", err)
+			return nil, nil, fmt.Errorf("AMM pool query failed: %w", err)
 		}
 
 		if queryPools == nil {
 			poolLogger.Error().Msg("Received nil response from AMM")
-			return nil, errors.New("nil response from AMM module")
+			return nil, nil, errors.New("nil response from AMM module")
 		}
 
 		// Append results from this page
 		allPools = append(allPools, queryPools.Pool...)
 		allExtraInfos = append(allExtraInfos, queryPools.ExtraInfos...)
-		
+
 		// Check if there are more pages
 		if queryPools.Pagination == nil || queryPools.Pagination.NextKey == nil || len(queryPools.Pagination.NextKey) == 0 {
 			break
 		}
-		
+
 		nextKey = queryPools.Pagination.NextKey
 		poolLogger.Debug().
 			Int("fetchedPools", len(queryPools.Pool)).
@@ -99,373 +292,257 @@ func GetPools(grpcClient *grpc.ClientConn, supportedTokens []string) ([]types.Po
 
 	if len(allPools) == 0 {
 		poolLogger.Error().Msg("No pools returned from AMM")
-		return nil, errors.New("no pools available from AMM module")
+		return nil, nil, errors.New("no pools available from AMM module")
 	}
 
 	poolLogger.Info().Int("poolCount", len(allPools)).Msg("Successfully fetched all pools from AMM")
 
-	// Filter pools to only include those with supported tokens
-	supportedPools, supportedExtraInfos := filterSupportedPools(allPools, allExtraInfos, supportedTokenMap)
-
-	if len(supportedPools) == 0 {
-		poolLogger.Warn().
-			Int("totalPools", len(allPools)).
-			Int("supportedTokens", len(supportedTokenMap)).
-			Msg("No pools found with supported tokens")
-		return nil, errors.New("no pools found with supported tokens")
-	}
-
-	poolLogger.Info().
-		Int("totalPools", len(allPools)).
-		Int("supportedPools", len(supportedPools)).
-		Msg("Filtered pools to supported tokens only")
+	return allPools, allExtraInfos, nil
+}
 
-	// Fetch tokens with strict validation - required for supported pools
-	tokenMap, err := GetTokens(grpcClient)
-	if err != nil {
-		poolLogger.Error().Err(err).Msg("Failed to fetch tokens")
-		return nil, fmt.Errorf("token fetch failed: %w", err)
+// poolAssetDenoms returns the distinct asset denoms referenced by the given pools
+func poolAssetDenoms(pools []amm.Pool) []string {
+	seen := make(map[string]bool)
+	denoms := make([]string, 0, len(pools)*2)
+	for _, pool := range pools {
+		for _, asset := range pool.PoolAssets {
+			if seen[asset.Token.Denom] {
+				continue
+			}
+			seen[asset.Token.Denom] = true
+			denoms = append(denoms, asset.Token.Denom)
+		}
 	}
+	return denoms
+}
 
-	if len(tokenMap) == 0 {
-		poolLogger.Error().Msg("No tokens available")
-		return nil, errors.New("no tokens available for pool calculations")
+// buildPool converts an AMM pool and its extra info into a validated types.Pool.
+// Returns error if any data the pool needs for financial calculations is missing or invalid.
+func buildPool(pool amm.Pool, extraInfo amm.PoolExtraInfo, tokenMap map[string]types.Token, poolAPRs map[uint64]masterchef.PoolApr, weeklyVolume WeeklyVolume) (types.Pool, error) {
+	// Strict validation of AMM pool data
+	if err := validateAMMPool(pool); err != nil {
+		poolLogger.Error().
+			Err(err).
+			Uint64("poolID", pool.PoolId).
+			Msg("AMM pool validation failed")
+		return types.Pool{}, fmt.Errorf("pool %d validation failed: %w", pool.PoolId, err)
 	}
 
-	poolLogger.Info().Int("tokenCount", len(tokenMap)).Msg("Successfully fetched tokens")
-
-	// Get token metadata with strict validation
-	tokenEntries, err := FetchAllTokens(grpcClient)
-	if err != nil {
-		poolLogger.Error().Err(err).Msg("Failed to fetch token metadata")
-		return nil, fmt.Errorf("token metadata fetch failed: %w", err)
+	// Validate extra info exists and is valid
+	if err := validatePoolExtraInfo(pool.PoolId, extraInfo); err != nil {
+		poolLogger.Error().
+			Err(err).
+			Uint64("poolID", pool.PoolId).
+			Msg("Pool extra info validation failed")
+		return types.Pool{}, fmt.Errorf("pool %d extra info validation failed: %w", pool.PoolId, err)
 	}
 
-	if len(tokenEntries) == 0 {
-		poolLogger.Error().Msg("No token metadata available")
-		return nil, errors.New("no token metadata available")
-	}
+	var newPool types.Pool
 
-	poolLogger.Info().Int("tokenEntryCount", len(tokenEntries)).Msg("Successfully fetched token metadata")
+	// Log pool data for debugging
+	poolJSON, _ := json.Marshal(pool)
+	poolLogger.Debug().
+		Uint64("poolID", pool.PoolId).
+		RawJSON("poolData", poolJSON).
+		Msg("Processing pool data")
 
-	// Create map for quick lookup of token entries by denom
-	entryMap := make(map[string]assetprofiletypes.Entry)
-	for _, entry := range tokenEntries {
-		entryMap[entry.Denom] = entry
-		// Also add BaseDenom for easier lookup
-		if entry.BaseDenom != entry.Denom {
-			entryMap[entry.BaseDenom] = entry
-		}
+	newPool.ID = types.PoolID(pool.PoolId)
+	newPool.Type = types.PoolTypeWeighted
+	if pool.PoolParams.UseOracle {
+		newPool.Type = types.PoolTypeOracle
 	}
 
-	// Fetch volume data with strict validation
-	volumeData, err := GetWeeklyVolumeByPool()
+	assets, err := buildPoolAssets(pool, tokenMap)
 	if err != nil {
-		poolLogger.Error().Err(err).Msg("Failed to fetch weekly volume data")
-		return nil, fmt.Errorf("volume data fetch failed: %w", err)
+		return types.Pool{}, err
 	}
+	newPool.Assets = assets
 
-	poolLogger.Info().Int("volumePoolCount", len(volumeData)).Msg("Successfully fetched weekly volume data")
+	poolLogger.Debug().
+		Uint64("poolID", pool.PoolId).
+		Str("poolType", string(newPool.Type)).
+		Str("assets", newPool.Symbols()).
+		Msg("Calculated USD-based pool weights")
 
-	// Fetch pool APRs with strict validation
-	poolAPRs, err := getPoolAPRs(grpcClient)
-	if err != nil {
-		poolLogger.Error().Err(err).Msg("Failed to fetch pool APRs")
-		return nil, fmt.Errorf("pool APR fetch failed: %w", err)
-	}
+	// Get TVL and other pool metrics (already validated above)
+	newPool.TvlUSD = extraInfo.Tvl.MustFloat64()
+	newPool.PriceImpactAPR = extraInfo.LpSavedApr.MustFloat64()
 
-	poolLogger.Info().Int("poolAPRCount", len(poolAPRs)).Msg("Successfully fetched pool APRs")
+	poolLogger.Debug().
+		Uint64("poolID", pool.PoolId).
+		Float64("tvlUSD", newPool.TvlUSD).
+		Float64("priceImpactAPR", newPool.PriceImpactAPR).
+		Msg("Pool metrics retrieved")
 
-	// Validate we have enough extra info for all supported pools
-	if len(supportedExtraInfos) != len(supportedPools) {
+	newPool.SwapFee = pool.PoolParams.SwapFee.MustFloat64()
+	newPool.TotalShares = pool.TotalShares.Amount
+	newPool.IsSmartShielded = pool.PoolParams.UseOracle
+
+	// Get APRs - must exist for financial calculations
+	apr, hasAPR := poolAPRs[pool.PoolId]
+	if !hasAPR {
 		poolLogger.Error().
-			Int("supportedPoolCount", len(supportedPools)).
-			Int("supportedExtraInfoCount", len(supportedExtraInfos)).
-			Msg("Mismatch between supported pool count and extra info count")
-		return nil, errors.New("incomplete pool data: missing extra info for some supported pools")
+			Uint64("poolID", pool.PoolId).
+			Msg("APR data not found for pool")
+		return types.Pool{}, fmt.Errorf("pool %d APR data not found", pool.PoolId)
 	}
 
-	var pools []types.Pool
-	processedCount := 0
-
-	for i, pool := range supportedPools {
-		poolLogger.Debug().
+	// Validate APR data
+	if err := validatePoolAPR(pool.PoolId, apr); err != nil {
+		poolLogger.Error().
+			Err(err).
 			Uint64("poolID", pool.PoolId).
-			Int("poolIndex", i).
-			Msg("Processing pool")
-
-		// Strict validation of AMM pool data
-		if err := validateAMMPool(pool); err != nil {
-			poolLogger.Error().
-				Err(err).
+			Msg("Pool APR validation failed")
+		return types.Pool{}, fmt.Errorf("pool %d APR validation failed: %w", pool.PoolId, err)
+	}
+
+	newPool.UsdcFeesAPR = apr.UsdcDexApr.MustFloat64()
+	newPool.EdenRewardsAPR = apr.EdenApr.MustFloat64()
+
+	poolLogger.Debug().
+		Uint64("poolID", pool.PoolId).
+		Float64("usdcFeesAPR", newPool.UsdcFeesAPR).
+		Float64("edenRewardsAPR", newPool.EdenRewardsAPR).
+		Msg("Pool APRs retrieved")
+
+	// Set age
+	// ! To:Do Make this fetch from chain data somehow, not a big deal for now
+	newPool.AgeInDays = 30
+
+	// Calculate 7-day volume - handle missing data based on environment
+	poolVolumeUSD, volumeExists := weeklyVolume.VolumeUSD[pool.PoolId]
+	if !volumeExists && weeklyVolume.Complete {
+		// The source saw every swap in the window, so the pool simply had none
+		newPool.Volume7dUSD = 0
+	} else if !volumeExists {
+		// In development environment, handle missing volume data gracefully
+		if os.Getenv("ENV") == "dev" {
+			poolLogger.Warn().
 				Uint64("poolID", pool.PoolId).
-				Msg("AMM pool validation failed")
-			return nil, fmt.Errorf("pool %d validation failed: %w", pool.PoolId, err)
-		}
+				Msg("Volume data not found for pool in dev environment - setting volume to 1 USD")
 
-		// Validate extra info exists and is valid
-		extraInfo := supportedExtraInfos[i]
-		if err := validatePoolExtraInfo(pool.PoolId, extraInfo); err != nil {
+			newPool.Volume7dUSD = 1.0
+		} else {
+			// In production/testnet, missing volume data is a critical error
 			poolLogger.Error().
-				Err(err).
 				Uint64("poolID", pool.PoolId).
-				Msg("Pool extra info validation failed")
-			return nil, fmt.Errorf("pool %d extra info validation failed: %w", pool.PoolId, err)
+				Msg("Volume data not found for pool")
+			return types.Pool{}, fmt.Errorf("pool %d volume data not found", pool.PoolId)
 		}
+	} else {
+		newPool.Volume7dUSD = poolVolumeUSD
+	}
 
-		var newPool types.Pool
-
-		// Log pool data for debugging
-		poolJSON, _ := json.Marshal(pool)
-		poolLogger.Debug().
-			Uint64("poolID", pool.PoolId).
-			RawJSON("poolData", poolJSON).
-			Msg("Processing pool data")
-
-		newPool.ID = types.PoolID(pool.PoolId)
-
-		// Get the balances from pool assets
-		balanceA := pool.PoolAssets[0].Token.Amount
-		balanceB := pool.PoolAssets[1].Token.Amount
-
-		// Get token denoms from pool assets
-		denomA := pool.PoolAssets[0].Token.Denom
-		denomB := pool.PoolAssets[1].Token.Denom
-
-		// Get tokens from map - both must exist for financial calculations
-		tokenA, hasTokenA := tokenMap[denomA]
-		tokenB, hasTokenB := tokenMap[denomB]
-
-		if !hasTokenA {
-			poolLogger.Error().
-				Str("token", denomA).
-				Uint64("poolID", pool.PoolId).
-				Msg("Token A not found in validated token map")
-			return nil, fmt.Errorf("pool %d token A (%s) not found in validated token data", pool.PoolId, denomA)
-		}
+	// Validate volume
+	if math.IsNaN(newPool.Volume7dUSD) || math.IsInf(newPool.Volume7dUSD, 0) || newPool.Volume7dUSD < 0 {
+		return types.Pool{}, fmt.Errorf("pool %d has invalid volume: %f", pool.PoolId, newPool.Volume7dUSD)
+	}
 
-		if !hasTokenB {
-			poolLogger.Error().
-				Str("token", denomB).
-				Uint64("poolID", pool.PoolId).
-				Msg("Token B not found in validated token map")
-			return nil, fmt.Errorf("pool %d token B (%s) not found in validated token data", pool.PoolId, denomB)
-		}
+	poolLogger.Debug().
+		Uint64("poolID", pool.PoolId).
+		Float64("volume7dUSD", newPool.Volume7dUSD).
+		Msg("Pool volume data retrieved")
 
-		newPool.TokenA = tokenA
-		newPool.TokenB = tokenB
+	// Final comprehensive validation of the complete pool
+	if err := validateFinalPool(newPool); err != nil {
+		poolLogger.Error().
+			Err(err).
+			Uint64("poolID", pool.PoolId).
+			Msg("Final pool validation failed")
+		return types.Pool{}, fmt.Errorf("final validation failed for pool %d: %w", pool.PoolId, err)
+	}
 
-		// Ensure TokenA is not USDC by swapping if needed
-		if newPool.TokenA.Symbol == "USDC" {
-			// Swap TokenA and TokenB
-			newPool.TokenA, newPool.TokenB = newPool.TokenB, newPool.TokenA
-			// Also swap the balances to maintain consistency
-			newPool.BalanceA, newPool.BalanceB = balanceB, balanceA
+	return newPool, nil
+}
 
-			poolLogger.Debug().
-				Uint64("poolID", pool.PoolId).
-				Str("newTokenA", newPool.TokenA.Symbol).
-				Str("newTokenB", newPool.TokenB.Symbol).
-				Msg("Swapped tokenA and tokenB to ensure TokenA is not USDC")
-		} else {
-			// Assign balances normally
-			newPool.BalanceA = balanceA
-			newPool.BalanceB = balanceB
-		}
+// buildPoolAssets converts the AMM pool assets into types.PoolAsset values, with each asset's share
+// of the pool's USD value as its weight. USDC, if present, is moved to the end.
+// Every asset must be in the validated token map for financial calculations.
+func buildPoolAssets(pool amm.Pool, tokenMap map[string]types.Token) ([]types.PoolAsset, error) {
+	totalTargetWeight := sdkmath.ZeroInt()
+	for _, asset := range pool.PoolAssets {
+		totalTargetWeight = totalTargetWeight.Add(asset.Weight)
+	}
 
-		// Calculate actual USD-based weights using token balances and prices
-		// Convert raw amounts to human-readable amounts using token precision
-		precisionFactorA := sdkmath.NewIntFromUint64(uint64(math.Pow10(newPool.TokenA.Precision)))
-		precisionFactorB := sdkmath.NewIntFromUint64(uint64(math.Pow10(newPool.TokenB.Precision)))
+	assets := make([]types.PoolAsset, 0, len(pool.PoolAssets))
+	usdValues := make([]float64, 0, len(pool.PoolAssets))
+	totalUSDValue := 0.0
 
-		// Validate precision factors
-		if precisionFactorA.IsZero() || precisionFactorB.IsZero() {
+	for i, poolAsset := range pool.PoolAssets {
+		denom := poolAsset.Token.Denom
+		token, hasToken := tokenMap[denom]
+		if !hasToken {
 			poolLogger.Error().
+				Str("token", denom).
 				Uint64("poolID", pool.PoolId).
-				Int("precisionA", newPool.TokenA.Precision).
-				Int("precisionB", newPool.TokenB.Precision).
-				Msg("Invalid precision factors")
-			return nil, fmt.Errorf("pool %d has invalid precision factors", pool.PoolId)
+				Int("assetIndex", i).
+				Msg("Pool asset not found in validated token map")
+			return nil, fmt.Errorf("pool %d asset %d (%s) not found in validated token data", pool.PoolId, i, denom)
 		}
 
-		// Convert to decimal by dividing by precision factor
-		humanAmountA, err := sdkmath.LegacyNewDecFromInt(newPool.BalanceA).QuoInt(precisionFactorA).Float64()
-		if err != nil {
-			return nil, fmt.Errorf("pool %d token A amount conversion failed: %w", pool.PoolId, err)
+		// Convert the raw amount to a human-readable amount using the token precision
+		precisionFactor := sdkmath.NewIntFromUint64(uint64(math.Pow10(token.Precision)))
+		if precisionFactor.IsZero() {
+			return nil, fmt.Errorf("pool %d asset %s has invalid precision: %d", pool.PoolId, token.Symbol, token.Precision)
 		}
-		humanAmountB, err := sdkmath.LegacyNewDecFromInt(newPool.BalanceB).QuoInt(precisionFactorB).Float64()
+		humanAmount, err := sdkmath.LegacyNewDecFromInt(poolAsset.Token.Amount).QuoInt(precisionFactor).Float64()
 		if err != nil {
-			return nil, fmt.Errorf("pool %d token B amount conversion failed: %w", pool.PoolId, err)
-		}
-
-		// Validate converted amounts
-		if math.IsNaN(humanAmountA) || math.IsInf(humanAmountA, 0) || humanAmountA <= 0 {
-			return nil, fmt.Errorf("pool %d token A has invalid human amount: %f", pool.PoolId, humanAmountA)
-		}
-		if math.IsNaN(humanAmountB) || math.IsInf(humanAmountB, 0) || humanAmountB <= 0 {
-			return nil, fmt.Errorf("pool %d token B has invalid human amount: %f", pool.PoolId, humanAmountB)
-		}
-
-		// Calculate USD values
-		usdValueA := humanAmountA * newPool.TokenA.PriceUSD
-		usdValueB := humanAmountB * newPool.TokenB.PriceUSD
-		totalUSDValue := usdValueA + usdValueB
-
-		// Validate USD calculations
-		if math.IsNaN(usdValueA) || math.IsInf(usdValueA, 0) || usdValueA < 0 {
-			return nil, fmt.Errorf("pool %d token A has invalid USD value: %f", pool.PoolId, usdValueA)
-		}
-		if math.IsNaN(usdValueB) || math.IsInf(usdValueB, 0) || usdValueB < 0 {
-			return nil, fmt.Errorf("pool %d token B has invalid USD value: %f", pool.PoolId, usdValueB)
+			return nil, fmt.Errorf("pool %d asset %s amount conversion failed: %w", pool.PoolId, token.Symbol, err)
 		}
-		if totalUSDValue <= 0 {
-			return nil, fmt.Errorf("pool %d has invalid total USD value: %f", pool.PoolId, totalUSDValue)
-		}
-
-		// Calculate weight percentages based on actual USD values
-		newPool.WeightA = usdValueA / totalUSDValue
-		newPool.WeightB = usdValueB / totalUSDValue
-
-		// Validate weights
-		if math.IsNaN(newPool.WeightA) || math.IsInf(newPool.WeightA, 0) || newPool.WeightA <= 0 || newPool.WeightA >= 1 {
-			return nil, fmt.Errorf("pool %d has invalid weight A: %f", pool.PoolId, newPool.WeightA)
-		}
-		if math.IsNaN(newPool.WeightB) || math.IsInf(newPool.WeightB, 0) || newPool.WeightB <= 0 || newPool.WeightB >= 1 {
-			return nil, fmt.Errorf("pool %d has invalid weight B: %f", pool.PoolId, newPool.WeightB)
-		}
-
-		poolLogger.Debug().
-			Uint64("poolID", pool.PoolId).
-			Str("tokenA", newPool.TokenA.Symbol).
-			Str("tokenB", newPool.TokenB.Symbol).
-			Float64("humanAmountA", humanAmountA).
-			Float64("humanAmountB", humanAmountB).
-			Float64("priceA", newPool.TokenA.PriceUSD).
-			Float64("priceB", newPool.TokenB.PriceUSD).
-			Float64("usdValueA", usdValueA).
-			Float64("usdValueB", usdValueB).
-			Float64("weightA", newPool.WeightA).
-			Float64("weightB", newPool.WeightB).
-			Msg("Calculated USD-based pool weights")
-
-		// Get TVL and other pool metrics (already validated above)
-		newPool.TvlUSD = extraInfo.Tvl.MustFloat64()
-		newPool.PriceImpactAPR = extraInfo.LpSavedApr.MustFloat64()
-
-		poolLogger.Debug().
-			Uint64("poolID", pool.PoolId).
-			Float64("tvlUSD", newPool.TvlUSD).
-			Float64("priceImpactAPR", newPool.PriceImpactAPR).
-			Msg("Pool metrics retrieved")
-
-		newPool.SwapFee = pool.PoolParams.SwapFee.MustFloat64()
-		newPool.TotalShares = pool.TotalShares.Amount
-		newPool.IsSmartShielded = pool.PoolParams.UseOracle
-
-		// Get APRs - must exist for financial calculations
-		apr, hasAPR := poolAPRs[pool.PoolId]
-		if !hasAPR {
-			poolLogger.Error().
-				Uint64("poolID", pool.PoolId).
-				Msg("APR data not found for pool")
-			return nil, fmt.Errorf("pool %d APR data not found", pool.PoolId)
+		if math.IsNaN(humanAmount) || math.IsInf(humanAmount, 0) || humanAmount <= 0 {
+			return nil, fmt.Errorf("pool %d asset %s has invalid human amount: %f", pool.PoolId, token.Symbol, humanAmount)
 		}
 
-		// Validate APR data
-		if err := validatePoolAPR(pool.PoolId, apr); err != nil {
-			poolLogger.Error().
-				Err(err).
-				Uint64("poolID", pool.PoolId).
-				Msg("Pool APR validation failed")
-			return nil, fmt.Errorf("pool %d APR validation failed: %w", pool.PoolId, err)
+		usdValue := humanAmount * token.PriceUSD
+		if math.IsNaN(usdValue) || math.IsInf(usdValue, 0) || usdValue < 0 {
+			return nil, fmt.Errorf("pool %d asset %s has invalid USD value: %f", pool.PoolId, token.Symbol, usdValue)
 		}
 
-		newPool.UsdcFeesAPR = apr.UsdcDexApr.MustFloat64()
-		newPool.EdenRewardsAPR = apr.EdenApr.MustFloat64()
-
-		poolLogger.Debug().
-			Uint64("poolID", pool.PoolId).
-			Float64("usdcFeesAPR", newPool.UsdcFeesAPR).
-			Float64("edenRewardsAPR", newPool.EdenRewardsAPR).
-			Msg("Pool APRs retrieved")
-
-		// Set age
-		// ! To:Do Make this fetch from chain data somehow, not a big deal for now
-		newPool.AgeInDays = 30
-
-		// Calculate 7-day volume - handle missing data based on environment
-		poolVolume, volumeExists := volumeData[pool.PoolId]
-		if !volumeExists {
-			// In development environment, handle missing volume data gracefully
-			if os.Getenv("ENV") == "dev" {
-				poolLogger.Warn().
-					Uint64("poolID", pool.PoolId).
-					Msg("Volume data not found for pool in dev environment - setting volume to 1 USD")
-
-				newPool.Volume7dUSD = 1.0
-			} else {
-				// In production/testnet, missing volume data is a critical error
-				poolLogger.Error().
-					Uint64("poolID", pool.PoolId).
-					Msg("Volume data not found for pool")
-				return nil, fmt.Errorf("pool %d volume data not found", pool.PoolId)
-			}
-		} else {
-			// Calculate volume from available data
-			var totalVolume uint64
-			for _, volume := range poolVolume {
-				totalVolume += volume
-			}
-			newPool.Volume7dUSD = float64(totalVolume)
+		targetWeight, err := sdkmath.LegacyNewDecFromInt(poolAsset.Weight).QuoInt(totalTargetWeight).Float64()
+		if err != nil {
+			return nil, fmt.Errorf("pool %d asset %s target weight conversion failed: %w", pool.PoolId, token.Symbol, err)
 		}
 
-		// Validate volume
-		if math.IsNaN(newPool.Volume7dUSD) || math.IsInf(newPool.Volume7dUSD, 0) || newPool.Volume7dUSD < 0 {
-			return nil, fmt.Errorf("pool %d has invalid volume: %f", pool.PoolId, newPool.Volume7dUSD)
-		}
+		assets = append(assets, types.PoolAsset{
+			Token:        token,
+			Balance:      poolAsset.Token.Amount,
+			TargetWeight: targetWeight,
+		})
+		usdValues = append(usdValues, usdValue)
+		totalUSDValue += usdValue
+	}
 
-		poolLogger.Debug().
-			Uint64("poolID", pool.PoolId).
-			Float64("volume7dUSD", newPool.Volume7dUSD).
-			Msg("Pool volume data retrieved")
+	if totalUSDValue <= 0 {
+		return nil, fmt.Errorf("pool %d has invalid total USD value: %f", pool.PoolId, totalUSDValue)
+	}
 
-		// Final comprehensive validation of the complete pool
-		if err := validateFinalPool(newPool); err != nil {
-			poolLogger.Error().
-				Err(err).
-				Uint64("poolID", pool.PoolId).
-				Msg("Final pool validation failed")
-			return nil, fmt.Errorf("final validation failed for pool %d: %w", pool.PoolId, err)
+	// Calculate weight percentages based on actual USD values
+	for i := range assets {
+		assets[i].Weight = usdValues[i] / totalUSDValue
+		if math.IsNaN(assets[i].Weight) || math.IsInf(assets[i].Weight, 0) || assets[i].Weight <= 0 || assets[i].Weight >= 1 {
+			return nil, fmt.Errorf("pool %d asset %s has invalid weight: %f", pool.PoolId, assets[i].Token.Symbol, assets[i].Weight)
 		}
 
-		pools = append(pools, newPool)
-		processedCount++
-
 		poolLogger.Debug().
 			Uint64("poolID", pool.PoolId).
-			Str("tokenA", newPool.TokenA.Symbol).
-			Str("tokenB", newPool.TokenB.Symbol).
-			Float64("tvl", newPool.TvlUSD).
-			Float64("volume", newPool.Volume7dUSD).
-			Msg("Successfully processed and validated pool")
+			Str("token", assets[i].Token.Symbol).
+			Float64("priceUSD", assets[i].Token.PriceUSD).
+			Float64("usdValue", usdValues[i]).
+			Float64("weight", assets[i].Weight).
+			Float64("targetWeight", assets[i].TargetWeight).
+			Msg("Pool asset weight calculated")
 	}
 
-	if processedCount == 0 {
-		poolLogger.Error().Msg("No pools were successfully processed")
-		return nil, errors.New("no valid pools found for financial calculations")
-	}
-
-	poolLogger.Info().
-		Int("totalPoolsFromAMM", len(allPools)).
-		Int("supportedPools", len(supportedPools)).
-		Int("processedPools", processedCount).
-		Msg("Pool retrieval complete with strict validation - only supported tokens processed")
+	// Keep the non-USDC assets first, as the scoring and planning code expects
+	sort.SliceStable(assets, func(i, j int) bool {
+		return !assets[i].Token.IsUSDC() && assets[j].Token.IsUSDC()
+	})
 
-	return pools, nil
+	return assets, nil
 }
 
 // getPoolAPRs fetches pool APRs with strict validation
-func getPoolAPRs(grpcClient *grpc.ClientConn) (map[uint64]masterchef.PoolApr, error) {
+func getPoolAPRs(ctx context.Context, grpcClient *grpc.ClientConn) (map[uint64]masterchef.PoolApr, error) {
 	if grpcClient == nil {
 		return nil, errors.New("GRPC client cannot be nil")
 	}
@@ -473,7 +550,7 @@ func getPoolAPRs(grpcClient *grpc.ClientConn) (map[uint64]masterchef.PoolApr, er
 	poolLogger.Debug().Msg("Fetching pool APRs from masterchef module")
 	masterchefClient := masterchef.NewQueryClient(grpcClient)
 
-	queryPools, err := masterchefClient.PoolAprs(context.Background(), &masterchef.QueryPoolAprsRequest{})
+	queryPools, err := masterchefClient.PoolAprs(ctx, &masterchef.QueryPoolAprsRequest{})
 	if err != nil {
 		poolLogger.Error().Err(err).Msg("Failed to fetch pool APRs from masterchef module")
 		return nil, fmt.Errorf("masterchef pool APR query failed: %w", err)
@@ -537,8 +614,8 @@ func filterSupportedPools(pools []amm.Pool, extraInfos []amm.PoolExtraInfo, supp
 	var supportedExtraInfos []amm.PoolExtraInfo
 
 	for i, pool := range pools {
-		// Check if both tokens in the pool are supported
-		if len(pool.PoolAssets) != 2 {
+		// Check if every token in the pool is supported
+		if len(pool.PoolAssets) < 2 {
 			poolLogger.Debug().
 				Uint64("poolID", pool.PoolId).
 				Int("assetCount", len(pool.PoolAssets)).
@@ -546,12 +623,17 @@ func filterSupportedPools(pools []amm.Pool, extraInfos []amm.PoolExtraInfo, supp
 			continue
 		}
 
-		tokenA := pool.PoolAssets[0].Token.Denom
-		tokenB := pool.PoolAssets[1].Token.Denom
+		denoms := make([]string, len(pool.PoolAssets))
+		tokensSupported := true
+		for j, asset := range pool.PoolAssets {
+			denoms[j] = asset.Token.Denom
+			if !supportedTokens[asset.Token.Denom] {
+				tokensSupported = false
+			}
+		}
 
-		// Both tokens must be supported AND the specific pool ID must be allowed
+		// All tokens must be supported AND the specific pool ID must be allowed
 		poolDenom := fmt.Sprintf("amm/pool/%d", pool.PoolId)
-		tokensSupported := supportedTokens[tokenA] && supportedTokens[tokenB]
 		poolAllowed := supportedTokens[poolDenom]
 
 		if tokensSupported && poolAllowed {
@@ -562,18 +644,15 @@ func filterSupportedPools(pools []amm.Pool, extraInfos []amm.PoolExtraInfo, supp
 
 			poolLogger.Debug().
 				Uint64("poolID", pool.PoolId).
-				Str("tokenA", tokenA).
-				Str("tokenB", tokenB).
+				Strs("tokens", denoms).
 				Str("poolDenom", poolDenom).
 				Msg("Pool included - tokens and pool ID are both supported")
 		} else {
 			poolLogger.Debug().
 				Uint64("poolID", pool.PoolId).
-				Str("tokenA", tokenA).
-				Str("tokenB", tokenB).
+				Strs("tokens", denoms).
 				Str("poolDenom", poolDenom).
-				Bool("tokenASupported", supportedTokens[tokenA]).
-				Bool("tokenBSupported", supportedTokens[tokenB]).
+				Bool("tokensSupported", tokensSupported).
 				Bool("poolAllowed", poolAllowed).
 				Msg("Pool skipped - tokens or pool ID not supported")
 		}
@@ -590,16 +669,23 @@ func validateAMMPool(pool amm.Pool) error {
 	}
 
 	// Validate pool assets
-	if len(pool.PoolAssets) != 2 {
-		return fmt.Errorf("pool %d must have exactly 2 assets, found %d", pool.PoolId, len(pool.PoolAssets))
+	if len(pool.PoolAssets) < 2 {
+		return fmt.Errorf("pool %d must have at least 2 assets, found %d", pool.PoolId, len(pool.PoolAssets))
 	}
 
 	// Validate each asset
+	seenDenoms := make(map[string]bool, len(pool.PoolAssets))
 	for i, asset := range pool.PoolAssets {
 		if strings.TrimSpace(asset.Token.Denom) == "" {
 			return fmt.Errorf("pool %d asset %d has empty denom", pool.PoolId, i)
 		}
 
+		// Validate assets are different
+		if seenDenoms[asset.Token.Denom] {
+			return fmt.Errorf("pool %d has duplicate token denoms: %s", pool.PoolId, asset.Token.Denom)
+		}
+		seenDenoms[asset.Token.Denom] = true
+
 		if asset.Token.Amount.IsNil() || asset.Token.Amount.IsNegative() {
 			return fmt.Errorf("pool %d asset %d has invalid amount: %s", pool.PoolId, i, asset.Token.Amount.String())
 		}
@@ -607,11 +693,10 @@ func validateAMMPool(pool amm.Pool) error {
 		if asset.Token.Amount.IsZero() {
 			return fmt.Errorf("pool %d asset %d has zero amount", pool.PoolId, i)
 		}
-	}
 
-	// Validate assets are different
-	if pool.PoolAssets[0].Token.Denom == pool.PoolAssets[1].Token.Denom {
-		return fmt.Errorf("pool %d has duplicate token denoms: %s", pool.PoolId, pool.PoolAssets[0].Token.Denom)
+		if asset.Weight.IsNil() || !asset.Weight.IsPositive() {
+			return fmt.Errorf("pool %d asset %d has invalid weight: %s", pool.PoolId, i, asset.Weight.String())
+		}
 	}
 
 	// Validate pool parameters exist (PoolParams is a struct, not a pointer)
@@ -742,43 +827,41 @@ func validateFinalPool(pool types.Pool) error {
 		return errors.New("final pool has zero ID")
 	}
 
-	// Validate tokens
-	if err := validateTokenForPool(pool.TokenA, uint64(pool.ID), "TokenA"); err != nil {
-		return fmt.Errorf("TokenA validation failed: %w", err)
-	}
-	if err := validateTokenForPool(pool.TokenB, uint64(pool.ID), "TokenB"); err != nil {
-		return fmt.Errorf("TokenB validation failed: %w", err)
+	// Validate assets
+	if len(pool.Assets) < 2 {
+		return fmt.Errorf("pool %d must have at least 2 assets, found %d", pool.ID, len(pool.Assets))
 	}
 
-	// Ensure tokens are different
-	if pool.TokenA.Denom == pool.TokenB.Denom {
-		return fmt.Errorf("pool %d has duplicate token denoms: %s", pool.ID, pool.TokenA.Denom)
-	}
+	weightSum := 0.0
+	seenDenoms := make(map[string]bool, len(pool.Assets))
+	for i, asset := range pool.Assets {
+		label := fmt.Sprintf("asset %d", i)
+		if err := validateTokenForPool(asset.Token, uint64(pool.ID), label); err != nil {
+			return fmt.Errorf("%s validation failed: %w", label, err)
+		}
 
-	// Validate balances
-	if pool.BalanceA.IsNil() || pool.BalanceA.IsNegative() || pool.BalanceA.IsZero() {
-		return fmt.Errorf("pool %d has invalid balance A: %s", pool.ID, pool.BalanceA.String())
-	}
-	if pool.BalanceB.IsNil() || pool.BalanceB.IsNegative() || pool.BalanceB.IsZero() {
-		return fmt.Errorf("pool %d has invalid balance B: %s", pool.ID, pool.BalanceB.String())
-	}
+		// Ensure tokens are different
+		if seenDenoms[asset.Token.Denom] {
+			return fmt.Errorf("pool %d has duplicate token denoms: %s", pool.ID, asset.Token.Denom)
+		}
+		seenDenoms[asset.Token.Denom] = true
 
-	// Validate weights
-	if pool.WeightA <= 0 || pool.WeightA >= 1 {
-		return fmt.Errorf("pool %d has invalid weight A: %f", pool.ID, pool.WeightA)
-	}
-	if pool.WeightB <= 0 || pool.WeightB >= 1 {
-		return fmt.Errorf("pool %d has invalid weight B: %f", pool.ID, pool.WeightB)
-	}
-	if math.IsNaN(pool.WeightA) || math.IsInf(pool.WeightA, 0) {
-		return fmt.Errorf("pool %d weight A is not finite: %f", pool.ID, pool.WeightA)
-	}
-	if math.IsNaN(pool.WeightB) || math.IsInf(pool.WeightB, 0) {
-		return fmt.Errorf("pool %d weight B is not finite: %f", pool.ID, pool.WeightB)
+		// Validate balance
+		if asset.Balance.IsNil() || asset.Balance.IsNegative() || asset.Balance.IsZero() {
+			return fmt.Errorf("pool %d has invalid balance for %s: %s", pool.ID, asset.Token.Symbol, asset.Balance.String())
+		}
+
+		// Validate weights
+		if math.IsNaN(asset.Weight) || math.IsInf(asset.Weight, 0) || asset.Weight <= 0 || asset.Weight >= 1 {
+			return fmt.Errorf("pool %d has invalid weight for %s: %f", pool.ID, asset.Token.Symbol, asset.Weight)
+		}
+		if math.IsNaN(asset.TargetWeight) || math.IsInf(asset.TargetWeight, 0) || asset.TargetWeight <= 0 || asset.TargetWeight >= 1 {
+			return fmt.Errorf("pool %d has invalid target weight for %s: %f", pool.ID, asset.Token.Symbol, asset.TargetWeight)
+		}
+		weightSum += asset.Weight
 	}
 
 	// Weights should sum to approximately 1.0
-	weightSum := pool.WeightA + pool.WeightB
 	if math.Abs(weightSum-1.0) > 0.01 {
 		return fmt.Errorf("pool %d weights don't sum to 1.0: %f", pool.ID, weightSum)
 	}
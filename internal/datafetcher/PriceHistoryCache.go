@@ -0,0 +1,188 @@
+/*
+This file serves hourly price history from the persistent price_history cache.
+
+Instead of downloading 30 days of hourly data for every token every cycle, only the hours
+missing from the cache are requested from the configured price history providers. Gaps anywhere
+in the window are backfilled, and the still-forming current hour is always refreshed.
+The cache is keyed by the token's Elys symbol, independent of the provider that supplied the bars.
+*/
+
+package datafetcher
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/elys-network/avm/internal/fixtures"
+	"github.com/elys-network/avm/internal/state"
+	"github.com/elys-network/avm/internal/types"
+)
+
+const (
+	MAX_GAP_REQUESTS          = 5 // Beyond this many separate gaps, refetch from the oldest gap in one request
+	MAX_CACHE_STALENESS_HOURS = 3 // How far the cache may lag the current hour when every provider is unavailable
+)
+
+// hourRange is an inclusive range of missing hourly bars
+type hourRange struct {
+	start time.Time
+	end   time.Time
+}
+
+// hours returns the number of hourly bars in the range
+func (r hourRange) hours() int {
+	return int(r.end.Sub(r.start)/time.Hour) + 1
+}
+
+// GetHistoricalPriceData returns exactly REQUIRED_HOURS hourly bars for the token, served from the
+// price_history cache and topped up with only the missing hours from the given providers.
+// Falls back to a full download when the database is unavailable.
+func GetHistoricalPriceData(ctx context.Context, coin string, providers []PriceHistoryProvider) ([]types.PriceData, error) {
+	coin = strings.TrimSpace(strings.ToUpper(coin))
+
+	windowEnd := fixtures.Now().UTC().Truncate(time.Hour)
+	windowStart := windowEnd.Add(-time.Duration(REQUIRED_HOURS-1) * time.Hour)
+
+	if state.DB == nil {
+		priceLogger.Warn().Str("coin", coin).Msg("Price history cache unavailable (database not initialized), fetching full history")
+		priceData, _, err := fetchFromProviders(ctx, providers, coin, REQUIRED_HOURS, windowEnd)
+		return priceData, err
+	}
+
+	cached, err := state.LoadPriceHistory(coin, windowStart, windowEnd)
+	if err != nil {
+		priceLogger.Warn().Err(err).Str("coin", coin).Msg("Failed to load cached price history, fetching full history")
+		cached = nil
+	}
+
+	bars := make(map[int64]float64, REQUIRED_HOURS)
+	for _, bar := range cached {
+		bars[bar.Timestamp.UTC().Truncate(time.Hour).Unix()] = bar.Price
+	}
+
+	// The current hour's bar is still forming, so it is always refreshed
+	delete(bars, windowEnd.Unix())
+
+	gaps := findMissingHourRanges(bars, windowStart, windowEnd)
+	if len(gaps) > MAX_GAP_REQUESTS {
+		gaps = []hourRange{{start: gaps[0].start, end: windowEnd}}
+	}
+
+	missingHours := 0
+	for _, gap := range gaps {
+		missingHours += gap.hours()
+	}
+
+	priceLogger.Debug().
+		Str("coin", coin).
+		Int("cachedBars", len(bars)).
+		Int("gaps", len(gaps)).
+		Int("missingHours", missingHours).
+		Msg("Price history cache lookup")
+
+	var fetchErr error
+	for _, gap := range gaps {
+		fetched, source, err := fetchFromProviders(ctx, providers, coin, gap.hours(), gap.end)
+		if err != nil {
+			fetchErr = err
+			priceLogger.Warn().
+				Err(err).
+				Str("coin", coin).
+				Time("gapStart", gap.start).
+				Time("gapEnd", gap.end).
+				Msg("Failed to backfill price history gap")
+			continue
+		}
+
+		if err := state.SavePriceHistory(coin, source, fetched); err != nil {
+			priceLogger.Warn().Err(err).Str("coin", coin).Msg("Failed to save fetched price history to cache")
+		}
+		for _, bar := range fetched {
+			bars[bar.Timestamp.UTC().Truncate(time.Hour).Unix()] = bar.Price
+		}
+	}
+
+	priceData := collectWindow(bars, windowStart, windowEnd)
+	if len(priceData) == REQUIRED_HOURS {
+		priceLogger.Info().
+			Str("coin", coin).
+			Int("fetchedHours", missingHours).
+			Int("dataPoints", len(priceData)).
+			Msg("Served price history from cache")
+		return priceData, nil
+	}
+
+	// The window is incomplete because a fetch failed. Tolerate a cache that lags slightly behind.
+	staleStart := windowStart.Add(-MAX_CACHE_STALENESS_HOURS * time.Hour)
+	stale, err := state.LoadPriceHistory(coin, staleStart, windowEnd)
+	if err == nil && len(stale) >= REQUIRED_HOURS {
+		stale = stale[len(stale)-REQUIRED_HOURS:]
+		newest := stale[len(stale)-1].Timestamp.UTC()
+		if !isHourlyContiguous(stale) {
+			priceLogger.Warn().
+				Str("coin", coin).
+				Time("newestBar", newest).
+				Msg("Stale price history in cache has gaps, not serving it")
+		} else if !newest.Before(windowEnd.Add(-MAX_CACHE_STALENESS_HOURS * time.Hour)) {
+			priceLogger.Warn().
+				Str("coin", coin).
+				Time("newestBar", newest).
+				Msg("Serving slightly stale price history from cache")
+			return stale, nil
+		}
+	}
+
+	if fetchErr != nil {
+		return nil, fmt.Errorf("%w: %s has %d of %d hours cached and backfill failed: %v",
+			ErrInsufficientData, coin, len(priceData), REQUIRED_HOURS, fetchErr)
+	}
+	return nil, fmt.Errorf("%w: %s has %d of %d hours available", ErrInsufficientData, coin, len(priceData), REQUIRED_HOURS)
+}
+
+// findMissingHourRanges returns the contiguous ranges of hours in [start, end] with no cached bar
+func findMissingHourRanges(bars map[int64]float64, start, end time.Time) []hourRange {
+	var gaps []hourRange
+	inGap := false
+
+	for hour := start; !hour.After(end); hour = hour.Add(time.Hour) {
+		if _, exists := bars[hour.Unix()]; exists {
+			inGap = false
+			continue
+		}
+		if inGap {
+			gaps[len(gaps)-1].end = hour
+			continue
+		}
+		gaps = append(gaps, hourRange{start: hour, end: hour})
+		inGap = true
+	}
+
+	return gaps
+}
+
+// isHourlyContiguous reports whether consecutive bars are exactly one hour apart
+func isHourlyContiguous(priceData []types.PriceData) bool {
+	for i := 1; i < len(priceData); i++ {
+		if !priceData[i].Timestamp.Equal(priceData[i-1].Timestamp.Add(time.Hour)) {
+			return false
+		}
+	}
+	return true
+}
+
+// collectWindow returns the cached bars within [start, end] in chronological order
+func collectWindow(bars map[int64]float64, start, end time.Time) []types.PriceData {
+	priceData := make([]types.PriceData, 0, REQUIRED_HOURS)
+	for unix, price := range bars {
+		ts := time.Unix(unix, 0).UTC()
+		if ts.Before(start) || ts.After(end) {
+			continue
+		}
+		priceData = append(priceData, types.PriceData{Timestamp: ts, Price: price})
+	}
+	sort.Slice(priceData, func(i, j int) bool { return priceData[i].Timestamp.Before(priceData[j].Timestamp) })
+	return priceData
+}
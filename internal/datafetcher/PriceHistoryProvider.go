@@ -0,0 +1,155 @@
+/*
+This file defines the pluggable sources of hourly price history.
+
+Providers are tried in the order configured by PRICE_HISTORY_PROVIDERS; if one fails for a
+token, the next is used. Each provider resolves a token's symbol through the
+token_symbol_mappings table and falls back to the token's own symbol.
+*/
+
+package datafetcher
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/elys-network/avm/internal/config"
+	"github.com/elys-network/avm/internal/metrics"
+	"github.com/elys-network/avm/internal/types"
+)
+
+// PriceHistoryProvider is a source of hourly USD close prices
+type PriceHistoryProvider interface {
+	// Name returns the provider name used in configuration and symbol mappings
+	Name() string
+	// FetchHourlyPrices returns exactly `hours` hourly bars, oldest first, with the newest bar at the hour of `to`
+	FetchHourlyPrices(ctx context.Context, tokenSymbol string, hours int, to time.Time) ([]types.PriceData, error)
+}
+
+// NewPriceHistoryProviders builds the providers named in order, using the given
+// provider -> token symbol -> provider symbol mappings
+func NewPriceHistoryProviders(names []string, mappings map[string]map[string]string) ([]PriceHistoryProvider, error) {
+	if len(names) == 0 {
+		return nil, errors.New("at least one price history provider is required")
+	}
+
+	providers := make([]PriceHistoryProvider, 0, len(names))
+	for _, name := range names {
+		symbols := mappings[name]
+		switch name {
+		case config.PriceProviderCryptoCompare:
+			providers = append(providers, &CryptoCompareProvider{symbols: symbols})
+		case config.PriceProviderCoinGecko:
+			providers = append(providers, &CoinGeckoProvider{
+				baseURL: config.CoinGeckoAPIURL,
+				apiKey:  config.CoinGeckoAPIKey,
+				symbols: symbols,
+			})
+		case config.PriceProviderFile:
+			providers = append(providers, &FilePriceHistoryProvider{
+				dir:     config.PriceHistoryFileDir,
+				symbols: symbols,
+			})
+		default:
+			return nil, fmt.Errorf("unknown price history provider: %s", name)
+		}
+	}
+
+	return providers, nil
+}
+
+// fetchFromProviders tries each provider in order and returns the first successful result
+// together with the name of the provider that supplied it
+func fetchFromProviders(ctx context.Context, providers []PriceHistoryProvider, tokenSymbol string, hours int, to time.Time) ([]types.PriceData, string, error) {
+	if len(providers) == 0 {
+		return nil, "", errors.New("no price history providers configured")
+	}
+
+	var errs []error
+	for _, provider := range providers {
+		if err := ctx.Err(); err != nil {
+			return nil, "", fmt.Errorf("price history fetch for %s cancelled: %w", tokenSymbol, err)
+		}
+
+		bars, err := provider.FetchHourlyPrices(ctx, tokenSymbol, hours, to)
+		if err == nil {
+			err = validateHourlyBars(bars, hours, to)
+		}
+		if err != nil {
+			metrics.CountFetchError(provider.Name(), err)
+			priceLogger.Warn().
+				Err(err).
+				Str("provider", provider.Name()).
+				Str("symbol", tokenSymbol).
+				Msg("Price history provider failed, trying next provider")
+			errs = append(errs, fmt.Errorf("%s: %w", provider.Name(), err))
+			continue
+		}
+		return bars, provider.Name(), nil
+	}
+
+	return nil, "", fmt.Errorf("all price history providers failed for %s: %w", tokenSymbol, errors.Join(errs...))
+}
+
+// resolveProviderSymbol returns the provider's identifier for a token, defaulting to the token symbol
+func resolveProviderSymbol(symbols map[string]string, tokenSymbol string) string {
+	if mapped, exists := symbols[strings.ToUpper(tokenSymbol)]; exists && mapped != "" {
+		return mapped
+	}
+	return tokenSymbol
+}
+
+// validateHourlyBars checks that a provider returned exactly the requested contiguous hourly bars
+// with finite, positive prices ending at the hour of `to`
+func validateHourlyBars(bars []types.PriceData, hours int, to time.Time) error {
+	if len(bars) != hours {
+		return fmt.Errorf("%w: expected %d hourly bars, got %d", ErrInsufficientData, hours, len(bars))
+	}
+
+	expected := to.UTC().Truncate(time.Hour).Add(-time.Duration(hours-1) * time.Hour)
+	for i, bar := range bars {
+		if !bar.Timestamp.UTC().Truncate(time.Hour).Equal(expected) {
+			return fmt.Errorf("%w: bar %d is at %s, expected %s", ErrInvalidPriceData, i,
+				bar.Timestamp.UTC().Format(time.RFC3339), expected.Format(time.RFC3339))
+		}
+		if math.IsNaN(bar.Price) || math.IsInf(bar.Price, 0) || bar.Price <= 0 {
+			return fmt.Errorf("%w: bar %d has invalid price %f", ErrInvalidPriceData, i, bar.Price)
+		}
+		expected = expected.Add(time.Hour)
+	}
+
+	return nil
+}
+
+// bucketHourly reduces timestamped samples to one close price per hour (the last sample in each hour)
+// and returns the `hours` bars ending at the hour of `to`. Missing hours are an error.
+func bucketHourly(samples []types.PriceData, hours int, to time.Time) ([]types.PriceData, error) {
+	end := to.UTC().Truncate(time.Hour)
+	start := end.Add(-time.Duration(hours-1) * time.Hour)
+
+	latest := make(map[int64]types.PriceData)
+	for _, sample := range samples {
+		ts := sample.Timestamp.UTC()
+		hour := ts.Truncate(time.Hour)
+		if hour.Before(start) || hour.After(end) {
+			continue
+		}
+		if existing, exists := latest[hour.Unix()]; !exists || ts.After(existing.Timestamp) {
+			latest[hour.Unix()] = types.PriceData{Timestamp: ts, Price: sample.Price}
+		}
+	}
+
+	bars := make([]types.PriceData, 0, hours)
+	for hour := start; !hour.After(end); hour = hour.Add(time.Hour) {
+		sample, exists := latest[hour.Unix()]
+		if !exists {
+			return nil, fmt.Errorf("%w: no price sample for hour %s", ErrInsufficientData, hour.Format(time.RFC3339))
+		}
+		bars = append(bars, types.PriceData{Timestamp: hour, Price: sample.Price})
+	}
+
+	return bars, nil
+}
@@ -0,0 +1,32 @@
+/*
+This file contains the quarantine helpers used by the token and pool fetchers.
+
+With QUARANTINE_ENABLED, a token or pool whose data fails to fetch or validate is recorded and
+skipped instead of failing the whole fetch. If more than QUARANTINE_MAX_FAILURE_RATIO of them
+fail, the data source itself is considered broken and the fetch fails after all.
+*/
+
+package datafetcher
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/elys-network/avm/internal/config"
+)
+
+var ErrTooManyDataFailures = errors.New("too many tokens or pools failed data validation")
+
+// checkFailureRatio returns the fraction of failed items and an error if it exceeds the configured maximum
+func checkFailureRatio(kind string, failed, total int) (float64, error) {
+	if total == 0 || failed == 0 {
+		return 0, nil
+	}
+
+	ratio := float64(failed) / float64(total)
+	if ratio > config.QuarantineMaxFailureRatio {
+		return ratio, fmt.Errorf("%w: %d of %d %s quarantined (%.1f%%, max %.1f%%)",
+			ErrTooManyDataFailures, failed, total, kind, ratio*100, config.QuarantineMaxFailureRatio*100)
+	}
+	return ratio, nil
+}
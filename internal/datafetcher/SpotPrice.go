@@ -0,0 +1,154 @@
+/*
+This file is used to fetch current spot prices from the CryptoCompare API.
+
+Spot prices are an off-chain reference for the price integrity guard, which compares them
+against the Elys oracle and AMM prices before the AVM acts on those prices.
+*/
+
+package datafetcher
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/elys-network/avm/internal/fixtures"
+	"github.com/elys-network/avm/internal/utils"
+)
+
+const (
+	SPOT_PRICE_URL = "https://min-api.cryptocompare.com/data/pricemulti"
+)
+
+// FetchSpotPrices fetches the current USD spot price for each CryptoCompare symbol in a single request.
+// Symbols without a valid price in the response are omitted from the returned map.
+func FetchSpotPrices(ctx context.Context, symbols []string) (map[string]float64, error) {
+	if len(symbols) == 0 {
+		return map[string]float64{}, nil
+	}
+
+	apiKey := os.Getenv("CRYPTOCOMPARE_API")
+	if apiKey == "" {
+		return nil, errors.New("CRYPTOCOMPARE_API environment variable is required")
+	}
+
+	// Normalize and de-duplicate symbols
+	seen := make(map[string]bool)
+	normalized := make([]string, 0, len(symbols))
+	for _, symbol := range symbols {
+		symbol = strings.TrimSpace(strings.ToUpper(symbol))
+		if symbol == "" || seen[symbol] {
+			continue
+		}
+		seen[symbol] = true
+		normalized = append(normalized, symbol)
+	}
+
+	// The key goes in a header so it never appears in the URL, which transport errors include
+	url := fmt.Sprintf("%s?fsyms=%s&tsyms=USD", SPOT_PRICE_URL, strings.Join(normalized, ","))
+	headers := map[string]string{"authorization": "Apikey " + apiKey}
+
+	client := &http.Client{
+		Timeout:   TIMEOUT_SECONDS * time.Second,
+		Transport: fixtures.Transport(),
+	}
+
+	var lastErr error
+	for attempt := 1; attempt <= MAX_RETRIES; attempt++ {
+		resp, err := fetchHTTP(ctx, client, url, headers)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, fmt.Errorf("spot price fetch cancelled: %w", ctx.Err())
+			}
+			lastErr = fmt.Errorf("HTTP request failed on attempt %d: %w", attempt, err)
+			priceLogger.Warn().
+				Err(err).
+				Int("attempt", attempt).
+				Msg("Spot price request failed, will retry if attempts remain")
+			if attempt < MAX_RETRIES {
+				if err := utils.SleepContext(ctx, time.Duration(attempt)*time.Second); err != nil {
+					return nil, fmt.Errorf("spot price fetch cancelled: %w", err)
+				}
+			}
+			continue
+		}
+
+		prices, err := processSpotPriceResponse(resp)
+		resp.Body.Close()
+		if err != nil {
+			lastErr = err
+			priceLogger.Warn().
+				Err(err).
+				Int("attempt", attempt).
+				Msg("Spot price response processing failed, will retry if attempts remain")
+			if attempt < MAX_RETRIES {
+				if err := utils.SleepContext(ctx, time.Duration(attempt)*time.Second); err != nil {
+					return nil, fmt.Errorf("spot price fetch cancelled: %w", err)
+				}
+			}
+			continue
+		}
+
+		priceLogger.Info().
+			Int("requestedSymbols", len(normalized)).
+			Int("pricedSymbols", len(prices)).
+			Msg("Successfully retrieved spot prices")
+		return prices, nil
+	}
+
+	return nil, fmt.Errorf("failed to fetch spot prices after %d attempts: %w", MAX_RETRIES, lastErr)
+}
+
+// processSpotPriceResponse parses a pricemulti response into a symbol -> USD price map
+func processSpotPriceResponse(resp *http.Response) (map[string]float64, error) {
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("API returned status %d", resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+	if len(body) == 0 {
+		return nil, errors.New("empty response body")
+	}
+
+	// Error responses share the endpoint but have a different shape
+	var errorResp struct {
+		Response string `json:"Response"`
+		Message  string `json:"Message"`
+	}
+	if err := json.Unmarshal(body, &errorResp); err == nil && errorResp.Response == "Error" {
+		return nil, fmt.Errorf("API error: %s", errorResp.Message)
+	}
+
+	var raw map[string]map[string]float64
+	if err := json.Unmarshal(body, &raw); err != nil {
+		return nil, fmt.Errorf("failed to parse JSON response: %w", err)
+	}
+
+	prices := make(map[string]float64, len(raw))
+	for symbol, quotes := range raw {
+		price, ok := quotes["USD"]
+		if !ok {
+			continue
+		}
+		if math.IsNaN(price) || math.IsInf(price, 0) || price <= 0 {
+			priceLogger.Warn().
+				Str("symbol", symbol).
+				Float64("price", price).
+				Msg("Ignoring invalid spot price")
+			continue
+		}
+		prices[symbol] = price
+	}
+
+	return prices, nil
+}
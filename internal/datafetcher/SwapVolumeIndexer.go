@@ -0,0 +1,454 @@
+/*
+This file contains the on-chain swap volume indexer.
+
+It walks blocks through the node's CometBFT RPC (NODE_RPC), reads the AMM swap events of every
+successful transaction, values each swap in USD and adds it to the pool's daily total in the
+pool_swap_volume_daily table. Each run continues from the last indexed block, so after the
+initial backfill only the blocks produced since the previous cycle are read.
+
+Swaps are valued with the on-chain prices at the time they are indexed. As the indexer runs
+every cycle, this is close to the price at the time of the swap, except during a backfill.
+*/
+
+package datafetcher
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/elys-network/avm/internal/config"
+	"github.com/elys-network/avm/internal/fixtures"
+	"github.com/elys-network/avm/internal/logger"
+	"github.com/elys-network/avm/internal/state"
+	"github.com/elys-network/avm/internal/types"
+	"github.com/elys-network/avm/internal/utils"
+	"google.golang.org/grpc"
+)
+
+var indexerLogger = logger.GetForComponent("swap_volume_indexer")
+
+// ErrVolumeIndexIncomplete is returned when the swap volume index does not yet cover the whole volume window
+var ErrVolumeIndexIncomplete = errors.New("swap volume index does not cover the volume window")
+
+const (
+	// AMM swap event emitted by the Elys amm module for every swap
+	SWAP_EVENT_TYPE      = "token_swapped"
+	SWAP_ATTR_POOL_ID    = "pool_id"
+	SWAP_ATTR_TOKENS_IN  = "tokens_in"
+	SWAP_ATTR_TOKENS_OUT = "tokens_out"
+
+	VOLUME_WINDOW_DAYS       = 7
+	INDEXER_BATCH_BLOCKS     = 200 // Blocks indexed and saved per database transaction
+	BLOCKCHAIN_INFO_MAX_SPAN = 20  // Max block headers returned by one /blockchain request
+)
+
+type rpcBlockchainResult struct {
+	BlockMetas []struct {
+		Header struct {
+			Height string    `json:"height"`
+			Time   time.Time `json:"time"`
+		} `json:"header"`
+	} `json:"block_metas"`
+}
+
+type rpcEvent struct {
+	Type       string `json:"type"`
+	Attributes []struct {
+		Key   string `json:"key"`
+		Value string `json:"value"`
+	} `json:"attributes"`
+}
+
+type rpcBlockResultsResult struct {
+	TxsResults []struct {
+		Code   uint32     `json:"code"`
+		Events []rpcEvent `json:"events"`
+	} `json:"txs_results"`
+	FinalizeBlockEvents []rpcEvent `json:"finalize_block_events"`
+}
+
+// indexedSwap is one AMM swap found in a block
+type indexedSwap struct {
+	poolID   uint64
+	valueUSD float64
+	priced   bool
+}
+
+// swapPricer values swapped coins in USD using on-chain prices and asset decimals
+type swapPricer struct {
+	priceUSD map[string]float64 // USD per whole token, keyed by on-chain denom
+	decimals map[string]int
+}
+
+// GetOnChainWeeklyVolume indexes any new blocks and returns the USD swap volume of each pool over
+// the last VOLUME_WINDOW_DAYS full UTC days. Pools without swaps in the window are absent.
+// It returns ErrVolumeIndexIncomplete while the index is still backfilling or behind the chain head.
+func GetOnChainWeeklyVolume(ctx context.Context, grpcClient *grpc.ClientConn) (map[types.PoolID]float64, error) {
+	caughtUp, err := IndexSwapVolume(ctx, grpcClient)
+	if err != nil {
+		return nil, fmt.Errorf("swap volume indexing failed: %w", err)
+	}
+	if !caughtUp {
+		return nil, fmt.Errorf("%w: indexer has not reached the chain head", ErrVolumeIndexIncomplete)
+	}
+
+	indexerState, err := state.GetSwapVolumeIndexerState()
+	if err != nil {
+		return nil, err
+	}
+	if indexerState == nil {
+		return nil, fmt.Errorf("%w: indexer has not run", ErrVolumeIndexIncomplete)
+	}
+
+	windowEnd := fixtures.Now().UTC().Truncate(24 * time.Hour)
+	windowStart := windowEnd.AddDate(0, 0, -VOLUME_WINDOW_DAYS)
+	if indexerState.FirstBlockTime.After(windowStart) {
+		return nil, fmt.Errorf("%w: index starts at %s, window starts at %s", ErrVolumeIndexIncomplete,
+			indexerState.FirstBlockTime.UTC().Format(time.RFC3339), windowStart.Format(time.RFC3339))
+	}
+
+	volumes, err := state.GetSwapVolumeByPool(windowStart, windowEnd)
+	if err != nil {
+		return nil, err
+	}
+
+	indexerLogger.Info().
+		Int("poolCount", len(volumes)).
+		Time("windowStart", windowStart).
+		Time("windowEnd", windowEnd).
+		Msg("Loaded on-chain weekly volume")
+
+	return volumes, nil
+}
+
+// IndexSwapVolume indexes up to VOLUME_INDEXER_MAX_BLOCKS_PER_RUN blocks after the last indexed one.
+// With no previous state, or when the previous state is older than the backfill range, the index is
+// reset and starts VOLUME_INDEXER_BACKFILL_BLOCKS behind the chain head. It reports whether the
+// index reached the chain head. When ctx is pinned to a block height, that height is the chain head.
+func IndexSwapVolume(ctx context.Context, grpcClient *grpc.ClientConn) (bool, error) {
+	if grpcClient == nil {
+		return false, errors.New("GRPC client cannot be nil")
+	}
+
+	client := &http.Client{
+		Timeout:   NODE_RPC_TIMEOUT,
+		Transport: fixtures.Transport(),
+	}
+
+	latestHeight, pinned := utils.BlockHeightFromContext(ctx)
+	if !pinned {
+		var err error
+		latestHeight, err = fetchLatestHeight(ctx, client)
+		if err != nil {
+			return false, err
+		}
+	}
+
+	indexerState, err := state.GetSwapVolumeIndexerState()
+	if err != nil {
+		return false, err
+	}
+
+	backfillStart := latestHeight - int64(config.VolumeIndexerBackfillBlocks) + 1
+	if backfillStart < 1 {
+		backfillStart = 1
+	}
+
+	var startHeight int64
+	if indexerState == nil || indexerState.LastHeight+1 < backfillStart {
+		if indexerState != nil {
+			// Blocks between the old index and the backfill range would be missing, so start over
+			indexerLogger.Warn().
+				Int64("lastIndexedHeight", indexerState.LastHeight).
+				Int64("backfillStart", backfillStart).
+				Msg("Swap volume index is older than the backfill range - resetting")
+			if err := state.ResetSwapVolumeIndex(); err != nil {
+				return false, err
+			}
+			indexerState = nil
+		}
+		startHeight = backfillStart
+	} else {
+		startHeight = indexerState.LastHeight + 1
+	}
+
+	if startHeight > latestHeight {
+		indexerLogger.Debug().Int64("latestHeight", latestHeight).Msg("Swap volume index is up to date")
+		return true, nil
+	}
+
+	endHeight := startHeight + int64(config.VolumeIndexerMaxBlocksPerRun) - 1
+	if endHeight > latestHeight {
+		endHeight = latestHeight
+	}
+
+	pricer, err := newSwapPricer(ctx, grpcClient)
+	if err != nil {
+		return false, err
+	}
+
+	indexerLogger.Info().
+		Int64("startHeight", startHeight).
+		Int64("endHeight", endHeight).
+		Int64("latestHeight", latestHeight).
+		Msg("Indexing swap volume")
+
+	totalSwaps := 0
+	unpricedSwaps := 0
+	for batchStart := startHeight; batchStart <= endHeight; batchStart += INDEXER_BATCH_BLOCKS {
+		batchEnd := batchStart + INDEXER_BATCH_BLOCKS - 1
+		if batchEnd > endHeight {
+			batchEnd = endHeight
+		}
+
+		blockTimes, err := fetchBlockTimes(ctx, client, batchStart, batchEnd)
+		if err != nil {
+			return false, err
+		}
+
+		blockSwaps := make([][]indexedSwap, batchEnd-batchStart+1)
+		err = utils.ForEachLimited(ctx, config.FetchMaxConcurrency, len(blockSwaps), func(ctx context.Context, i int) error {
+			swaps, err := fetchBlockSwaps(ctx, client, batchStart+int64(i), pricer)
+			if err != nil {
+				return err
+			}
+			blockSwaps[i] = swaps
+			return nil
+		})
+		if err != nil {
+			return false, err
+		}
+
+		// Aggregate the batch into daily totals per pool
+		type poolDay struct {
+			poolID uint64
+			day    time.Time
+		}
+		daily := make(map[poolDay]*types.PoolDailyVolume)
+		for i, swaps := range blockSwaps {
+			day := blockTimes[batchStart+int64(i)].UTC().Truncate(24 * time.Hour)
+			for _, swap := range swaps {
+				totalSwaps++
+				if !swap.priced {
+					unpricedSwaps++
+				}
+
+				key := poolDay{poolID: swap.poolID, day: day}
+				volume, exists := daily[key]
+				if !exists {
+					volume = &types.PoolDailyVolume{PoolID: types.PoolID(swap.poolID), Day: day}
+					daily[key] = volume
+				}
+				volume.VolumeUSD += swap.valueUSD
+				volume.SwapCount++
+			}
+		}
+
+		volumes := make([]types.PoolDailyVolume, 0, len(daily))
+		for _, volume := range daily {
+			volumes = append(volumes, *volume)
+		}
+
+		newState := types.SwapVolumeIndexerState{
+			FirstHeight:    batchStart,
+			FirstBlockTime: blockTimes[batchStart],
+			LastHeight:     batchEnd,
+			LastBlockTime:  blockTimes[batchEnd],
+		}
+		if indexerState != nil {
+			newState.FirstHeight = indexerState.FirstHeight
+			newState.FirstBlockTime = indexerState.FirstBlockTime
+		}
+
+		if err := state.SaveSwapVolume(volumes, newState); err != nil {
+			return false, err
+		}
+		indexerState = &newState
+	}
+
+	if unpricedSwaps > 0 {
+		indexerLogger.Warn().
+			Int("unpricedSwaps", unpricedSwaps).
+			Int("totalSwaps", totalSwaps).
+			Msg("Some swaps had no on-chain price for either side and were counted with zero volume")
+	}
+
+	indexerLogger.Info().
+		Int64("indexedBlocks", endHeight-startHeight+1).
+		Int("swaps", totalSwaps).
+		Int64("blocksBehind", latestHeight-endHeight).
+		Msg("Indexed swap volume")
+
+	return endHeight == latestHeight, nil
+}
+
+// newSwapPricer loads on-chain prices and asset decimals for valuing swaps
+func newSwapPricer(ctx context.Context, grpcClient *grpc.ClientConn) (*swapPricer, error) {
+	entries, err := FetchAllTokens(ctx, grpcClient)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch token metadata for swap pricing: %w", err)
+	}
+	prices, err := FetchAllTokenPrices(ctx, grpcClient)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch token prices for swap pricing: %w", err)
+	}
+
+	pricer := &swapPricer{
+		priceUSD: make(map[string]float64),
+		decimals: make(map[string]int),
+	}
+	for _, entry := range entries {
+		price, exists := prices[entry.Denom]
+		if !exists || entry.Decimals == 0 {
+			continue
+		}
+
+		var priceUSD float64
+		if price.OraclePrice.IsPositive() {
+			priceUSD, err = price.OraclePrice.Float64()
+		} else if price.AmmPrice.IsPositive() {
+			priceUSD, err = price.AmmPrice.Float64()
+		} else {
+			continue
+		}
+		if err != nil || math.IsNaN(priceUSD) || math.IsInf(priceUSD, 0) || priceUSD <= 0 {
+			continue
+		}
+
+		pricer.priceUSD[entry.Denom] = priceUSD
+		pricer.decimals[entry.Denom] = int(entry.Decimals)
+	}
+
+	if len(pricer.priceUSD) == 0 {
+		return nil, errors.New("no priced tokens available for swap pricing")
+	}
+
+	return pricer, nil
+}
+
+// valueUSD returns the USD value of coins, and false if any of them has no price
+func (p *swapPricer) valueUSD(coins sdk.Coins) (float64, bool) {
+	if coins.Empty() {
+		return 0, false
+	}
+
+	total := 0.0
+	for _, coin := range coins {
+		price, exists := p.priceUSD[coin.Denom]
+		if !exists {
+			return 0, false
+		}
+		amount, err := strconv.ParseFloat(coin.Amount.String(), 64)
+		if err != nil {
+			return 0, false
+		}
+		total += amount / math.Pow10(p.decimals[coin.Denom]) * price
+	}
+
+	if math.IsNaN(total) || math.IsInf(total, 0) {
+		return 0, false
+	}
+	return total, true
+}
+
+// fetchBlockSwaps returns the AMM swaps in one block. Swaps of failed transactions are ignored.
+func fetchBlockSwaps(ctx context.Context, client *http.Client, height int64, pricer *swapPricer) ([]indexedSwap, error) {
+	var result rpcBlockResultsResult
+	if err := queryNodeRPC(ctx, client, fmt.Sprintf("/block_results?height=%d", height), &result); err != nil {
+		return nil, fmt.Errorf("failed to fetch block results at height %d: %w", height, err)
+	}
+
+	events := make([]rpcEvent, 0)
+	for _, txResult := range result.TxsResults {
+		if txResult.Code != 0 {
+			continue
+		}
+		events = append(events, txResult.Events...)
+	}
+	events = append(events, result.FinalizeBlockEvents...)
+
+	swaps := make([]indexedSwap, 0)
+	for _, event := range events {
+		if event.Type != SWAP_EVENT_TYPE {
+			continue
+		}
+
+		attributes := make(map[string]string, len(event.Attributes))
+		for _, attribute := range event.Attributes {
+			attributes[attribute.Key] = attribute.Value
+		}
+
+		poolID, err := strconv.ParseUint(attributes[SWAP_ATTR_POOL_ID], 10, 64)
+		if err != nil || poolID == 0 {
+			indexerLogger.Warn().
+				Int64("height", height).
+				Str("poolID", attributes[SWAP_ATTR_POOL_ID]).
+				Msg("Skipping swap event with invalid pool ID")
+			continue
+		}
+
+		swap := indexedSwap{poolID: poolID}
+		// Value the swap by what went in, or by what came out if the input has no price
+		for _, key := range []string{SWAP_ATTR_TOKENS_IN, SWAP_ATTR_TOKENS_OUT} {
+			coins, err := sdk.ParseCoinsNormalized(attributes[key])
+			if err != nil {
+				continue
+			}
+			if valueUSD, priced := pricer.valueUSD(coins); priced {
+				swap.valueUSD = valueUSD
+				swap.priced = true
+				break
+			}
+		}
+		swaps = append(swaps, swap)
+	}
+
+	return swaps, nil
+}
+
+// fetchBlockTimes returns the header time of every block in [minHeight, maxHeight]
+func fetchBlockTimes(ctx context.Context, client *http.Client, minHeight, maxHeight int64) (map[int64]time.Time, error) {
+	spanCount := int((maxHeight-minHeight)/BLOCKCHAIN_INFO_MAX_SPAN) + 1
+	spans := make([]rpcBlockchainResult, spanCount)
+
+	err := utils.ForEachLimited(ctx, config.FetchMaxConcurrency, spanCount, func(ctx context.Context, i int) error {
+		spanStart := minHeight + int64(i)*BLOCKCHAIN_INFO_MAX_SPAN
+		spanEnd := spanStart + BLOCKCHAIN_INFO_MAX_SPAN - 1
+		if spanEnd > maxHeight {
+			spanEnd = maxHeight
+		}
+		path := fmt.Sprintf("/blockchain?minHeight=%d&maxHeight=%d", spanStart, spanEnd)
+		if err := queryNodeRPC(ctx, client, path, &spans[i]); err != nil {
+			return fmt.Errorf("failed to fetch block headers %d-%d: %w", spanStart, spanEnd, err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	blockTimes := make(map[int64]time.Time, maxHeight-minHeight+1)
+	for _, span := range spans {
+		for _, meta := range span.BlockMetas {
+			height, err := strconv.ParseInt(meta.Header.Height, 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid block height %q in block header: %w", meta.Header.Height, err)
+			}
+			blockTimes[height] = meta.Header.Time
+		}
+	}
+
+	for height := minHeight; height <= maxHeight; height++ {
+		if blockTimes[height].IsZero() {
+			return nil, fmt.Errorf("missing block time for height %d", height)
+		}
+	}
+
+	return blockTimes, nil
+}
@@ -0,0 +1,79 @@
+/*
+This file provides the per-provider symbol of each token for the price history providers.
+
+The token_symbol_mappings table is the source of truth, but it only exists in PostgreSQL.
+defaultSymbolMappings carries the mappings seeded by migrations 0002 and 0014, so a store
+without the table (SQLite, or a database that is unreachable) still resolves WETH to ETH.
+Rows in the table override the defaults.
+*/
+
+package datafetcher
+
+import (
+	"github.com/elys-network/avm/internal/config"
+	"github.com/elys-network/avm/internal/state"
+)
+
+// defaultSymbolMappings is provider -> token symbol -> provider symbol, matching the seeded rows
+var defaultSymbolMappings = map[string]map[string]string{
+	config.PriceProviderCryptoCompare: {
+		"WETH":             "ETH",
+		"WRAPPED BITCOIN":  "WBTC", // Testnet display name
+		"WRAPPED ETHEREUM": "ETH",  // Testnet display name
+	},
+	config.PriceProviderCoinGecko: {
+		"ATOM":  "cosmos",
+		"OSMO":  "osmosis",
+		"TIA":   "celestia",
+		"USDC":  "usd-coin",
+		"USDT":  "tether",
+		"WBTC":  "wrapped-bitcoin",
+		"WETH":  "weth",
+		"PAXG":  "pax-gold",
+		"STARS": "stargaze",
+		"KAVA":  "kava",
+		"STRD":  "stride",
+		"AKT":   "akash-network",
+		"BLD":   "agoric",
+		"SCRT":  "secret",
+		"FET":   "fetch-ai",
+		"ELYS":  "elys-network",
+		"XION":  "xion-2",
+		"NTRN":  "neutron-3",
+		"OM":    "mantra-dao",
+		"SAGA":  "saga-2",
+		"BABY":  "babylon",
+	},
+}
+
+// loadSymbolMappings returns the default symbol mappings overridden by the token_symbol_mappings table.
+// The defaults alone are returned when the table cannot be read.
+func loadSymbolMappings() map[string]map[string]string {
+	mappings := make(map[string]map[string]string, len(defaultSymbolMappings))
+	for provider, symbols := range defaultSymbolMappings {
+		mappings[provider] = make(map[string]string, len(symbols))
+		for tokenSymbol, providerSymbol := range symbols {
+			mappings[provider][tokenSymbol] = providerSymbol
+		}
+	}
+
+	if state.DB == nil {
+		tokenLogger.Debug().Msg("Token symbol mappings table unavailable (database not initialized), using the built-in mappings")
+		return mappings
+	}
+
+	stored, err := state.LoadSymbolMappings()
+	if err != nil {
+		tokenLogger.Warn().Err(err).Msg("Failed to load token symbol mappings, using the built-in mappings")
+		return mappings
+	}
+	for provider, symbols := range stored {
+		if mappings[provider] == nil {
+			mappings[provider] = make(map[string]string, len(symbols))
+		}
+		for tokenSymbol, providerSymbol := range symbols {
+			mappings[provider][tokenSymbol] = providerSymbol
+		}
+	}
+	return mappings
+}
@@ -10,10 +10,12 @@ import (
 	"github.com/cosmos/cosmos-sdk/types/query"
 	"github.com/elys-network/avm/internal/analyzer"
 	"github.com/elys-network/avm/internal/logger"
+	"github.com/elys-network/avm/internal/metrics"
 	"google.golang.org/grpc"
 
 	"github.com/elys-network/avm/internal/config"
 	"github.com/elys-network/avm/internal/types"
+	"github.com/elys-network/avm/internal/utils"
 	tier "github.com/elys-network/elys/v6/x/tier/types"
 
 	assetprofiletypes "github.com/elys-network/elys/v6/x/assetprofile/types"
@@ -24,48 +26,104 @@ var ErrInvalidTokenData = errors.New("invalid token data")
 var ErrMissingRequiredData = errors.New("missing required token data")
 var ErrInsufficientPriceData = errors.New("insufficient price data for financial calculations")
 
-// GetTokens fetches all tokens on chain and returns them as a map keyed by denom
-// Returns error if any token fails validation - no partial results with financial data
-func GetTokens(grpcClient *grpc.ClientConn) (map[string]types.Token, error) {
+// GetTokens fetches tokens on chain and returns them as a map keyed by denom.
+// When denoms is non-nil, only tokens whose denom or IBC denom is in the list are processed,
+// so the cycle only pays for the price history of tokens that its pools actually reference.
+// Historical prices are fetched by a bounded worker pool (FETCH_MAX_CONCURRENCY).
+// Returns error if any token fails validation - no partial results with financial data - unless
+// quarantine is enabled, in which case failing tokens are left out and returned with their reasons.
+func GetTokens(ctx context.Context, grpcClient *grpc.ClientConn, denoms []string) (map[string]types.Token, []types.QuarantinedToken, error) {
 	tokenLogger.Info().Msg("Starting strict token data retrieval")
 
 	// Validate GRPC client
 	if grpcClient == nil {
-		return nil, errors.New("GRPC client cannot be nil")
+		return nil, nil, errors.New("GRPC client cannot be nil")
 	}
 
-	// Fetch all token metadata with strict validation
-	tokens, err := FetchAllTokens(grpcClient)
+	// Fetch token metadata and prices concurrently; both are independent chain queries
+	var tokens []assetprofiletypes.Entry
+	var priceMap map[string]*tier.Price
+	err := utils.RunConcurrently(ctx,
+		func(ctx context.Context) error {
+			var err error
+			tokens, err = FetchAllTokens(ctx, grpcClient)
+			if err != nil {
+				metrics.CountFetchError(metrics.SourceAssetProfile, err)
+				tokenLogger.Error().Err(err).Msg("Failed to fetch token metadata")
+				return fmt.Errorf("token metadata fetch failed: %w", err)
+			}
+			return nil
+		},
+		func(ctx context.Context) error {
+			var err error
+			priceMap, err = FetchAllTokenPrices(ctx, grpcClient)
+			if err != nil {
+				metrics.CountFetchError(metrics.SourceOracle, err)
+				tokenLogger.Error().Err(err).Msg("Failed to fetch token prices")
+				return fmt.Errorf("token price fetch failed: %w", err)
+			}
+			return nil
+		},
+	)
 	if err != nil {
-		tokenLogger.Error().Err(err).Msg("Failed to fetch token metadata")
-		return nil, fmt.Errorf("token metadata fetch failed: %w", err)
+		return nil, nil, err
 	}
 
 	if len(tokens) == 0 {
 		tokenLogger.Error().Msg("No tokens returned from metadata fetch")
-		return nil, errors.New("no token metadata available")
-	}
-
-	// Fetch all token prices with strict validation
-	priceMap, err := FetchAllTokenPrices(grpcClient)
-	if err != nil {
-		tokenLogger.Error().Err(err).Msg("Failed to fetch token prices")
-		return nil, fmt.Errorf("token price fetch failed: %w", err)
+		return nil, nil, errors.New("no token metadata available")
 	}
 
 	if len(priceMap) == 0 {
 		tokenLogger.Error().Msg("No token prices available")
-		return nil, errors.New("no token price data available")
+		return nil, nil, errors.New("no token price data available")
 	}
 
-	// Initialize the token map
-	tokenMap := make(map[string]types.Token)
-	processedCount := 0
+	// Restrict processing to the requested denoms, matching either the base or the IBC denom
+	var wanted map[string]bool
+	if denoms != nil {
+		wanted = make(map[string]bool, len(denoms))
+		for _, denom := range denoms {
+			wanted[denom] = true
+		}
+	}
+
+	// Load per-provider token symbols. Tokens without one use their own symbol.
+	symbolMappings := loadSymbolMappings()
+
+	priceHistoryProviders, err := NewPriceHistoryProviders(config.PriceHistoryProviders, symbolMappings)
+	if err != nil {
+		return nil, nil, fmt.Errorf("price history provider setup failed: %w", err)
+	}
 
 	tokenLogger.Info().
 		Int("totalTokensFromAPI", len(tokens)).
+		Int("requestedDenoms", len(wanted)).
 		Msg("Starting token processing")
 
+	// Tokens that fail are either fatal or, with quarantine enabled, recorded and skipped
+	var quarantined []types.QuarantinedToken
+	quarantineToken := func(denom, ibcDenom, symbol string, reason error) error {
+		if !config.QuarantineEnabled {
+			return reason
+		}
+		tokenLogger.Warn().
+			Err(reason).
+			Str("denom", denom).
+			Str("symbol", symbol).
+			Msg("Quarantining token for this cycle")
+		quarantined = append(quarantined, types.QuarantinedToken{
+			Denom:    denom,
+			IBCDenom: ibcDenom,
+			Symbol:   symbol,
+			Reason:   reason.Error(),
+		})
+		return nil
+	}
+
+	// First pass: validate metadata and on-chain prices, which needs no network access
+	var candidates []types.Token
+	consideredCount := 0
 	for i, token := range tokens {
 		tokenLogger.Debug().
 			Int("tokenIndex", i).
@@ -88,6 +146,14 @@ func GetTokens(grpcClient *grpc.ClientConn) (map[string]types.Token, error) {
 			continue
 		}
 
+		// Skip tokens not referenced by the caller
+		if wanted != nil && !wanted[token.Denom] && !wanted[token.BaseDenom] {
+			tokenLogger.Debug().Str("denom", token.Denom).Msg("Skipping token not referenced by any requested denom")
+			continue
+		}
+
+		consideredCount++
+
 		// Strict validation of token metadata
 		if err := validateTokenMetadata(token); err != nil {
 			tokenLogger.Error().
@@ -95,7 +161,11 @@ func GetTokens(grpcClient *grpc.ClientConn) (map[string]types.Token, error) {
 				Str("denom", token.Denom).
 				Str("displayName", token.DisplayName).
 				Msg("Token metadata validation failed")
-			return nil, fmt.Errorf("token metadata validation failed for %s: %w", token.DisplayName, err)
+			reason := fmt.Errorf("token metadata validation failed for %s: %w", token.DisplayName, err)
+			if err := quarantineToken(token.BaseDenom, token.Denom, strings.ToUpper(token.DisplayName), reason); err != nil {
+				return nil, nil, err
+			}
+			continue
 		}
 
 		// Check if price data exists
@@ -127,53 +197,66 @@ func GetTokens(grpcClient *grpc.ClientConn) (map[string]types.Token, error) {
 			PriceData: []types.PriceData{},
 		}
 
-		// Set price information (guaranteed to be valid from validation above)
+		// Keep the raw on-chain prices for the price integrity guard
 		if price.OraclePrice.IsPositive() {
-			priceFloat, err := price.OraclePrice.Float64()
+			newToken.OraclePriceUSD, err = price.OraclePrice.Float64()
 			if err != nil {
-				return nil, fmt.Errorf("oracle price conversion failed for %s: %w", token.DisplayName, err)
+				reason := fmt.Errorf("oracle price conversion failed for %s: %w", token.DisplayName, err)
+				if err := quarantineToken(newToken.Denom, newToken.IBCDenom, newToken.Symbol, reason); err != nil {
+					return nil, nil, err
+				}
+				continue
 			}
-			newToken.PriceUSD = priceFloat
-			newToken.OracleSourced = true
-		} else {
-			priceFloat, err := price.AmmPrice.Float64()
+		}
+		if price.AmmPrice.IsPositive() {
+			newToken.AmmPriceUSD, err = price.AmmPrice.Float64()
 			if err != nil {
-				return nil, fmt.Errorf("AMM price conversion failed for %s: %w", token.DisplayName, err)
+				reason := fmt.Errorf("AMM price conversion failed for %s: %w", token.DisplayName, err)
+				if err := quarantineToken(newToken.Denom, newToken.IBCDenom, newToken.Symbol, reason); err != nil {
+					return nil, nil, err
+				}
+				continue
 			}
-			newToken.PriceUSD = priceFloat
-			newToken.OracleSourced = false
 		}
 
-		// Check if symbol has CryptoCompare mapping, fallback to symbol itself
-		ccSymbol := config.CoinToCCId[newToken.Symbol]
-		if ccSymbol == "" {
-			tokenLogger.Debug().
-				Str("symbol", newToken.Symbol).
-				Msg("Token symbol not found in CoinToCCId mapping, using symbol directly")
-			ccSymbol = newToken.Symbol
+		// Set price information (guaranteed to be valid from validation above)
+		if price.OraclePrice.IsPositive() {
+			newToken.PriceUSD = newToken.OraclePriceUSD
+			newToken.OracleSourced = true
 		} else {
-			tokenLogger.Info().
-				Str("symbol", newToken.Symbol).
-				Str("mappedSymbol", ccSymbol).
-				Msg("Found CryptoCompare mapping for token")
+			newToken.PriceUSD = newToken.AmmPriceUSD
+			newToken.OracleSourced = false
+		}
+
+		candidates = append(candidates, newToken)
+	}
+
+	// Second pass: fetch historical prices and compute volatility in parallel. With quarantine
+	// enabled, a token's failure is kept in failures[i] instead of cancelling the other workers.
+	failures := make([]error, len(candidates))
+	err = utils.ForEachLimited(ctx, config.FetchMaxConcurrency, len(candidates), func(ctx context.Context, i int) error {
+		newToken := &candidates[i]
+		fail := func(err error) error {
+			if !config.QuarantineEnabled || ctx.Err() != nil {
+				return err
+			}
+			failures[i] = err
+			return nil
 		}
 
-		// Fetch historical price data (required for volatility)
 		tokenLogger.Info().
 			Str("symbol", newToken.Symbol).
-			Str("ccSymbol", ccSymbol).
 			Str("denom", newToken.Denom).
 			Float64("priceUSD", newToken.PriceUSD).
 			Msg("Fetching historical price data for token")
 
-		thirtyDayPrices, err := FetchHistoricalPriceData(ccSymbol)
+		thirtyDayPrices, err := GetHistoricalPriceData(ctx, newToken.Symbol, priceHistoryProviders)
 		if err != nil {
 			tokenLogger.Error().
 				Err(err).
 				Str("symbol", newToken.Symbol).
-				Str("ccSymbol", ccSymbol).
 				Msg("Failed to fetch historical price data")
-			return nil, fmt.Errorf("historical price data fetch failed for %s: %w", ccSymbol, err)
+			return fail(fmt.Errorf("historical price data fetch failed for %s: %w", newToken.Symbol, err))
 		}
 
 		newToken.PriceData = thirtyDayPrices
@@ -190,10 +273,28 @@ func GetTokens(grpcClient *grpc.ClientConn) (map[string]types.Token, error) {
 				Err(err).
 				Str("symbol", newToken.Symbol).
 				Msg("Failed to calculate volatility")
-			return nil, fmt.Errorf("volatility calculation failed for %s: %w", newToken.Symbol, err)
+			return fail(fmt.Errorf("volatility calculation failed for %s: %w", newToken.Symbol, err))
 		}
 
 		newToken.Volatility = volatility
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Final pass: validate and index the tokens in their original order
+	tokenMap := make(map[string]types.Token)
+	ccSymbols := make(map[string]string) // Denom -> CryptoCompare symbol, used for spot reference prices
+	processedCount := 0
+
+	for i, newToken := range candidates {
+		if failures[i] != nil {
+			if err := quarantineToken(newToken.Denom, newToken.IBCDenom, newToken.Symbol, failures[i]); err != nil {
+				return nil, nil, err
+			}
+			continue
+		}
 
 		// Final validation - ensure token is ready for financial use
 		if err := validateTokenForFinancialUse(newToken); err != nil {
@@ -201,11 +302,17 @@ func GetTokens(grpcClient *grpc.ClientConn) (map[string]types.Token, error) {
 				Err(err).
 				Str("symbol", newToken.Symbol).
 				Msg("Final token validation failed")
-			return nil, fmt.Errorf("final validation failed for %s: %w", newToken.Symbol, err)
+			reason := fmt.Errorf("final validation failed for %s: %w", newToken.Symbol, err)
+			if err := quarantineToken(newToken.Denom, newToken.IBCDenom, newToken.Symbol, reason); err != nil {
+				return nil, nil, err
+			}
+			continue
 		}
 
 		// Add validated token to map
 		tokenMap[newToken.Denom] = newToken
+		// CryptoCompare symbol for the spot reference price, falling back to the symbol itself
+		ccSymbols[newToken.Denom] = resolveProviderSymbol(symbolMappings[config.PriceProviderCryptoCompare], newToken.Symbol)
 
 		// Also add an entry using IBCDenom as key if it's different
 		if newToken.IBCDenom != newToken.Denom {
@@ -224,19 +331,64 @@ func GetTokens(grpcClient *grpc.ClientConn) (map[string]types.Token, error) {
 
 	if processedCount == 0 {
 		tokenLogger.Error().Msg("No tokens were successfully processed")
-		return nil, errors.New("no valid tokens found for financial calculations")
+		return nil, nil, errors.New("no valid tokens found for financial calculations")
 	}
 
+	// Too many failures point at a broken data source rather than a few bad tokens
+	if _, err := checkFailureRatio("tokens", len(quarantined), consideredCount); err != nil {
+		tokenLogger.Error().Err(err).Msg("Token quarantine threshold exceeded")
+		return nil, nil, err
+	}
+
+	// Attach off-chain spot reference prices. A failure here is not fatal: the price integrity
+	// guard reports tokens without a reference price instead of silently trusting them.
+	attachReferencePrices(ctx, tokenMap, ccSymbols)
+
 	tokenLogger.Info().
 		Int("totalTokens", len(tokens)).
 		Int("processedTokens", processedCount).
+		Int("quarantinedTokens", len(quarantined)).
 		Int("mapEntries", len(tokenMap)).
 		Msg("Successfully retrieved and validated all token data")
 
-	return tokenMap, nil
+	return tokenMap, quarantined, nil
+}
+
+// attachReferencePrices fetches CryptoCompare spot prices for all processed tokens and stores them
+// as the token's ReferencePriceUSD under both its Denom and IBCDenom keys
+func attachReferencePrices(ctx context.Context, tokenMap map[string]types.Token, ccSymbols map[string]string) {
+	symbols := make([]string, 0, len(ccSymbols))
+	for _, ccSymbol := range ccSymbols {
+		symbols = append(symbols, ccSymbol)
+	}
+
+	spotPrices, err := FetchSpotPrices(ctx, symbols)
+	if err != nil {
+		metrics.CountFetchError(metrics.SourceSpotPrice, err)
+		tokenLogger.Warn().Err(err).Msg("Failed to fetch spot reference prices - price integrity checks will be degraded")
+		return
+	}
+
+	for denom, ccSymbol := range ccSymbols {
+		spot, exists := spotPrices[strings.ToUpper(ccSymbol)]
+		if !exists {
+			tokenLogger.Warn().
+				Str("denom", denom).
+				Str("ccSymbol", ccSymbol).
+				Msg("No spot reference price available for token")
+			continue
+		}
+
+		token := tokenMap[denom]
+		token.ReferencePriceUSD = spot
+		tokenMap[denom] = token
+		if token.IBCDenom != token.Denom {
+			tokenMap[token.IBCDenom] = token
+		}
+	}
 }
 
-func FetchAllTokens(grpcClient *grpc.ClientConn) ([]assetprofiletypes.Entry, error) {
+func FetchAllTokens(ctx context.Context, grpcClient *grpc.ClientConn) ([]assetprofiletypes.Entry, error) {
 	if grpcClient == nil {
 		return nil, errors.New("GRPC client cannot be nil")
 	}
@@ -258,7 +410,7 @@ func FetchAllTokens(grpcClient *grpc.ClientConn) ([]assetprofiletypes.Entry, err
 		}
 
 		response, err := assetProfileClient.EntryAll(
-			context.Background(),
+			ctx,
 			&assetprofiletypes.QueryAllEntryRequest{
 				Pagination: paginationReq,
 			},
@@ -301,7 +453,7 @@ func FetchAllTokens(grpcClient *grpc.ClientConn) ([]assetprofiletypes.Entry, err
 }
 
 // FetchAllTokenPrices fetches all token prices and returns them as a map keyed by denom
-func FetchAllTokenPrices(grpcClient *grpc.ClientConn) (map[string]*tier.Price, error) {
+func FetchAllTokenPrices(ctx context.Context, grpcClient *grpc.ClientConn) (map[string]*tier.Price, error) {
 	if grpcClient == nil {
 		return nil, errors.New("GRPC client cannot be nil")
 	}
@@ -323,7 +475,7 @@ func FetchAllTokenPrices(grpcClient *grpc.ClientConn) (map[string]*tier.Price, e
 		}
 
 		response, err := tierClient.GetAllPrices(
-			context.Background(),
+			ctx,
 			&tier.QueryGetAllPricesRequest{
 				Pagination: paginationReq,
 			},
@@ -0,0 +1,142 @@
+/*
+This file selects where the 7-day pool volume comes from, according to VOLUME_SOURCE:
+the Elys Supply API, the on-chain swap volume index, or the Supply API cross-checked
+against the index.
+*/
+
+package datafetcher
+
+import (
+	"context"
+	"errors"
+	"math"
+
+	"github.com/elys-network/avm/internal/config"
+	"github.com/elys-network/avm/internal/metrics"
+	"github.com/elys-network/avm/internal/types"
+	"google.golang.org/grpc"
+)
+
+// WeeklyVolume is the 7-day USD volume of each pool from one volume source
+type WeeklyVolume struct {
+	VolumeUSD map[uint64]float64 // Keyed by pool ID
+	Complete  bool               // True if a pool without an entry had no swaps, rather than missing data
+	Source    string             // The VOLUME_SOURCE the data came from
+}
+
+// fetchWeeklyVolume returns the 7-day USD volume of each pool from the configured source.
+// The on-chain source falls back to the Supply API while its index is incomplete.
+func fetchWeeklyVolume(ctx context.Context, grpcClient *grpc.ClientConn) (WeeklyVolume, error) {
+	switch config.VolumeSource {
+	case config.VolumeSourceOnChain:
+		onChainVolume, err := GetOnChainWeeklyVolume(ctx, grpcClient)
+		if err == nil {
+			return WeeklyVolume{
+				VolumeUSD: onChainVolumeByPool(onChainVolume),
+				Complete:  true,
+				Source:    config.VolumeSourceOnChain,
+			}, nil
+		}
+		if !errors.Is(err, ErrVolumeIndexIncomplete) {
+			return WeeklyVolume{}, metrics.CountFetchError(metrics.SourceSwapVolumeIndex, err)
+		}
+		volumeLogger.Warn().Err(err).Msg("On-chain volume index is incomplete - using the Supply API for this cycle")
+		return fetchSupplyAPIVolume(ctx)
+
+	case config.VolumeSourceCrossCheck:
+		volume, err := fetchSupplyAPIVolume(ctx)
+		if err != nil {
+			return WeeklyVolume{}, err
+		}
+
+		// The cross-check is informational, so an unavailable index never fails the fetch
+		onChainVolume, err := GetOnChainWeeklyVolume(ctx, grpcClient)
+		if err != nil {
+			if ctx.Err() != nil {
+				return WeeklyVolume{}, ctx.Err()
+			}
+			if !errors.Is(err, ErrVolumeIndexIncomplete) {
+				metrics.CountFetchError(metrics.SourceSwapVolumeIndex, err)
+			}
+			volumeLogger.Warn().Err(err).Msg("On-chain volume unavailable - skipping volume cross-check")
+			return volume, nil
+		}
+
+		crossCheckVolume(volume.VolumeUSD, onChainVolumeByPool(onChainVolume))
+		return volume, nil
+
+	default:
+		return fetchSupplyAPIVolume(ctx)
+	}
+}
+
+// fetchSupplyAPIVolume returns the Supply API volume, summing each pool's token volumes
+func fetchSupplyAPIVolume(ctx context.Context) (WeeklyVolume, error) {
+	volumeData, err := GetWeeklyVolumeByPool(ctx)
+	if err != nil {
+		return WeeklyVolume{}, metrics.CountFetchError(metrics.SourceSupplyAPI, err)
+	}
+
+	volume := WeeklyVolume{
+		VolumeUSD: make(map[uint64]float64, len(volumeData)),
+		Source:    config.VolumeSourceSupplyAPI,
+	}
+	for poolID, poolVolume := range volumeData {
+		var totalVolume uint64
+		for _, tokenVolume := range poolVolume {
+			totalVolume += tokenVolume
+		}
+		volume.VolumeUSD[poolID] = float64(totalVolume)
+	}
+	return volume, nil
+}
+
+// onChainVolumeByPool re-keys the on-chain volume by raw pool ID
+func onChainVolumeByPool(volumes map[types.PoolID]float64) map[uint64]float64 {
+	byPool := make(map[uint64]float64, len(volumes))
+	for poolID, volumeUSD := range volumes {
+		byPool[uint64(poolID)] = volumeUSD
+	}
+	return byPool
+}
+
+// crossCheckVolume logs every pool whose Supply API and on-chain volumes differ by more than
+// VOLUME_CROSSCHECK_MAX_DIVERGENCE_PERCENT. A pool missing from one source counts as zero volume there.
+func crossCheckVolume(supplyVolume, onChainVolume map[uint64]float64) {
+	poolIDs := make(map[uint64]bool)
+	for poolID := range supplyVolume {
+		poolIDs[poolID] = true
+	}
+	for poolID := range onChainVolume {
+		poolIDs[poolID] = true
+	}
+
+	divergentCount := 0
+	for poolID := range poolIDs {
+		supplyUSD := supplyVolume[poolID]
+		onChainUSD := onChainVolume[poolID]
+
+		larger := math.Max(supplyUSD, onChainUSD)
+		if larger == 0 {
+			continue
+		}
+		divergencePercent := math.Abs(supplyUSD-onChainUSD) / larger * 100
+		if divergencePercent <= config.VolumeCrossCheckMaxDivergencePercent {
+			continue
+		}
+
+		divergentCount++
+		volumeLogger.Warn().
+			Uint64("poolID", poolID).
+			Float64("supplyAPIVolumeUSD", supplyUSD).
+			Float64("onChainVolumeUSD", onChainUSD).
+			Float64("divergencePercent", divergencePercent).
+			Msg("Pool volume differs between the Supply API and on-chain swaps")
+	}
+
+	volumeLogger.Info().
+		Int("comparedPools", len(poolIDs)).
+		Int("divergentPools", divergentCount).
+		Float64("maxDivergencePercent", config.VolumeCrossCheckMaxDivergencePercent).
+		Msg("Cross-checked pool volume against on-chain swaps")
+}
@@ -1,6 +1,7 @@
 package datafetcher
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -13,6 +14,7 @@ import (
 	"time"
 
 	"github.com/elys-network/avm/internal/config"
+	"github.com/elys-network/avm/internal/fixtures"
 	"github.com/elys-network/avm/internal/logger"
 )
 
@@ -40,12 +42,13 @@ type WeeklyVolumeData struct {
 type PoolVolumeMap map[uint64]map[string]uint64
 
 // GetWeeklyVolumeByPool fetches the weekly volume data for all pools with strict validation
-func GetWeeklyVolumeByPool() (PoolVolumeMap, error) {
+func GetWeeklyVolumeByPool(ctx context.Context) (PoolVolumeMap, error) {
 	volumeLogger.Info().Msg("Starting strict weekly volume data retrieval")
 
 	// Create HTTP client with timeout
 	client := &http.Client{
-		Timeout: VOLUME_TIMEOUT,
+		Timeout:   VOLUME_TIMEOUT,
+		Transport: fixtures.Transport(),
 	}
 
 	// Fetch data from the Elys Supply Stats API
@@ -54,7 +57,7 @@ func GetWeeklyVolumeByPool() (PoolVolumeMap, error) {
 		Dur("timeout", VOLUME_TIMEOUT).
 		Msg("Making API request for volume data")
 
-	resp, err := client.Get(config.SupplyAPI + VOLUME_API_ROUTE)
+	resp, err := fetchHTTP(ctx, client, config.SupplyAPI+VOLUME_API_ROUTE, nil)
 	if err != nil {
 		volumeLogger.Error().
 			Err(err).
@@ -262,12 +265,12 @@ func validateVolumeDataEntry(data WeeklyVolumeData) error {
 	}
 
 	// Date cannot be in the future (with small tolerance)
-	if parsedDate.After(time.Now().Add(24 * time.Hour)) {
+	if parsedDate.After(fixtures.Now().Add(24 * time.Hour)) {
 		return fmt.Errorf("date cannot be in the future: %s", data.Date)
 	}
 
 	// Date cannot be too old (more than 1 year)
-	if parsedDate.Before(time.Now().Add(-365 * 24 * time.Hour)) {
+	if parsedDate.Before(fixtures.Now().Add(-365 * 24 * time.Hour)) {
 		return fmt.Errorf("date is too old for reliable volume data: %s", data.Date)
 	}
 
@@ -0,0 +1,138 @@
+/*
+
+This package contains the in-process event bus of the AVM's cycles. RunCycle publishes each step
+transition, the action plan, the transactions and the cycle's outcome, and the web server streams
+them to the dashboard at /api/events.
+
+The bus keeps the events of the current cycle, or of the last one between cycles, so a subscriber
+that connects mid-cycle is replayed what it missed. Older events are not kept: the snapshots are
+the record of past cycles.
+
+*/
+
+package events
+
+import (
+	"sync"
+	"time"
+)
+
+// Type says what an event reports
+type Type string
+
+// Event types
+const (
+	TypeCycleStarted  Type = "cycle_started"  // A cycle started; replay starts here
+	TypeStepStarted   Type = "step_started"   // A step of the cycle started
+	TypeStepFinished  Type = "step_finished"  // A step of the cycle ended, completed or not
+	TypePlan          Type = "plan"           // The action plan of the cycle
+	TypeTransaction   Type = "transaction"    // A transaction of the plan was included
+	TypeCycleFinished Type = "cycle_finished" // The cycle ended, with its outcome
+)
+
+// Cycle steps, in the order they run. A cycle can end after any of them.
+const (
+	StepFetch    = "fetch"    // Pools, tokens and prices
+	StepAssess   = "assess"   // Vault positions and value, and the price integrity guard
+	StepScore    = "score"    // Pool scoring, selection and target allocations
+	StepPlan     = "plan"     // Withdrawals and deposits
+	StepWithdraw = "withdraw" // The withdrawal transaction
+	StepDeposit  = "deposit"  // The deposit transaction
+	StepFinalize = "finalize" // The final vault state, slippage and gas
+)
+
+// Step statuses, in the "status" of step_finished events
+const (
+	StepCompleted = "completed"
+	StepFailed    = "failed"
+)
+
+// subscriberBuffer is how many events a subscriber can fall behind before it is dropped
+const subscriberBuffer = 256
+
+// Event is one thing that happened in a cycle
+type Event struct {
+	ID          uint64                 `json:"id"` // Increases by one with each event of the process
+	Type        Type                   `json:"type"`
+	CycleID     string                 `json:"cycle_id"`
+	CycleNumber int                    `json:"cycle_number"`
+	Step        string                 `json:"step,omitempty"`
+	Timestamp   time.Time              `json:"timestamp"`
+	Data        map[string]interface{} `json:"data,omitempty"`
+}
+
+// Bus fans the cycle events out to subscribers. It is safe for concurrent use, and a nil *Bus
+// silently drops what is published.
+type Bus struct {
+	mu          sync.Mutex
+	lastID      uint64
+	cycle       []Event // Events of the current or last cycle
+	subscribers map[chan Event]struct{}
+}
+
+// NewBus creates an empty Bus
+func NewBus() *Bus {
+	return &Bus{subscribers: make(map[chan Event]struct{})}
+}
+
+// Publish assigns the event its ID and timestamp and sends it to every subscriber. It never blocks:
+// a subscriber whose buffer is full is dropped, its channel closed, and can subscribe again.
+func (b *Bus) Publish(event Event) {
+	if b == nil {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.lastID++
+	event.ID = b.lastID
+	event.Timestamp = time.Now().UTC()
+	if event.Type == TypeCycleStarted {
+		b.cycle = b.cycle[:0]
+	}
+	b.cycle = append(b.cycle, event)
+
+	for ch := range b.subscribers {
+		select {
+		case ch <- event:
+		default:
+			delete(b.subscribers, ch)
+			close(ch)
+		}
+	}
+}
+
+// Subscribe returns the events of the current or last cycle after afterID, the channel of the
+// events published from now on, and the function that ends the subscription. The channel is closed
+// when the subscription ends or the subscriber falls too far behind.
+func (b *Bus) Subscribe(afterID uint64) ([]Event, <-chan Event, func()) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	// An ID this bus has not assigned yet comes from before a restart
+	if afterID > b.lastID {
+		afterID = 0
+	}
+	replay := make([]Event, 0, len(b.cycle))
+	for _, event := range b.cycle {
+		if event.ID > afterID {
+			replay = append(replay, event)
+		}
+	}
+
+	ch := make(chan Event, subscriberBuffer)
+	b.subscribers[ch] = struct{}{}
+	var once sync.Once
+	cancel := func() {
+		once.Do(func() {
+			b.mu.Lock()
+			defer b.mu.Unlock()
+			if _, subscribed := b.subscribers[ch]; subscribed {
+				delete(b.subscribers, ch)
+				close(ch)
+			}
+		})
+	}
+	return replay, ch, cancel
+}
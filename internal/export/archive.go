@@ -0,0 +1,105 @@
+/*
+
+This file implements the cycle snapshot retention job. Snapshots older than the retention period
+are archived to files, one directory per UTC day, and then deleted from the database.
+
+Each day directory holds the flattened tables in the configured format and cycles.jsonl, the
+complete snapshots, so nothing is lost when the rows are pruned. A day is written to a
+temporary directory that is renamed into place, and its rows are only deleted afterwards; a job
+interrupted at any point archives the day again on its next run.
+
+*/
+
+package export
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/elys-network/avm/internal/state"
+	"github.com/rs/zerolog/log"
+)
+
+// FullSnapshotsFile is the name of the file holding a day's complete snapshots in an archive
+const FullSnapshotsFile = "cycles.jsonl"
+
+// ArchiveResult summarizes one run of ArchiveSnapshots
+type ArchiveResult struct {
+	Days        []string `json:"days"` // Archived UTC days, as YYYY-MM-DD
+	Snapshots   int      `json:"snapshots"`
+	Deleted     int64    `json:"deleted"`
+	Directories []string `json:"directories"`
+}
+
+// ArchiveSnapshots archives the cycle snapshots taken before the UTC day containing before into
+// dir, in the given format, and deletes them from the store. Only complete days are archived.
+func ArchiveSnapshots(store state.Store, dir string, format Format, before time.Time) (*ArchiveResult, error) {
+	cutoff := before.UTC().Truncate(24 * time.Hour)
+	result := &ArchiveResult{Days: make([]string, 0), Directories: make([]string, 0)}
+
+	for {
+		oldest, err := store.GetOldestCycleTimestamp()
+		if err != nil {
+			return result, err
+		}
+		if oldest == nil || !oldest.Before(cutoff) {
+			break
+		}
+
+		dayStart := oldest.UTC().Truncate(24 * time.Hour)
+		dayEnd := dayStart.Add(24 * time.Hour)
+		snapshots, err := store.GetCyclesInRange(dayStart, dayEnd)
+		if err != nil {
+			return result, err
+		}
+		if len(snapshots) == 0 {
+			// GetOldestCycleTimestamp found a row in this day, so it cannot be empty
+			return result, fmt.Errorf("no snapshots found in %s although the oldest snapshot is at %s",
+				dayStart.Format("2006-01-02"), oldest.Format(time.RFC3339))
+		}
+
+		day := dayStart.Format("2006-01-02")
+		dayDir := filepath.Join(dir, day)
+		if err := writeArchiveDay(dayDir, format, Flatten(snapshots), func(w io.Writer) error {
+			return WriteSnapshotsJSONL(w, snapshots)
+		}); err != nil {
+			return result, fmt.Errorf("failed to archive snapshots of %s: %w", day, err)
+		}
+
+		deleted, err := store.DeleteCyclesBefore(dayEnd)
+		if err != nil {
+			return result, err
+		}
+
+		result.Days = append(result.Days, day)
+		result.Snapshots += len(snapshots)
+		result.Deleted += deleted
+		result.Directories = append(result.Directories, dayDir)
+		log.Info().Str("day", day).Int("snapshots", len(snapshots)).Str("dir", dayDir).Msg("Archived cycle snapshots")
+	}
+
+	return result, nil
+}
+
+// writeArchiveDay writes a day's tables and complete snapshots to dayDir, replacing any earlier
+// attempt. Files are written to dayDir.partial first and renamed into place when complete.
+func writeArchiveDay(dayDir string, format Format, dataset *Dataset, writeFull func(w io.Writer) error) error {
+	partialDir := dayDir + ".partial"
+	if err := os.RemoveAll(partialDir); err != nil {
+		return err
+	}
+	if _, err := dataset.WriteFiles(partialDir, format); err != nil {
+		return err
+	}
+	if err := writeFile(filepath.Join(partialDir, FullSnapshotsFile), writeFull); err != nil {
+		return err
+	}
+
+	if err := os.RemoveAll(dayDir); err != nil {
+		return err
+	}
+	return os.Rename(partialDir, dayDir)
+}
@@ -0,0 +1,450 @@
+/*
+
+This file flattens cycle snapshots into tables for analysis outside the AVM and writes them as
+CSV, JSON Lines or Parquet. A snapshot becomes one row of the snapshots table, one row per
+initial and final position, one row per pool in its target allocations and one row per action
+receipt. Every row carries the snapshot's ID, cycle number and timestamp, so the tables can be
+joined.
+
+*/
+
+package export
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/elys-network/avm/internal/types"
+	"github.com/parquet-go/parquet-go"
+)
+
+// Format is the file format of an exported table
+type Format string
+
+const (
+	FormatCSV     Format = "csv"
+	FormatJSONL   Format = "jsonl"
+	FormatParquet Format = "parquet"
+)
+
+// ParseFormat returns the format with the given name
+func ParseFormat(name string) (Format, error) {
+	switch format := Format(strings.ToLower(strings.TrimSpace(name))); format {
+	case FormatCSV, FormatJSONL, FormatParquet:
+		return format, nil
+	default:
+		return "", fmt.Errorf("unsupported export format %q, must be %s, %s or %s", name, FormatCSV, FormatJSONL, FormatParquet)
+	}
+}
+
+// Extension returns the file extension of the format, without the dot
+func (f Format) Extension() string {
+	return string(f)
+}
+
+// ContentType returns the MIME type of the format
+func (f Format) ContentType() string {
+	switch f {
+	case FormatCSV:
+		return "text/csv"
+	case FormatJSONL:
+		return "application/x-ndjson"
+	default:
+		return "application/vnd.apache.parquet"
+	}
+}
+
+// Table is one of the flattened snapshot tables
+type Table string
+
+const (
+	TableSnapshots   Table = "snapshots"   // One row per cycle snapshot
+	TablePositions   Table = "positions"   // One row per initial and final position
+	TableAllocations Table = "allocations" // One row per pool with a target or final allocation
+	TableReceipts    Table = "receipts"    // One row per action receipt
+)
+
+// Tables lists every table, in the order they are written
+var Tables = []Table{TableSnapshots, TablePositions, TableAllocations, TableReceipts}
+
+// ParseTable returns the table with the given name
+func ParseTable(name string) (Table, error) {
+	table := Table(strings.ToLower(strings.TrimSpace(name)))
+	for _, known := range Tables {
+		if table == known {
+			return table, nil
+		}
+	}
+	return "", fmt.Errorf("unknown export table %q, must be one of %s, %s, %s or %s", name, TableSnapshots, TablePositions, TableAllocations, TableReceipts)
+}
+
+// Position stages in PositionRow.Stage
+const (
+	StageInitial = "initial"
+	StageFinal   = "final"
+)
+
+// SnapshotRow is the flattened summary of one cycle snapshot
+type SnapshotRow struct {
+	SnapshotID                  int64     `json:"snapshot_id" parquet:"snapshot_id"`
+	CycleNumber                 int       `json:"cycle_number" parquet:"cycle_number"`
+	Timestamp                   time.Time `json:"timestamp" parquet:"timestamp,timestamp(millisecond)"`
+	BlockHeight                 int64     `json:"block_height" parquet:"block_height"`
+	ScoringParamsID             *int64    `json:"scoring_params_id" parquet:"scoring_params_id,optional"`
+	InitialVaultValueUSD        float64   `json:"initial_vault_value_usd" parquet:"initial_vault_value_usd"`
+	InitialLiquidUSDC           float64   `json:"initial_liquid_usdc" parquet:"initial_liquid_usdc"`
+	FinalVaultValueUSD          float64   `json:"final_vault_value_usd" parquet:"final_vault_value_usd"`
+	FinalLiquidUSDC             float64   `json:"final_liquid_usdc" parquet:"final_liquid_usdc"`
+	NetReturnUSD                float64   `json:"net_return_usd" parquet:"net_return_usd"`
+	TotalSlippageUSD            float64   `json:"total_slippage_usd" parquet:"total_slippage_usd"`
+	TotalGasFeeUSD              float64   `json:"total_gas_fee_usd" parquet:"total_gas_fee_usd"`
+	AllocationEfficiencyPercent float64   `json:"allocation_efficiency_percent" parquet:"allocation_efficiency_percent"`
+	InitialPositionCount        int       `json:"initial_position_count" parquet:"initial_position_count"`
+	FinalPositionCount          int       `json:"final_position_count" parquet:"final_position_count"`
+	PlannedActionCount          int       `json:"planned_action_count" parquet:"planned_action_count"`
+	ExecutedActionCount         int       `json:"executed_action_count" parquet:"executed_action_count"`
+	FailedActionCount           int       `json:"failed_action_count" parquet:"failed_action_count"`
+	TransactionHashes           string    `json:"transaction_hashes" parquet:"transaction_hashes"` // Separated by ";"
+}
+
+// PositionRow is one LP position held at the start or end of a cycle
+type PositionRow struct {
+	SnapshotID        int64     `json:"snapshot_id" parquet:"snapshot_id"`
+	CycleNumber       int       `json:"cycle_number" parquet:"cycle_number"`
+	Timestamp         time.Time `json:"timestamp" parquet:"timestamp,timestamp(millisecond)"`
+	Stage             string    `json:"stage" parquet:"stage"` // StageInitial or StageFinal
+	PoolID            uint64    `json:"pool_id" parquet:"pool_id"`
+	PoolAssets        string    `json:"pool_assets" parquet:"pool_assets"` // Separated by "/"
+	LPShares          string    `json:"lp_shares" parquet:"lp_shares"`
+	EstimatedValueUSD float64   `json:"estimated_value_usd" parquet:"estimated_value_usd"`
+	AllocationPercent float64   `json:"allocation_percent" parquet:"allocation_percent"`
+	AgeDays           int       `json:"age_days" parquet:"age_days"`
+	PoolTVL           float64   `json:"pool_tvl" parquet:"pool_tvl"`
+	PoolScore         float64   `json:"pool_score" parquet:"pool_score"`
+}
+
+// AllocationRow compares a pool's target allocation with its allocation at the end of the cycle
+type AllocationRow struct {
+	SnapshotID     int64     `json:"snapshot_id" parquet:"snapshot_id"`
+	CycleNumber    int       `json:"cycle_number" parquet:"cycle_number"`
+	Timestamp      time.Time `json:"timestamp" parquet:"timestamp,timestamp(millisecond)"`
+	PoolID         uint64    `json:"pool_id" parquet:"pool_id"`
+	TargetFraction float64   `json:"target_fraction" parquet:"target_fraction"` // Fraction of the vault the analyzer targeted
+	FinalPercent   float64   `json:"final_percent" parquet:"final_percent"`     // Allocation percent of the final position, 0 if none
+}
+
+// ReceiptRow is the outcome of one executed sub-action
+type ReceiptRow struct {
+	SnapshotID       int64     `json:"snapshot_id" parquet:"snapshot_id"`
+	CycleNumber      int       `json:"cycle_number" parquet:"cycle_number"`
+	Timestamp        time.Time `json:"timestamp" parquet:"timestamp,timestamp(millisecond)"`
+	ReceiptIndex     int       `json:"receipt_index" parquet:"receipt_index"` // Position in the cycle's receipts
+	ExecutedAt       time.Time `json:"executed_at" parquet:"executed_at,timestamp(millisecond)"`
+	ActionType       string    `json:"action_type" parquet:"action_type"`
+	PoolID           uint64    `json:"pool_id" parquet:"pool_id"` // Pool deposited to, withdrawn from or swapped in; 0 if any
+	Success          bool      `json:"success" parquet:"success"`
+	Message          string    `json:"message" parquet:"message"`
+	TokenIn          string    `json:"token_in" parquet:"token_in"`
+	TokenOutDenom    string    `json:"token_out_denom" parquet:"token_out_denom"`
+	ExpectedSlippage float64   `json:"expected_slippage" parquet:"expected_slippage"`
+	ActualAmountUSD  float64   `json:"actual_amount_usd" parquet:"actual_amount_usd"`
+	LPSharesChanged  string    `json:"lp_shares_changed" parquet:"lp_shares_changed"`
+	ResultingCoins   string    `json:"resulting_coins" parquet:"resulting_coins"` // Separated by ","
+}
+
+// Dataset holds the flattened tables of a set of snapshots
+type Dataset struct {
+	Snapshots   []SnapshotRow
+	Positions   []PositionRow
+	Allocations []AllocationRow
+	Receipts    []ReceiptRow
+}
+
+// Flatten turns cycle snapshots into the rows of every table, keeping the snapshots' order
+func Flatten(snapshots []types.CycleSnapshot) *Dataset {
+	dataset := &Dataset{
+		Snapshots:   make([]SnapshotRow, 0, len(snapshots)),
+		Positions:   make([]PositionRow, 0),
+		Allocations: make([]AllocationRow, 0),
+		Receipts:    make([]ReceiptRow, 0),
+	}
+
+	for _, snapshot := range snapshots {
+		timestamp := snapshot.Timestamp.UTC()
+
+		failed := 0
+		for _, receipt := range snapshot.ActionReceipts {
+			if !receipt.Success {
+				failed++
+			}
+		}
+		dataset.Snapshots = append(dataset.Snapshots, SnapshotRow{
+			SnapshotID:                  snapshot.SnapshotID,
+			CycleNumber:                 snapshot.CycleNumber,
+			Timestamp:                   timestamp,
+			BlockHeight:                 snapshot.BlockHeight,
+			ScoringParamsID:             snapshot.ScoringParamsID,
+			InitialVaultValueUSD:        snapshot.InitialVaultValueUSD,
+			InitialLiquidUSDC:           snapshot.InitialLiquidUSDC,
+			FinalVaultValueUSD:          snapshot.FinalVaultValueUSD,
+			FinalLiquidUSDC:             snapshot.FinalLiquidUSDC,
+			NetReturnUSD:                snapshot.NetReturnUSD,
+			TotalSlippageUSD:            snapshot.TotalSlippageUSD,
+			TotalGasFeeUSD:              snapshot.TotalGasFeeUSD,
+			AllocationEfficiencyPercent: snapshot.AllocationEfficiencyPercent,
+			InitialPositionCount:        len(snapshot.InitialPositions),
+			FinalPositionCount:          len(snapshot.FinalPositions),
+			PlannedActionCount:          len(snapshot.ActionPlan.SubActions),
+			ExecutedActionCount:         len(snapshot.ActionReceipts),
+			FailedActionCount:           failed,
+			TransactionHashes:           strings.Join(snapshot.TransactionHashes, ";"),
+		})
+
+		stages := []struct {
+			name      string
+			positions []types.PositionSnapshot
+		}{{StageInitial, snapshot.InitialPositions}, {StageFinal, snapshot.FinalPositions}}
+		for _, stage := range stages {
+			for _, position := range stage.positions {
+				dataset.Positions = append(dataset.Positions, PositionRow{
+					SnapshotID:        snapshot.SnapshotID,
+					CycleNumber:       snapshot.CycleNumber,
+					Timestamp:         timestamp,
+					Stage:             stage.name,
+					PoolID:            uint64(position.PoolID),
+					PoolAssets:        strings.Join(position.PoolAssets, "/"),
+					LPShares:          position.LPShares,
+					EstimatedValueUSD: position.EstimatedValueUSD,
+					AllocationPercent: position.AllocationPercent,
+					AgeDays:           position.AgeDays,
+					PoolTVL:           position.PoolTVL,
+					PoolScore:         position.PoolScore,
+				})
+			}
+		}
+
+		dataset.Allocations = append(dataset.Allocations, flattenAllocations(snapshot, timestamp)...)
+
+		for i, receipt := range snapshot.ActionReceipts {
+			dataset.Receipts = append(dataset.Receipts, flattenReceipt(snapshot, timestamp, i, receipt))
+		}
+	}
+
+	return dataset
+}
+
+// flattenAllocations returns one row for every pool with a target allocation or a final position, by pool ID
+func flattenAllocations(snapshot types.CycleSnapshot, timestamp time.Time) []AllocationRow {
+	rows := make(map[types.PoolID]*AllocationRow)
+	row := func(poolID types.PoolID) *AllocationRow {
+		if existing, ok := rows[poolID]; ok {
+			return existing
+		}
+		created := &AllocationRow{SnapshotID: snapshot.SnapshotID, CycleNumber: snapshot.CycleNumber, Timestamp: timestamp, PoolID: uint64(poolID)}
+		rows[poolID] = created
+		return created
+	}
+	for poolID, fraction := range snapshot.TargetAllocations {
+		row(poolID).TargetFraction = fraction
+	}
+	for _, position := range snapshot.FinalPositions {
+		row(position.PoolID).FinalPercent += position.AllocationPercent
+	}
+
+	flattened := make([]AllocationRow, 0, len(rows))
+	for _, r := range rows {
+		flattened = append(flattened, *r)
+	}
+	sort.Slice(flattened, func(i, j int) bool {
+		return flattened[i].PoolID < flattened[j].PoolID
+	})
+	return flattened
+}
+
+// flattenReceipt returns the row of one action receipt
+func flattenReceipt(snapshot types.CycleSnapshot, timestamp time.Time, index int, receipt types.ActionReceipt) ReceiptRow {
+	action := receipt.OriginalSubAction
+	row := ReceiptRow{
+		SnapshotID:       snapshot.SnapshotID,
+		CycleNumber:      snapshot.CycleNumber,
+		Timestamp:        timestamp,
+		ReceiptIndex:     index,
+		ExecutedAt:       receipt.Timestamp.UTC(),
+		ActionType:       string(action.Type),
+		PoolID:           uint64(action.PoolID()),
+		Success:          receipt.Success,
+		Message:          receipt.Message,
+		TokenOutDenom:    action.TokenOutDenom,
+		ExpectedSlippage: action.ExpectedSlippage,
+		ActualAmountUSD:  receipt.ActualAmountUSD,
+	}
+	if action.TokenIn.Denom != "" {
+		row.TokenIn = action.TokenIn.String()
+	}
+	if !receipt.LPSharesChanged.IsNil() {
+		row.LPSharesChanged = receipt.LPSharesChanged.String()
+	}
+	coins := make([]string, len(receipt.ResultingCoins))
+	for i, coin := range receipt.ResultingCoins {
+		coins[i] = coin.String()
+	}
+	row.ResultingCoins = strings.Join(coins, ",")
+
+	return row
+}
+
+// WriteTable writes one table of the dataset to w in the given format
+func (d *Dataset) WriteTable(w io.Writer, table Table, format Format) error {
+	switch table {
+	case TableSnapshots:
+		return writeRows(w, d.Snapshots, format)
+	case TablePositions:
+		return writeRows(w, d.Positions, format)
+	case TableAllocations:
+		return writeRows(w, d.Allocations, format)
+	case TableReceipts:
+		return writeRows(w, d.Receipts, format)
+	default:
+		return fmt.Errorf("unknown export table %q", table)
+	}
+}
+
+// WriteFiles writes every table of the dataset into dir as <table>.<extension>, creating dir if
+// needed, and returns the paths written.
+func (d *Dataset) WriteFiles(dir string, format Format) ([]string, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create export directory: %w", err)
+	}
+
+	paths := make([]string, 0, len(Tables))
+	for _, table := range Tables {
+		path := filepath.Join(dir, string(table)+"."+format.Extension())
+		if err := writeFile(path, func(w io.Writer) error { return d.WriteTable(w, table, format) }); err != nil {
+			return paths, fmt.Errorf("failed to write %s: %w", path, err)
+		}
+		paths = append(paths, path)
+	}
+	return paths, nil
+}
+
+// WriteSnapshotsJSONL writes the complete snapshots, one JSON object per line. Unlike the
+// flattened tables this keeps every field, including the action plan and risk reports.
+func WriteSnapshotsJSONL(w io.Writer, snapshots []types.CycleSnapshot) error {
+	encoder := json.NewEncoder(w)
+	for _, snapshot := range snapshots {
+		if err := encoder.Encode(snapshot); err != nil {
+			return fmt.Errorf("failed to encode snapshot %d: %w", snapshot.SnapshotID, err)
+		}
+	}
+	return nil
+}
+
+// writeFile creates path and writes it with write, syncing it to disk before closing
+func writeFile(path string, write func(w io.Writer) error) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := write(file); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Sync(); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// writeRows writes rows in the given format. Column names are the rows' json tags.
+func writeRows[T any](w io.Writer, rows []T, format Format) error {
+	switch format {
+	case FormatCSV:
+		return writeCSV(w, rows)
+	case FormatJSONL:
+		encoder := json.NewEncoder(w)
+		for _, row := range rows {
+			if err := encoder.Encode(row); err != nil {
+				return fmt.Errorf("failed to encode row: %w", err)
+			}
+		}
+		return nil
+	case FormatParquet:
+		writer := parquet.NewGenericWriter[T](w)
+		if _, err := writer.Write(rows); err != nil {
+			return fmt.Errorf("failed to write parquet rows: %w", err)
+		}
+		if err := writer.Close(); err != nil {
+			return fmt.Errorf("failed to finish parquet file: %w", err)
+		}
+		return nil
+	default:
+		return fmt.Errorf("unsupported export format %q", format)
+	}
+}
+
+// writeCSV writes a header of the rows' json tags followed by one record per row
+func writeCSV[T any](w io.Writer, rows []T) error {
+	rowType := reflect.TypeOf((*T)(nil)).Elem()
+	header := make([]string, rowType.NumField())
+	for i := range header {
+		header[i] = strings.Split(rowType.Field(i).Tag.Get("json"), ",")[0]
+	}
+
+	writer := csv.NewWriter(w)
+	if err := writer.Write(header); err != nil {
+		return err
+	}
+	record := make([]string, len(header))
+	for _, row := range rows {
+		value := reflect.ValueOf(row)
+		for i := range record {
+			record[i] = csvValue(value.Field(i))
+		}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// csvValue formats a row field for CSV; nil pointers and zero times are empty
+func csvValue(value reflect.Value) string {
+	if value.Kind() == reflect.Pointer {
+		if value.IsNil() {
+			return ""
+		}
+		value = value.Elem()
+	}
+	if t, ok := value.Interface().(time.Time); ok {
+		if t.IsZero() {
+			return ""
+		}
+		return t.UTC().Format(time.RFC3339Nano)
+	}
+
+	switch value.Kind() {
+	case reflect.String:
+		return value.String()
+	case reflect.Bool:
+		return strconv.FormatBool(value.Bool())
+	case reflect.Int, reflect.Int64:
+		return strconv.FormatInt(value.Int(), 10)
+	case reflect.Uint64:
+		return strconv.FormatUint(value.Uint(), 10)
+	case reflect.Float64:
+		return strconv.FormatFloat(value.Float(), 'f', -1, 64)
+	default:
+		return fmt.Sprint(value.Interface())
+	}
+}
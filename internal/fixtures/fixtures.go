@@ -0,0 +1,213 @@
+/*
+This file contains the fixture bundle and the process-wide recorder/replayer.
+
+In record mode every gRPC, ABCI and HTTP response is appended to the bundle, which Save writes
+to FIXTURES_FILE. In replay mode the bundle is loaded at startup and each request is answered
+with the next recorded response for the same request; once those run out the last one is
+repeated. A request that was never recorded fails with ErrFixtureNotFound.
+*/
+
+package fixtures
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/elys-network/avm/internal/config"
+	"github.com/elys-network/avm/internal/logger"
+)
+
+var fixturesLogger = logger.GetForComponent("fixtures")
+
+// ErrFixtureNotFound is returned in replay mode for a request that is not in the bundle
+var ErrFixtureNotFound = errors.New("no recorded response for request")
+
+const (
+	BUNDLE_VERSION = 1
+
+	// Kinds of recorded interaction
+	KindGRPC = "grpc"
+	KindABCI = "abci"
+	KindRPC  = "rpc"
+	KindHTTP = "http"
+)
+
+// Interaction is one recorded request and its response
+type Interaction struct {
+	Kind       string `json:"kind"`
+	Key        string `json:"key"`                   // Identifies the request; replay matches on Kind and Key
+	Request    string `json:"request"`               // Human-readable description (method, path or redacted URL)
+	Response   []byte `json:"response,omitempty"`    // Raw protobuf (gRPC) or HTTP body
+	StatusCode int    `json:"status_code,omitempty"` // HTTP status code
+	GRPCCode   uint32 `json:"grpc_code,omitempty"`   // gRPC status code of a failed call
+	Error      string `json:"error,omitempty"`       // Error returned instead of a response
+}
+
+// Bundle is the on-disk fixture file
+type Bundle struct {
+	Version      int           `json:"version"`
+	RecordedAt   time.Time     `json:"recorded_at"`
+	ChainID      string        `json:"chain_id"`
+	Interactions []Interaction `json:"interactions"`
+}
+
+type interactionKey struct {
+	kind string
+	key  string
+}
+
+var (
+	mu        sync.Mutex
+	mode      = config.FixturesModeOff
+	bundle    *Bundle
+	replayPos map[interactionKey]int
+	replayed  map[interactionKey][]Interaction
+	startedAt time.Time
+)
+
+// Start enables recording or replaying for the rest of the process.
+// It must be called before any gRPC connection or HTTP client is created.
+func Start(fixturesMode, path string) error {
+	mu.Lock()
+	defer mu.Unlock()
+
+	switch fixturesMode {
+	case config.FixturesModeOff:
+		mode = fixturesMode
+		return nil
+
+	case config.FixturesModeRecord:
+		bundle = &Bundle{
+			Version:      BUNDLE_VERSION,
+			RecordedAt:   time.Now().UTC(),
+			ChainID:      config.ChainID,
+			Interactions: make([]Interaction, 0),
+		}
+
+	case config.FixturesModeReplay:
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("failed to read fixture bundle %s: %w", path, err)
+		}
+		var loaded Bundle
+		if err := json.Unmarshal(data, &loaded); err != nil {
+			return fmt.Errorf("failed to parse fixture bundle %s: %w", path, err)
+		}
+		if loaded.Version != BUNDLE_VERSION {
+			return fmt.Errorf("fixture bundle %s has version %d, expected %d", path, loaded.Version, BUNDLE_VERSION)
+		}
+		if loaded.ChainID != config.ChainID {
+			fixturesLogger.Warn().
+				Str("bundleChainID", loaded.ChainID).
+				Str("chainID", config.ChainID).
+				Msg("Fixture bundle was recorded on a different chain")
+		}
+
+		bundle = &loaded
+		replayPos = make(map[interactionKey]int)
+		replayed = make(map[interactionKey][]Interaction)
+		for _, interaction := range loaded.Interactions {
+			key := interactionKey{kind: interaction.Kind, key: interaction.Key}
+			replayed[key] = append(replayed[key], interaction)
+		}
+
+	default:
+		return fmt.Errorf("unknown fixtures mode %q", fixturesMode)
+	}
+
+	mode = fixturesMode
+	startedAt = time.Now()
+
+	fixturesLogger.Info().
+		Str("mode", mode).
+		Str("file", path).
+		Int("interactions", len(bundle.Interactions)).
+		Time("recordedAt", bundle.RecordedAt).
+		Msg("Fixtures enabled")
+	return nil
+}
+
+// Enabled reports whether external I/O is being recorded or replayed
+func Enabled() bool {
+	mu.Lock()
+	defer mu.Unlock()
+	return mode != config.FixturesModeOff
+}
+
+// Replaying reports whether responses are served from the bundle
+func Replaying() bool {
+	mu.Lock()
+	defer mu.Unlock()
+	return mode == config.FixturesModeReplay
+}
+
+// Now returns the current time. When replaying it returns the recording's start time plus the
+// time elapsed since replay started, so time windows resolve to the same requests as when recorded.
+func Now() time.Time {
+	mu.Lock()
+	defer mu.Unlock()
+	if mode == config.FixturesModeReplay {
+		return bundle.RecordedAt.Add(time.Since(startedAt))
+	}
+	return time.Now()
+}
+
+// Save writes the recorded bundle to path. It does nothing unless recording.
+func Save(path string) error {
+	mu.Lock()
+	defer mu.Unlock()
+	if mode != config.FixturesModeRecord {
+		return nil
+	}
+
+	data, err := json.MarshalIndent(bundle, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal fixture bundle: %w", err)
+	}
+	if dir := filepath.Dir(path); dir != "" {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return fmt.Errorf("failed to create fixture directory %s: %w", dir, err)
+		}
+	}
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write fixture bundle %s: %w", path, err)
+	}
+
+	fixturesLogger.Info().
+		Str("file", path).
+		Int("interactions", len(bundle.Interactions)).
+		Msg("Saved fixture bundle")
+	return nil
+}
+
+// record appends an interaction to the bundle
+func record(interaction Interaction) {
+	mu.Lock()
+	defer mu.Unlock()
+	bundle.Interactions = append(bundle.Interactions, interaction)
+}
+
+// lookup returns the next recorded response for a request
+func lookup(kind, key string) (Interaction, error) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	k := interactionKey{kind: kind, key: key}
+	interactions := replayed[k]
+	if len(interactions) == 0 {
+		fixturesLogger.Error().Str("kind", kind).Str("key", key).Msg("Request not found in fixture bundle")
+		return Interaction{}, fmt.Errorf("%w: %s %s", ErrFixtureNotFound, kind, key)
+	}
+
+	pos := replayPos[k]
+	if pos >= len(interactions) {
+		pos = len(interactions) - 1
+	}
+	replayPos[k] = pos + 1
+	return interactions[pos], nil
+}
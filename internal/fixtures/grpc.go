@@ -0,0 +1,94 @@
+/*
+This file records and replays unary gRPC calls through a client interceptor.
+Requests are identified by their full method name, a hash of the encoded request and the
+block height the query is pinned to, if any.
+*/
+
+package fixtures
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+
+	grpctypes "github.com/cosmos/cosmos-sdk/types/grpc"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// protoMessage is implemented by the gogoproto-generated Elys and Cosmos SDK messages
+type protoMessage interface {
+	Marshal() ([]byte, error)
+	Unmarshal([]byte) error
+}
+
+// GRPCDialOption returns the dial option that records or replays calls on a gRPC connection.
+// When fixtures are off it returns an option that does nothing.
+func GRPCDialOption() grpc.DialOption {
+	if !Enabled() {
+		return grpc.EmptyDialOption{}
+	}
+	return grpc.WithChainUnaryInterceptor(unaryInterceptor)
+}
+
+// unaryInterceptor records the response of each call, or serves it from the bundle when replaying
+func unaryInterceptor(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	request, ok := req.(protoMessage)
+	if !ok {
+		return fmt.Errorf("fixtures: cannot encode request of type %T for %s", req, method)
+	}
+	response, ok := reply.(protoMessage)
+	if !ok {
+		return fmt.Errorf("fixtures: cannot encode response of type %T for %s", reply, method)
+	}
+
+	requestBytes, err := request.Marshal()
+	if err != nil {
+		return fmt.Errorf("fixtures: failed to encode request for %s: %w", method, err)
+	}
+	hash := sha256.Sum256(requestBytes)
+	key := method + ":" + hex.EncodeToString(hash[:])
+	if md, ok := metadata.FromOutgoingContext(ctx); ok {
+		if heights := md.Get(grpctypes.GRPCBlockHeightHeader); len(heights) > 0 {
+			key += "@" + heights[len(heights)-1]
+		}
+	}
+
+	if Replaying() {
+		if err := ctx.Err(); err != nil {
+			return status.FromContextError(err).Err()
+		}
+		interaction, err := lookup(KindGRPC, key)
+		if err != nil {
+			return status.Error(codes.NotFound, err.Error())
+		}
+		if interaction.Error != "" {
+			return status.Error(codes.Code(interaction.GRPCCode), interaction.Error)
+		}
+		if err := response.Unmarshal(interaction.Response); err != nil {
+			return fmt.Errorf("fixtures: failed to decode recorded response for %s: %w", method, err)
+		}
+		return nil
+	}
+
+	// The interceptor is only installed while recording or replaying
+	callErr := invoker(ctx, method, req, reply, cc, opts...)
+
+	interaction := Interaction{Kind: KindGRPC, Key: key, Request: method}
+	if callErr != nil {
+		callStatus := status.Convert(callErr)
+		interaction.GRPCCode = uint32(callStatus.Code())
+		interaction.Error = callStatus.Message()
+	} else {
+		interaction.Response, err = response.Marshal()
+		if err != nil {
+			return fmt.Errorf("fixtures: failed to encode response for %s: %w", method, err)
+		}
+	}
+	record(interaction)
+
+	return callErr
+}
@@ -0,0 +1,209 @@
+/*
+This file records and replays HTTP requests through an http.RoundTripper.
+
+JSON-RPC requests to the node are matched on their method and parameters rather than the raw
+body, so the request ID does not matter; ABCI queries are matched on their path and data.
+Other requests are matched on method, URL and body. API keys in query parameters are redacted
+before a URL is written to the bundle.
+*/
+
+package fixtures
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Query parameters that carry API keys; their values are never written to a bundle
+var redactedParams = map[string]bool{
+	"api_key":           true,
+	"apikey":            true,
+	"x_cg_demo_api_key": true,
+	"x_cg_pro_api_key":  true,
+}
+
+type transport struct {
+	next http.RoundTripper
+}
+
+// Transport returns the round tripper that records or replays HTTP requests.
+// When fixtures are off it returns http.DefaultTransport.
+func Transport() http.RoundTripper {
+	if !Enabled() {
+		return http.DefaultTransport
+	}
+	return &transport{next: http.DefaultTransport}
+}
+
+// RoundTrip records the response to req, or serves it from the bundle when replaying
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	body, err := readRequestBody(req)
+	if err != nil {
+		return nil, fmt.Errorf("fixtures: failed to read request body: %w", err)
+	}
+	kind, key, description, rpcID := classifyRequest(req, body)
+
+	if Replaying() {
+		if err := req.Context().Err(); err != nil {
+			return nil, err
+		}
+		interaction, err := lookup(kind, key)
+		if err != nil {
+			return nil, err
+		}
+		if interaction.Error != "" {
+			return nil, errors.New(interaction.Error)
+		}
+
+		responseBody := interaction.Response
+		if rpcID != nil {
+			responseBody = withRPCID(responseBody, rpcID)
+		}
+		return &http.Response{
+			StatusCode:    interaction.StatusCode,
+			Status:        fmt.Sprintf("%d %s", interaction.StatusCode, http.StatusText(interaction.StatusCode)),
+			Proto:         "HTTP/1.1",
+			ProtoMajor:    1,
+			ProtoMinor:    1,
+			Header:        http.Header{"Content-Type": []string{"application/json"}},
+			Body:          io.NopCloser(bytes.NewReader(responseBody)),
+			ContentLength: int64(len(responseBody)),
+			Request:       req,
+		}, nil
+	}
+
+	interaction := Interaction{Kind: kind, Key: key, Request: description}
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		interaction.Error = err.Error()
+		record(interaction)
+		return nil, err
+	}
+
+	responseBody, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, fmt.Errorf("fixtures: failed to read response body: %w", err)
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(responseBody))
+
+	interaction.StatusCode = resp.StatusCode
+	interaction.Response = responseBody
+	record(interaction)
+
+	return resp, nil
+}
+
+// readRequestBody returns the request body, leaving req readable for the real transport
+func readRequestBody(req *http.Request) ([]byte, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil, nil
+	}
+	if req.GetBody != nil {
+		bodyCopy, err := req.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		defer bodyCopy.Close()
+		return io.ReadAll(bodyCopy)
+	}
+
+	body, err := io.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	req.Body = io.NopCloser(bytes.NewReader(body))
+	return body, nil
+}
+
+// classifyRequest returns the kind and matching key of a request, a description for the bundle,
+// and the JSON-RPC request ID if it is a JSON-RPC call
+func classifyRequest(req *http.Request, body []byte) (string, string, string, json.RawMessage) {
+	redactedURL := redactURL(req.URL)
+
+	var rpcRequest struct {
+		JSONRPC string          `json:"jsonrpc"`
+		ID      json.RawMessage `json:"id"`
+		Method  string          `json:"method"`
+		Params  json.RawMessage `json:"params"`
+	}
+	if req.Method == http.MethodPost && json.Unmarshal(body, &rpcRequest) == nil && rpcRequest.JSONRPC != "" && rpcRequest.Method != "" {
+		if rpcRequest.Method == "abci_query" {
+			var params struct {
+				Path   string `json:"path"`
+				Data   string `json:"data"`
+				Height string `json:"height"`
+			}
+			if json.Unmarshal(rpcRequest.Params, &params) == nil {
+				key := params.Path + ":" + hashHex([]byte(strings.ToLower(params.Data)))
+				if params.Height != "" && params.Height != "0" {
+					key += "@" + params.Height
+				}
+				return KindABCI, key, params.Path, rpcRequest.ID
+			}
+		}
+		return KindRPC, rpcRequest.Method + ":" + hashHex(rpcRequest.Params), rpcRequest.Method, rpcRequest.ID
+	}
+
+	key := req.Method + " " + redactedURL
+	if len(body) > 0 {
+		key += " " + hashHex(body)
+	}
+	return KindHTTP, key, req.Method + " " + redactedURL, nil
+}
+
+// RedactURL returns the raw URL with API key parameters replaced, for logging.
+// Returns an empty string if the URL cannot be parsed.
+func RedactURL(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	return redactURL(u)
+}
+
+// redactURL returns the URL with API key parameters replaced and query parameters sorted
+func redactURL(u *url.URL) string {
+	redacted := *u
+	query := redacted.Query()
+	for name := range query {
+		if redactedParams[strings.ToLower(name)] {
+			query.Set(name, "REDACTED")
+		}
+	}
+	redacted.RawQuery = query.Encode()
+	return redacted.String()
+}
+
+// withRPCID returns a recorded JSON-RPC response carrying the ID of the replayed request,
+// since JSON-RPC clients reject responses whose ID does not match their request
+func withRPCID(responseBody []byte, rpcID json.RawMessage) []byte {
+	var response map[string]json.RawMessage
+	if err := json.Unmarshal(responseBody, &response); err != nil {
+		return responseBody
+	}
+	if bytes.Equal(response["id"], rpcID) {
+		return responseBody
+	}
+
+	response["id"] = rpcID
+	rewritten, err := json.Marshal(response)
+	if err != nil {
+		return responseBody
+	}
+	return rewritten
+}
+
+func hashHex(data []byte) string {
+	hash := sha256.Sum256(data)
+	return hex.EncodeToString(hash[:])
+}
@@ -0,0 +1,239 @@
+/*
+
+This package defines the AVM's Prometheus metrics, served by the web server at /metrics.
+
+The metrics live in their own registry, with the Go runtime and process collectors, so only what is
+defined here is exported. Packages record through the functions below rather than the collectors,
+which keeps the metric names and labels in one place.
+
+*/
+
+package metrics
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/elys-network/avm/internal/types"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "avm"
+
+// Cycle outcomes, the values of the avm_cycles_total "outcome" label
+const (
+	CycleOutcomeExecuted         = "executed"          // The plan was executed
+	CycleOutcomeNoActions        = "no_actions"        // The vault was already at its targets
+	CycleOutcomeNoPoolsSelected  = "no_pools_selected" // No pool qualified for investment
+	CycleOutcomeHalted           = "halted"            // The price integrity guard halted execution
+	CycleOutcomeExecutionSkipped = "execution_skipped" // Paused or emergency stopped before execution
+	CycleOutcomeExecutionFailed  = "execution_failed"  // A transaction failed
+	CycleOutcomeAborted          = "aborted"           // An error ended the cycle before execution
+	CycleOutcomeEmergencyStopped = "emergency_stopped" // The cycle did not start because of an emergency stop
+)
+
+// Simulation kinds, the values of the avm_simulation_duration_seconds "kind" label
+const (
+	SimulationSwap     = "swap"
+	SimulationJoinPool = "join_pool"
+	SimulationExitPool = "exit_pool"
+	SimulationGas      = "gas"
+)
+
+// Datafetcher sources, the values of the avm_datafetcher_errors_total "source" label. Price
+// history errors use the provider's name.
+const (
+	SourceAMM             = "amm"
+	SourceMasterchef      = "masterchef"
+	SourceAssetProfile    = "assetprofile"
+	SourceOracle          = "oracle"
+	SourceSpotPrice       = "spot_price"
+	SourceSupplyAPI       = "supply_api"
+	SourceSwapVolumeIndex = "swap_volume_index"
+	SourceNodeRPC         = "node_rpc"
+)
+
+// Transaction results, the values of the avm_transactions_total "result" label
+const (
+	TxResultIncluded        = "included"         // Found in a block
+	TxResultBroadcastFailed = "broadcast_failed" // Not accepted by the node
+	TxResultNotIncluded     = "not_included"     // Broadcast, but not found in a block in time
+)
+
+var registry = prometheus.NewRegistry()
+
+var (
+	cycleDuration = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "cycle_duration_seconds",
+		Help:      "Duration of AVM cycles.",
+		Buckets:   []float64{5, 10, 20, 30, 60, 90, 120, 180, 300, 600},
+	})
+	cycleStepDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "cycle_step_duration_seconds",
+		Help:      "Duration of each step of the AVM cycles, as in the snapshots' step timings.",
+		Buckets:   []float64{0.1, 0.25, 0.5, 1, 2.5, 5, 10, 20, 30, 60, 120},
+	}, []string{"step"})
+	cyclesTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "cycles_total",
+		Help:      "AVM cycles by outcome.",
+	}, []string{"outcome"})
+
+	vaultNAVPerShare = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "vault_nav_per_share",
+		Help:      "Vault NAV per share in USD at the start of the latest cycle.",
+	})
+	vaultValueUSD = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "vault_value_usd",
+		Help:      "Total vault value in USD at the end of the latest cycle.",
+	})
+	vaultLiquidUSDC = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "vault_liquid_usdc",
+		Help:      "Liquid USDC of the vault at the end of the latest cycle.",
+	})
+	poolAllocation = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "pool_allocation_ratio",
+		Help:      "Share of the vault value held in each pool at the end of the latest cycle.",
+	}, []string{"pool_id"})
+	poolTargetAllocation = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "pool_target_allocation_ratio",
+		Help:      "Target share of the vault value of each pool in the latest cycle.",
+	}, []string{"pool_id"})
+	slippageUSD = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "realized_slippage_usd_total",
+		Help:      "Vault value lost to slippage by executed cycles, in USD.",
+	})
+	gasFeesUSD = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "gas_fees_usd_total",
+		Help:      "Gas fees paid by executed cycles, in USD.",
+	})
+
+	simulationDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "simulation_duration_seconds",
+		Help:      "Latency of swap, join, exit and gas simulations; the count is the number of calls.",
+		Buckets:   []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 20},
+	}, []string{"kind", "result"})
+	datafetcherErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "datafetcher_errors_total",
+		Help:      "Failed data fetches by source.",
+	}, []string{"source"})
+
+	txInclusion = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "tx_inclusion_seconds",
+		Help:      "Time from broadcasting a transaction to finding it in a block.",
+		Buckets:   []float64{2, 5, 10, 15, 20, 30, 45, 60, 120, 300},
+	})
+	transactionsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "transactions_total",
+		Help:      "Transactions sent by the AVM by result.",
+	}, []string{"result"})
+)
+
+func init() {
+	registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		cycleDuration, cycleStepDuration, cyclesTotal,
+		vaultNAVPerShare, vaultValueUSD, vaultLiquidUSDC, poolAllocation, poolTargetAllocation, slippageUSD, gasFeesUSD,
+		simulationDuration, datafetcherErrors,
+		txInclusion, transactionsTotal,
+	)
+}
+
+// Handler serves the metrics in the Prometheus exposition format
+func Handler() http.Handler {
+	return promhttp.HandlerFor(registry, promhttp.HandlerOpts{})
+}
+
+// ObserveCycle records a finished cycle's outcome and duration
+func ObserveCycle(outcome string, duration time.Duration) {
+	cyclesTotal.WithLabelValues(outcome).Inc()
+	if outcome != CycleOutcomeEmergencyStopped {
+		cycleDuration.Observe(duration.Seconds())
+	}
+}
+
+// RecordCycleSnapshot records the step timings, final vault state, allocations, slippage and gas
+// of a cycle's snapshot. The allocation gauges only hold the pools of the latest cycle.
+func RecordCycleSnapshot(snapshot types.CycleSnapshot) {
+	for _, timing := range snapshot.StepTimings {
+		cycleStepDuration.WithLabelValues(timing.Step).Observe(timing.DurationMs / 1000)
+	}
+
+	vaultValueUSD.Set(snapshot.FinalVaultValueUSD)
+	vaultLiquidUSDC.Set(snapshot.FinalLiquidUSDC)
+
+	poolAllocation.Reset()
+	for _, position := range snapshot.FinalPositions {
+		poolAllocation.WithLabelValues(poolLabel(position.PoolID)).Set(position.AllocationPercent / 100)
+	}
+	poolTargetAllocation.Reset()
+	for poolID, target := range snapshot.TargetAllocations {
+		poolTargetAllocation.WithLabelValues(poolLabel(poolID)).Set(target)
+	}
+
+	// Counters cannot decrease; both are non-negative unless a snapshot is inconsistent
+	if snapshot.TotalSlippageUSD > 0 {
+		slippageUSD.Add(snapshot.TotalSlippageUSD)
+	}
+	if snapshot.TotalGasFeeUSD > 0 {
+		gasFeesUSD.Add(snapshot.TotalGasFeeUSD)
+	}
+}
+
+// SetNAVPerShare records the vault's latest NAV per share
+func SetNAVPerShare(navPerShare float64) {
+	vaultNAVPerShare.Set(navPerShare)
+}
+
+// ObserveSimulation records a simulation call of a kind, started at start
+func ObserveSimulation(kind string, start time.Time, err error) {
+	result := "ok"
+	if err != nil {
+		result = "error"
+	}
+	simulationDuration.WithLabelValues(kind, result).Observe(time.Since(start).Seconds())
+}
+
+// CountFetchError counts a failed fetch from source and returns err. Cancellations are not
+// counted: they follow another failure or the end of the cycle.
+func CountFetchError(source string, err error) error {
+	if err != nil && !errors.Is(err, context.Canceled) {
+		datafetcherErrors.WithLabelValues(source).Inc()
+	}
+	return err
+}
+
+// CountTransaction counts a transaction by result
+func CountTransaction(result string) {
+	transactionsTotal.WithLabelValues(result).Inc()
+}
+
+// ObserveTxInclusion records how long a transaction took to be included after its broadcast
+func ObserveTxInclusion(duration time.Duration) {
+	transactionsTotal.WithLabelValues(TxResultIncluded).Inc()
+	txInclusion.Observe(duration.Seconds())
+}
+
+// poolLabel formats a pool ID as a label value
+func poolLabel(poolID types.PoolID) string {
+	return strconv.FormatUint(uint64(poolID), 10)
+}
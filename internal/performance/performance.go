@@ -0,0 +1,176 @@
+/*
+
+This file computes the vault's NAV per share and the returns derived from it.
+
+The vault value alone mixes strategy performance with user deposits and withdrawals. The NAV
+per share does not: deposits and withdrawals mint and burn shares at the current NAV, so only
+the strategy moves it. Its growth is the time-weighted return. The money-weighted return also
+weighs each period by the capital invested in it, so it reflects the timing of the flows.
+
+*/
+
+package performance
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	sdkmath "cosmossdk.io/math"
+	"github.com/elys-network/avm/internal/types"
+)
+
+// ShareDecimals is the number of decimals of the vault's share denom. The NAV per share is
+// quoted per whole share.
+const ShareDecimals = 6
+
+const (
+	year = 365 * 24 * time.Hour
+	// minAnnualizationPeriod is the shortest history a return is annualized over; shorter
+	// periods extrapolate noise into absurd rates.
+	minAnnualizationPeriod = 24 * time.Hour
+)
+
+// Rolling APY windows
+const (
+	APYWindow7d  = 7 * 24 * time.Hour
+	APYWindow30d = 30 * 24 * time.Hour
+)
+
+// NewNAVPoint computes the NAV per share of the vault at the start of a cycle. previous is the
+// last recorded point, or nil for the first one; the net flow since it is valued at the new NAV.
+func NewNAVPoint(cycleNumber int, timestamp time.Time, blockHeight int64, vaultValueUSD float64, shareSupply sdkmath.Int, previous *types.NAVPoint) (types.NAVPoint, error) {
+	if math.IsNaN(vaultValueUSD) || math.IsInf(vaultValueUSD, 0) || vaultValueUSD < 0 {
+		return types.NAVPoint{}, fmt.Errorf("invalid vault value: %f", vaultValueUSD)
+	}
+	if shareSupply.IsNil() || !shareSupply.IsPositive() {
+		return types.NAVPoint{}, errors.New("share supply must be positive to compute the NAV per share")
+	}
+
+	shares, err := wholeShares(shareSupply)
+	if err != nil {
+		return types.NAVPoint{}, err
+	}
+	point := types.NAVPoint{
+		CycleNumber:   cycleNumber,
+		Timestamp:     timestamp.UTC(),
+		BlockHeight:   blockHeight,
+		VaultValueUSD: vaultValueUSD,
+		ShareSupply:   shareSupply.String(),
+		NAVPerShare:   vaultValueUSD / shares,
+	}
+
+	if previous != nil {
+		previousSupply, ok := sdkmath.NewIntFromString(previous.ShareSupply)
+		if !ok {
+			return types.NAVPoint{}, fmt.Errorf("invalid share supply of cycle %d: %q", previous.CycleNumber, previous.ShareSupply)
+		}
+		mintedShares, err := wholeShares(shareSupply.Sub(previousSupply))
+		if err != nil {
+			return types.NAVPoint{}, err
+		}
+		point.NetFlowUSD = mintedShares * point.NAVPerShare
+	}
+
+	return point, nil
+}
+
+// wholeShares converts a raw share amount to whole shares
+func wholeShares(amount sdkmath.Int) (float64, error) {
+	shares, err := sdkmath.LegacyNewDecFromIntWithPrec(amount, ShareDecimals).Float64()
+	if err != nil {
+		return 0, fmt.Errorf("failed to convert share amount %s: %w", amount, err)
+	}
+	return shares, nil
+}
+
+// CalculateReturns computes the returns of the vault up to the last point of history, which must
+// be ordered oldest first.
+func CalculateReturns(history []types.NAVPoint) types.VaultReturns {
+	var returns types.VaultReturns
+	if len(history) < 2 {
+		return returns
+	}
+	first, last := history[0], history[len(history)-1]
+
+	returns.TWRInception = last.NAVPerShare/first.NAVPerShare - 1
+	returns.APY7d = rollingAPY(history, APYWindow7d)
+	returns.APY30d = rollingAPY(history, APYWindow30d)
+	if last.Timestamp.Sub(first.Timestamp) >= minAnnualizationPeriod {
+		returns.APYInception = annualizedGrowth(first, last)
+		returns.MWRAnnualized = moneyWeightedReturn(history)
+	}
+	return returns
+}
+
+// rollingAPY annualizes the NAV per share growth since the latest point at least window older
+// than the last point; nil if the history is shorter than window
+func rollingAPY(history []types.NAVPoint, window time.Duration) *float64 {
+	last := history[len(history)-1]
+	cutoff := last.Timestamp.Add(-window)
+	for i := len(history) - 2; i >= 0; i-- {
+		if !history[i].Timestamp.After(cutoff) {
+			return annualizedGrowth(history[i], last)
+		}
+	}
+	return nil
+}
+
+// annualizedGrowth compounds the NAV per share growth from start to end over a year
+func annualizedGrowth(start, end types.NAVPoint) *float64 {
+	elapsed := end.Timestamp.Sub(start.Timestamp)
+	if elapsed <= 0 || start.NAVPerShare <= 0 {
+		return nil
+	}
+	return finiteOrNil(math.Pow(end.NAVPerShare/start.NAVPerShare, float64(year)/float64(elapsed)) - 1)
+}
+
+// moneyWeightedReturn solves for the annual rate r at which the starting value and the net flows
+// of the history, compounded to the last point, equal its value:
+//
+//	V_0 * (1+r)^t_0 + sum(F_i * (1+r)^t_i) = V_n, with t_i the years from point i to the last point
+//
+// It returns nil if there is no such rate.
+func moneyWeightedReturn(history []types.NAVPoint) *float64 {
+	first, last := history[0], history[len(history)-1]
+	if first.VaultValueUSD <= 0 {
+		return nil
+	}
+
+	yearsToEnd := func(point types.NAVPoint) float64 {
+		return float64(last.Timestamp.Sub(point.Timestamp)) / float64(year)
+	}
+	// Surplus of the compounded contributions over the final value, with g = ln(1+r).
+	// It increases with g while the compounded capital is positive.
+	surplus := func(g float64) float64 {
+		total := first.VaultValueUSD*math.Exp(g*yearsToEnd(first)) - last.VaultValueUSD
+		for _, point := range history[1:] {
+			total += point.NetFlowUSD * math.Exp(g*yearsToEnd(point))
+		}
+		return total
+	}
+
+	// Bisect g over rates from about -100% to e^10 - 1
+	low, high := -10.0, 10.0
+	if surplus(low) > 0 || surplus(high) < 0 {
+		return nil
+	}
+	for i := 0; i < 200; i++ {
+		mid := (low + high) / 2
+		if surplus(mid) > 0 {
+			high = mid
+		} else {
+			low = mid
+		}
+	}
+	return finiteOrNil(math.Exp((low+high)/2) - 1)
+}
+
+// finiteOrNil returns a pointer to value, or nil if it is NaN or infinite
+func finiteOrNil(value float64) *float64 {
+	if math.IsNaN(value) || math.IsInf(value, 0) {
+		return nil
+	}
+	return &value
+}
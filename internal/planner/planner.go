@@ -1,6 +1,7 @@
 package planner
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"math"
@@ -39,8 +40,10 @@ type ExtendedAction struct {
 }
 
 // GenerateActionPlan creates a strategic action plan for vault rebalancing using single-sided deposits
-// Returns two separate action plans: withdrawals/consolidation, then single-sided deposits
+// Returns two separate action plans: withdrawals/consolidation, then single-sided deposits.
+// Simulations run at the block height pinned on ctx, if any.
 func GenerateActionPlan(
+	ctx context.Context,
 	currentPositions []types.Position,
 	initialLiquidUSDC float64,
 	targetAllocations map[types.PoolID]float64,
@@ -104,7 +107,7 @@ func GenerateActionPlan(
 		Msg("High-level action analysis complete")
 
 	// ===== PROCESS WITHDRAWALS =====
-	withdrawalActions, newLiquidUSDC, tempNonUSDCAssets, err := processWithdrawals(
+	withdrawalActions, newLiquidUSDC, tempNonUSDCAssets, err := processWithdrawals(ctx,
 		highLevelWithdrawals, currentPositions, poolsData, simulatedLiquidUSDC,
 		usdcToken, tendermintRPCEndpoint, scoringParams)
 	if err != nil {
@@ -114,7 +117,7 @@ func GenerateActionPlan(
 	simulatedLiquidUSDC = newLiquidUSDC
 
 	// ===== CONSOLIDATE NON-USDC ASSETS =====
-	consolidationActions, finalLiquidUSDC, err := processConsolidation(
+	consolidationActions, finalLiquidUSDC, err := processConsolidation(ctx,
 		tempNonUSDCAssets, simulatedLiquidUSDC, usdcToken, tendermintRPCEndpoint, scoringParams)
 	if err != nil {
 		actionLogger.Error().Err(err).Msg("Asset consolidation failed")
@@ -126,7 +129,7 @@ func GenerateActionPlan(
 	withdrawalActions = append(withdrawalActions, consolidationActions...)
 
 	// ===== PROCESS DEPOSITS =====
-	depositActions, err = processDeposits(
+	depositActions, err = processDeposits(ctx,
 		highLevelDeposits, poolsData, simulatedLiquidUSDC, usdcToken,
 		tendermintRPCEndpoint, scoringParams)
 	if err != nil {
@@ -188,9 +191,10 @@ func validateInputs(
 		totalAllocation += allocation
 	}
 
-	if math.Abs(totalAllocation-1.0) > 0.01 {
+	// Allocations may sum to less than 1 when the token exposure cap leaves part of the vault in USDC
+	if totalAllocation > 1.01 {
 		return errors.Join(ErrInvalidTargetAllocations,
-			fmt.Errorf("total allocations (%.6f) do not sum to 1.0", totalAllocation))
+			fmt.Errorf("total allocations (%.6f) exceed 1.0", totalAllocation))
 	}
 
 	// Validate current positions
@@ -292,7 +296,7 @@ func validateScoringParams(params types.ScoringParameters) error {
 func validateAndGetUSDCToken(tokenDataMap map[string]types.Token) (types.Token, error) {
 	usdcToken, found := types.Token{}, false
 	for _, token := range tokenDataMap {
-		if token.Symbol == "USDC" {
+		if token.IsUSDC() {
 			usdcToken = token
 			found = true
 			break
@@ -465,6 +469,7 @@ func calculateTargetShares(targetUSD float64, poolID types.PoolID, poolsData map
 
 // processWithdrawals handles all withdrawal operations
 func processWithdrawals(
+	ctx context.Context,
 	withdrawals []ExtendedAction,
 	currentPositions []types.Position,
 	poolsData map[types.PoolID]types.Pool,
@@ -505,9 +510,16 @@ func processWithdrawals(
 			return nil, 0, nil, fmt.Errorf("pool %d data missing for withdrawal", withdrawal.PoolID)
 		}
 
+		// Exit single-sided to USDC where the pool holds it. Otherwise exit to all of the pool's
+		// assets, which are consolidated into USDC below.
+		exitDenom := ""
+		if poolInfo.HasDenom(usdcToken.IBCDenom) {
+			exitDenom = usdcToken.IBCDenom
+		}
+
 		// Simulate withdrawal with proper error handling
-		exitEst, err := simulations.SimulateLeavePool(rpcEndpoint, uint64(withdrawal.PoolID),
-			sharesToWithdraw, usdcToken.IBCDenom)
+		exitEst, err := simulations.SimulateLeavePool(ctx, rpcEndpoint, uint64(withdrawal.PoolID),
+			sharesToWithdraw, exitDenom)
 		if err != nil {
 			return nil, 0, nil, errors.Join(ErrSimulationFailed,
 				fmt.Errorf("failed to simulate leave pool %d: %w", withdrawal.PoolID, err))
@@ -533,7 +545,7 @@ func processWithdrawals(
 			Type:                 types.SubActionWithdrawLP,
 			PoolIDToWithdraw:     withdrawal.PoolID,
 			LPSharesToWithdraw:   sharesToWithdraw,
-			TargetDenomOnExit:    usdcToken.IBCDenom,
+			TargetDenomOnExit:    exitDenom,
 			ExpectedAmountsOut:   exitEst.AmountsOut,
 			ExpectedSlippage:     exitEst.Slippage,
 			SlippageTolerancePct: maxSlippage,
@@ -569,6 +581,7 @@ func processWithdrawals(
 
 // processConsolidation handles consolidation of non-USDC assets
 func processConsolidation(
+	ctx context.Context,
 	tempNonUSDCAssets map[string]sdkmath.Int,
 	simulatedLiquidUSDC float64,
 	usdcToken types.Token,
@@ -599,7 +612,7 @@ func processConsolidation(
 		}
 
 		// Try to swap with reduced amounts if slippage is too high
-		swapEst, finalAmount, err := findViableSwapAmount(rpcEndpoint, amount, denom,
+		swapEst, finalAmount, err := findViableSwapAmount(ctx, rpcEndpoint, amount, denom,
 			usdcToken.IBCDenom, maxSlippage, scoringParams.ViableSwapReductionFactor)
 		if err != nil {
 			actionLogger.Error().Err(err).Str("denom", denom).
@@ -640,6 +653,7 @@ func processConsolidation(
 
 // processDeposits handles all deposit operations
 func processDeposits(
+	ctx context.Context,
 	deposits []ExtendedAction,
 	poolsData map[types.PoolID]types.Pool,
 	simulatedLiquidUSDC float64,
@@ -698,13 +712,32 @@ func processDeposits(
 			return nil, fmt.Errorf("failed to convert USDC amount for pool %d: %w", deposit.PoolID, err)
 		}
 
+		// A pool without USDC is entered by swapping USDC into one of its assets first
+		if !poolInfo.HasDenom(usdcToken.IBCDenom) {
+			swapDepositActions, swappedUSDC, err := planSwapDeposit(ctx, rpcEndpoint, deposit.PoolID,
+				usdcAmount, poolInfo, usdcToken, scoringParams)
+			if err != nil {
+				actionLogger.Error().Err(err).Uint64("poolID", uint64(deposit.PoolID)).
+					Msg("Failed to plan swap and deposit, skipping")
+				continue
+			}
+			actions = append(actions, swapDepositActions...)
+
+			usedUSDC, err := utils.SDKIntToFloat64(swappedUSDC, usdcToken.Precision)
+			if err != nil {
+				return nil, fmt.Errorf("failed to convert used USDC amount: %w", err)
+			}
+			simulatedLiquidUSDC -= usedUSDC
+			continue
+		}
+
 		amountsIn := []sdktypes.Coin{{
 			Denom:  usdcToken.IBCDenom,
 			Amount: usdcAmount,
 		}}
 
 		// Find viable deposit amount considering slippage
-		joinEst, finalAmount, err := findViableDepositAmount(rpcEndpoint, uint64(deposit.PoolID),
+		joinEst, finalAmount, err := findViableDepositAmount(ctx, rpcEndpoint, uint64(deposit.PoolID),
 			amountsIn, poolInfo, scoringParams)
 		if err != nil {
 			actionLogger.Error().Err(err).Uint64("poolID", uint64(deposit.PoolID)).
@@ -749,6 +782,93 @@ func processDeposits(
 	return actions, nil
 }
 
+// planSwapDeposit plans a deposit of up to usdcAmount into a pool that does not hold USDC.
+// The USDC is swapped into the pool's largest asset, which normally has the deepest liquidity,
+// and the swap's minimum output is deposited single-sided, so the deposit cannot exceed what the
+// swap delivers. Returns the swap and deposit actions, in execution order, and the USDC swapped.
+func planSwapDeposit(
+	ctx context.Context,
+	rpcEndpoint string,
+	poolID types.PoolID,
+	usdcAmount sdkmath.Int,
+	poolInfo types.Pool,
+	usdcToken types.Token,
+	scoringParams types.ScoringParameters,
+) ([]types.SubAction, sdkmath.Int, error) {
+
+	actionLogger := logger.GetForComponent("action_planner")
+
+	if len(poolInfo.Assets) == 0 {
+		return nil, sdkmath.ZeroInt(), fmt.Errorf("pool %d has no assets", poolID)
+	}
+	depositAsset := poolInfo.Assets[0]
+	for _, asset := range poolInfo.Assets[1:] {
+		if asset.Weight > depositAsset.Weight {
+			depositAsset = asset
+		}
+	}
+	depositDenom := depositAsset.Token.IBCDenom
+
+	maxSwapSlippage := scoringParams.NormalPoolSlippagePercent / 100.0
+	swapEst, swapAmount, err := findViableSwapAmount(ctx, rpcEndpoint, usdcAmount, usdcToken.IBCDenom,
+		depositDenom, maxSwapSlippage, scoringParams.ViableSwapReductionFactor)
+	if err != nil {
+		return nil, sdkmath.ZeroInt(), fmt.Errorf("no viable swap from USDC to %s: %w", depositAsset.Token.Symbol, err)
+	}
+
+	minTokenOut := sdkmath.LegacyNewDecFromInt(swapEst.TokenOutAmount).
+		Mul(sdkmath.LegacyMustNewDecFromStr(fmt.Sprintf("%f", 1.0-maxSwapSlippage))).
+		TruncateInt()
+	if !minTokenOut.IsPositive() {
+		return nil, sdkmath.ZeroInt(), fmt.Errorf("swap from USDC to %s yields no tokens to deposit", depositAsset.Token.Symbol)
+	}
+
+	amountsIn := []sdktypes.Coin{{
+		Denom:  depositDenom,
+		Amount: minTokenOut,
+	}}
+	joinEst, err := simulations.SimulateJoinPool(ctx, rpcEndpoint, uint64(poolID), amountsIn)
+	if err != nil {
+		return nil, sdkmath.ZeroInt(), errors.Join(ErrSimulationFailed,
+			fmt.Errorf("failed to simulate join pool %d: %w", poolID, err))
+	}
+
+	maxSlippage := getSlippageLimit(poolInfo, scoringParams)
+	if joinEst.Slippage > maxSlippage {
+		return nil, sdkmath.ZeroInt(), fmt.Errorf("deposit slippage %f exceeds limit %f", joinEst.Slippage, maxSlippage)
+	}
+
+	actions := []types.SubAction{
+		{
+			Type:                 types.SubActionSwap,
+			TokenIn:              sdktypes.NewCoin(usdcToken.IBCDenom, swapAmount),
+			TokenOutDenom:        depositDenom,
+			ExpectedTokenOut:     swapEst.TokenOutAmount,
+			ExpectedSlippage:     swapEst.Slippage,
+			SlippageTolerancePct: maxSwapSlippage,
+		},
+		{
+			Type:                 types.SubActionDepositLP,
+			PoolIDToDeposit:      poolID,
+			AmountsToDeposit:     amountsIn,
+			ExpectedSharesOut:    joinEst.ShareAmountOut.Amount,
+			ExpectedSlippage:     joinEst.Slippage,
+			SlippageTolerancePct: maxSlippage,
+		},
+	}
+
+	actionLogger.Info().
+		Uint64("poolID", uint64(poolID)).
+		Str("depositToken", depositAsset.Token.Symbol).
+		Str("usdcSwapped", swapAmount.String()).
+		Str("depositAmount", minTokenOut.String()).
+		Float64("swapSlippage", swapEst.Slippage).
+		Float64("depositSlippage", joinEst.Slippage).
+		Msg("Swap and deposit actions created")
+
+	return actions, swapAmount, nil
+}
+
 // Helper functions with proper error handling
 
 func validateExitEstimation(exitEst simulations.ExitPoolEstimationResult, poolID types.PoolID) error {
@@ -765,6 +885,7 @@ func validateExitEstimation(exitEst simulations.ExitPoolEstimationResult, poolID
 }
 
 func findViableSwapAmount(
+	ctx context.Context,
 	rpcEndpoint string,
 	maxAmount sdkmath.Int,
 	fromDenom, toDenom string,
@@ -780,7 +901,7 @@ func findViableSwapAmount(
 			break
 		}
 
-		swapEst, err := simulations.SimulateSwap(rpcEndpoint, currentAmount, fromDenom, toDenom)
+		swapEst, err := simulations.SimulateSwap(ctx, rpcEndpoint, currentAmount, fromDenom, toDenom)
 		if err == nil && swapEst.Slippage <= maxSlippage {
 			return swapEst, currentAmount, nil
 		}
@@ -802,6 +923,7 @@ func findViableSwapAmount(
 }
 
 func findViableDepositAmount(
+	ctx context.Context,
 	rpcEndpoint string,
 	poolID uint64,
 	amountsIn []sdktypes.Coin,
@@ -812,7 +934,7 @@ func findViableDepositAmount(
 	maxSlippage := getSlippageLimit(poolInfo, scoringParams)
 
 	// Try original amount first
-	joinEst, err := simulations.SimulateJoinPool(rpcEndpoint, poolID, amountsIn)
+	joinEst, err := simulations.SimulateJoinPool(ctx, rpcEndpoint, poolID, amountsIn)
 	if err == nil && joinEst.Slippage <= maxSlippage {
 		return joinEst, amountsIn[0].Amount, nil
 	}
@@ -825,7 +947,7 @@ func findViableDepositAmount(
 		Amount: reducedAmount,
 	}}
 
-	joinEst, err = simulations.SimulateJoinPool(rpcEndpoint, poolID, reducedAmountsIn)
+	joinEst, err = simulations.SimulateJoinPool(ctx, rpcEndpoint, poolID, reducedAmountsIn)
 	if err == nil && joinEst.Slippage <= maxSlippage {
 		return joinEst, reducedAmount, nil
 	}
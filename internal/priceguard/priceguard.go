@@ -0,0 +1,222 @@
+/*
+
+This file contains the price integrity guard. It cross-checks the oracle, AMM and CryptoCompare
+spot prices of every token before the AVM acts on them, flags tokens whose sources disagree,
+detects a USDC depeg, and decides which pools must be excluded and whether execution must halt.
+
+*/
+
+package priceguard
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/elys-network/avm/internal/config"
+	"github.com/elys-network/avm/internal/logger"
+	"github.com/elys-network/avm/internal/types"
+)
+
+var priceGuardLogger = logger.GetForComponent("price_guard")
+
+// Flag reasons recorded in PriceIntegrityFlag.Reason
+const (
+	ReasonOracleAmmDivergence = "oracle_amm_divergence"
+	ReasonReferenceDivergence = "reference_divergence"
+	ReasonUSDCDepeg           = "usdc_depeg"
+)
+
+// CheckPriceIntegrity compares the price sources of every token against the configured thresholds.
+// The token map is keyed by both Denom and IBCDenom, so each token is checked once by Denom.
+// Pools holding a flagged token are listed in ExcludedPools. Execution is halted if USDC has
+// depegged, if the vault currently holds a position in an excluded pool, or if no pools remain.
+func CheckPriceIntegrity(
+	tokens map[string]types.Token,
+	pools []types.Pool,
+	positions []types.Position,
+) types.PriceIntegrityReport {
+	report := types.PriceIntegrityReport{
+		Flags:         []types.PriceIntegrityFlag{},
+		ExcludedPools: []types.PoolID{},
+	}
+
+	// De-duplicate tokens by Denom and check them in a stable order
+	uniqueTokens := make(map[string]types.Token)
+	for _, token := range tokens {
+		uniqueTokens[token.Denom] = token
+	}
+	denoms := make([]string, 0, len(uniqueTokens))
+	for denom := range uniqueTokens {
+		denoms = append(denoms, denom)
+	}
+	sort.Strings(denoms)
+
+	flaggedDenoms := make(map[string]bool)
+	for _, denom := range denoms {
+		token := uniqueTokens[denom]
+
+		var flag *types.PriceIntegrityFlag
+		// USDC is the vault's numeraire; it is checked against a $1 peg rather than a reference price
+		if token.IsUSDC() {
+			flag = checkUSDCPeg(token)
+			if flag != nil {
+				report.USDCDepegged = true
+			}
+		} else {
+			flag = checkTokenPrices(token)
+		}
+
+		if flag != nil {
+			priceGuardLogger.Warn().
+				Str("symbol", flag.Symbol).
+				Str("reason", flag.Reason).
+				Float64("oraclePrice", flag.OraclePriceUSD).
+				Float64("ammPrice", flag.AmmPriceUSD).
+				Float64("referencePrice", flag.ReferencePriceUSD).
+				Float64("divergencePercent", flag.DivergencePercent).
+				Msg("Token failed price integrity check")
+			report.Flags = append(report.Flags, *flag)
+			flaggedDenoms[token.Denom] = true
+			if token.IBCDenom != "" {
+				flaggedDenoms[token.IBCDenom] = true
+			}
+		}
+	}
+
+	// Exclude every pool that holds a flagged token
+	excluded := make(map[types.PoolID]bool)
+	for _, pool := range pools {
+		if holdsFlaggedToken(pool, flaggedDenoms) {
+			excluded[pool.ID] = true
+			report.ExcludedPools = append(report.ExcludedPools, pool.ID)
+		}
+	}
+	sort.Slice(report.ExcludedPools, func(i, j int) bool { return report.ExcludedPools[i] < report.ExcludedPools[j] })
+
+	// Decide whether execution must halt
+	switch {
+	case report.USDCDepegged:
+		report.HaltExecution = true
+		report.HaltReason = "USDC has depegged beyond the configured threshold"
+	case len(pools) > 0 && len(excluded) == len(pools):
+		report.HaltExecution = true
+		report.HaltReason = "all pools hold a token that failed the price integrity check"
+	default:
+		for _, position := range positions {
+			if excluded[position.PoolID] {
+				report.HaltExecution = true
+				report.HaltReason = fmt.Sprintf("vault holds a position in pool %d, which holds a token that failed the price integrity check", position.PoolID)
+				break
+			}
+		}
+	}
+
+	logEvent := priceGuardLogger.Info()
+	if report.HaltExecution {
+		logEvent = priceGuardLogger.Error().Str("haltReason", report.HaltReason)
+	}
+	logEvent.
+		Int("tokensChecked", len(denoms)).
+		Int("flaggedTokens", len(report.Flags)).
+		Int("excludedPools", len(report.ExcludedPools)).
+		Bool("usdcDepegged", report.USDCDepegged).
+		Bool("haltExecution", report.HaltExecution).
+		Msg("Price integrity check completed")
+
+	return report
+}
+
+// ExcludeFlaggedPools returns the pools that are not listed in the report's ExcludedPools
+func ExcludeFlaggedPools(pools []types.Pool, report types.PriceIntegrityReport) []types.Pool {
+	if len(report.ExcludedPools) == 0 {
+		return pools
+	}
+
+	excluded := make(map[types.PoolID]bool, len(report.ExcludedPools))
+	for _, id := range report.ExcludedPools {
+		excluded[id] = true
+	}
+
+	filtered := make([]types.Pool, 0, len(pools))
+	for _, pool := range pools {
+		if !excluded[pool.ID] {
+			filtered = append(filtered, pool)
+		}
+	}
+	return filtered
+}
+
+// checkTokenPrices flags a non-USDC token whose oracle and AMM prices disagree, or whose on-chain
+// price disagrees with the CryptoCompare spot price. Checks that lack one of their sources are skipped.
+func checkTokenPrices(token types.Token) *types.PriceIntegrityFlag {
+	if token.OraclePriceUSD > 0 && token.AmmPriceUSD > 0 {
+		divergence := divergencePercent(token.AmmPriceUSD, token.OraclePriceUSD)
+		if divergence > config.MaxOracleAmmDivergencePercent {
+			return newFlag(token, ReasonOracleAmmDivergence, divergence)
+		}
+	}
+
+	if token.ReferencePriceUSD <= 0 {
+		priceGuardLogger.Warn().
+			Str("symbol", token.Symbol).
+			Msg("No reference price available for token, skipping reference divergence check")
+		return nil
+	}
+
+	divergence := divergencePercent(token.PriceUSD, token.ReferencePriceUSD)
+	if divergence > config.MaxReferenceDivergencePercent {
+		return newFlag(token, ReasonReferenceDivergence, divergence)
+	}
+
+	return nil
+}
+
+// checkUSDCPeg flags USDC if any of its available price sources deviates from $1 beyond the threshold
+func checkUSDCPeg(token types.Token) *types.PriceIntegrityFlag {
+	maxDeviation := 0.0
+	for _, price := range []float64{token.OraclePriceUSD, token.AmmPriceUSD, token.ReferencePriceUSD, token.PriceUSD} {
+		if price <= 0 {
+			continue
+		}
+		maxDeviation = math.Max(maxDeviation, divergencePercent(price, 1.0))
+	}
+
+	if maxDeviation > config.MaxUSDCDepegPercent {
+		return newFlag(token, ReasonUSDCDepeg, maxDeviation)
+	}
+	return nil
+}
+
+// divergencePercent returns how far price deviates from base, as a percentage of base
+func divergencePercent(price, base float64) float64 {
+	if base <= 0 || math.IsNaN(price) || math.IsInf(price, 0) {
+		return math.Inf(1)
+	}
+	return math.Abs(price-base) / base * 100
+}
+
+// newFlag builds a PriceIntegrityFlag from the token's price sources
+func newFlag(token types.Token, reason string, divergence float64) *types.PriceIntegrityFlag {
+	return &types.PriceIntegrityFlag{
+		Denom:             token.Denom,
+		IBCDenom:          token.IBCDenom,
+		Symbol:            token.Symbol,
+		Reason:            reason,
+		OraclePriceUSD:    token.OraclePriceUSD,
+		AmmPriceUSD:       token.AmmPriceUSD,
+		ReferencePriceUSD: token.ReferencePriceUSD,
+		DivergencePercent: divergence,
+	}
+}
+
+// holdsFlaggedToken reports whether any of the pool's tokens matches a flagged denom
+func holdsFlaggedToken(pool types.Pool, flaggedDenoms map[string]bool) bool {
+	for _, asset := range pool.Assets {
+		token := asset.Token
+		if (token.Denom != "" && flaggedDenoms[token.Denom]) || (token.IBCDenom != "" && flaggedDenoms[token.IBCDenom]) {
+			return true
+		}
+	}
+	return false
+}
@@ -2,6 +2,7 @@ package simulations
 
 import (
 	"bytes"
+	"context"
 	"encoding/base64"
 	"encoding/hex"
 	"encoding/json"
@@ -14,7 +15,10 @@ import (
 	"cosmossdk.io/math"
 	sdk "github.com/cosmos/cosmos-sdk/types"
 	"github.com/elys-network/avm/internal/config"
+	"github.com/elys-network/avm/internal/fixtures"
 	"github.com/elys-network/avm/internal/logger"
+	"github.com/elys-network/avm/internal/metrics"
+	"github.com/elys-network/avm/internal/utils"
 	amm "github.com/elys-network/elys/v6/x/amm/types"
 	"github.com/gogo/protobuf/proto"
 	"github.com/rs/zerolog"
@@ -105,17 +109,20 @@ type ExitPoolEstimationResult struct {
 
 // --- Simulation Functions ---
 
-// SimulateSwap simulates a token swap using Tendermint RPC
+// SimulateSwap simulates a token swap using Tendermint RPC.
+// Like the other simulations, it runs at the block height pinned on ctx, if any.
 func SimulateSwap(
+	ctx context.Context,
 	_ any, // Unused parameter for compatibility
 	tokenInAmount math.Int,
 	tokenInDenom, tokenOutDenom string,
 ) (SwapEstimationResult, error) {
-	return simulateSwapWithEndpoint(config.NodeRPC, tokenInAmount, tokenInDenom, tokenOutDenom)
+	return simulateSwapWithEndpoint(ctx, config.NodeRPC, tokenInAmount, tokenInDenom, tokenOutDenom)
 }
 
 // simulateSwapWithEndpoint performs the actual swap simulation
 func simulateSwapWithEndpoint(
+	ctx context.Context,
 	rpcEndpoint string,
 	tokenInAmount math.Int,
 	tokenInDenom, tokenOutDenom string,
@@ -134,13 +141,16 @@ func simulateSwapWithEndpoint(
 		Address: address,
 	}
 
+	start := time.Now()
 	result, err := executeRPCQuery(
+		ctx,
 		rpcEndpoint,
 		"/elys.amm.Query/SwapEstimationByDenom",
 		grpcRequest,
 		swapLogger,
 		1, // RPC ID
 	)
+	metrics.ObserveSimulation(metrics.SimulationSwap, start, err)
 	if err != nil {
 		return SwapEstimationResult{}, err
 	}
@@ -173,6 +183,7 @@ func simulateSwapWithEndpoint(
 
 // SimulateJoinPool simulates joining a liquidity pool
 func SimulateJoinPool(
+	ctx context.Context,
 	rpcEndpoint string,
 	poolId uint64,
 	amountsIn []sdk.Coin,
@@ -192,13 +203,16 @@ func SimulateJoinPool(
 		AmountsIn: sdkCoins,
 	}
 
+	start := time.Now()
 	result, err := executeRPCQuery(
+		ctx,
 		rpcEndpoint,
 		"/elys.amm.Query/JoinPoolEstimation",
 		grpcRequest,
 		joinPoolLogger,
 		2, // RPC ID
 	)
+	metrics.ObserveSimulation(metrics.SimulationJoinPool, start, err)
 	if err != nil {
 		return JoinPoolEstimationResult{}, err
 	}
@@ -250,6 +264,7 @@ func SimulateJoinPool(
 
 // SimulateLeavePool simulates exiting a liquidity pool
 func SimulateLeavePool(
+	ctx context.Context,
 	rpcEndpoint string,
 	poolId uint64,
 	sharesIn math.Int,
@@ -262,13 +277,16 @@ func SimulateLeavePool(
 		TokenOutDenom: tokenOutDenom,
 	}
 
+	start := time.Now()
 	result, err := executeRPCQuery(
+		ctx,
 		rpcEndpoint,
 		"/elys.amm.Query/ExitPoolEstimation",
 		grpcRequest,
 		exitPoolLogger,
 		3, // RPC ID
 	)
+	metrics.ObserveSimulation(metrics.SimulationExitPool, start, err)
 	if err != nil {
 		return ExitPoolEstimationResult{}, err
 	}
@@ -318,6 +336,7 @@ func SimulateLeavePool(
 
 // executeRPCQuery executes a generic RPC query and returns the decoded result
 func executeRPCQuery(
+	ctx context.Context,
 	rpcEndpoint string,
 	abciPath string,
 	grpcRequest proto.Message,
@@ -342,6 +361,9 @@ func executeRPCQuery(
 			Data: hexEncodedData,
 		},
 	}
+	if height, ok := utils.BlockHeightFromContext(ctx); ok {
+		jsonRPCReq.Params.Height = strconv.FormatInt(height, 10)
+	}
 
 	jsonData, err := json.Marshal(jsonRPCReq)
 	if err != nil {
@@ -355,8 +377,8 @@ func executeRPCQuery(
 		Msg("Executing RPC query")
 
 	// Make HTTP request
-	httpClient := http.Client{Timeout: rpcTimeout}
-	req, err := http.NewRequest("POST", rpcEndpoint, bytes.NewBuffer(jsonData))
+	httpClient := http.Client{Timeout: rpcTimeout, Transport: fixtures.Transport()}
+	req, err := http.NewRequestWithContext(ctx, "POST", rpcEndpoint, bytes.NewBuffer(jsonData))
 	if err != nil {
 		logger.Error().Err(err).Msg("Failed to create HTTP request")
 		return nil, fmt.Errorf("failed to create HTTP request: %w", err)
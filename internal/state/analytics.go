@@ -4,9 +4,9 @@ import (
 	"database/sql"
 	"encoding/json"
 	"fmt"
+	"time"
 
-	"github.com/elys-network/avm/internal/types" // PostgreSQL driver for array support
-	"github.com/lib/pq"
+	"github.com/elys-network/avm/internal/types"
 	"github.com/rs/zerolog/log"
 )
 
@@ -21,38 +21,69 @@ type VaultSummary struct {
 
 // PerformanceMetrics represents aggregated performance data
 type PerformanceMetrics struct {
-	TotalReturn             float64 `json:"total_return"`
+	TotalReturn             float64 `json:"total_return"` // Sum of the cycles' value changes, including deposits and withdrawals
 	TotalGasFees            float64 `json:"total_gas_fees"`
 	TotalSlippage           float64 `json:"total_slippage"`
 	AvgAllocationEfficiency float64 `json:"avg_allocation_efficiency"`
 	TotalCycles             int     `json:"total_cycles"`
 	SuccessfulCycles        int     `json:"successful_cycles"`
+
+	NAV *types.VaultPerformance `json:"nav"` // Latest NAV per share and returns; nil until one is recorded
 }
 
-// GetRecentCycles retrieves recent cycle snapshots with pagination
-func GetRecentCycles(limit int) ([]types.CycleSnapshot, error) {
-	if DB == nil {
-		return nil, fmt.Errorf("database not initialized")
+// cycleSnapshotColumns are the cycle_snapshots columns read by scanCycleSnapshot
+const cycleSnapshotColumns = `
+	snapshot_id, cycle_number, snapshot_timestamp, scoring_params_id,
+	initial_vault_value_usd, initial_liquid_usdc, initial_positions,
+	target_allocations, action_plan,
+	final_vault_value_usd, final_liquid_usdc, final_positions,
+	transaction_hashes, action_receipts,
+	allocation_efficiency_percent, net_return_usd, total_slippage_usd, total_gas_fee_usd,
+	target_token_exposures, token_exposures, price_integrity, quarantine, step_timings,
+	COALESCE(block_height, 0), scoring_params_reload, pool_scores, COALESCE(unallocated_target, 0)
+`
+
+// scanCycleSnapshot reads a row selected with cycleSnapshotColumns. Scan errors are returned
+// unwrapped, so sql.ErrNoRows can be compared directly.
+func (s *sqlStore) scanCycleSnapshot(row rowScanner) (*types.CycleSnapshot, error) {
+	var cycle types.CycleSnapshot
+	var initialPositionsJSON, targetAllocationsJSON, actionPlanJSON, finalPositionsJSON, actionReceiptsJSON, targetTokenExposuresJSON, tokenExposuresJSON, priceIntegrityJSON, quarantineJSON, stepTimingsJSON, scoringParamsReloadJSON, poolScoresJSON []byte
+
+	err := row.Scan(
+		&cycle.SnapshotID, &cycle.CycleNumber, &cycle.Timestamp, &cycle.ScoringParamsID,
+		&cycle.InitialVaultValueUSD, &cycle.InitialLiquidUSDC, &initialPositionsJSON,
+		&targetAllocationsJSON, &actionPlanJSON,
+		&cycle.FinalVaultValueUSD, &cycle.FinalLiquidUSDC, &finalPositionsJSON,
+		s.dialect.scanStringArray(&cycle.TransactionHashes), &actionReceiptsJSON,
+		&cycle.AllocationEfficiencyPercent, &cycle.NetReturnUSD, &cycle.TotalSlippageUSD, &cycle.TotalGasFeeUSD,
+		&targetTokenExposuresJSON, &tokenExposuresJSON, &priceIntegrityJSON, &quarantineJSON, &stepTimingsJSON,
+		&cycle.BlockHeight, &scoringParamsReloadJSON, &poolScoresJSON, &cycle.UnallocatedTarget,
+	)
+	if err != nil {
+		return nil, err
 	}
 
+	// Unmarshal JSON fields
+	if err := unmarshalJSONFields(&cycle, initialPositionsJSON, targetAllocationsJSON, actionPlanJSON, finalPositionsJSON, actionReceiptsJSON, targetTokenExposuresJSON, tokenExposuresJSON, priceIntegrityJSON, quarantineJSON, stepTimingsJSON, scoringParamsReloadJSON, poolScoresJSON); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal JSON fields of snapshot %d: %w", cycle.SnapshotID, err)
+	}
+	return &cycle, nil
+}
+
+// GetRecentCycles retrieves recent cycle snapshots with pagination
+func (s *sqlStore) GetRecentCycles(limit int) ([]types.CycleSnapshot, error) {
 	if limit <= 0 || limit > 100 {
 		limit = 10 // Default limit
 	}
 
 	query := `
-		SELECT 
-			snapshot_id, cycle_number, snapshot_timestamp, scoring_params_id,
-			initial_vault_value_usd, initial_liquid_usdc, initial_positions,
-			target_allocations, action_plan,
-			final_vault_value_usd, final_liquid_usdc, final_positions,
-			transaction_hashes, action_receipts,
-			allocation_efficiency_percent, net_return_usd, total_slippage_usd, total_gas_fee_usd
+		SELECT ` + cycleSnapshotColumns + `
 		FROM cycle_snapshots 
 		ORDER BY snapshot_timestamp DESC 
 		LIMIT $1
 	`
 
-	rows, err := DB.Query(query, limit)
+	rows, err := s.db.Query(query, limit)
 	if err != nil {
 		log.Error().Err(err).Msg("Failed to query recent cycles")
 		return nil, fmt.Errorf("failed to query recent cycles: %w", err)
@@ -61,29 +92,12 @@ func GetRecentCycles(limit int) ([]types.CycleSnapshot, error) {
 
 	var cycles []types.CycleSnapshot
 	for rows.Next() {
-		var cycle types.CycleSnapshot
-		var initialPositionsJSON, targetAllocationsJSON, actionPlanJSON, finalPositionsJSON, actionReceiptsJSON []byte
-
-		err := rows.Scan(
-			&cycle.SnapshotID, &cycle.CycleNumber, &cycle.Timestamp, &cycle.ScoringParamsID,
-			&cycle.InitialVaultValueUSD, &cycle.InitialLiquidUSDC, &initialPositionsJSON,
-			&targetAllocationsJSON, &actionPlanJSON,
-			&cycle.FinalVaultValueUSD, &cycle.FinalLiquidUSDC, &finalPositionsJSON,
-			pq.Array(&cycle.TransactionHashes), &actionReceiptsJSON, // Use pq.Array for PostgreSQL array
-			&cycle.AllocationEfficiencyPercent, &cycle.NetReturnUSD, &cycle.TotalSlippageUSD, &cycle.TotalGasFeeUSD,
-		)
+		cycle, err := s.scanCycleSnapshot(rows)
 		if err != nil {
-			log.Error().Err(err).Msg("Failed to scan cycle row")
+			log.Error().Err(err).Msg("Failed to read cycle row")
 			continue // Skip this row and continue with others
 		}
-
-		// Unmarshal JSON fields
-		if err := unmarshalJSONFields(&cycle, initialPositionsJSON, targetAllocationsJSON, actionPlanJSON, finalPositionsJSON, actionReceiptsJSON); err != nil {
-			log.Error().Err(err).Int("cycle_number", cycle.CycleNumber).Msg("Failed to unmarshal JSON fields for cycle")
-			continue // Skip this row and continue with others
-		}
-
-		cycles = append(cycles, cycle)
+		cycles = append(cycles, *cycle)
 	}
 
 	if err := rows.Err(); err != nil {
@@ -95,8 +109,53 @@ func GetRecentCycles(limit int) ([]types.CycleSnapshot, error) {
 	return cycles, nil
 }
 
+// GetCyclesInRange returns the cycle snapshots taken within [from, to), oldest first. Unlike
+// GetRecentCycles it fails on a row it cannot read, so exports and archives are never incomplete.
+func (s *sqlStore) GetCyclesInRange(from, to time.Time) ([]types.CycleSnapshot, error) {
+	query := `
+		SELECT ` + cycleSnapshotColumns + `
+		FROM cycle_snapshots
+		WHERE snapshot_timestamp >= $1 AND snapshot_timestamp < $2
+		ORDER BY snapshot_timestamp ASC, snapshot_id ASC
+	`
+
+	rows, err := s.db.Query(query, from.UTC(), to.UTC())
+	if err != nil {
+		return nil, fmt.Errorf("failed to query cycles in range: %w", err)
+	}
+	defer rows.Close()
+
+	cycles := make([]types.CycleSnapshot, 0)
+	for rows.Next() {
+		cycle, err := s.scanCycleSnapshot(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read cycle row: %w", err)
+		}
+		cycles = append(cycles, *cycle)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error during row iteration: %w", err)
+	}
+
+	return cycles, nil
+}
+
+// GetOldestCycleTimestamp returns the timestamp of the oldest cycle snapshot, or nil if there are none
+func (s *sqlStore) GetOldestCycleTimestamp() (*time.Time, error) {
+	var oldest time.Time
+	err := s.db.QueryRow(`SELECT snapshot_timestamp FROM cycle_snapshots ORDER BY snapshot_timestamp ASC LIMIT 1`).Scan(&oldest)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query oldest cycle: %w", err)
+	}
+	oldest = oldest.UTC()
+	return &oldest, nil
+}
+
 // unmarshalJSONFields unmarshals JSON fields for a cycle snapshot
-func unmarshalJSONFields(cycle *types.CycleSnapshot, initialPositionsJSON, targetAllocationsJSON, actionPlanJSON, finalPositionsJSON, actionReceiptsJSON []byte) error {
+func unmarshalJSONFields(cycle *types.CycleSnapshot, initialPositionsJSON, targetAllocationsJSON, actionPlanJSON, finalPositionsJSON, actionReceiptsJSON, targetTokenExposuresJSON, tokenExposuresJSON, priceIntegrityJSON, quarantineJSON, stepTimingsJSON, scoringParamsReloadJSON, poolScoresJSON []byte) error {
 	// Unmarshal initial positions
 	if len(initialPositionsJSON) > 0 {
 		if err := json.Unmarshal(initialPositionsJSON, &cycle.InitialPositions); err != nil {
@@ -132,39 +191,67 @@ func unmarshalJSONFields(cycle *types.CycleSnapshot, initialPositionsJSON, targe
 		}
 	}
 
+	// Unmarshal target token exposures
+	if len(targetTokenExposuresJSON) > 0 {
+		if err := json.Unmarshal(targetTokenExposuresJSON, &cycle.TargetTokenExposures); err != nil {
+			return fmt.Errorf("failed to unmarshal target token exposures: %w", err)
+		}
+	}
+
+	// Unmarshal token exposures
+	if len(tokenExposuresJSON) > 0 {
+		if err := json.Unmarshal(tokenExposuresJSON, &cycle.TokenExposures); err != nil {
+			return fmt.Errorf("failed to unmarshal token exposures: %w", err)
+		}
+	}
+
+	// Unmarshal price integrity report
+	if len(priceIntegrityJSON) > 0 {
+		if err := json.Unmarshal(priceIntegrityJSON, &cycle.PriceIntegrity); err != nil {
+			return fmt.Errorf("failed to unmarshal price integrity report: %w", err)
+		}
+	}
+
+	// Unmarshal quarantine report
+	if len(quarantineJSON) > 0 {
+		if err := json.Unmarshal(quarantineJSON, &cycle.Quarantine); err != nil {
+			return fmt.Errorf("failed to unmarshal quarantine report: %w", err)
+		}
+	}
+
+	// Unmarshal step timings
+	if len(stepTimingsJSON) > 0 {
+		if err := json.Unmarshal(stepTimingsJSON, &cycle.StepTimings); err != nil {
+			return fmt.Errorf("failed to unmarshal step timings: %w", err)
+		}
+	}
+
+	// Unmarshal scoring parameters reload
+	if len(scoringParamsReloadJSON) > 0 {
+		if err := json.Unmarshal(scoringParamsReloadJSON, &cycle.ScoringParamsReload); err != nil {
+			return fmt.Errorf("failed to unmarshal scoring parameters reload: %w", err)
+		}
+	}
+
+	// Unmarshal pool scores
+	if len(poolScoresJSON) > 0 {
+		if err := json.Unmarshal(poolScoresJSON, &cycle.PoolScores); err != nil {
+			return fmt.Errorf("failed to unmarshal pool scores: %w", err)
+		}
+	}
+
 	return nil
 }
 
 // GetCycleByID retrieves a specific cycle by its ID
-func GetCycleByID(snapshotID int64) (*types.CycleSnapshot, error) {
-	if DB == nil {
-		return nil, fmt.Errorf("database not initialized")
-	}
-
+func (s *sqlStore) GetCycleByID(snapshotID int64) (*types.CycleSnapshot, error) {
 	query := `
-		SELECT 
-			snapshot_id, cycle_number, snapshot_timestamp, scoring_params_id,
-			initial_vault_value_usd, initial_liquid_usdc, initial_positions,
-			target_allocations, action_plan,
-			final_vault_value_usd, final_liquid_usdc, final_positions,
-			transaction_hashes, action_receipts,
-			allocation_efficiency_percent, net_return_usd, total_slippage_usd, total_gas_fee_usd
+		SELECT ` + cycleSnapshotColumns + `
 		FROM cycle_snapshots 
 		WHERE snapshot_id = $1
 	`
 
-	var cycle types.CycleSnapshot
-	var initialPositionsJSON, targetAllocationsJSON, actionPlanJSON, finalPositionsJSON, actionReceiptsJSON []byte
-
-	err := DB.QueryRow(query, snapshotID).Scan(
-		&cycle.SnapshotID, &cycle.CycleNumber, &cycle.Timestamp, &cycle.ScoringParamsID,
-		&cycle.InitialVaultValueUSD, &cycle.InitialLiquidUSDC, &initialPositionsJSON,
-		&targetAllocationsJSON, &actionPlanJSON,
-		&cycle.FinalVaultValueUSD, &cycle.FinalLiquidUSDC, &finalPositionsJSON,
-		pq.Array(&cycle.TransactionHashes), &actionReceiptsJSON, // Use pq.Array for PostgreSQL array
-		&cycle.AllocationEfficiencyPercent, &cycle.NetReturnUSD, &cycle.TotalSlippageUSD, &cycle.TotalGasFeeUSD,
-	)
-
+	cycle, err := s.scanCycleSnapshot(s.db.QueryRow(query, snapshotID))
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, fmt.Errorf("cycle with ID %d not found", snapshotID)
@@ -173,22 +260,12 @@ func GetCycleByID(snapshotID int64) (*types.CycleSnapshot, error) {
 		return nil, fmt.Errorf("failed to query cycle by ID: %w", err)
 	}
 
-	// Unmarshal JSON fields
-	if err := unmarshalJSONFields(&cycle, initialPositionsJSON, targetAllocationsJSON, actionPlanJSON, finalPositionsJSON, actionReceiptsJSON); err != nil {
-		log.Error().Err(err).Int64("snapshot_id", snapshotID).Msg("Failed to unmarshal JSON fields for cycle")
-		return nil, fmt.Errorf("failed to unmarshal JSON fields: %w", err)
-	}
-
 	log.Info().Int64("snapshot_id", snapshotID).Int("cycle_number", cycle.CycleNumber).Msg("Retrieved cycle by ID")
-	return &cycle, nil
+	return cycle, nil
 }
 
 // GetVaultSummary retrieves high-level vault statistics
-func GetVaultSummary() (*VaultSummary, error) {
-	if DB == nil {
-		return nil, fmt.Errorf("database not initialized")
-	}
-
+func (s *sqlStore) GetVaultSummary() (*VaultSummary, error) {
 	summary := &VaultSummary{}
 
 	// Get latest vault value and liquid USDC from most recent cycle
@@ -203,7 +280,7 @@ func GetVaultSummary() (*VaultSummary, error) {
 	`
 
 	var lastUpdated sql.NullString
-	err := DB.QueryRow(query).Scan(&summary.TotalValue, &summary.LiquidUSDC, &lastUpdated)
+	err := s.db.QueryRow(query).Scan(&summary.TotalValue, &summary.LiquidUSDC, &lastUpdated)
 	if err != nil && err != sql.ErrNoRows {
 		return nil, fmt.Errorf("failed to get latest vault values: %w", err)
 	}
@@ -213,7 +290,7 @@ func GetVaultSummary() (*VaultSummary, error) {
 	}
 
 	// Get total cycle count
-	err = DB.QueryRow("SELECT COUNT(*) FROM cycle_snapshots").Scan(&summary.TotalCycles)
+	err = s.db.QueryRow("SELECT COUNT(*) FROM cycle_snapshots").Scan(&summary.TotalCycles)
 	if err != nil {
 		log.Error().Err(err).Msg("Failed to get total cycle count")
 	}
@@ -227,11 +304,7 @@ func GetVaultSummary() (*VaultSummary, error) {
 }
 
 // GetPerformanceMetrics retrieves aggregated performance metrics
-func GetPerformanceMetrics() (*PerformanceMetrics, error) {
-	if DB == nil {
-		return nil, fmt.Errorf("database not initialized")
-	}
-
+func (s *sqlStore) GetPerformanceMetrics() (*PerformanceMetrics, error) {
 	metrics := &PerformanceMetrics{}
 
 	// Get aggregated metrics from all cycles
@@ -246,7 +319,7 @@ func GetPerformanceMetrics() (*PerformanceMetrics, error) {
 		FROM cycle_snapshots
 	`
 
-	err := DB.QueryRow(query).Scan(
+	err := s.db.QueryRow(query).Scan(
 		&metrics.TotalReturn,
 		&metrics.TotalGasFees,
 		&metrics.TotalSlippage,
@@ -259,6 +332,11 @@ func GetPerformanceMetrics() (*PerformanceMetrics, error) {
 		return nil, fmt.Errorf("failed to get performance metrics: %w", err)
 	}
 
+	metrics.NAV, err = s.GetLatestVaultPerformance()
+	if err != nil {
+		return nil, err
+	}
+
 	log.Info().
 		Float64("totalReturn", metrics.TotalReturn).
 		Float64("totalGasFees", metrics.TotalGasFees).
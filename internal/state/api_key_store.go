@@ -0,0 +1,196 @@
+/*
+
+This file manages the web API's keys. Only the hash of each key is stored; see internal/auth.
+Creating and revoking a key are recorded in the audit log in the same transaction. Revoked keys
+are kept, so their names stay taken and the audit log's actors stay unambiguous.
+
+*/
+
+package state
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/elys-network/avm/internal/types"
+	"github.com/rs/zerolog/log"
+)
+
+var (
+	// ErrAPIKeyNotFound is returned when a named API key does not exist.
+	ErrAPIKeyNotFound = errors.New("API key not found")
+	// ErrAPIKeyExists is returned when an API key name is already taken, even by a revoked key.
+	ErrAPIKeyExists = errors.New("API key name already exists")
+	// ErrAPIKeyRevoked is returned when revoking a key that is already revoked.
+	ErrAPIKeyRevoked = errors.New("API key already revoked")
+)
+
+const apiKeyColumns = `key_id, name, role, key_prefix, created_at, created_by, last_used_at, revoked_at`
+
+// scanAPIKey scans a row selected with apiKeyColumns
+func scanAPIKey(row rowScanner) (*types.APIKey, error) {
+	var key types.APIKey
+	var role string
+	var lastUsedAt, revokedAt sql.NullTime
+	if err := row.Scan(&key.ID, &key.Name, &role, &key.Prefix, &key.CreatedAt, &key.CreatedBy, &lastUsedAt, &revokedAt); err != nil {
+		return nil, err
+	}
+	key.Role = types.APIRole(role)
+	key.CreatedAt = key.CreatedAt.UTC()
+	if lastUsedAt.Valid {
+		t := lastUsedAt.Time.UTC()
+		key.LastUsedAt = &t
+	}
+	if revokedAt.Valid {
+		t := revokedAt.Time.UTC()
+		key.RevokedAt = &t
+	}
+	return &key, nil
+}
+
+// CreateAPIKey stores a new API key by its hash and records who created it in the audit log
+func (s *sqlStore) CreateAPIKey(name string, role types.APIRole, keyHash, keyPrefix string, audit types.AuditInfo) (*types.APIKey, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback() // No-op after a successful commit
+
+	var exists int
+	err = tx.QueryRow(`SELECT 1 FROM api_keys WHERE name = $1`, name).Scan(&exists)
+	if err == nil {
+		return nil, fmt.Errorf("%w: %s", ErrAPIKeyExists, name)
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("failed to check API key %s: %w", name, err)
+	}
+
+	key, err := scanAPIKey(tx.QueryRow(`
+		INSERT INTO api_keys (name, role, key_prefix, key_hash, created_at, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING `+apiKeyColumns,
+		name, string(role), keyPrefix, keyHash, time.Now().UTC(), audit.Actor,
+	))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create API key %s: %w", name, err)
+	}
+
+	diff, err := json.Marshal(map[string]string{"role": string(role), "prefix": keyPrefix})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode API key details: %w", err)
+	}
+	_, err = insertAuditEvent(tx, types.AuditEvent{
+		Action:    types.AuditActionAPIKeyCreate,
+		Target:    APIKeyAuditTarget(name),
+		AuditInfo: audit,
+		Diff:      diff,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit API key: %w", err)
+	}
+
+	log.Info().Str("name", name).Str("role", string(role)).Msg("Created API key")
+	return key, nil
+}
+
+// GetAPIKeyByHash returns the API key with a hash, revoked or not, or nil if there is none
+func (s *sqlStore) GetAPIKeyByHash(keyHash string) (*types.APIKey, error) {
+	key, err := scanAPIKey(s.db.QueryRow(`SELECT `+apiKeyColumns+` FROM api_keys WHERE key_hash = $1`, keyHash))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up API key: %w", err)
+	}
+	return key, nil
+}
+
+// ListAPIKeys returns every API key, revoked ones included, in order of creation
+func (s *sqlStore) ListAPIKeys() ([]types.APIKey, error) {
+	rows, err := s.db.Query(`SELECT ` + apiKeyColumns + ` FROM api_keys ORDER BY key_id ASC`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query API keys: %w", err)
+	}
+	defer rows.Close()
+
+	keys := make([]types.APIKey, 0)
+	for rows.Next() {
+		key, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan API key: %w", err)
+		}
+		keys = append(keys, *key)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error during API key iteration: %w", err)
+	}
+	return keys, nil
+}
+
+// RevokeAPIKey revokes a named API key and records who revoked it in the audit log.
+// Returns ErrAPIKeyNotFound if no key has that name and ErrAPIKeyRevoked if it is already revoked.
+func (s *sqlStore) RevokeAPIKey(name string, audit types.AuditInfo) (*types.APIKey, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback() // No-op after a successful commit
+
+	key, err := scanAPIKey(tx.QueryRow(`SELECT `+apiKeyColumns+` FROM api_keys WHERE name = $1`, name))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("%w: %s", ErrAPIKeyNotFound, name)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load API key %s: %w", name, err)
+	}
+	if key.RevokedAt != nil {
+		return nil, fmt.Errorf("%w: %s at %s", ErrAPIKeyRevoked, name, key.RevokedAt.Format(time.RFC3339))
+	}
+
+	revokedAt := time.Now().UTC()
+	if _, err := tx.Exec(`UPDATE api_keys SET revoked_at = $2 WHERE key_id = $1`, key.ID, revokedAt); err != nil {
+		return nil, fmt.Errorf("failed to revoke API key %s: %w", name, err)
+	}
+	key.RevokedAt = &revokedAt
+
+	diff, err := json.Marshal(map[string]string{"role": string(key.Role), "prefix": key.Prefix})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode API key details: %w", err)
+	}
+	_, err = insertAuditEvent(tx, types.AuditEvent{
+		Action:    types.AuditActionAPIKeyRevoke,
+		Target:    APIKeyAuditTarget(name),
+		AuditInfo: audit,
+		Diff:      diff,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit API key revocation: %w", err)
+	}
+
+	log.Info().Str("name", name).Msg("Revoked API key")
+	return key, nil
+}
+
+// TouchAPIKey records when an API key was last used
+func (s *sqlStore) TouchAPIKey(keyID int64, usedAt time.Time) error {
+	if _, err := s.db.Exec(`UPDATE api_keys SET last_used_at = $2 WHERE key_id = $1`, keyID, usedAt.UTC()); err != nil {
+		return fmt.Errorf("failed to record use of API key %d: %w", keyID, err)
+	}
+	return nil
+}
+
+// APIKeyAuditTarget names an API key in the audit log
+func APIKeyAuditTarget(name string) string {
+	return "api_keys/" + name
+}
@@ -0,0 +1,166 @@
+/*
+
+This file manages the audit log: the append-only record of parameter changes and operator actions.
+
+Changes made through the Store, such as saving or activating scoring parameters, write their event
+in the same transaction as the change, so neither can be stored without the other. Actions outside
+the database record theirs with RecordAuditEvent.
+
+*/
+
+package state
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/elys-network/avm/internal/types"
+	"github.com/rs/zerolog/log"
+)
+
+// ErrAuditActorRequired is returned when a change is made without saying who made it.
+var ErrAuditActorRequired = errors.New("an actor is required for audited changes")
+
+// defaultAuditEventsLimit caps GetAuditEvents when the filter sets no limit
+const defaultAuditEventsLimit = 100
+
+// queryRower is implemented by *sql.DB and *sql.Tx
+type queryRower interface {
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+// RecordAuditEvent appends an event to the audit log and returns its ID. The timestamp defaults to now.
+func (s *sqlStore) RecordAuditEvent(event types.AuditEvent) (int64, error) {
+	return insertAuditEvent(s.db, event)
+}
+
+// GetAuditEvents returns the audit events matching a filter, newest first
+func (s *sqlStore) GetAuditEvents(filter types.AuditEventFilter) ([]types.AuditEvent, error) {
+	conditions := make([]string, 0)
+	args := make([]interface{}, 0)
+	addCondition := func(condition string, arg interface{}) {
+		args = append(args, arg)
+		conditions = append(conditions, fmt.Sprintf(condition, len(args)))
+	}
+	if !filter.From.IsZero() {
+		addCondition("recorded_at >= $%d", filter.From.UTC())
+	}
+	if !filter.To.IsZero() {
+		addCondition("recorded_at <= $%d", filter.To.UTC())
+	}
+	if filter.Action != "" {
+		addCondition("action = $%d", string(filter.Action))
+	}
+	if filter.Actor != "" {
+		addCondition("actor = $%d", filter.Actor)
+	}
+	if filter.Target != "" {
+		// A target matches itself and everything below it, e.g. "scoring_parameters/default"
+		addCondition("(target = $%[1]d OR target LIKE $%[1]d || '/%%')", filter.Target)
+	}
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = defaultAuditEventsLimit
+	}
+
+	query := `SELECT event_id, recorded_at, action, target, actor, reason, diff FROM audit_events`
+	if len(conditions) > 0 {
+		query += ` WHERE ` + strings.Join(conditions, " AND ")
+	}
+	args = append(args, limit)
+	query += fmt.Sprintf(` ORDER BY recorded_at DESC, event_id DESC LIMIT $%d`, len(args))
+
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query audit events: %w", err)
+	}
+	defer rows.Close()
+
+	events := make([]types.AuditEvent, 0)
+	for rows.Next() {
+		var event types.AuditEvent
+		var action string
+		var diff []byte
+		if err := rows.Scan(&event.ID, &event.Timestamp, &action, &event.Target, &event.Actor, &event.Reason, &diff); err != nil {
+			return nil, fmt.Errorf("failed to scan audit event: %w", err)
+		}
+		event.Action = types.AuditAction(action)
+		event.Timestamp = event.Timestamp.UTC()
+		if len(diff) > 0 {
+			event.Diff = json.RawMessage(diff)
+		}
+		events = append(events, event)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error during audit event iteration: %w", err)
+	}
+	return events, nil
+}
+
+// insertAuditEvent appends an event to the audit log through q, a database or a transaction
+func insertAuditEvent(q queryRower, event types.AuditEvent) (int64, error) {
+	if strings.TrimSpace(event.Actor) == "" {
+		return 0, fmt.Errorf("%w: %s %s", ErrAuditActorRequired, event.Action, event.Target)
+	}
+	if event.Timestamp.IsZero() {
+		event.Timestamp = time.Now()
+	}
+	var diff interface{}
+	if len(event.Diff) > 0 {
+		diff = []byte(event.Diff)
+	}
+
+	var eventID int64
+	err := q.QueryRow(`
+		INSERT INTO audit_events (recorded_at, action, target, actor, reason, diff)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING event_id
+	`, event.Timestamp.UTC(), string(event.Action), event.Target, event.Actor, event.Reason, diff).Scan(&eventID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to record audit event %s %s: %w", event.Action, event.Target, err)
+	}
+
+	log.Info().
+		Int64("eventID", eventID).
+		Str("action", string(event.Action)).
+		Str("target", event.Target).
+		Str("actor", event.Actor).
+		Str("reason", event.Reason).
+		Msg("Recorded audit event")
+	return eventID, nil
+}
+
+// recordScoringParametersAuditEvent records a change of a config's scoring parameters within tx,
+// with the parameters that differ from fromParamsID, or from zero if it is nil, as the diff
+func recordScoringParametersAuditEvent(tx *sql.Tx, action types.AuditAction, audit types.AuditInfo, configName string, version int, fromParamsID *int64, to types.ScoringParameters) error {
+	var from types.ScoringParameters
+	if fromParamsID != nil {
+		v, err := scanScoringParametersVersion(tx.QueryRow(`SELECT `+scoringParametersVersionColumns+`
+			FROM scoring_parameters WHERE params_id = $1`, *fromParamsID))
+		if err != nil {
+			return fmt.Errorf("failed to load params_id %d for the audit log: %w", *fromParamsID, err)
+		}
+		from = v.Parameters
+	}
+
+	diff, err := json.Marshal(types.DiffScoringParameters(from, to))
+	if err != nil {
+		return fmt.Errorf("failed to encode scoring parameters diff: %w", err)
+	}
+	_, err = insertAuditEvent(tx, types.AuditEvent{
+		Action:    action,
+		Target:    ScoringParametersAuditTarget(configName, version),
+		AuditInfo: audit,
+		Diff:      diff,
+	})
+	return err
+}
+
+// ScoringParametersAuditTarget names a version of a config's scoring parameters in the audit log
+func ScoringParametersAuditTarget(configName string, version int) string {
+	return fmt.Sprintf("scoring_parameters/%s/v%d", configName, version)
+}
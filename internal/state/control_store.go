@@ -0,0 +1,95 @@
+/*
+
+This file persists the AVM's operator control mode (see types.ControlState), so a pause or an
+emergency stop survives restarts. Each change is recorded in the audit log in the same transaction.
+
+*/
+
+package state
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/elys-network/avm/internal/types"
+	"github.com/rs/zerolog/log"
+)
+
+// ControlAuditTarget names the AVM's control mode in the audit log
+const ControlAuditTarget = "avm/control"
+
+// LoadControlState returns the persisted control mode, or the running mode if it was never changed
+func (s *sqlStore) LoadControlState() (*types.ControlState, error) {
+	var controlState types.ControlState
+	var mode string
+	err := s.db.QueryRow(`SELECT mode, updated_at, updated_by, reason FROM avm_control_state WHERE id = 1`).
+		Scan(&mode, &controlState.UpdatedAt, &controlState.Actor, &controlState.Reason)
+	if errors.Is(err, sql.ErrNoRows) {
+		return &types.ControlState{Mode: types.ControlModeRunning}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load control state: %w", err)
+	}
+	controlState.Mode = types.ControlMode(mode)
+	controlState.UpdatedAt = controlState.UpdatedAt.UTC()
+	return &controlState, nil
+}
+
+// SaveControlState persists a new control mode and records the change as action in the audit log
+func (s *sqlStore) SaveControlState(mode types.ControlMode, action types.AuditAction, audit types.AuditInfo) (*types.ControlState, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback() // No-op after a successful commit
+
+	previousMode := types.ControlModeRunning
+	var storedMode string
+	err = tx.QueryRow(`SELECT mode FROM avm_control_state WHERE id = 1`).Scan(&storedMode)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("failed to load control state: %w", err)
+	}
+	if err == nil {
+		previousMode = types.ControlMode(storedMode)
+	}
+
+	controlState := types.ControlState{Mode: mode, UpdatedAt: time.Now().UTC(), AuditInfo: audit}
+	// The audit event is written first, so a change without an actor is rejected before it is stored
+	diff, err := json.Marshal(map[string]types.ControlMode{"from": previousMode, "to": mode})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode control mode change: %w", err)
+	}
+	_, err = insertAuditEvent(tx, types.AuditEvent{
+		Timestamp: controlState.UpdatedAt,
+		Action:    action,
+		Target:    ControlAuditTarget,
+		AuditInfo: audit,
+		Diff:      diff,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = tx.Exec(`
+		INSERT INTO avm_control_state (id, mode, updated_at, updated_by, reason)
+		VALUES (1, $1, $2, $3, $4)
+		ON CONFLICT (id) DO UPDATE SET
+			mode = excluded.mode,
+			updated_at = excluded.updated_at,
+			updated_by = excluded.updated_by,
+			reason = excluded.reason
+	`, string(mode), controlState.UpdatedAt, audit.Actor, audit.Reason)
+	if err != nil {
+		return nil, fmt.Errorf("failed to save control state: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit control state: %w", err)
+	}
+
+	log.Info().Str("from", string(previousMode)).Str("to", string(mode)).Str("actor", audit.Actor).Msg("Saved control state")
+	return &controlState, nil
+}
@@ -15,11 +15,7 @@ import (
 )
 
 // ensureCycleCounterTable creates the cycle_counter table if it doesn't exist
-func ensureCycleCounterTable() error {
-	if DB == nil {
-		return fmt.Errorf("database not initialized")
-	}
-
+func (s *sqlStore) ensureCycleCounterTable() error {
 	createTableSQL := `
 		CREATE TABLE IF NOT EXISTS cycle_counter (
 			id INTEGER PRIMARY KEY DEFAULT 1,
@@ -34,7 +30,7 @@ func ensureCycleCounterTable() error {
 		ON CONFLICT (id) DO NOTHING;
 	`
 
-	_, err := DB.Exec(createTableSQL)
+	_, err := s.db.Exec(createTableSQL)
 	if err != nil {
 		return fmt.Errorf("failed to create cycle_counter table: %w", err)
 	}
@@ -44,20 +40,16 @@ func ensureCycleCounterTable() error {
 }
 
 // GetCurrentCycleNumber retrieves the current cycle number from the database
-func GetCurrentCycleNumber() (int, error) {
-	if DB == nil {
-		return 0, fmt.Errorf("database not initialized")
-	}
-
+func (s *sqlStore) GetCurrentCycleNumber() (int, error) {
 	// Ensure the table exists
-	if err := ensureCycleCounterTable(); err != nil {
+	if err := s.ensureCycleCounterTable(); err != nil {
 		return 0, err
 	}
 
 	query := `SELECT current_cycle FROM cycle_counter WHERE id = 1;`
 	
 	var currentCycle int
-	row := DB.QueryRow(query)
+	row := s.db.QueryRow(query)
 	err := row.Scan(&currentCycle)
 
 	if err != nil {
@@ -74,13 +66,9 @@ func GetCurrentCycleNumber() (int, error) {
 }
 
 // IncrementCycleNumber increments the cycle counter and returns the new value
-func IncrementCycleNumber() (int, error) {
-	if DB == nil {
-		return 0, fmt.Errorf("database not initialized")
-	}
-
+func (s *sqlStore) IncrementCycleNumber() (int, error) {
 	// Ensure the table exists
-	if err := ensureCycleCounterTable(); err != nil {
+	if err := s.ensureCycleCounterTable(); err != nil {
 		return 0, err
 	}
 
@@ -92,7 +80,7 @@ func IncrementCycleNumber() (int, error) {
 		RETURNING current_cycle;`
 
 	var newCycle int
-	row := DB.QueryRow(updateQuery)
+	row := s.db.QueryRow(updateQuery)
 	err := row.Scan(&newCycle)
 
 	if err != nil {
@@ -104,13 +92,9 @@ func IncrementCycleNumber() (int, error) {
 }
 
 // ResetCycleNumber resets the cycle counter to a specific value (for testing/maintenance)
-func ResetCycleNumber(cycleNumber int) error {
-	if DB == nil {
-		return fmt.Errorf("database not initialized")
-	}
-
+func (s *sqlStore) ResetCycleNumber(cycleNumber int) error {
 	// Ensure the table exists
-	if err := ensureCycleCounterTable(); err != nil {
+	if err := s.ensureCycleCounterTable(); err != nil {
 		return err
 	}
 
@@ -124,7 +108,7 @@ func ResetCycleNumber(cycleNumber int) error {
 		    updated_at = CURRENT_TIMESTAMP 
 		WHERE id = 1;`
 
-	result, err := DB.Exec(updateQuery, cycleNumber)
+	result, err := s.db.Exec(updateQuery, cycleNumber)
 	if err != nil {
 		return fmt.Errorf("failed to reset cycle number to %d: %w", cycleNumber, err)
 	}
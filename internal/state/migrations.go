@@ -0,0 +1,407 @@
+/*
+
+This file applies the versioned schema migrations in the migrations directory.
+
+Each migration is a pair of files, NNNN_name.up.sql and NNNN_name.down.sql, numbered from 1
+without gaps. A migration is applied in one transaction together with its row in
+schema_migrations, which records the SHA-256 checksum of the up file. Applied migrations must
+never be edited: a changed checksum stops the AVM from starting, so schema changes always go
+into a new migration.
+
+*/
+
+package state
+
+import (
+	"crypto/sha256"
+	"embed"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+var (
+	// ErrSchemaTooNew is returned when the database has migrations applied that this build does not know.
+	ErrSchemaTooNew = errors.New("database schema is newer than this build")
+	// ErrMigrationModified is returned when an applied migration's file no longer matches its recorded checksum.
+	ErrMigrationModified = errors.New("applied migration has been modified")
+)
+
+// migrationLockID is the Postgres advisory lock key held while a migration is applied or rolled back,
+// so two processes never migrate at the same time
+const migrationLockID int64 = 0x61766d5f6d6967 // "avm_mig"
+
+var migrationFilePattern = regexp.MustCompile(`^(\d{4})_([a-z0-9_]+)\.(up|down)\.sql$`)
+
+// migration is one numbered schema migration
+type migration struct {
+	Version  int
+	Name     string
+	Up       string
+	Down     string
+	Checksum string // SHA-256 of Up, hex encoded
+}
+
+// appliedMigration is a row of schema_migrations
+type appliedMigration struct {
+	Version   int
+	Name      string
+	Checksum  string
+	AppliedAt time.Time
+}
+
+// MigrationStatus describes one migration, known to this build or applied to the database
+type MigrationStatus struct {
+	Version   int        `json:"version"`
+	Name      string     `json:"name"`
+	Applied   bool       `json:"applied"`
+	AppliedAt *time.Time `json:"applied_at,omitempty"`
+	Modified  bool       `json:"modified"` // Applied, but the file no longer matches the recorded checksum
+	Unknown   bool       `json:"unknown"`  // Applied by a newer build; this build has no such migration
+}
+
+// loadMigrations reads the embedded migration files, ordered by version
+func loadMigrations() ([]migration, error) {
+	entries, err := fs.ReadDir(migrationFiles, "migrations")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read migrations: %w", err)
+	}
+
+	byVersion := make(map[int]*migration)
+	for _, entry := range entries {
+		matches := migrationFilePattern.FindStringSubmatch(entry.Name())
+		if matches == nil {
+			return nil, fmt.Errorf("migration file %s does not match NNNN_name.up.sql or NNNN_name.down.sql", entry.Name())
+		}
+		version, _ := strconv.Atoi(matches[1])
+		name, direction := matches[2], matches[3]
+
+		content, err := migrationFiles.ReadFile(path.Join("migrations", entry.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read migration %s: %w", entry.Name(), err)
+		}
+
+		m, exists := byVersion[version]
+		if !exists {
+			m = &migration{Version: version, Name: name}
+			byVersion[version] = m
+		}
+		if m.Name != name {
+			return nil, fmt.Errorf("migration %04d has files with different names: %s and %s", version, m.Name, name)
+		}
+		if direction == "up" {
+			m.Up = string(content)
+			hash := sha256.Sum256(content)
+			m.Checksum = hex.EncodeToString(hash[:])
+		} else {
+			m.Down = string(content)
+		}
+	}
+
+	migrations := make([]migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" || m.Down == "" {
+			return nil, fmt.Errorf("migration %04d_%s needs both an up and a down file", m.Version, m.Name)
+		}
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+	for i, m := range migrations {
+		if m.Version != i+1 {
+			return nil, fmt.Errorf("migrations must be numbered from 1 without gaps, found %04d at position %d", m.Version, i+1)
+		}
+	}
+
+	return migrations, nil
+}
+
+// ensureMigrationsTable creates the schema_migrations table if it does not exist
+func ensureMigrationsTable() error {
+	_, err := DB.Exec(`
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			version INTEGER PRIMARY KEY,
+			name TEXT NOT NULL,
+			checksum TEXT NOT NULL,
+			applied_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+		);
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create schema_migrations table: %w", err)
+	}
+	return nil
+}
+
+// loadAppliedMigrations returns the applied migrations, ordered by version
+func loadAppliedMigrations() ([]appliedMigration, error) {
+	rows, err := DB.Query(`SELECT version, name, checksum, applied_at FROM schema_migrations ORDER BY version ASC`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query schema_migrations: %w", err)
+	}
+	defer rows.Close()
+
+	applied := make([]appliedMigration, 0)
+	for rows.Next() {
+		var a appliedMigration
+		if err := rows.Scan(&a.Version, &a.Name, &a.Checksum, &a.AppliedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan schema_migrations row: %w", err)
+		}
+		applied = append(applied, a)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error during schema_migrations iteration: %w", err)
+	}
+
+	return applied, nil
+}
+
+// checkApplied verifies that every applied migration is known to this build and unchanged
+func checkApplied(migrations []migration, applied []appliedMigration) error {
+	for _, a := range applied {
+		if a.Version > len(migrations) {
+			return fmt.Errorf("%w: migration %04d_%s is applied, but this build only knows migrations up to %04d",
+				ErrSchemaTooNew, a.Version, a.Name, len(migrations))
+		}
+		m := migrations[a.Version-1]
+		if a.Checksum != m.Checksum {
+			return fmt.Errorf("%w: %04d_%s was applied with checksum %s, but the file now has %s",
+				ErrMigrationModified, m.Version, m.Name, a.Checksum, m.Checksum)
+		}
+	}
+	return nil
+}
+
+// CheckSchemaCompatibility returns ErrSchemaTooNew if the database has migrations applied that this
+// build does not know, and ErrMigrationModified if an applied migration's file has changed since.
+func CheckSchemaCompatibility() error {
+	if DB == nil {
+		return fmt.Errorf("database not initialized")
+	}
+
+	migrations, err := loadMigrations()
+	if err != nil {
+		return err
+	}
+	if err := ensureMigrationsTable(); err != nil {
+		return err
+	}
+	applied, err := loadAppliedMigrations()
+	if err != nil {
+		return err
+	}
+	return checkApplied(migrations, applied)
+}
+
+// MigrateUp applies every pending migration in order, each in its own transaction, and returns
+// how many were applied.
+func MigrateUp() (int, error) {
+	if DB == nil {
+		return 0, fmt.Errorf("database not initialized")
+	}
+
+	migrations, err := loadMigrations()
+	if err != nil {
+		return 0, err
+	}
+	if err := ensureMigrationsTable(); err != nil {
+		return 0, err
+	}
+	applied, err := loadAppliedMigrations()
+	if err != nil {
+		return 0, err
+	}
+	if err := checkApplied(migrations, applied); err != nil {
+		return 0, err
+	}
+
+	isApplied := make(map[int]bool, len(applied))
+	for _, a := range applied {
+		isApplied[a.Version] = true
+	}
+
+	count := 0
+	for _, m := range migrations {
+		if isApplied[m.Version] {
+			continue
+		}
+		ran, err := applyMigration(m)
+		if err != nil {
+			return count, err
+		}
+		if ran {
+			count++
+		}
+	}
+
+	return count, nil
+}
+
+// applyMigration runs a migration's up file and records it. It reports false if another process
+// applied the migration first.
+func applyMigration(m migration) (bool, error) {
+	tx, err := DB.Begin()
+	if err != nil {
+		return false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback() // No-op after a successful commit
+
+	if _, err := tx.Exec(`SELECT pg_advisory_xact_lock($1)`, migrationLockID); err != nil {
+		return false, fmt.Errorf("failed to acquire migration lock: %w", err)
+	}
+
+	var exists bool
+	if err := tx.QueryRow(`SELECT EXISTS (SELECT 1 FROM schema_migrations WHERE version = $1)`, m.Version).Scan(&exists); err != nil {
+		return false, fmt.Errorf("failed to check migration %04d: %w", m.Version, err)
+	}
+	if exists {
+		return false, nil
+	}
+
+	if _, err := tx.Exec(m.Up); err != nil {
+		return false, fmt.Errorf("failed to apply migration %04d_%s: %w", m.Version, m.Name, err)
+	}
+	if _, err := tx.Exec(`INSERT INTO schema_migrations (version, name, checksum) VALUES ($1, $2, $3)`,
+		m.Version, m.Name, m.Checksum); err != nil {
+		return false, fmt.Errorf("failed to record migration %04d_%s: %w", m.Version, m.Name, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, fmt.Errorf("failed to commit migration %04d_%s: %w", m.Version, m.Name, err)
+	}
+
+	log.Info().Int("version", m.Version).Str("name", m.Name).Msg("Applied database migration")
+	return true, nil
+}
+
+// MigrateDown rolls back the latest steps applied migrations, newest first, each in its own
+// transaction, and returns how many were rolled back.
+func MigrateDown(steps int) (int, error) {
+	if DB == nil {
+		return 0, fmt.Errorf("database not initialized")
+	}
+	if steps <= 0 {
+		return 0, fmt.Errorf("steps must be positive, got %d", steps)
+	}
+
+	migrations, err := loadMigrations()
+	if err != nil {
+		return 0, err
+	}
+	if err := ensureMigrationsTable(); err != nil {
+		return 0, err
+	}
+	applied, err := loadAppliedMigrations()
+	if err != nil {
+		return 0, err
+	}
+	// A migration from a newer build cannot be rolled back without its down file
+	if err := checkApplied(migrations, applied); err != nil {
+		return 0, err
+	}
+
+	count := 0
+	for i := len(applied) - 1; i >= 0 && count < steps; i-- {
+		m := migrations[applied[i].Version-1]
+		if err := rollbackMigration(m); err != nil {
+			return count, err
+		}
+		count++
+	}
+
+	return count, nil
+}
+
+// rollbackMigration runs a migration's down file and removes its record
+func rollbackMigration(m migration) error {
+	tx, err := DB.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback() // No-op after a successful commit
+
+	if _, err := tx.Exec(`SELECT pg_advisory_xact_lock($1)`, migrationLockID); err != nil {
+		return fmt.Errorf("failed to acquire migration lock: %w", err)
+	}
+
+	result, err := tx.Exec(`DELETE FROM schema_migrations WHERE version = $1`, m.Version)
+	if err != nil {
+		return fmt.Errorf("failed to remove record of migration %04d_%s: %w", m.Version, m.Name, err)
+	}
+	if deleted, _ := result.RowsAffected(); deleted == 0 {
+		return fmt.Errorf("migration %04d_%s was rolled back by another process", m.Version, m.Name)
+	}
+
+	if _, err := tx.Exec(m.Down); err != nil {
+		return fmt.Errorf("failed to roll back migration %04d_%s: %w", m.Version, m.Name, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit rollback of migration %04d_%s: %w", m.Version, m.Name, err)
+	}
+
+	log.Info().Int("version", m.Version).Str("name", m.Name).Msg("Rolled back database migration")
+	return nil
+}
+
+// GetMigrationStatus lists every migration known to this build, followed by any applied migrations
+// it does not know, with whether and when each was applied.
+func GetMigrationStatus() ([]MigrationStatus, error) {
+	if DB == nil {
+		return nil, fmt.Errorf("database not initialized")
+	}
+
+	migrations, err := loadMigrations()
+	if err != nil {
+		return nil, err
+	}
+	if err := ensureMigrationsTable(); err != nil {
+		return nil, err
+	}
+	applied, err := loadAppliedMigrations()
+	if err != nil {
+		return nil, err
+	}
+
+	appliedByVersion := make(map[int]appliedMigration, len(applied))
+	for _, a := range applied {
+		appliedByVersion[a.Version] = a
+	}
+
+	statuses := make([]MigrationStatus, 0, len(migrations))
+	for _, m := range migrations {
+		status := MigrationStatus{Version: m.Version, Name: m.Name}
+		if a, ok := appliedByVersion[m.Version]; ok {
+			appliedAt := a.AppliedAt
+			status.Applied = true
+			status.AppliedAt = &appliedAt
+			status.Modified = a.Checksum != m.Checksum
+		}
+		statuses = append(statuses, status)
+	}
+	for _, a := range applied {
+		if a.Version > len(migrations) {
+			appliedAt := a.AppliedAt
+			statuses = append(statuses, MigrationStatus{
+				Version:   a.Version,
+				Name:      a.Name,
+				Applied:   true,
+				AppliedAt: &appliedAt,
+				Unknown:   true,
+			})
+		}
+	}
+
+	return statuses, nil
+}
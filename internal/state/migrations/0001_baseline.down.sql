@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS cycle_counter;
+DROP TABLE IF EXISTS cycle_snapshots;
+DROP TABLE IF EXISTS action_receipts;
+DROP TABLE IF EXISTS scoring_parameters;
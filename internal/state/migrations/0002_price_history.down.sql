@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS token_symbol_mappings;
+DROP TABLE IF EXISTS price_history;
@@ -0,0 +1,40 @@
+-- Hourly price history cache (CryptoCompare close prices)
+CREATE TABLE IF NOT EXISTS price_history (
+	symbol TEXT NOT NULL,
+	bar_time TIMESTAMPTZ NOT NULL,
+	close_price DECIMAL(30, 18) NOT NULL,
+	source TEXT NOT NULL DEFAULT 'cryptocompare', -- Provider that supplied the bar
+	fetched_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	PRIMARY KEY (symbol, bar_time)
+);
+ALTER TABLE price_history ADD COLUMN IF NOT EXISTS source TEXT NOT NULL DEFAULT 'cryptocompare';
+
+-- Per-provider symbol for each token (tokens without a row use their own symbol)
+CREATE TABLE IF NOT EXISTS token_symbol_mappings (
+	token_symbol TEXT NOT NULL,
+	provider TEXT NOT NULL,
+	provider_symbol TEXT NOT NULL,
+	updated_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	PRIMARY KEY (token_symbol, provider)
+);
+
+-- Seed known mappings; existing rows are never overwritten
+INSERT INTO token_symbol_mappings (token_symbol, provider, provider_symbol) VALUES
+	('WETH', 'cryptocompare', 'ETH'),
+	('ATOM', 'coingecko', 'cosmos'),
+	('OSMO', 'coingecko', 'osmosis'),
+	('TIA', 'coingecko', 'celestia'),
+	('USDC', 'coingecko', 'usd-coin'),
+	('USDT', 'coingecko', 'tether'),
+	('WBTC', 'coingecko', 'wrapped-bitcoin'),
+	('WETH', 'coingecko', 'weth'),
+	('PAXG', 'coingecko', 'pax-gold'),
+	('STARS', 'coingecko', 'stargaze'),
+	('KAVA', 'coingecko', 'kava'),
+	('STRD', 'coingecko', 'stride'),
+	('AKT', 'coingecko', 'akash-network'),
+	('BLD', 'coingecko', 'agoric'),
+	('SCRT', 'coingecko', 'secret'),
+	('FET', 'coingecko', 'fetch-ai'),
+	('ELYS', 'coingecko', 'elys-network')
+ON CONFLICT (token_symbol, provider) DO NOTHING;
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS swap_volume_indexer_state;
+DROP TABLE IF EXISTS pool_swap_volume_daily;
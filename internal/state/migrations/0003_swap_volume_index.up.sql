@@ -0,0 +1,21 @@
+-- Daily USD swap volume per pool, aggregated from on-chain AMM swap events
+CREATE TABLE IF NOT EXISTS pool_swap_volume_daily (
+	pool_id BIGINT NOT NULL,
+	day DATE NOT NULL,
+	volume_usd DECIMAL(30, 8) NOT NULL DEFAULT 0,
+	swap_count INTEGER NOT NULL DEFAULT 0,
+	updated_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	PRIMARY KEY (pool_id, day)
+);
+CREATE INDEX IF NOT EXISTS idx_pool_swap_volume_daily_day ON pool_swap_volume_daily(day DESC);
+
+-- Progress of the swap volume indexer (single row)
+CREATE TABLE IF NOT EXISTS swap_volume_indexer_state (
+	id INTEGER PRIMARY KEY DEFAULT 1,
+	first_height BIGINT NOT NULL,
+	first_block_time TIMESTAMPTZ NOT NULL,
+	last_height BIGINT NOT NULL,
+	last_block_time TIMESTAMPTZ NOT NULL,
+	updated_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	CONSTRAINT swap_volume_indexer_single_row CHECK (id = 1)
+);
@@ -0,0 +1,22 @@
+-- Per-pool metrics time series: one raw point per pool each cycle, downsampled to
+-- hourly and daily points as it ages (see internal/state/pool_metrics_store.go)
+CREATE TABLE IF NOT EXISTS pool_metrics (
+	pool_id BIGINT NOT NULL,
+	resolution TEXT NOT NULL, -- 'raw', 'hourly' or 'daily'
+	recorded_at TIMESTAMPTZ NOT NULL, -- Cycle start for raw points, bucket start otherwise
+	sample_count INTEGER NOT NULL DEFAULT 1,
+	cycle_number INTEGER NOT NULL,
+	block_height BIGINT NOT NULL,
+	tvl_usd DECIMAL(30, 8) NOT NULL,
+	volume_7d_usd DECIMAL(30, 8) NOT NULL,
+	eden_rewards_apr DECIMAL(20, 10) NOT NULL,
+	usdc_fees_apr DECIMAL(20, 10) NOT NULL,
+	price_impact_apr DECIMAL(20, 10) NOT NULL,
+	total_apr DECIMAL(20, 10) NOT NULL,
+	swap_fee DECIMAL(20, 10) NOT NULL,
+	total_shares NUMERIC NOT NULL,
+	assets JSONB NOT NULL,
+	PRIMARY KEY (pool_id, resolution, recorded_at)
+);
+CREATE INDEX IF NOT EXISTS idx_pool_metrics_pool_time ON pool_metrics(pool_id, recorded_at DESC);
+CREATE INDEX IF NOT EXISTS idx_pool_metrics_resolution_time ON pool_metrics(resolution, recorded_at);
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS scoring_parameter_activations;
+ALTER TABLE scoring_parameters DROP COLUMN IF EXISTS viable_deposit_reduction_factor;
+ALTER TABLE scoring_parameters DROP COLUMN IF EXISTS viable_swap_reduction_factor;
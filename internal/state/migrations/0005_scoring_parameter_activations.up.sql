@@ -0,0 +1,21 @@
+-- Persist the planner's reduction factors, which were previously lost when parameters were saved
+ALTER TABLE scoring_parameters ADD COLUMN IF NOT EXISTS viable_swap_reduction_factor DECIMAL(10, 8) NOT NULL DEFAULT 0.9;
+ALTER TABLE scoring_parameters ADD COLUMN IF NOT EXISTS viable_deposit_reduction_factor DECIMAL(10, 8) NOT NULL DEFAULT 0.8;
+
+-- History of which parameter version was active for each config, so an activation can be rolled back.
+-- previous_params_id is the version a rollback of this activation returns to.
+CREATE TABLE IF NOT EXISTS scoring_parameter_activations (
+	activation_id SERIAL PRIMARY KEY,
+	config_name VARCHAR(255) NOT NULL,
+	params_id INTEGER NOT NULL REFERENCES scoring_parameters(params_id),
+	previous_params_id INTEGER REFERENCES scoring_parameters(params_id),
+	action VARCHAR(20) NOT NULL, -- 'activate' or 'rollback'
+	activated_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_scoring_parameter_activations_config ON scoring_parameter_activations(config_name, activation_id DESC);
+
+-- Start the history with the currently active version of each config
+INSERT INTO scoring_parameter_activations (config_name, params_id, action, activated_at)
+SELECT config_name, params_id, 'activate', activated_at
+FROM scoring_parameters
+WHERE is_active = TRUE;
@@ -0,0 +1,2 @@
+-- Scoring parameters picked up (or rejected) by the AVM at the start of a cycle
+ALTER TABLE cycle_snapshots ADD COLUMN IF NOT EXISTS scoring_params_reload JSONB;
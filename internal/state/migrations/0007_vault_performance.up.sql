@@ -0,0 +1,18 @@
+-- NAV per share time series: one point per cycle with the vault's share supply and the returns
+-- derived from it (see internal/performance). Kept apart from cycle_snapshots so the history
+-- back to inception survives snapshot retention.
+CREATE TABLE IF NOT EXISTS vault_performance (
+	cycle_number INTEGER PRIMARY KEY,
+	recorded_at TIMESTAMPTZ NOT NULL, -- Cycle start
+	block_height BIGINT NOT NULL,
+	vault_value_usd DECIMAL(30, 8) NOT NULL,
+	share_supply NUMERIC NOT NULL,
+	nav_per_share DECIMAL(30, 12) NOT NULL,
+	net_flow_usd DECIMAL(30, 8) NOT NULL,
+	twr_inception DECIMAL(20, 10) NOT NULL,
+	mwr_annualized DECIMAL(20, 10),
+	apy_7d DECIMAL(20, 10),
+	apy_30d DECIMAL(20, 10),
+	apy_inception DECIMAL(20, 10)
+);
+CREATE INDEX IF NOT EXISTS idx_vault_performance_time ON vault_performance(recorded_at);
@@ -0,0 +1,21 @@
+-- Per-pool PnL attribution: one row per pool the vault held or acted on in a cycle
+-- (see internal/attribution)
+CREATE TABLE IF NOT EXISTS pool_pnl_attribution (
+	cycle_number INTEGER NOT NULL,
+	pool_id BIGINT NOT NULL, -- 0 for execution costs not tied to a pool
+	recorded_at TIMESTAMPTZ NOT NULL, -- Cycle start
+	period_seconds DOUBLE PRECISION NOT NULL,
+	start_value_usd DECIMAL(30, 8) NOT NULL,
+	end_value_usd DECIMAL(30, 8) NOT NULL,
+	lp_fees_usd DECIMAL(30, 8) NOT NULL,
+	eden_rewards_usd DECIMAL(30, 8) NOT NULL,
+	usdc_rewards_usd DECIMAL(30, 8) NOT NULL,
+	price_return_usd DECIMAL(30, 8) NOT NULL,
+	impermanent_loss_usd DECIMAL(30, 8) NOT NULL,
+	slippage_usd DECIMAL(30, 8) NOT NULL,
+	gas_usd DECIMAL(30, 8) NOT NULL,
+	total_pnl_usd DECIMAL(30, 8) NOT NULL,
+	market_data_complete BOOLEAN NOT NULL,
+	PRIMARY KEY (cycle_number, pool_id)
+);
+CREATE INDEX IF NOT EXISTS idx_pool_pnl_attribution_time ON pool_pnl_attribution(recorded_at);
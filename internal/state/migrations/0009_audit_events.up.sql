@@ -0,0 +1,29 @@
+-- Append-only audit log of parameter changes and operator actions. Rows can only be inserted:
+-- the trigger below rejects every update, delete and truncate.
+CREATE TABLE IF NOT EXISTS audit_events (
+	event_id BIGSERIAL PRIMARY KEY,
+	recorded_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	action VARCHAR(64) NOT NULL, -- types.AuditAction, e.g. 'params.activate'
+	target VARCHAR(255) NOT NULL, -- What was changed, e.g. 'scoring_parameters/default/v3'
+	actor VARCHAR(255) NOT NULL,
+	reason TEXT NOT NULL,
+	diff JSONB
+);
+CREATE INDEX IF NOT EXISTS idx_audit_events_time ON audit_events(recorded_at DESC);
+CREATE INDEX IF NOT EXISTS idx_audit_events_action ON audit_events(action, recorded_at DESC);
+
+CREATE OR REPLACE FUNCTION audit_events_append_only() RETURNS TRIGGER AS $$
+BEGIN
+	RAISE EXCEPTION 'audit_events is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS audit_events_no_update ON audit_events;
+CREATE TRIGGER audit_events_no_update
+	BEFORE UPDATE OR DELETE ON audit_events
+	FOR EACH ROW EXECUTE FUNCTION audit_events_append_only();
+
+DROP TRIGGER IF EXISTS audit_events_no_truncate ON audit_events;
+CREATE TRIGGER audit_events_no_truncate
+	BEFORE TRUNCATE ON audit_events
+	FOR EACH STATEMENT EXECUTE FUNCTION audit_events_append_only();
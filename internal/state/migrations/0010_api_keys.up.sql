@@ -0,0 +1,12 @@
+-- API keys of the web API. Only the SHA-256 hash of each key is stored (see internal/auth).
+CREATE TABLE IF NOT EXISTS api_keys (
+	key_id SERIAL PRIMARY KEY,
+	name VARCHAR(255) NOT NULL UNIQUE,
+	role VARCHAR(20) NOT NULL, -- 'viewer', 'operator' or 'admin'
+	key_prefix VARCHAR(32) NOT NULL,
+	key_hash CHAR(64) NOT NULL UNIQUE,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	created_by VARCHAR(255) NOT NULL,
+	last_used_at TIMESTAMPTZ,
+	revoked_at TIMESTAMPTZ
+);
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS avm_control_state;
@@ -0,0 +1,9 @@
+-- Operator control mode of the AVM (running, paused or emergency_stopped), kept across restarts.
+-- A single row; the changes themselves are in audit_events.
+CREATE TABLE IF NOT EXISTS avm_control_state (
+	id SMALLINT PRIMARY KEY CHECK (id = 1),
+	mode VARCHAR(32) NOT NULL,
+	updated_at TIMESTAMPTZ NOT NULL,
+	updated_by VARCHAR(255) NOT NULL,
+	reason TEXT NOT NULL
+);
@@ -0,0 +1 @@
+ALTER TABLE cycle_snapshots DROP COLUMN IF EXISTS pool_scores;
@@ -0,0 +1,2 @@
+-- Score and score components of every pool a cycle scored, for comparing cycles
+ALTER TABLE cycle_snapshots ADD COLUMN IF NOT EXISTS pool_scores JSONB;
@@ -0,0 +1,2 @@
+-- Fraction of the vault a cycle left in liquid USDC because the token exposure cap left no pool room for it
+ALTER TABLE cycle_snapshots ADD COLUMN IF NOT EXISTS unallocated_target DECIMAL(10, 8);
@@ -0,0 +1,9 @@
+DELETE FROM token_symbol_mappings WHERE (token_symbol, provider, provider_symbol) IN (
+	('WRAPPED BITCOIN', 'cryptocompare', 'WBTC'),
+	('WRAPPED ETHEREUM', 'cryptocompare', 'ETH'),
+	('XION', 'coingecko', 'xion-2'),
+	('NTRN', 'coingecko', 'neutron-3'),
+	('OM', 'coingecko', 'mantra-dao'),
+	('SAGA', 'coingecko', 'saga-2'),
+	('BABY', 'coingecko', 'babylon')
+);
@@ -0,0 +1,11 @@
+-- Seed the mappings 0002 missed: the testnet CryptoCompare symbols and the CoinGecko IDs of
+-- the remaining tokens. Existing rows are never overwritten.
+INSERT INTO token_symbol_mappings (token_symbol, provider, provider_symbol) VALUES
+	('WRAPPED BITCOIN', 'cryptocompare', 'WBTC'),
+	('WRAPPED ETHEREUM', 'cryptocompare', 'ETH'),
+	('XION', 'coingecko', 'xion-2'),
+	('NTRN', 'coingecko', 'neutron-3'),
+	('OM', 'coingecko', 'mantra-dao'),
+	('SAGA', 'coingecko', 'saga-2'),
+	('BABY', 'coingecko', 'babylon')
+ON CONFLICT (token_symbol, provider) DO NOTHING;
@@ -0,0 +1,7 @@
+-- Copy the cached bars back under the CryptoCompare symbols; the rows keyed by token symbol are kept
+INSERT INTO price_history (symbol, bar_time, close_price, source, fetched_at)
+SELECT m.provider_symbol, p.bar_time, p.close_price, p.source, p.fetched_at
+FROM price_history p
+JOIN token_symbol_mappings m ON m.provider = 'cryptocompare' AND m.token_symbol = p.symbol
+WHERE m.token_symbol <> m.provider_symbol
+ON CONFLICT (symbol, bar_time) DO NOTHING;
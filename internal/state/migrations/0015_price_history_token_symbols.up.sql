@@ -0,0 +1,17 @@
+-- The price history cache was keyed by the CryptoCompare symbol (ETH), and is now keyed by the
+-- token's Elys symbol (WETH). Copy the cached bars to every token mapped to a different
+-- CryptoCompare symbol, then drop those keyed by a symbol no token uses.
+INSERT INTO price_history (symbol, bar_time, close_price, source, fetched_at)
+SELECT m.token_symbol, p.bar_time, p.close_price, p.source, p.fetched_at
+FROM price_history p
+JOIN token_symbol_mappings m ON m.provider = 'cryptocompare' AND m.provider_symbol = p.symbol
+WHERE m.token_symbol <> m.provider_symbol
+ON CONFLICT (symbol, bar_time) DO NOTHING;
+
+DELETE FROM price_history p
+WHERE EXISTS (
+	SELECT 1 FROM token_symbol_mappings m
+	WHERE m.provider = 'cryptocompare' AND m.provider_symbol = p.symbol AND m.token_symbol <> m.provider_symbol
+) AND NOT EXISTS (
+	SELECT 1 FROM token_symbol_mappings m WHERE m.token_symbol = p.symbol
+);
@@ -0,0 +1,345 @@
+/*
+
+This file manages the stored versions of the scoring parameters: listing them, activating a
+version and rolling back to the previously active one.
+
+Every activation is recorded in scoring_parameter_activations together with the version it
+replaced, so repeated rollbacks walk back through the activation history.
+
+*/
+
+package state
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/elys-network/avm/internal/types"
+	"github.com/rs/zerolog/log"
+)
+
+var (
+	// ErrScoringParametersNotFound is returned when a requested parameter version does not exist.
+	ErrScoringParametersNotFound = errors.New("scoring parameters not found")
+	// ErrNothingToRollBack is returned when the active version has no earlier version to return to.
+	ErrNothingToRollBack = errors.New("no previous scoring parameters to roll back to")
+)
+
+const (
+	scoringParametersActionActivate = "activate"
+	scoringParametersActionRollback = "rollback"
+)
+
+const scoringParametersVersionColumns = `
+	params_id, config_name, version, is_active, activated_at, created_at,
+	eden_weight, usdc_fee_weight, price_impact_weight,
+	apr_coefficient, trading_volume_coefficient,
+	il_risk_coefficient, volatility_coefficient, new_pool_coefficient, tvl_coefficient,
+	smart_shield_bonus, continuity_coefficient, sentiment_impact_factor,
+	il_confidence_factor, il_holding_period_years, smart_shield_reduction_factor,
+	min_tvl_threshold, pool_maturity_days, continuity_lookback_days,
+	rebalance_threshold_amount, max_rebalance_percent_per_cycle, max_pools, min_allocation, max_allocation,
+	smart_shield_slippage_percent, normal_pool_slippage_percent, min_liquid_usdc_buffer, learning_rate, max_parameter_change,
+	optimization_interval_cycles, elys_forced_allocation_minimum, max_token_exposure,
+	viable_swap_reduction_factor, viable_deposit_reduction_factor
+`
+
+// rowScanner is implemented by *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanScoringParametersVersion scans a row selected with scoringParametersVersionColumns
+func scanScoringParametersVersion(row rowScanner) (*types.ScoringParametersVersion, error) {
+	v := &types.ScoringParametersVersion{}
+	p := &v.Parameters
+	err := row.Scan(
+		&v.ParamsID, &v.ConfigName, &v.Version, &v.IsActive, &v.ActivatedAt, &v.CreatedAt,
+		&p.EdenWeight, &p.UsdcFeeWeight, &p.PriceImpactWeight,
+		&p.AprCoefficient, &p.TradingVolumeCoefficient,
+		&p.IlRiskCoefficient, &p.VolatilityCoefficient, &p.NewPoolCoefficient, &p.TvlCoefficient,
+		&p.SmartShieldBonus, &p.ContinuityCoefficient, &p.SentimentImpactFactor,
+		&p.IlConfidenceFactor, &p.IlHoldingPeriodYears, &p.SmartShieldReductionFactor,
+		&p.MinTVLThreshold, &p.PoolMaturityDays, &p.ContinuityLookbackDays,
+		&p.RebalanceThresholdAmount, &p.MaxRebalancePercentPerCycle, &p.MaxPools, &p.MinAllocation, &p.MaxAllocation,
+		&p.SmartShieldSlippagePercent, &p.NormalPoolSlippagePercent, &p.MinLiquidUSDCBuffer, &p.LearningRate, &p.MaxParameterChange,
+		&p.OptimizationIntervalCycles, &p.ElysForcedAllocationMinimum, &p.MaxTokenExposure,
+		&p.ViableSwapReductionFactor, &p.ViableDepositReductionFactor,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// ListScoringParameters returns every stored version of a config's scoring parameters, oldest first.
+func (s *sqlStore) ListScoringParameters(configName string) ([]types.ScoringParametersVersion, error) {
+	query := `SELECT ` + scoringParametersVersionColumns + `
+		FROM scoring_parameters
+		WHERE config_name = $1
+		ORDER BY version ASC`
+
+	rows, err := s.db.Query(query, configName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query scoring parameters for config '%s': %w", configName, err)
+	}
+	defer rows.Close()
+
+	versions := make([]types.ScoringParametersVersion, 0)
+	for rows.Next() {
+		v, err := scanScoringParametersVersion(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan scoring parameters for config '%s': %w", configName, err)
+		}
+		versions = append(versions, *v)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error during scoring parameters iteration for config '%s': %w", configName, err)
+	}
+
+	return versions, nil
+}
+
+// LoadScoringParametersVersion loads one version of a config's scoring parameters.
+// Returns ErrScoringParametersNotFound if it does not exist.
+func (s *sqlStore) LoadScoringParametersVersion(configName string, version int) (*types.ScoringParametersVersion, error) {
+	query := `SELECT ` + scoringParametersVersionColumns + `
+		FROM scoring_parameters
+		WHERE config_name = $1 AND version = $2`
+
+	v, err := scanScoringParametersVersion(s.db.QueryRow(query, configName, version))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("%w: config '%s' version %d", ErrScoringParametersNotFound, configName, version)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load scoring parameters for config '%s' version %d: %w", configName, version, err)
+	}
+	return v, nil
+}
+
+// LoadScoringParametersByID loads a version of the scoring parameters by its params_id, as
+// referenced by cycle snapshots. Returns ErrScoringParametersNotFound if it does not exist.
+func (s *sqlStore) LoadScoringParametersByID(paramsID int64) (*types.ScoringParametersVersion, error) {
+	query := `SELECT ` + scoringParametersVersionColumns + `
+		FROM scoring_parameters
+		WHERE params_id = $1`
+
+	v, err := scanScoringParametersVersion(s.db.QueryRow(query, paramsID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("%w: params_id %d", ErrScoringParametersNotFound, paramsID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load scoring parameters %d: %w", paramsID, err)
+	}
+	return v, nil
+}
+
+// LoadActiveScoringParametersVersion loads the active version of a config's scoring parameters.
+// Returns ErrScoringParametersNotFound if no version is active.
+func (s *sqlStore) LoadActiveScoringParametersVersion(configName string) (*types.ScoringParametersVersion, error) {
+	query := `SELECT ` + scoringParametersVersionColumns + `
+		FROM scoring_parameters
+		WHERE config_name = $1 AND is_active = TRUE
+		ORDER BY activated_at DESC
+		LIMIT 1`
+
+	v, err := scanScoringParametersVersion(s.db.QueryRow(query, configName))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("%w: no active version for config '%s'", ErrScoringParametersNotFound, configName)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load active scoring parameters for config '%s': %w", configName, err)
+	}
+	return v, nil
+}
+
+// NextScoringParametersVersion returns the version number for a new version of a config's parameters.
+func (s *sqlStore) NextScoringParametersVersion(configName string) (int, error) {
+	var version int
+	err := s.db.QueryRow(`SELECT COALESCE(MAX(version), 0) + 1 FROM scoring_parameters WHERE config_name = $1`, configName).Scan(&version)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get next scoring parameters version for config '%s': %w", configName, err)
+	}
+	return version, nil
+}
+
+// ActivateScoringParameters makes a stored version the active scoring parameters of its config
+// and records who activated it in the audit log.
+func (s *sqlStore) ActivateScoringParameters(configName string, version int, audit types.AuditInfo) (*types.ScoringParametersVersion, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback() // No-op after a successful commit
+
+	previousParamsID, err := s.lockActiveScoringParameters(tx, configName)
+	if err != nil {
+		return nil, err
+	}
+
+	var paramsID int64
+	err = tx.QueryRow(`SELECT params_id FROM scoring_parameters WHERE config_name = $1 AND version = $2`, configName, version).Scan(&paramsID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("%w: config '%s' version %d", ErrScoringParametersNotFound, configName, version)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find scoring parameters for config '%s' version %d: %w", configName, version, err)
+	}
+	if previousParamsID != nil && *previousParamsID == paramsID {
+		return nil, fmt.Errorf("version %d of config '%s' is already active", version, configName)
+	}
+
+	if err := setActiveScoringParameters(tx, configName, paramsID); err != nil {
+		return nil, err
+	}
+	if err := recordScoringParametersActivation(tx, configName, paramsID, previousParamsID, scoringParametersActionActivate); err != nil {
+		return nil, err
+	}
+	target, err := scanScoringParametersVersion(tx.QueryRow(`SELECT `+scoringParametersVersionColumns+`
+		FROM scoring_parameters WHERE params_id = $1`, paramsID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load params_id %d: %w", paramsID, err)
+	}
+	err = recordScoringParametersAuditEvent(tx, types.AuditActionParamsActivate, audit, configName, version, previousParamsID, target.Parameters)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit activation: %w", err)
+	}
+
+	log.Info().Str("config", configName).Int("version", version).Int64("params_id", paramsID).Msg("Activated scoring parameters")
+	return s.LoadScoringParametersVersion(configName, version)
+}
+
+// RollbackScoringParameters reactivates the version that was active before the current one and
+// records who rolled back in the audit log. Returns ErrNothingToRollBack if there is none.
+func (s *sqlStore) RollbackScoringParameters(configName string, audit types.AuditInfo) (*types.ScoringParametersVersion, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback() // No-op after a successful commit
+
+	if _, err := s.lockActiveScoringParameters(tx, configName); err != nil {
+		return nil, err
+	}
+
+	// The latest activation says which version was active before the current one
+	var activationID, currentParamsID int64
+	var targetParamsID sql.NullInt64
+	err = tx.QueryRow(`
+		SELECT activation_id, params_id, previous_params_id
+		FROM scoring_parameter_activations
+		WHERE config_name = $1
+		ORDER BY activation_id DESC
+		LIMIT 1
+	`, configName).Scan(&activationID, &currentParamsID, &targetParamsID)
+	if errors.Is(err, sql.ErrNoRows) || (err == nil && !targetParamsID.Valid) {
+		return nil, fmt.Errorf("%w for config '%s'", ErrNothingToRollBack, configName)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load activation history for config '%s': %w", configName, err)
+	}
+
+	// A later rollback returns to whatever the target replaced when it was last activated
+	var nextPreviousParamsID sql.NullInt64
+	err = tx.QueryRow(`
+		SELECT previous_params_id
+		FROM scoring_parameter_activations
+		WHERE config_name = $1 AND params_id = $2 AND activation_id < $3
+		ORDER BY activation_id DESC
+		LIMIT 1
+	`, configName, targetParamsID.Int64, activationID).Scan(&nextPreviousParamsID)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("failed to load activation history for config '%s': %w", configName, err)
+	}
+	var nextPrevious *int64
+	if nextPreviousParamsID.Valid {
+		nextPrevious = &nextPreviousParamsID.Int64
+	}
+
+	if err := setActiveScoringParameters(tx, configName, targetParamsID.Int64); err != nil {
+		return nil, err
+	}
+	if err := recordScoringParametersActivation(tx, configName, targetParamsID.Int64, nextPrevious, scoringParametersActionRollback); err != nil {
+		return nil, err
+	}
+
+	target, err := scanScoringParametersVersion(tx.QueryRow(`SELECT `+scoringParametersVersionColumns+`
+		FROM scoring_parameters WHERE params_id = $1`, targetParamsID.Int64))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load params_id %d: %w", targetParamsID.Int64, err)
+	}
+	version := target.Version
+	err = recordScoringParametersAuditEvent(tx, types.AuditActionParamsRollback, audit, configName, version, &currentParamsID, target.Parameters)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit rollback: %w", err)
+	}
+
+	log.Info().
+		Str("config", configName).
+		Int("version", version).
+		Int64("params_id", targetParamsID.Int64).
+		Int64("replaced_params_id", currentParamsID).
+		Msg("Rolled back scoring parameters")
+	return s.LoadScoringParametersVersion(configName, version)
+}
+
+// lockActiveScoringParameters serializes activation changes of a config for the rest of the
+// transaction and returns the params_id of its active version, or nil if none is active.
+func (s *sqlStore) lockActiveScoringParameters(tx *sql.Tx, configName string) (*int64, error) {
+	if err := s.dialect.lockScoringConfig(tx, configName); err != nil {
+		return nil, fmt.Errorf("failed to lock scoring parameters for config '%s': %w", configName, err)
+	}
+	return activeScoringParametersID(tx, configName)
+}
+
+// activeScoringParametersID returns the params_id of a config's active version, or nil if none is active
+func activeScoringParametersID(q queryRower, configName string) (*int64, error) {
+	var paramsID int64
+	err := q.QueryRow(`
+		SELECT params_id FROM scoring_parameters
+		WHERE config_name = $1 AND is_active = TRUE
+		ORDER BY activated_at DESC
+		LIMIT 1
+	`, configName).Scan(&paramsID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get active scoring parameters for config '%s': %w", configName, err)
+	}
+	return &paramsID, nil
+}
+
+// setActiveScoringParameters deactivates every version of a config and activates paramsID
+func setActiveScoringParameters(tx *sql.Tx, configName string, paramsID int64) error {
+	if _, err := tx.Exec(`UPDATE scoring_parameters SET is_active = FALSE WHERE config_name = $1 AND is_active = TRUE`, configName); err != nil {
+		return fmt.Errorf("failed to deactivate existing active parameters for %s: %w", configName, err)
+	}
+	_, err := tx.Exec(`UPDATE scoring_parameters SET is_active = TRUE, activated_at = $2 WHERE params_id = $1`, paramsID, time.Now().UTC())
+	if err != nil {
+		return fmt.Errorf("failed to activate params_id %d: %w", paramsID, err)
+	}
+	return nil
+}
+
+// recordScoringParametersActivation adds an entry to the activation history
+func recordScoringParametersActivation(tx *sql.Tx, configName string, paramsID int64, previousParamsID *int64, action string) error {
+	_, err := tx.Exec(`
+		INSERT INTO scoring_parameter_activations (config_name, params_id, previous_params_id, action)
+		VALUES ($1, $2, $3, $4)
+	`, configName, paramsID, previousParamsID, action)
+	if err != nil {
+		return fmt.Errorf("failed to record activation of params_id %d: %w", paramsID, err)
+	}
+	return nil
+}
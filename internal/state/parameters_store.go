@@ -10,13 +10,10 @@ import (
 	"github.com/rs/zerolog/log"
 )
 
-// SaveScoringParameters saves a new version of scoring parameters.
-func SaveScoringParameters(params types.ScoringParameters, configName string, version int, makeActive bool) (int64, error) {
-	if DB == nil {
-		return 0, fmt.Errorf("database not initialized")
-	}
-
-	tx, err := DB.Begin()
+// SaveScoringParameters saves a new version of scoring parameters and records who saved it in the
+// audit log, with the changes from the active version.
+func (s *sqlStore) SaveScoringParameters(params types.ScoringParameters, configName string, version int, makeActive bool, audit types.AuditInfo) (int64, error) {
+	tx, err := s.db.Begin()
 	if err != nil {
 		return 0, fmt.Errorf("failed to begin transaction: %w", err)
 	}
@@ -29,7 +26,13 @@ func SaveScoringParameters(params types.ScoringParameters, configName string, ve
 		}
 	}()
 
+	var previousParamsID *int64
 	if makeActive {
+		previousParamsID, err = s.lockActiveScoringParameters(tx, configName)
+		if err != nil {
+			return 0, err
+		}
+
 		stmtDeactivate := `UPDATE scoring_parameters SET is_active = FALSE WHERE config_name = $1 AND is_active = TRUE;`
 		_, err = tx.Exec(stmtDeactivate, configName)
 		if err != nil {
@@ -68,7 +71,8 @@ func SaveScoringParameters(params types.ScoringParameters, configName string, ve
             min_tvl_threshold, pool_maturity_days, continuity_lookback_days,
             rebalance_threshold_amount, max_rebalance_percent_per_cycle, max_pools, min_allocation, max_allocation,
             smart_shield_slippage_percent, normal_pool_slippage_percent, min_liquid_usdc_buffer, learning_rate, max_parameter_change,
-            optimization_interval_cycles, elys_forced_allocation_minimum
+            optimization_interval_cycles, elys_forced_allocation_minimum, max_token_exposure,
+            viable_swap_reduction_factor, viable_deposit_reduction_factor
         ) VALUES (
             $1, $2, $3, $4, $5,  -- version, config_name, is_active, activated_at, created_at
             $6, $7, $8,          -- eden_w, usdc_fee_w, price_impact_w
@@ -79,11 +83,12 @@ func SaveScoringParameters(params types.ScoringParameters, configName string, ve
             $21, $22, $23,       -- min_tvl_t, pool_mat_d, cont_look_d
             $24, $25, $26, $27, $28,  -- rebal_thresh_a, max_rebalance_percent_per_cycle, max_pools, min_alloc, max_alloc
             $29, $30, $31, $32, $33,  -- smart_shield_slippage_percent, normal_pool_slippage_percent, min_liquid_usdc_buffer, learning_rate, max_parameter_change
-            $34, $35             -- opt_int_cycles, elys_forced_allocation_minimum
+            $34, $35, $36,       -- opt_int_cycles, elys_forced_allocation_minimum, max_token_exposure
+            $37, $38             -- viable_swap_reduction_factor, viable_deposit_reduction_factor
         ) RETURNING params_id;`
 
 	var paramsID int64
-	currentTime := time.Now()
+	currentTime := time.Now().UTC()
 	err = tx.QueryRow(
 		stmt,
 		version, configName, makeActive, currentTime, currentTime, // activated_at, created_at
@@ -95,13 +100,38 @@ func SaveScoringParameters(params types.ScoringParameters, configName string, ve
 		params.MinTVLThreshold, params.PoolMaturityDays, params.ContinuityLookbackDays,
 		params.RebalanceThresholdAmount, params.MaxRebalancePercentPerCycle, params.MaxPools, params.MinAllocation, params.MaxAllocation,
 		params.SmartShieldSlippagePercent, params.NormalPoolSlippagePercent, params.MinLiquidUSDCBuffer, params.LearningRate, params.MaxParameterChange,
-		params.OptimizationIntervalCycles, params.ElysForcedAllocationMinimum,
+		params.OptimizationIntervalCycles, params.ElysForcedAllocationMinimum, params.MaxTokenExposure,
+		params.ViableSwapReductionFactor, params.ViableDepositReductionFactor,
 	).Scan(&paramsID)
 
 	if err != nil {
 		return 0, fmt.Errorf("failed to insert scoring parameters: %w", err)
 	}
 
+	if makeActive {
+		err = recordScoringParametersActivation(tx, configName, paramsID, previousParamsID, scoringParametersActionActivate)
+		if err != nil {
+			return 0, err
+		}
+	} else {
+		// Not locked: the diff is only informative for a version that is not activated
+		previousParamsID, err = activeScoringParametersID(tx, configName)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	err = recordScoringParametersAuditEvent(tx, types.AuditActionParamsSave, audit, configName, version, previousParamsID, params)
+	if err != nil {
+		return 0, err
+	}
+	if makeActive {
+		err = recordScoringParametersAuditEvent(tx, types.AuditActionParamsActivate, audit, configName, version, previousParamsID, params)
+		if err != nil {
+			return 0, err
+		}
+	}
+
 	err = tx.Commit()
 	if err != nil {
 		return 0, fmt.Errorf("failed to commit transaction: %w", err)
@@ -117,11 +147,7 @@ func SaveScoringParameters(params types.ScoringParameters, configName string, ve
 }
 
 // LoadActiveScoringParameters loads the currently active scoring parameters.
-func LoadActiveScoringParameters(configName string) (*types.ScoringParameters, error) {
-	if DB == nil {
-		return nil, fmt.Errorf("database not initialized")
-	}
-
+func (s *sqlStore) LoadActiveScoringParameters(configName string) (*types.ScoringParameters, error) {
 	query := `
         SELECT
             eden_weight, usdc_fee_weight, price_impact_weight,
@@ -132,14 +158,15 @@ func LoadActiveScoringParameters(configName string) (*types.ScoringParameters, e
             min_tvl_threshold, pool_maturity_days, continuity_lookback_days,
             rebalance_threshold_amount, max_rebalance_percent_per_cycle, max_pools, min_allocation, max_allocation,
             smart_shield_slippage_percent, normal_pool_slippage_percent, min_liquid_usdc_buffer, learning_rate, max_parameter_change,
-            optimization_interval_cycles, elys_forced_allocation_minimum
+            optimization_interval_cycles, elys_forced_allocation_minimum, max_token_exposure,
+            viable_swap_reduction_factor, viable_deposit_reduction_factor
         FROM scoring_parameters
         WHERE config_name = $1 AND is_active = TRUE
         ORDER BY activated_at DESC
         LIMIT 1;`
 
 	p := &types.ScoringParameters{}
-	row := DB.QueryRow(query, configName)
+	row := s.db.QueryRow(query, configName)
 	err := row.Scan(
 		&p.EdenWeight, &p.UsdcFeeWeight, &p.PriceImpactWeight,
 		&p.AprCoefficient, &p.TradingVolumeCoefficient,
@@ -149,7 +176,8 @@ func LoadActiveScoringParameters(configName string) (*types.ScoringParameters, e
 		&p.MinTVLThreshold, &p.PoolMaturityDays, &p.ContinuityLookbackDays,
 		&p.RebalanceThresholdAmount, &p.MaxRebalancePercentPerCycle, &p.MaxPools, &p.MinAllocation, &p.MaxAllocation,
 		&p.SmartShieldSlippagePercent, &p.NormalPoolSlippagePercent, &p.MinLiquidUSDCBuffer, &p.LearningRate, &p.MaxParameterChange,
-		&p.OptimizationIntervalCycles, &p.ElysForcedAllocationMinimum,
+		&p.OptimizationIntervalCycles, &p.ElysForcedAllocationMinimum, &p.MaxTokenExposure,
+		&p.ViableSwapReductionFactor, &p.ViableDepositReductionFactor,
 	)
 
 	if err != nil {
@@ -163,11 +191,7 @@ func LoadActiveScoringParameters(configName string) (*types.ScoringParameters, e
 }
 
 // LoadLatestScoringParameters loads the most recently activated scoring parameters for a given config name.
-func LoadLatestScoringParameters(configName string) (*types.ScoringParameters, error) {
-	if DB == nil {
-		return nil, fmt.Errorf("database not initialized")
-	}
-
+func (s *sqlStore) LoadLatestScoringParameters(configName string) (*types.ScoringParameters, error) {
 	query := `
         SELECT
             eden_weight, usdc_fee_weight, price_impact_weight,
@@ -178,14 +202,15 @@ func LoadLatestScoringParameters(configName string) (*types.ScoringParameters, e
             min_tvl_threshold, pool_maturity_days, continuity_lookback_days,
             rebalance_threshold_amount, max_rebalance_percent_per_cycle, max_pools, min_allocation, max_allocation,
             smart_shield_slippage_percent, normal_pool_slippage_percent, min_liquid_usdc_buffer, learning_rate, max_parameter_change,
-            optimization_interval_cycles, elys_forced_allocation_minimum
+            optimization_interval_cycles, elys_forced_allocation_minimum, max_token_exposure,
+            viable_swap_reduction_factor, viable_deposit_reduction_factor
         FROM scoring_parameters
         WHERE config_name = $1
         ORDER BY activated_at DESC, created_at DESC
         LIMIT 1;`
 
 	p := &types.ScoringParameters{}
-	row := DB.QueryRow(query, configName)
+	row := s.db.QueryRow(query, configName)
 	err := row.Scan(
 		&p.EdenWeight, &p.UsdcFeeWeight, &p.PriceImpactWeight,
 		&p.AprCoefficient, &p.TradingVolumeCoefficient,
@@ -195,7 +220,8 @@ func LoadLatestScoringParameters(configName string) (*types.ScoringParameters, e
 		&p.MinTVLThreshold, &p.PoolMaturityDays, &p.ContinuityLookbackDays,
 		&p.RebalanceThresholdAmount, &p.MaxRebalancePercentPerCycle, &p.MaxPools, &p.MinAllocation, &p.MaxAllocation,
 		&p.SmartShieldSlippagePercent, &p.NormalPoolSlippagePercent, &p.MinLiquidUSDCBuffer, &p.LearningRate, &p.MaxParameterChange,
-		&p.OptimizationIntervalCycles, &p.ElysForcedAllocationMinimum,
+		&p.OptimizationIntervalCycles, &p.ElysForcedAllocationMinimum, &p.MaxTokenExposure,
+		&p.ViableSwapReductionFactor, &p.ViableDepositReductionFactor,
 	)
 
 	if err != nil {
@@ -209,11 +235,7 @@ func LoadLatestScoringParameters(configName string) (*types.ScoringParameters, e
 }
 
 // GetActiveScoringParametersID returns the params_id of the currently active scoring parameters
-func GetActiveScoringParametersID(configName string) (*int64, error) {
-	if DB == nil {
-		return nil, fmt.Errorf("database not initialized")
-	}
-
+func (s *sqlStore) GetActiveScoringParametersID(configName string) (*int64, error) {
 	query := `
         SELECT params_id
         FROM scoring_parameters
@@ -222,7 +244,7 @@ func GetActiveScoringParametersID(configName string) (*int64, error) {
         LIMIT 1;`
 
 	var paramsID int64
-	row := DB.QueryRow(query, configName)
+	row := s.db.QueryRow(query, configName)
 	err := row.Scan(&paramsID)
 
 	if err != nil {
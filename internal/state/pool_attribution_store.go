@@ -0,0 +1,140 @@
+/*
+
+This file manages the per-pool PnL attribution computed at the end of each cycle.
+
+*/
+
+package state
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/elys-network/avm/internal/types"
+	"github.com/rs/zerolog/log"
+)
+
+const poolAttributionSelectColumns = `
+	cycle_number, recorded_at, pool_id, period_seconds, start_value_usd, end_value_usd,
+	lp_fees_usd, eden_rewards_usd, usdc_rewards_usd, price_return_usd, impermanent_loss_usd,
+	slippage_usd, gas_usd, total_pnl_usd, market_data_complete
+`
+
+// SavePoolAttributions replaces the PnL attribution of a cycle
+func (s *sqlStore) SavePoolAttributions(cycleNumber int, attributions []types.PoolAttribution) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback() // No-op after a successful commit
+
+	if _, err := tx.Exec(`DELETE FROM pool_pnl_attribution WHERE cycle_number = $1`, cycleNumber); err != nil {
+		return fmt.Errorf("failed to clear attribution of cycle %d: %w", cycleNumber, err)
+	}
+
+	stmt, err := tx.Prepare(`
+		INSERT INTO pool_pnl_attribution (` + poolAttributionSelectColumns + `)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15)
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to prepare attribution insert: %w", err)
+	}
+	defer stmt.Close()
+
+	for _, attribution := range attributions {
+		_, err := stmt.Exec(
+			cycleNumber, attribution.Timestamp.UTC(), uint64(attribution.PoolID), attribution.PeriodSeconds,
+			attribution.StartValueUSD, attribution.EndValueUSD,
+			attribution.LPFeesUSD, attribution.EdenRewardsUSD, attribution.UsdcRewardsUSD,
+			attribution.PriceReturnUSD, attribution.ImpermanentLossUSD,
+			attribution.SlippageUSD, attribution.GasUSD, attribution.TotalPnLUSD, attribution.MarketDataComplete,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to save attribution of pool %d in cycle %d: %w", attribution.PoolID, cycleNumber, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit attribution: %w", err)
+	}
+
+	log.Debug().Int("cycleNumber", cycleNumber).Int("pools", len(attributions)).Msg("Saved PnL attribution")
+	return nil
+}
+
+// GetPoolAttributions returns the PnL attribution of a cycle, ordered by pool ID
+func (s *sqlStore) GetPoolAttributions(cycleNumber int) ([]types.PoolAttribution, error) {
+	rows, err := s.db.Query(`
+		SELECT `+poolAttributionSelectColumns+`
+		FROM pool_pnl_attribution
+		WHERE cycle_number = $1
+		ORDER BY pool_id ASC
+	`, cycleNumber)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query attribution of cycle %d: %w", cycleNumber, err)
+	}
+	defer rows.Close()
+
+	attributions := make([]types.PoolAttribution, 0)
+	for rows.Next() {
+		var attribution types.PoolAttribution
+		var poolID uint64
+		err := rows.Scan(
+			&attribution.CycleNumber, &attribution.Timestamp, &poolID, &attribution.PeriodSeconds,
+			&attribution.StartValueUSD, &attribution.EndValueUSD,
+			&attribution.LPFeesUSD, &attribution.EdenRewardsUSD, &attribution.UsdcRewardsUSD,
+			&attribution.PriceReturnUSD, &attribution.ImpermanentLossUSD,
+			&attribution.SlippageUSD, &attribution.GasUSD, &attribution.TotalPnLUSD, &attribution.MarketDataComplete,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan attribution row: %w", err)
+		}
+		attribution.PoolID = types.PoolID(poolID)
+		attribution.Timestamp = attribution.Timestamp.UTC()
+		attributions = append(attributions, attribution)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error during attribution iteration: %w", err)
+	}
+	return attributions, nil
+}
+
+// GetPoolAttributionSummary sums each pool's PnL attribution over the cycles started within
+// [from, to], ordered by pool ID
+func (s *sqlStore) GetPoolAttributionSummary(from, to time.Time) ([]types.PoolAttributionSummary, error) {
+	rows, err := s.db.Query(`
+		SELECT pool_id, COUNT(*),
+			SUM(lp_fees_usd), SUM(eden_rewards_usd), SUM(usdc_rewards_usd),
+			SUM(price_return_usd), SUM(impermanent_loss_usd),
+			SUM(slippage_usd), SUM(gas_usd), SUM(total_pnl_usd)
+		FROM pool_pnl_attribution
+		WHERE recorded_at >= $1 AND recorded_at <= $2
+		GROUP BY pool_id
+		ORDER BY pool_id ASC
+	`, from.UTC(), to.UTC())
+	if err != nil {
+		return nil, fmt.Errorf("failed to query attribution summary: %w", err)
+	}
+	defer rows.Close()
+
+	summaries := make([]types.PoolAttributionSummary, 0)
+	for rows.Next() {
+		var summary types.PoolAttributionSummary
+		var poolID uint64
+		err := rows.Scan(
+			&poolID, &summary.Cycles,
+			&summary.LPFeesUSD, &summary.EdenRewardsUSD, &summary.UsdcRewardsUSD,
+			&summary.PriceReturnUSD, &summary.ImpermanentLossUSD,
+			&summary.SlippageUSD, &summary.GasUSD, &summary.TotalPnLUSD,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan attribution summary row: %w", err)
+		}
+		summary.PoolID = types.PoolID(poolID)
+		summaries = append(summaries, summary)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error during attribution summary iteration: %w", err)
+	}
+	return summaries, nil
+}
@@ -0,0 +1,264 @@
+/*
+
+This file manages the pool metrics time series.
+Every cycle adds one raw point per pool. As points age they are downsampled: raw points are
+averaged into hourly points, hourly points into daily points, and daily points are eventually
+deleted. Each time range is held at exactly one resolution, so reading all stored points for a
+range gives a continuous series that gets coarser further back.
+
+*/
+
+package state
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/elys-network/avm/internal/types"
+	"github.com/rs/zerolog/log"
+)
+
+// Metrics columns that are averaged, weighted by sample count, when points are downsampled
+var poolMetricsAveragedColumns = []string{
+	"tvl_usd", "volume_7d_usd", "eden_rewards_apr", "usdc_fees_apr", "price_impact_apr", "total_apr", "swap_fee",
+}
+
+const poolMetricsSelectColumns = `
+	pool_id, recorded_at, resolution, sample_count, cycle_number, block_height,
+	tvl_usd, volume_7d_usd, eden_rewards_apr, usdc_fees_apr, price_impact_apr, total_apr, swap_fee,
+	total_shares, assets
+`
+
+// SavePoolMetrics records one raw metrics point for each pool, taken at the start of a cycle.
+func (s *sqlStore) SavePoolMetrics(cycleNumber int, blockHeight int64, recordedAt time.Time, pools []types.Pool) error {
+	if len(pools) == 0 {
+		return nil
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback() // No-op after a successful commit
+
+	stmt, err := tx.Prepare(`
+		INSERT INTO pool_metrics (
+			pool_id, resolution, recorded_at, sample_count, cycle_number, block_height,
+			tvl_usd, volume_7d_usd, eden_rewards_apr, usdc_fees_apr, price_impact_apr, total_apr, swap_fee,
+			total_shares, assets
+		) VALUES ($1, $2, $3, 1, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
+		ON CONFLICT (pool_id, resolution, recorded_at) DO NOTHING;
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to prepare pool metrics insert: %w", err)
+	}
+	defer stmt.Close()
+
+	for _, pool := range pools {
+		assets := make([]types.PoolMetricsAsset, len(pool.Assets))
+		for i, asset := range pool.Assets {
+			assets[i] = types.PoolMetricsAsset{
+				Symbol: asset.Token.Symbol,
+				Denom:  asset.Token.Denom,
+				Amount: asset.Balance.String(),
+				Weight: asset.Weight,
+			}
+		}
+		assetsJSON, err := json.Marshal(assets)
+		if err != nil {
+			return fmt.Errorf("failed to marshal assets of pool %d: %w", pool.ID, err)
+		}
+
+		totalShares := "0"
+		if !pool.TotalShares.IsNil() {
+			totalShares = pool.TotalShares.String()
+		}
+		totalAPR := pool.EdenRewardsAPR + pool.UsdcFeesAPR + pool.PriceImpactAPR
+
+		_, err = stmt.Exec(
+			uint64(pool.ID), string(types.PoolMetricsRaw), recordedAt.UTC(), cycleNumber, blockHeight,
+			pool.TvlUSD, pool.Volume7dUSD, pool.EdenRewardsAPR, pool.UsdcFeesAPR, pool.PriceImpactAPR, totalAPR, pool.SwapFee,
+			totalShares, string(assetsJSON),
+		)
+		if err != nil {
+			return fmt.Errorf("failed to save metrics for pool %d: %w", pool.ID, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit pool metrics: %w", err)
+	}
+
+	log.Debug().Int("cycleNumber", cycleNumber).Int("pools", len(pools)).Msg("Saved pool metrics")
+	return nil
+}
+
+// poolMetricsIntervalResolution returns the resolution and bucket size of a history interval:
+// empty for the stored points, "hour" or "day"
+func poolMetricsIntervalResolution(interval string) (types.PoolMetricsResolution, time.Duration, error) {
+	switch interval {
+	case "":
+		return types.PoolMetricsRaw, 0, nil
+	case "hour":
+		return types.PoolMetricsHourly, time.Hour, nil
+	case "day":
+		return types.PoolMetricsDaily, 24 * time.Hour, nil
+	default:
+		return "", 0, fmt.Errorf("unsupported interval %q, must be empty, \"hour\" or \"day\"", interval)
+	}
+}
+
+// loadPoolMetrics returns a pool's stored metrics points recorded within [from, to], oldest first
+func (s *sqlStore) loadPoolMetrics(poolID types.PoolID, from, to time.Time) ([]types.PoolMetricsPoint, error) {
+	query := `
+		SELECT ` + poolMetricsSelectColumns + `
+		FROM pool_metrics
+		WHERE pool_id = $1 AND recorded_at >= $2 AND recorded_at <= $3
+		ORDER BY recorded_at ASC
+	`
+	rows, err := s.db.Query(query, uint64(poolID), from.UTC(), to.UTC())
+	if err != nil {
+		return nil, fmt.Errorf("failed to query metrics history for pool %d: %w", poolID, err)
+	}
+	defer rows.Close()
+
+	return scanPoolMetricsPoints(rows)
+}
+
+// GetPoolMetricsAt returns the latest metrics point of each pool recorded within (at - maxAge, at]
+func (s *sqlStore) GetPoolMetricsAt(at time.Time, maxAge time.Duration) (map[types.PoolID]types.PoolMetricsPoint, error) {
+	rows, err := s.db.Query(`
+		SELECT `+poolMetricsSelectColumns+`
+		FROM pool_metrics
+		WHERE recorded_at <= $1 AND recorded_at > $2
+		ORDER BY pool_id ASC, recorded_at DESC
+	`, at.UTC(), at.Add(-maxAge).UTC())
+	if err != nil {
+		return nil, fmt.Errorf("failed to query pool metrics at %s: %w", at.Format(time.RFC3339), err)
+	}
+	defer rows.Close()
+
+	points, err := scanPoolMetricsPoints(rows)
+	if err != nil {
+		return nil, err
+	}
+	latest := make(map[types.PoolID]types.PoolMetricsPoint)
+	for _, point := range points {
+		if _, seen := latest[point.PoolID]; !seen {
+			latest[point.PoolID] = point
+		}
+	}
+	return latest, nil
+}
+
+// scanPoolMetricsPoints reads rows selected with poolMetricsSelectColumns
+func scanPoolMetricsPoints(rows *sql.Rows) ([]types.PoolMetricsPoint, error) {
+	points := make([]types.PoolMetricsPoint, 0)
+	for rows.Next() {
+		var point types.PoolMetricsPoint
+		var poolIDValue uint64
+		var resolution string
+		var assetsJSON []byte
+		err := rows.Scan(
+			&poolIDValue, &point.Timestamp, &resolution, &point.SampleCount, &point.CycleNumber, &point.BlockHeight,
+			&point.TvlUSD, &point.Volume7dUSD, &point.EdenRewardsAPR, &point.UsdcFeesAPR, &point.PriceImpactAPR, &point.TotalAPR, &point.SwapFee,
+			&point.TotalShares, &assetsJSON,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan pool metrics row: %w", err)
+		}
+		point.PoolID = types.PoolID(poolIDValue)
+		point.Resolution = types.PoolMetricsResolution(resolution)
+		point.Timestamp = point.Timestamp.UTC()
+		if err := json.Unmarshal(assetsJSON, &point.Assets); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal assets of pool %d: %w", poolIDValue, err)
+		}
+		points = append(points, point)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error during pool metrics iteration: %w", err)
+	}
+
+	return points, nil
+}
+
+// GetPoolMetricsStats summarizes a pool's TVL, volume and APRs over [from, to].
+// Points are weighted by the number of cycles they cover.
+func (s *sqlStore) GetPoolMetricsStats(poolID types.PoolID, from, to time.Time) (*types.PoolMetricsStats, error) {
+	points, err := s.loadPoolMetrics(poolID, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	stats := &types.PoolMetricsStats{
+		PoolID:     poolID,
+		From:       from.UTC(),
+		To:         to.UTC(),
+		PointCount: len(points),
+	}
+	if len(points) == 0 {
+		return stats, nil
+	}
+
+	weights := make([]float64, len(points))
+	for i, point := range points {
+		weights[i] = float64(point.SampleCount)
+		stats.SampleCount += point.SampleCount
+	}
+	metric := func(value func(types.PoolMetricsPoint) float64) types.MetricSummary {
+		values := make([]float64, len(points))
+		for i, point := range points {
+			values[i] = value(point)
+		}
+		return summarizeMetric(values, weights)
+	}
+
+	stats.TvlUSD = metric(func(p types.PoolMetricsPoint) float64 { return p.TvlUSD })
+	stats.Volume7dUSD = metric(func(p types.PoolMetricsPoint) float64 { return p.Volume7dUSD })
+	stats.TotalAPR = metric(func(p types.PoolMetricsPoint) float64 { return p.TotalAPR })
+	stats.UsdcFeesAPR = metric(func(p types.PoolMetricsPoint) float64 { return p.UsdcFeesAPR })
+	stats.EdenAPR = metric(func(p types.PoolMetricsPoint) float64 { return p.EdenRewardsAPR })
+
+	return stats, nil
+}
+
+// summarizeMetric returns the first, last, min, max, weighted mean and weighted standard deviation
+// of a series. values must not be empty.
+func summarizeMetric(values, weights []float64) types.MetricSummary {
+	summary := types.MetricSummary{
+		First: values[0],
+		Last:  values[len(values)-1],
+		Min:   values[0],
+		Max:   values[0],
+	}
+
+	var weightedSum, totalWeight float64
+	for i, value := range values {
+		summary.Min = math.Min(summary.Min, value)
+		summary.Max = math.Max(summary.Max, value)
+		weightedSum += value * weights[i]
+		totalWeight += weights[i]
+	}
+	if totalWeight <= 0 {
+		return summary
+	}
+	summary.Mean = weightedSum / totalWeight
+
+	var squaredDeviations float64
+	for i, value := range values {
+		deviation := value - summary.Mean
+		squaredDeviations += weights[i] * deviation * deviation
+	}
+	summary.StdDev = math.Sqrt(squaredDeviations / totalWeight)
+
+	if summary.First != 0 {
+		summary.ChangePercent = (summary.Last - summary.First) / math.Abs(summary.First) * 100
+	}
+	if summary.Mean != 0 {
+		summary.CoefficientOfVariation = summary.StdDev / math.Abs(summary.Mean)
+	}
+	return summary
+}
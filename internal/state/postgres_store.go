@@ -0,0 +1,188 @@
+/*
+
+This file implements the Store on PostgreSQL, the production backend. Its schema is managed by
+the versioned migrations in migrations/.
+
+*/
+
+package state
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/elys-network/avm/internal/types"
+	"github.com/lib/pq"
+	"github.com/rs/zerolog/log"
+)
+
+// PostgresStore is the Store backed by PostgreSQL
+type PostgresStore struct {
+	sqlStore
+}
+
+// NewPostgresStore returns a Store using an open PostgreSQL connection pool.
+func NewPostgresStore(db *sql.DB) *PostgresStore {
+	return &PostgresStore{sqlStore{db: db, dialect: postgresDialect{}}}
+}
+
+// postgresDialect is the sqlDialect of PostgreSQL
+type postgresDialect struct{}
+
+// lockScoringConfig takes a transaction-scoped advisory lock on the config
+func (postgresDialect) lockScoringConfig(tx *sql.Tx, configName string) error {
+	_, err := tx.Exec(`SELECT pg_advisory_xact_lock(hashtext('scoring_parameters:' || $1))`, configName)
+	return err
+}
+
+// stringArray stores values in a TEXT[] column
+func (postgresDialect) stringArray(values []string) interface{} {
+	return pq.Array(values)
+}
+
+// scanStringArray reads a TEXT[] column
+func (postgresDialect) scanStringArray(dest *[]string) interface{} {
+	return pq.Array(dest)
+}
+
+// DownsamplePoolMetrics applies the pool metrics retention policy: raw points older than rawRetention
+// are averaged into hourly points, hourly points older than hourlyRetention into daily points, and
+// daily points older than dailyRetention are deleted. A dailyRetention of 0 keeps daily points forever.
+// Only complete hours and days are downsampled.
+func (s *PostgresStore) DownsamplePoolMetrics(rawRetention, hourlyRetention, dailyRetention time.Duration) error {
+	now := time.Now().UTC()
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback() // No-op after a successful commit
+
+	hourlyCutoff := now.Add(-rawRetention).Truncate(time.Hour)
+	rawMoved, err := downsamplePoolMetricsTier(tx, types.PoolMetricsRaw, types.PoolMetricsHourly, "hour", hourlyCutoff)
+	if err != nil {
+		return err
+	}
+
+	dailyCutoff := now.Add(-hourlyRetention).Truncate(24 * time.Hour)
+	hourlyMoved, err := downsamplePoolMetricsTier(tx, types.PoolMetricsHourly, types.PoolMetricsDaily, "day", dailyCutoff)
+	if err != nil {
+		return err
+	}
+
+	var dailyDeleted int64
+	if dailyRetention > 0 {
+		result, err := tx.Exec(`DELETE FROM pool_metrics WHERE resolution = $1 AND recorded_at < $2`,
+			string(types.PoolMetricsDaily), now.Add(-dailyRetention))
+		if err != nil {
+			return fmt.Errorf("failed to delete expired daily pool metrics: %w", err)
+		}
+		dailyDeleted, _ = result.RowsAffected()
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit pool metrics downsampling: %w", err)
+	}
+
+	if rawMoved > 0 || hourlyMoved > 0 || dailyDeleted > 0 {
+		log.Info().
+			Int64("rawPointsDownsampled", rawMoved).
+			Int64("hourlyPointsDownsampled", hourlyMoved).
+			Int64("dailyPointsDeleted", dailyDeleted).
+			Msg("Downsampled pool metrics")
+	}
+	return nil
+}
+
+// downsamplePoolMetricsTier replaces the points of one resolution recorded before cutoff with their
+// averages per pool and bucket at the next resolution, and returns how many points were replaced.
+// bucket is the date_trunc unit of the next resolution; buckets are aligned to UTC.
+func downsamplePoolMetricsTier(tx *sql.Tx, from, to types.PoolMetricsResolution, bucket string, cutoff time.Time) (int64, error) {
+	averages := make([]string, len(poolMetricsAveragedColumns))
+	merges := make([]string, len(poolMetricsAveragedColumns))
+	for i, column := range poolMetricsAveragedColumns {
+		averages[i] = fmt.Sprintf("SUM(%[1]s * sample_count) / SUM(sample_count)", column)
+		merges[i] = fmt.Sprintf(
+			"%[1]s = (pool_metrics.%[1]s * pool_metrics.sample_count + EXCLUDED.%[1]s * EXCLUDED.sample_count) / (pool_metrics.sample_count + EXCLUDED.sample_count)",
+			column)
+	}
+
+	// The CTE deletes the old points and the insert writes their averages in one statement.
+	// A bucket that already exists at the next resolution is merged, keeping the averages exact.
+	query := fmt.Sprintf(`
+		WITH moved AS (
+			DELETE FROM pool_metrics
+			WHERE resolution = $1 AND recorded_at < $2
+			RETURNING *
+		), inserted AS (
+			INSERT INTO pool_metrics (
+				pool_id, resolution, recorded_at, sample_count, cycle_number, block_height,
+				%[1]s,
+				total_shares, assets
+			)
+			SELECT
+				pool_id, $3::text, date_trunc('%[2]s', recorded_at AT TIME ZONE 'UTC') AT TIME ZONE 'UTC' AS bucket,
+				SUM(sample_count), MAX(cycle_number), MAX(block_height),
+				%[3]s,
+				(ARRAY_AGG(total_shares ORDER BY recorded_at DESC))[1],
+				(ARRAY_AGG(assets ORDER BY recorded_at DESC))[1]
+			FROM moved
+			GROUP BY pool_id, bucket
+			ON CONFLICT (pool_id, resolution, recorded_at) DO UPDATE
+			SET %[4]s,
+				sample_count = pool_metrics.sample_count + EXCLUDED.sample_count,
+				total_shares = CASE WHEN EXCLUDED.cycle_number >= pool_metrics.cycle_number THEN EXCLUDED.total_shares ELSE pool_metrics.total_shares END,
+				assets = CASE WHEN EXCLUDED.cycle_number >= pool_metrics.cycle_number THEN EXCLUDED.assets ELSE pool_metrics.assets END,
+				cycle_number = GREATEST(pool_metrics.cycle_number, EXCLUDED.cycle_number),
+				block_height = GREATEST(pool_metrics.block_height, EXCLUDED.block_height)
+		)
+		SELECT COUNT(*) FROM moved
+	`, strings.Join(poolMetricsAveragedColumns, ", "), bucket, strings.Join(averages, ",\n\t\t\t\t"), strings.Join(merges, ",\n\t\t\t\t"))
+
+	var moved int64
+	if err := tx.QueryRow(query, string(from), cutoff.UTC(), string(to)).Scan(&moved); err != nil {
+		return 0, fmt.Errorf("failed to downsample %s pool metrics to %s: %w", from, to, err)
+	}
+	return moved, nil
+}
+
+// GetPoolMetricsHistory returns a pool's metrics points recorded within [from, to], oldest first.
+// With an empty interval the stored points are returned as they are, so older parts of the range
+// come at a coarser resolution. With interval "hour" or "day" the points are averaged into
+// buckets of that size, weighted by sample count.
+func (s *PostgresStore) GetPoolMetricsHistory(poolID types.PoolID, from, to time.Time, interval string) ([]types.PoolMetricsPoint, error) {
+	resolution, _, err := poolMetricsIntervalResolution(interval)
+	if err != nil {
+		return nil, err
+	}
+	if interval == "" {
+		return s.loadPoolMetrics(poolID, from, to)
+	}
+
+	averages := make([]string, len(poolMetricsAveragedColumns))
+	for i, column := range poolMetricsAveragedColumns {
+		averages[i] = fmt.Sprintf("SUM(%[1]s * sample_count) / SUM(sample_count)", column)
+	}
+	query := fmt.Sprintf(`
+		SELECT
+			pool_id, date_trunc('%[1]s', recorded_at AT TIME ZONE 'UTC') AT TIME ZONE 'UTC' AS bucket, '%[2]s',
+			SUM(sample_count), MAX(cycle_number), MAX(block_height),
+			%[3]s,
+			(ARRAY_AGG(total_shares ORDER BY recorded_at DESC))[1],
+			(ARRAY_AGG(assets ORDER BY recorded_at DESC))[1]
+		FROM pool_metrics
+		WHERE pool_id = $1 AND recorded_at >= $2 AND recorded_at <= $3
+		GROUP BY pool_id, bucket
+		ORDER BY bucket ASC
+	`, interval, resolution, strings.Join(averages, ", "))
+
+	rows, err := s.db.Query(query, uint64(poolID), from.UTC(), to.UTC())
+	if err != nil {
+		return nil, fmt.Errorf("failed to query metrics history for pool %d: %w", poolID, err)
+	}
+	defer rows.Close()
+
+	return scanPoolMetricsPoints(rows)
+}
@@ -0,0 +1,132 @@
+/*
+
+This file manages the persistent hourly price history cache.
+Bars are keyed by symbol and hour so repeated writes of the same hour simply refresh its close price.
+
+*/
+
+package state
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/elys-network/avm/internal/types"
+	"github.com/rs/zerolog/log"
+)
+
+// SavePriceHistory upserts hourly bars for a symbol, recording the provider that supplied them.
+// Bar timestamps are truncated to the hour.
+func SavePriceHistory(symbol string, source string, bars []types.PriceData) error {
+	if DB == nil {
+		return fmt.Errorf("database not initialized")
+	}
+	if len(bars) == 0 {
+		return nil
+	}
+
+	tx, err := DB.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback() // No-op after a successful commit
+
+	stmt, err := tx.Prepare(`
+		INSERT INTO price_history (symbol, bar_time, close_price, source, fetched_at)
+		VALUES ($1, $2, $3, $4, CURRENT_TIMESTAMP)
+		ON CONFLICT (symbol, bar_time) DO UPDATE
+		SET close_price = EXCLUDED.close_price, source = EXCLUDED.source, fetched_at = EXCLUDED.fetched_at;
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to prepare price history insert: %w", err)
+	}
+	defer stmt.Close()
+
+	for _, bar := range bars {
+		if _, err := stmt.Exec(symbol, bar.Timestamp.UTC().Truncate(time.Hour), bar.Price, source); err != nil {
+			return fmt.Errorf("failed to save price bar for %s at %s: %w", symbol, bar.Timestamp.UTC().Format(time.RFC3339), err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit price history for %s: %w", symbol, err)
+	}
+
+	log.Debug().Str("symbol", symbol).Str("source", source).Int("bars", len(bars)).Msg("Saved price history bars")
+	return nil
+}
+
+// LoadPriceHistory returns the cached hourly bars for a symbol within [from, to], oldest first.
+func LoadPriceHistory(symbol string, from, to time.Time) ([]types.PriceData, error) {
+	if DB == nil {
+		return nil, fmt.Errorf("database not initialized")
+	}
+
+	query := `
+		SELECT bar_time, close_price
+		FROM price_history
+		WHERE symbol = $1 AND bar_time >= $2 AND bar_time <= $3
+		ORDER BY bar_time ASC
+	`
+
+	rows, err := DB.Query(query, symbol, from.UTC(), to.UTC())
+	if err != nil {
+		return nil, fmt.Errorf("failed to query price history for %s: %w", symbol, err)
+	}
+	defer rows.Close()
+
+	bars := make([]types.PriceData, 0)
+	for rows.Next() {
+		var bar types.PriceData
+		if err := rows.Scan(&bar.Timestamp, &bar.Price); err != nil {
+			return nil, fmt.Errorf("failed to scan price history row for %s: %w", symbol, err)
+		}
+		bars = append(bars, bar)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error during price history iteration for %s: %w", symbol, err)
+	}
+
+	return bars, nil
+}
+
+// GetPriceHistoryFreshness reports, for every cached symbol, the newest bar, when it was last
+// written, and how many bars fall within the given window ending now.
+func GetPriceHistoryFreshness(window time.Duration) ([]types.PriceHistoryFreshness, error) {
+	if DB == nil {
+		return nil, fmt.Errorf("database not initialized")
+	}
+
+	query := `
+		SELECT
+			symbol,
+			MAX(bar_time) AS latest_bar,
+			MAX(fetched_at) AS last_fetched_at,
+			COUNT(*) FILTER (WHERE bar_time > $1) AS bar_count
+		FROM price_history
+		GROUP BY symbol
+		ORDER BY symbol
+	`
+
+	now := time.Now().UTC()
+	rows, err := DB.Query(query, now.Add(-window))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query price history freshness: %w", err)
+	}
+	defer rows.Close()
+
+	freshness := make([]types.PriceHistoryFreshness, 0)
+	for rows.Next() {
+		var f types.PriceHistoryFreshness
+		if err := rows.Scan(&f.Symbol, &f.LatestBar, &f.LastFetchedAt, &f.BarCount); err != nil {
+			return nil, fmt.Errorf("failed to scan price history freshness row: %w", err)
+		}
+		f.AgeHours = now.Sub(f.LatestBar).Hours()
+		freshness = append(freshness, f)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error during price history freshness iteration: %w", err)
+	}
+
+	return freshness, nil
+}
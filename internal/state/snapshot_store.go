@@ -4,18 +4,14 @@ package state
 import (
 	"encoding/json"
 	"fmt"
+	"time"
 
 	"github.com/elys-network/avm/internal/types"
-	"github.com/lib/pq" // PostgreSQL driver for array support
 	"github.com/rs/zerolog/log"
 )
 
 // SaveCycleSnapshot saves a complete cycle snapshot to the database.
-func SaveCycleSnapshot(snapshot types.CycleSnapshot) (int64, error) {
-	if DB == nil {
-		return 0, fmt.Errorf("database not initialized")
-	}
-
+func (s *sqlStore) SaveCycleSnapshot(snapshot types.CycleSnapshot) (int64, error) {
 	// Marshal all JSONB fields
 	initialPositionsJSON, err := json.Marshal(snapshot.InitialPositions)
 	if err != nil {
@@ -42,6 +38,41 @@ func SaveCycleSnapshot(snapshot types.CycleSnapshot) (int64, error) {
 		return 0, fmt.Errorf("failed to marshal action_receipts: %w", err)
 	}
 
+	targetTokenExposuresJSON, err := json.Marshal(snapshot.TargetTokenExposures)
+	if err != nil {
+		return 0, fmt.Errorf("failed to marshal target_token_exposures: %w", err)
+	}
+
+	tokenExposuresJSON, err := json.Marshal(snapshot.TokenExposures)
+	if err != nil {
+		return 0, fmt.Errorf("failed to marshal token_exposures: %w", err)
+	}
+
+	priceIntegrityJSON, err := json.Marshal(snapshot.PriceIntegrity)
+	if err != nil {
+		return 0, fmt.Errorf("failed to marshal price_integrity: %w", err)
+	}
+
+	quarantineJSON, err := json.Marshal(snapshot.Quarantine)
+	if err != nil {
+		return 0, fmt.Errorf("failed to marshal quarantine: %w", err)
+	}
+
+	stepTimingsJSON, err := json.Marshal(snapshot.StepTimings)
+	if err != nil {
+		return 0, fmt.Errorf("failed to marshal step_timings: %w", err)
+	}
+
+	scoringParamsReloadJSON, err := json.Marshal(snapshot.ScoringParamsReload)
+	if err != nil {
+		return 0, fmt.Errorf("failed to marshal scoring_params_reload: %w", err)
+	}
+
+	poolScoresJSON, err := json.Marshal(snapshot.PoolScores)
+	if err != nil {
+		return 0, fmt.Errorf("failed to marshal pool_scores: %w", err)
+	}
+
 	query := `
 		INSERT INTO cycle_snapshots (
 			cycle_number, snapshot_timestamp, scoring_params_id,
@@ -49,20 +80,24 @@ func SaveCycleSnapshot(snapshot types.CycleSnapshot) (int64, error) {
 			target_allocations, action_plan,
 			final_vault_value_usd, final_liquid_usdc, final_positions,
 			transaction_hashes, action_receipts,
-			allocation_efficiency_percent, net_return_usd, total_slippage_usd, total_gas_fee_usd
-		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17)
+			allocation_efficiency_percent, net_return_usd, total_slippage_usd, total_gas_fee_usd,
+			target_token_exposures, token_exposures, price_integrity, quarantine, step_timings,
+			block_height, scoring_params_reload, pool_scores, unallocated_target
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21, $22, $23, $24, $25, $26)
 		RETURNING snapshot_id;
 	`
 
 	var snapshotID int64
-	err = DB.QueryRow(
+	err = s.db.QueryRow(
 		query,
-		snapshot.CycleNumber, snapshot.Timestamp, snapshot.ScoringParamsID,
-		snapshot.InitialVaultValueUSD, snapshot.InitialLiquidUSDC, initialPositionsJSON,
-		targetAllocationsJSON, actionPlanJSON,
-		snapshot.FinalVaultValueUSD, snapshot.FinalLiquidUSDC, finalPositionsJSON,
-		pq.Array(snapshot.TransactionHashes), actionReceiptsJSON,
+		snapshot.CycleNumber, snapshot.Timestamp.UTC(), snapshot.ScoringParamsID,
+		snapshot.InitialVaultValueUSD, snapshot.InitialLiquidUSDC, string(initialPositionsJSON),
+		string(targetAllocationsJSON), string(actionPlanJSON),
+		snapshot.FinalVaultValueUSD, snapshot.FinalLiquidUSDC, string(finalPositionsJSON),
+		s.dialect.stringArray(snapshot.TransactionHashes), string(actionReceiptsJSON),
 		snapshot.AllocationEfficiencyPercent, snapshot.NetReturnUSD, snapshot.TotalSlippageUSD, snapshot.TotalGasFeeUSD,
+		string(targetTokenExposuresJSON), string(tokenExposuresJSON), string(priceIntegrityJSON), string(quarantineJSON), string(stepTimingsJSON),
+		snapshot.BlockHeight, string(scoringParamsReloadJSON), string(poolScoresJSON), snapshot.UnallocatedTarget,
 	).Scan(&snapshotID)
 
 	if err != nil {
@@ -77,3 +112,19 @@ func SaveCycleSnapshot(snapshot types.CycleSnapshot) (int64, error) {
 
 	return snapshotID, nil
 }
+
+// DeleteCyclesBefore deletes the cycle snapshots taken before the given time and returns how many
+// were deleted. Callers archive them first; see internal/export.
+func (s *sqlStore) DeleteCyclesBefore(before time.Time) (int64, error) {
+	result, err := s.db.Exec(`DELETE FROM cycle_snapshots WHERE snapshot_timestamp < $1`, before.UTC())
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete cycle snapshots before %s: %w", before.UTC().Format(time.RFC3339), err)
+	}
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to check rows affected: %w", err)
+	}
+
+	log.Info().Int64("deleted", deleted).Time("before", before.UTC()).Msg("Deleted cycle snapshots")
+	return deleted, nil
+}
@@ -0,0 +1,105 @@
+-- Baseline SQLite schema: the tables of the Store, matching the PostgreSQL schema after
+-- migration 0006. Decimals are stored as REAL, JSON as TEXT and transaction hashes as a JSON array.
+
+CREATE TABLE scoring_parameters (
+	params_id INTEGER PRIMARY KEY AUTOINCREMENT,
+	version INTEGER NOT NULL DEFAULT 1,
+	config_name TEXT NOT NULL DEFAULT 'default',
+	is_active BOOLEAN NOT NULL DEFAULT FALSE,
+	activated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	eden_weight REAL NOT NULL, usdc_fee_weight REAL NOT NULL, price_impact_weight REAL NOT NULL,
+	apr_coefficient REAL NOT NULL, trading_volume_coefficient REAL NOT NULL,
+	il_risk_coefficient REAL NOT NULL, volatility_coefficient REAL NOT NULL,
+	new_pool_coefficient REAL NOT NULL, tvl_coefficient REAL NOT NULL,
+	smart_shield_bonus REAL NOT NULL, continuity_coefficient REAL NOT NULL,
+	sentiment_impact_factor REAL NOT NULL,
+	il_confidence_factor REAL NOT NULL, il_holding_period_years REAL NOT NULL,
+	smart_shield_reduction_factor REAL NOT NULL,
+	min_tvl_threshold REAL NOT NULL, pool_maturity_days INTEGER NOT NULL, continuity_lookback_days INTEGER NOT NULL,
+	rebalance_threshold_amount REAL NOT NULL, max_pools INTEGER NOT NULL,
+	min_allocation REAL NOT NULL, max_allocation REAL NOT NULL,
+	smart_shield_slippage_percent REAL NOT NULL,
+	normal_pool_slippage_percent REAL NOT NULL,
+	min_liquid_usdc_buffer REAL NOT NULL DEFAULT 50.0,
+	max_rebalance_percent_per_cycle REAL NOT NULL DEFAULT 5.0,
+	learning_rate REAL NOT NULL DEFAULT 0.01,
+	max_parameter_change REAL NOT NULL DEFAULT 0.1,
+	optimization_interval_cycles INTEGER NOT NULL,
+	elys_forced_allocation_minimum REAL NOT NULL DEFAULT 0.10,
+	max_token_exposure REAL NOT NULL DEFAULT 0.30,
+	viable_swap_reduction_factor REAL NOT NULL DEFAULT 0.9,
+	viable_deposit_reduction_factor REAL NOT NULL DEFAULT 0.8,
+	CONSTRAINT uq_scoring_parameters_config_version UNIQUE (config_name, version)
+);
+CREATE INDEX idx_scoring_parameters_config_active_timestamp ON scoring_parameters(config_name, is_active, activated_at DESC);
+CREATE INDEX idx_scoring_parameters_config_timestamp ON scoring_parameters(config_name, activated_at DESC);
+
+CREATE TABLE scoring_parameter_activations (
+	activation_id INTEGER PRIMARY KEY AUTOINCREMENT,
+	config_name TEXT NOT NULL,
+	params_id INTEGER NOT NULL REFERENCES scoring_parameters(params_id),
+	previous_params_id INTEGER REFERENCES scoring_parameters(params_id),
+	action TEXT NOT NULL, -- 'activate' or 'rollback'
+	activated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX idx_scoring_parameter_activations_config ON scoring_parameter_activations(config_name, activation_id DESC);
+
+CREATE TABLE cycle_snapshots (
+	snapshot_id INTEGER PRIMARY KEY AUTOINCREMENT,
+	cycle_number INTEGER NOT NULL,
+	snapshot_timestamp TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	scoring_params_id INTEGER REFERENCES scoring_parameters(params_id),
+	initial_vault_value_usd REAL NOT NULL,
+	initial_liquid_usdc REAL NOT NULL,
+	initial_positions TEXT,
+	target_allocations TEXT,
+	action_plan TEXT,
+	final_vault_value_usd REAL NOT NULL,
+	final_liquid_usdc REAL NOT NULL,
+	final_positions TEXT,
+	transaction_hashes TEXT, -- JSON array of strings
+	action_receipts TEXT,
+	allocation_efficiency_percent REAL,
+	net_return_usd REAL,
+	total_slippage_usd REAL,
+	total_gas_fee_usd REAL,
+	target_token_exposures TEXT,
+	token_exposures TEXT,
+	price_integrity TEXT,
+	quarantine TEXT,
+	step_timings TEXT,
+	block_height INTEGER,
+	scoring_params_reload TEXT
+);
+CREATE INDEX idx_cycle_snapshots_timestamp ON cycle_snapshots(snapshot_timestamp DESC);
+CREATE INDEX idx_cycle_snapshots_cycle ON cycle_snapshots(cycle_number DESC);
+
+CREATE TABLE cycle_counter (
+	id INTEGER PRIMARY KEY DEFAULT 1,
+	current_cycle INTEGER NOT NULL DEFAULT 0,
+	updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	CONSTRAINT single_row_check CHECK (id = 1)
+);
+INSERT INTO cycle_counter (id, current_cycle) VALUES (1, 0);
+
+CREATE TABLE pool_metrics (
+	pool_id INTEGER NOT NULL,
+	resolution TEXT NOT NULL, -- 'raw', 'hourly' or 'daily'
+	recorded_at TIMESTAMP NOT NULL, -- Cycle start for raw points, bucket start otherwise
+	sample_count INTEGER NOT NULL DEFAULT 1,
+	cycle_number INTEGER NOT NULL,
+	block_height INTEGER NOT NULL,
+	tvl_usd REAL NOT NULL,
+	volume_7d_usd REAL NOT NULL,
+	eden_rewards_apr REAL NOT NULL,
+	usdc_fees_apr REAL NOT NULL,
+	price_impact_apr REAL NOT NULL,
+	total_apr REAL NOT NULL,
+	swap_fee REAL NOT NULL,
+	total_shares TEXT NOT NULL, -- Arbitrary precision integer
+	assets TEXT NOT NULL,
+	PRIMARY KEY (pool_id, resolution, recorded_at)
+);
+CREATE INDEX idx_pool_metrics_pool_time ON pool_metrics(pool_id, recorded_at DESC);
+CREATE INDEX idx_pool_metrics_resolution_time ON pool_metrics(resolution, recorded_at);
@@ -0,0 +1,16 @@
+-- NAV per share time series, matching PostgreSQL migration 0007
+CREATE TABLE vault_performance (
+	cycle_number INTEGER PRIMARY KEY,
+	recorded_at TIMESTAMP NOT NULL, -- Cycle start
+	block_height INTEGER NOT NULL,
+	vault_value_usd REAL NOT NULL,
+	share_supply TEXT NOT NULL, -- Arbitrary precision integer
+	nav_per_share REAL NOT NULL,
+	net_flow_usd REAL NOT NULL,
+	twr_inception REAL NOT NULL,
+	mwr_annualized REAL,
+	apy_7d REAL,
+	apy_30d REAL,
+	apy_inception REAL
+);
+CREATE INDEX idx_vault_performance_time ON vault_performance(recorded_at);
@@ -0,0 +1,20 @@
+-- Per-pool PnL attribution, matching PostgreSQL migration 0008
+CREATE TABLE pool_pnl_attribution (
+	cycle_number INTEGER NOT NULL,
+	pool_id INTEGER NOT NULL, -- 0 for execution costs not tied to a pool
+	recorded_at TIMESTAMP NOT NULL, -- Cycle start
+	period_seconds REAL NOT NULL,
+	start_value_usd REAL NOT NULL,
+	end_value_usd REAL NOT NULL,
+	lp_fees_usd REAL NOT NULL,
+	eden_rewards_usd REAL NOT NULL,
+	usdc_rewards_usd REAL NOT NULL,
+	price_return_usd REAL NOT NULL,
+	impermanent_loss_usd REAL NOT NULL,
+	slippage_usd REAL NOT NULL,
+	gas_usd REAL NOT NULL,
+	total_pnl_usd REAL NOT NULL,
+	market_data_complete BOOLEAN NOT NULL,
+	PRIMARY KEY (cycle_number, pool_id)
+);
+CREATE INDEX idx_pool_pnl_attribution_time ON pool_pnl_attribution(recorded_at);
@@ -0,0 +1,22 @@
+-- Append-only audit log, matching PostgreSQL migration 0009
+CREATE TABLE audit_events (
+	event_id INTEGER PRIMARY KEY AUTOINCREMENT,
+	recorded_at TIMESTAMP NOT NULL,
+	action TEXT NOT NULL,
+	target TEXT NOT NULL,
+	actor TEXT NOT NULL,
+	reason TEXT NOT NULL,
+	diff TEXT -- JSON
+);
+CREATE INDEX idx_audit_events_time ON audit_events(recorded_at DESC);
+CREATE INDEX idx_audit_events_action ON audit_events(action, recorded_at DESC);
+
+CREATE TRIGGER audit_events_no_update BEFORE UPDATE ON audit_events
+BEGIN
+	SELECT RAISE(ABORT, 'audit_events is append-only');
+END;
+
+CREATE TRIGGER audit_events_no_delete BEFORE DELETE ON audit_events
+BEGIN
+	SELECT RAISE(ABORT, 'audit_events is append-only');
+END;
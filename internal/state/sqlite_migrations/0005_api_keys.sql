@@ -0,0 +1,12 @@
+-- API keys of the web API, matching PostgreSQL migration 0010
+CREATE TABLE api_keys (
+	key_id INTEGER PRIMARY KEY AUTOINCREMENT,
+	name TEXT NOT NULL UNIQUE,
+	role TEXT NOT NULL,
+	key_prefix TEXT NOT NULL,
+	key_hash TEXT NOT NULL UNIQUE,
+	created_at TIMESTAMP NOT NULL,
+	created_by TEXT NOT NULL,
+	last_used_at TIMESTAMP,
+	revoked_at TIMESTAMP
+);
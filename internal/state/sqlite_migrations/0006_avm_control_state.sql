@@ -0,0 +1,8 @@
+-- Operator control mode of the AVM, matching PostgreSQL migration 0011
+CREATE TABLE avm_control_state (
+	id INTEGER PRIMARY KEY CHECK (id = 1),
+	mode TEXT NOT NULL,
+	updated_at TIMESTAMP NOT NULL,
+	updated_by TEXT NOT NULL,
+	reason TEXT NOT NULL
+);
@@ -0,0 +1,2 @@
+-- Score and score components of every pool a cycle scored, matching PostgreSQL migration 0012
+ALTER TABLE cycle_snapshots ADD COLUMN pool_scores TEXT;
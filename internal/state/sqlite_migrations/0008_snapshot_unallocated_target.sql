@@ -0,0 +1,2 @@
+-- Fraction of the vault a cycle left in liquid USDC because of the token exposure cap, matching PostgreSQL migration 0013
+ALTER TABLE cycle_snapshots ADD COLUMN unallocated_target REAL;
@@ -0,0 +1,385 @@
+/*
+
+This file implements the Store on SQLite, an embedded single-file database for development,
+backtests and small vaults. It needs no server: the file is created on first open and its
+schema is brought up to date from the migrations in sqlite_migrations/.
+
+SQLite allows one writer at a time, so the store uses a single connection and starts every
+transaction with the write lock held (BEGIN IMMEDIATE). Times are stored as UTC text, which
+sorts chronologically.
+
+*/
+
+package state
+
+import (
+	"database/sql"
+	"embed"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/elys-network/avm/internal/types"
+	"github.com/rs/zerolog/log"
+	_ "modernc.org/sqlite"
+)
+
+//go:embed sqlite_migrations/*.sql
+var sqliteMigrationFiles embed.FS
+
+var sqliteMigrationFilePattern = regexp.MustCompile(`^(\d{4})_([a-z0-9_]+)\.sql$`)
+
+// SQLiteStore is the Store backed by a local SQLite file
+type SQLiteStore struct {
+	sqlStore
+	path string
+}
+
+// OpenSQLiteStore opens (creating it if needed) the SQLite database at path and applies any
+// pending schema migrations.
+func OpenSQLiteStore(dbPath string) (*SQLiteStore, error) {
+	if dbPath == "" {
+		return nil, fmt.Errorf("sqlite database path is required")
+	}
+	if dir := filepath.Dir(dbPath); dir != "." {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return nil, fmt.Errorf("failed to create directory for sqlite database: %w", err)
+		}
+	}
+
+	dsn := "file:" + dbPath +
+		"?_pragma=foreign_keys(1)&_pragma=journal_mode(WAL)&_pragma=busy_timeout(5000)" +
+		"&_time_format=sqlite&_txlock=immediate"
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open sqlite database: %w", err)
+	}
+	// A single connection serializes writers; queries must never be made outside an open transaction
+	// while it is held
+	db.SetMaxOpenConns(1)
+
+	store := &SQLiteStore{sqlStore: sqlStore{db: db, dialect: sqliteDialect{}}, path: dbPath}
+	if err := store.Ping(); err != nil {
+		db.Close()
+		return nil, err
+	}
+	if err := store.migrate(); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	log.Info().Str("path", dbPath).Msg("Opened SQLite store")
+	return store, nil
+}
+
+// migrate applies the embedded migrations newer than the database's user_version, each in its
+// own transaction together with the version bump
+func (s *SQLiteStore) migrate() error {
+	migrations, err := loadSQLiteMigrations()
+	if err != nil {
+		return err
+	}
+
+	var current int
+	if err := s.db.QueryRow(`PRAGMA user_version`).Scan(&current); err != nil {
+		return fmt.Errorf("failed to read sqlite schema version: %w", err)
+	}
+	if current > len(migrations) {
+		return fmt.Errorf("%w: %s is at schema version %d, but this build only knows versions up to %d",
+			ErrSchemaTooNew, s.path, current, len(migrations))
+	}
+
+	for _, m := range migrations[current:] {
+		tx, err := s.db.Begin()
+		if err != nil {
+			return fmt.Errorf("failed to begin transaction: %w", err)
+		}
+		if _, err := tx.Exec(m.Up); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("failed to apply sqlite migration %04d_%s: %w", m.Version, m.Name, err)
+		}
+		// PRAGMA does not take parameters; the version is a number
+		if _, err := tx.Exec(fmt.Sprintf(`PRAGMA user_version = %d`, m.Version)); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("failed to record sqlite migration %04d_%s: %w", m.Version, m.Name, err)
+		}
+		if err := tx.Commit(); err != nil {
+			return fmt.Errorf("failed to commit sqlite migration %04d_%s: %w", m.Version, m.Name, err)
+		}
+		log.Info().Int("version", m.Version).Str("name", m.Name).Msg("Applied SQLite migration")
+	}
+	return nil
+}
+
+// loadSQLiteMigrations reads the embedded SQLite migration files, ordered by version.
+// Unlike the PostgreSQL migrations they only go up.
+func loadSQLiteMigrations() ([]migration, error) {
+	entries, err := fs.ReadDir(sqliteMigrationFiles, "sqlite_migrations")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read sqlite migrations: %w", err)
+	}
+
+	migrations := make([]migration, 0, len(entries))
+	for _, entry := range entries {
+		matches := sqliteMigrationFilePattern.FindStringSubmatch(entry.Name())
+		if matches == nil {
+			return nil, fmt.Errorf("sqlite migration file %s does not match NNNN_name.sql", entry.Name())
+		}
+		version, _ := strconv.Atoi(matches[1])
+		content, err := sqliteMigrationFiles.ReadFile(path.Join("sqlite_migrations", entry.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read sqlite migration %s: %w", entry.Name(), err)
+		}
+		migrations = append(migrations, migration{Version: version, Name: matches[2], Up: string(content)})
+	}
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+	for i, m := range migrations {
+		if m.Version != i+1 {
+			return nil, fmt.Errorf("sqlite migrations must be numbered from 1 without gaps, found %04d at position %d", m.Version, i+1)
+		}
+	}
+
+	return migrations, nil
+}
+
+// sqliteDialect is the sqlDialect of SQLite
+type sqliteDialect struct{}
+
+// lockScoringConfig is a no-op: transactions already start with the database write lock held
+func (sqliteDialect) lockScoringConfig(tx *sql.Tx, configName string) error {
+	return nil
+}
+
+// stringArray stores values as a JSON array in a TEXT column
+func (sqliteDialect) stringArray(values []string) interface{} {
+	if values == nil {
+		return nil
+	}
+	encoded, err := json.Marshal(values)
+	if err != nil {
+		// Marshaling a string slice cannot fail
+		return nil
+	}
+	return string(encoded)
+}
+
+// scanStringArray reads a TEXT column holding a JSON array
+func (sqliteDialect) scanStringArray(dest *[]string) interface{} {
+	return &jsonStringArray{dest: dest}
+}
+
+// jsonStringArray is a Scan destination decoding a JSON array of strings
+type jsonStringArray struct {
+	dest *[]string
+}
+
+// Scan implements sql.Scanner
+func (a *jsonStringArray) Scan(src interface{}) error {
+	var data []byte
+	switch value := src.(type) {
+	case nil:
+		*a.dest = nil
+		return nil
+	case string:
+		data = []byte(value)
+	case []byte:
+		data = value
+	default:
+		return fmt.Errorf("cannot scan %T into a string array", src)
+	}
+	return json.Unmarshal(data, a.dest)
+}
+
+// DownsamplePoolMetrics applies the pool metrics retention policy: raw points older than rawRetention
+// are averaged into hourly points, hourly points older than hourlyRetention into daily points, and
+// daily points older than dailyRetention are deleted. A dailyRetention of 0 keeps daily points forever.
+// Only complete hours and days are downsampled.
+func (s *SQLiteStore) DownsamplePoolMetrics(rawRetention, hourlyRetention, dailyRetention time.Duration) error {
+	now := time.Now().UTC()
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback() // No-op after a successful commit
+
+	hourlyCutoff := now.Add(-rawRetention).Truncate(time.Hour)
+	rawMoved, err := downsampleSQLitePoolMetricsTier(tx, types.PoolMetricsRaw, types.PoolMetricsHourly, time.Hour, hourlyCutoff)
+	if err != nil {
+		return err
+	}
+
+	dailyCutoff := now.Add(-hourlyRetention).Truncate(24 * time.Hour)
+	hourlyMoved, err := downsampleSQLitePoolMetricsTier(tx, types.PoolMetricsHourly, types.PoolMetricsDaily, 24*time.Hour, dailyCutoff)
+	if err != nil {
+		return err
+	}
+
+	var dailyDeleted int64
+	if dailyRetention > 0 {
+		result, err := tx.Exec(`DELETE FROM pool_metrics WHERE resolution = $1 AND recorded_at < $2`,
+			string(types.PoolMetricsDaily), now.Add(-dailyRetention))
+		if err != nil {
+			return fmt.Errorf("failed to delete expired daily pool metrics: %w", err)
+		}
+		dailyDeleted, _ = result.RowsAffected()
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit pool metrics downsampling: %w", err)
+	}
+
+	if rawMoved > 0 || hourlyMoved > 0 || dailyDeleted > 0 {
+		log.Info().
+			Int64("rawPointsDownsampled", rawMoved).
+			Int64("hourlyPointsDownsampled", hourlyMoved).
+			Int64("dailyPointsDeleted", dailyDeleted).
+			Msg("Downsampled pool metrics")
+	}
+	return nil
+}
+
+// downsampleSQLitePoolMetricsTier replaces the points of one resolution recorded before cutoff with
+// their averages per pool and bucket at the next resolution, and returns how many points were replaced.
+// SQLite has no date_trunc, so the points are bucketed in Go; buckets are aligned to UTC.
+func downsampleSQLitePoolMetricsTier(tx *sql.Tx, from, to types.PoolMetricsResolution, bucketSize time.Duration, cutoff time.Time) (int64, error) {
+	rows, err := tx.Query(`
+		SELECT `+poolMetricsSelectColumns+`
+		FROM pool_metrics
+		WHERE resolution = $1 AND recorded_at < $2
+		ORDER BY pool_id ASC, recorded_at ASC
+	`, string(from), cutoff.UTC())
+	if err != nil {
+		return 0, fmt.Errorf("failed to query %s pool metrics to downsample: %w", from, err)
+	}
+	points, err := scanPoolMetricsPoints(rows)
+	rows.Close()
+	if err != nil {
+		return 0, err
+	}
+	if len(points) == 0 {
+		return 0, nil
+	}
+
+	if _, err := tx.Exec(`DELETE FROM pool_metrics WHERE resolution = $1 AND recorded_at < $2`, string(from), cutoff.UTC()); err != nil {
+		return 0, fmt.Errorf("failed to delete downsampled %s pool metrics: %w", from, err)
+	}
+
+	// A bucket that already exists at the next resolution is merged, keeping the averages exact
+	merges := make([]string, len(poolMetricsAveragedColumns))
+	for i, column := range poolMetricsAveragedColumns {
+		merges[i] = fmt.Sprintf(
+			"%[1]s = (pool_metrics.%[1]s * pool_metrics.sample_count + excluded.%[1]s * excluded.sample_count) / (pool_metrics.sample_count + excluded.sample_count)",
+			column)
+	}
+	stmt, err := tx.Prepare(fmt.Sprintf(`
+		INSERT INTO pool_metrics (
+			pool_id, resolution, recorded_at, sample_count, cycle_number, block_height,
+			%[1]s,
+			total_shares, assets
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15)
+		ON CONFLICT (pool_id, resolution, recorded_at) DO UPDATE
+		SET %[2]s,
+			sample_count = pool_metrics.sample_count + excluded.sample_count,
+			total_shares = CASE WHEN excluded.cycle_number >= pool_metrics.cycle_number THEN excluded.total_shares ELSE pool_metrics.total_shares END,
+			assets = CASE WHEN excluded.cycle_number >= pool_metrics.cycle_number THEN excluded.assets ELSE pool_metrics.assets END,
+			cycle_number = MAX(pool_metrics.cycle_number, excluded.cycle_number),
+			block_height = MAX(pool_metrics.block_height, excluded.block_height)
+	`, strings.Join(poolMetricsAveragedColumns, ", "), strings.Join(merges, ",\n\t\t\t")))
+	if err != nil {
+		return 0, fmt.Errorf("failed to prepare %s pool metrics insert: %w", to, err)
+	}
+	defer stmt.Close()
+
+	for _, bucket := range bucketPoolMetrics(points, to, bucketSize) {
+		assetsJSON, err := json.Marshal(bucket.Assets)
+		if err != nil {
+			return 0, fmt.Errorf("failed to marshal assets of pool %d: %w", bucket.PoolID, err)
+		}
+		_, err = stmt.Exec(
+			uint64(bucket.PoolID), string(to), bucket.Timestamp, bucket.SampleCount, bucket.CycleNumber, bucket.BlockHeight,
+			bucket.TvlUSD, bucket.Volume7dUSD, bucket.EdenRewardsAPR, bucket.UsdcFeesAPR, bucket.PriceImpactAPR, bucket.TotalAPR, bucket.SwapFee,
+			bucket.TotalShares, string(assetsJSON),
+		)
+		if err != nil {
+			return 0, fmt.Errorf("failed to save %s metrics for pool %d: %w", to, bucket.PoolID, err)
+		}
+	}
+
+	return int64(len(points)), nil
+}
+
+// GetPoolMetricsHistory returns a pool's metrics points recorded within [from, to], oldest first.
+// With an empty interval the stored points are returned as they are, so older parts of the range
+// come at a coarser resolution. With interval "hour" or "day" the points are averaged into
+// buckets of that size, weighted by sample count.
+func (s *SQLiteStore) GetPoolMetricsHistory(poolID types.PoolID, from, to time.Time, interval string) ([]types.PoolMetricsPoint, error) {
+	resolution, bucketSize, err := poolMetricsIntervalResolution(interval)
+	if err != nil {
+		return nil, err
+	}
+
+	points, err := s.loadPoolMetrics(poolID, from, to)
+	if err != nil || interval == "" {
+		return points, err
+	}
+	return bucketPoolMetrics(points, resolution, bucketSize), nil
+}
+
+// bucketPoolMetrics averages points, ordered by pool and time, into UTC-aligned buckets of
+// bucketSize, weighted by sample count. Each bucket keeps the total shares and assets of its
+// latest point and the highest cycle number and block height.
+func bucketPoolMetrics(points []types.PoolMetricsPoint, resolution types.PoolMetricsResolution, bucketSize time.Duration) []types.PoolMetricsPoint {
+	buckets := make([]types.PoolMetricsPoint, 0)
+	for i := 0; i < len(points); {
+		start := points[i].Timestamp.Truncate(bucketSize)
+		bucket := types.PoolMetricsPoint{PoolID: points[i].PoolID, Timestamp: start, Resolution: resolution}
+
+		j := i
+		for ; j < len(points) && points[j].PoolID == bucket.PoolID && points[j].Timestamp.Truncate(bucketSize).Equal(start); j++ {
+			point := points[j]
+			weight := float64(point.SampleCount)
+			bucket.SampleCount += point.SampleCount
+			bucket.TvlUSD += point.TvlUSD * weight
+			bucket.Volume7dUSD += point.Volume7dUSD * weight
+			bucket.EdenRewardsAPR += point.EdenRewardsAPR * weight
+			bucket.UsdcFeesAPR += point.UsdcFeesAPR * weight
+			bucket.PriceImpactAPR += point.PriceImpactAPR * weight
+			bucket.TotalAPR += point.TotalAPR * weight
+			bucket.SwapFee += point.SwapFee * weight
+			if point.CycleNumber > bucket.CycleNumber {
+				bucket.CycleNumber = point.CycleNumber
+			}
+			if point.BlockHeight > bucket.BlockHeight {
+				bucket.BlockHeight = point.BlockHeight
+			}
+			// Points are in time order, so the last one holds the latest shares and assets
+			bucket.TotalShares = point.TotalShares
+			bucket.Assets = point.Assets
+		}
+		i = j
+
+		if bucket.SampleCount > 0 {
+			total := float64(bucket.SampleCount)
+			bucket.TvlUSD /= total
+			bucket.Volume7dUSD /= total
+			bucket.EdenRewardsAPR /= total
+			bucket.UsdcFeesAPR /= total
+			bucket.PriceImpactAPR /= total
+			bucket.TotalAPR /= total
+			bucket.SwapFee /= total
+		}
+		buckets = append(buckets, bucket)
+	}
+	return buckets
+}
@@ -0,0 +1,161 @@
+/*
+
+This file defines the Store interface, through which the AVM core, the web server and avmctl
+persist and query scoring parameters, the cycle counter, cycle snapshots, analytics, the pool
+metrics time series, the audit log, the web API keys and the operator control mode.
+
+There are two implementations: PostgresStore for production and SQLiteStore, an embedded
+single-file database for development, backtests and small vaults. Both embed sqlStore, which
+holds the SQL they share; sqlDialect covers the few differences.
+
+The datafetcher's caches (price history, swap volume index and symbol mappings) are not part of
+the Store. They stay in PostgreSQL through DB and are skipped when it is not initialized: price
+history is then downloaded in full, the datafetcher uses its built-in symbol mappings, and the
+AVM refuses to start with an on-chain VOLUME_SOURCE.
+
+*/
+
+package state
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/elys-network/avm/internal/types"
+)
+
+const (
+	// StorageBackendPostgres stores everything in PostgreSQL (the default).
+	StorageBackendPostgres = "postgres"
+	// StorageBackendSQLite stores the Store's data in a local SQLite file.
+	StorageBackendSQLite = "sqlite"
+)
+
+// Store is the persistence used by the AVM core, the web server and avmctl.
+type Store interface {
+	// Ping checks that the database is reachable.
+	Ping() error
+	// Close closes the database.
+	Close() error
+
+	// Scoring parameters
+	SaveScoringParameters(params types.ScoringParameters, configName string, version int, makeActive bool, audit types.AuditInfo) (int64, error)
+	LoadActiveScoringParameters(configName string) (*types.ScoringParameters, error)
+	LoadLatestScoringParameters(configName string) (*types.ScoringParameters, error)
+	GetActiveScoringParametersID(configName string) (*int64, error)
+	ListScoringParameters(configName string) ([]types.ScoringParametersVersion, error)
+	LoadScoringParametersVersion(configName string, version int) (*types.ScoringParametersVersion, error)
+	LoadScoringParametersByID(paramsID int64) (*types.ScoringParametersVersion, error)
+	LoadActiveScoringParametersVersion(configName string) (*types.ScoringParametersVersion, error)
+	NextScoringParametersVersion(configName string) (int, error)
+	ActivateScoringParameters(configName string, version int, audit types.AuditInfo) (*types.ScoringParametersVersion, error)
+	RollbackScoringParameters(configName string, audit types.AuditInfo) (*types.ScoringParametersVersion, error)
+
+	// Cycle counter
+	GetCurrentCycleNumber() (int, error)
+	IncrementCycleNumber() (int, error)
+	ResetCycleNumber(cycleNumber int) error
+
+	// Cycle snapshots and analytics
+	SaveCycleSnapshot(snapshot types.CycleSnapshot) (int64, error)
+	GetRecentCycles(limit int) ([]types.CycleSnapshot, error)
+	GetCycleByID(snapshotID int64) (*types.CycleSnapshot, error)
+	GetCyclesInRange(from, to time.Time) ([]types.CycleSnapshot, error)
+	GetOldestCycleTimestamp() (*time.Time, error)
+	DeleteCyclesBefore(before time.Time) (int64, error)
+	GetVaultSummary() (*VaultSummary, error)
+	GetPerformanceMetrics() (*PerformanceMetrics, error)
+
+	// Pool metrics time series
+	SavePoolMetrics(cycleNumber int, blockHeight int64, recordedAt time.Time, pools []types.Pool) error
+	DownsamplePoolMetrics(rawRetention, hourlyRetention, dailyRetention time.Duration) error
+	GetPoolMetricsHistory(poolID types.PoolID, from, to time.Time, interval string) ([]types.PoolMetricsPoint, error)
+	GetPoolMetricsStats(poolID types.PoolID, from, to time.Time) (*types.PoolMetricsStats, error)
+	GetPoolMetricsAt(at time.Time, maxAge time.Duration) (map[types.PoolID]types.PoolMetricsPoint, error)
+
+	// Vault performance time series
+	SaveVaultPerformance(performance types.VaultPerformance) error
+	GetNAVHistory() ([]types.NAVPoint, error)
+	GetLatestVaultPerformance() (*types.VaultPerformance, error)
+
+	// Per-pool PnL attribution
+	SavePoolAttributions(cycleNumber int, attributions []types.PoolAttribution) error
+	GetPoolAttributions(cycleNumber int) ([]types.PoolAttribution, error)
+	GetPoolAttributionSummary(from, to time.Time) ([]types.PoolAttributionSummary, error)
+
+	// Audit log
+	RecordAuditEvent(event types.AuditEvent) (int64, error)
+	GetAuditEvents(filter types.AuditEventFilter) ([]types.AuditEvent, error)
+
+	// Web API keys
+	CreateAPIKey(name string, role types.APIRole, keyHash, keyPrefix string, audit types.AuditInfo) (*types.APIKey, error)
+	GetAPIKeyByHash(keyHash string) (*types.APIKey, error)
+	ListAPIKeys() ([]types.APIKey, error)
+	RevokeAPIKey(name string, audit types.AuditInfo) (*types.APIKey, error)
+	TouchAPIKey(keyID int64, usedAt time.Time) error
+
+	// Operator control mode
+	LoadControlState() (*types.ControlState, error)
+	SaveControlState(mode types.ControlMode, action types.AuditAction, audit types.AuditInfo) (*types.ControlState, error)
+}
+
+// StoreConfig selects and configures the storage backend.
+type StoreConfig struct {
+	Backend    string   // StorageBackendPostgres (or empty) or StorageBackendSQLite
+	Postgres   DBConfig // Used by the postgres backend
+	SQLitePath string   // Database file of the sqlite backend
+}
+
+// OpenStore opens the configured storage backend. The postgres backend also initializes DB,
+// which the datafetcher's caches and the schema migrations use; its schema is managed with
+// EnsureSchema and cmd/migrate. The sqlite backend brings its own schema up to date on open.
+func OpenStore(cfg StoreConfig) (Store, error) {
+	switch strings.ToLower(strings.TrimSpace(cfg.Backend)) {
+	case "", StorageBackendPostgres:
+		if err := InitDB(cfg.Postgres); err != nil {
+			return nil, err
+		}
+		return NewPostgresStore(DB), nil
+	case StorageBackendSQLite:
+		return OpenSQLiteStore(cfg.SQLitePath)
+	default:
+		return nil, fmt.Errorf("unsupported storage backend %q, must be %q or %q", cfg.Backend, StorageBackendPostgres, StorageBackendSQLite)
+	}
+}
+
+// sqlDialect covers the SQL that differs between PostgreSQL and SQLite
+type sqlDialect interface {
+	// lockScoringConfig serializes activation changes of a config for the rest of the transaction
+	lockScoringConfig(tx *sql.Tx, configName string) error
+	// stringArray returns a query argument storing values in a string array column
+	stringArray(values []string) interface{}
+	// scanStringArray returns a Scan destination reading a string array column into dest
+	scanStringArray(dest *[]string) interface{}
+}
+
+// sqlStore implements the queries shared by PostgresStore and SQLiteStore.
+// Both databases accept $N placeholders.
+type sqlStore struct {
+	db      *sql.DB
+	dialect sqlDialect
+}
+
+// Ping checks that the database is reachable
+func (s *sqlStore) Ping() error {
+	// Use a short timeout context for health checks
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := s.db.PingContext(ctx); err != nil {
+		return fmt.Errorf("database ping failed: %w", err)
+	}
+	return nil
+}
+
+// Close closes the database
+func (s *sqlStore) Close() error {
+	return s.db.Close()
+}
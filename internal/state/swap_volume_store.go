@@ -0,0 +1,173 @@
+/*
+
+This file manages the on-chain swap volume index.
+Daily volumes are written together with the indexer's progress, so a crash between batches
+can never count the same block twice.
+
+*/
+
+package state
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/elys-network/avm/internal/types"
+	"github.com/rs/zerolog/log"
+)
+
+// GetSwapVolumeIndexerState returns how far the swap volume indexer has walked the chain,
+// or nil if it has never run.
+func GetSwapVolumeIndexerState() (*types.SwapVolumeIndexerState, error) {
+	if DB == nil {
+		return nil, fmt.Errorf("database not initialized")
+	}
+
+	query := `
+		SELECT first_height, first_block_time, last_height, last_block_time, updated_at
+		FROM swap_volume_indexer_state
+		WHERE id = 1
+	`
+
+	var indexerState types.SwapVolumeIndexerState
+	err := DB.QueryRow(query).Scan(
+		&indexerState.FirstHeight,
+		&indexerState.FirstBlockTime,
+		&indexerState.LastHeight,
+		&indexerState.LastBlockTime,
+		&indexerState.UpdatedAt,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query swap volume indexer state: %w", err)
+	}
+
+	return &indexerState, nil
+}
+
+// SaveSwapVolume adds a batch of daily volumes to the index and records the indexer's new
+// progress in the same transaction.
+func SaveSwapVolume(volumes []types.PoolDailyVolume, indexerState types.SwapVolumeIndexerState) error {
+	if DB == nil {
+		return fmt.Errorf("database not initialized")
+	}
+
+	tx, err := DB.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback() // No-op after a successful commit
+
+	if len(volumes) > 0 {
+		stmt, err := tx.Prepare(`
+			INSERT INTO pool_swap_volume_daily (pool_id, day, volume_usd, swap_count, updated_at)
+			VALUES ($1, $2, $3, $4, CURRENT_TIMESTAMP)
+			ON CONFLICT (pool_id, day) DO UPDATE
+			SET volume_usd = pool_swap_volume_daily.volume_usd + EXCLUDED.volume_usd,
+				swap_count = pool_swap_volume_daily.swap_count + EXCLUDED.swap_count,
+				updated_at = EXCLUDED.updated_at;
+		`)
+		if err != nil {
+			return fmt.Errorf("failed to prepare swap volume insert: %w", err)
+		}
+		defer stmt.Close()
+
+		for _, volume := range volumes {
+			day := volume.Day.UTC().Format("2006-01-02")
+			if _, err := stmt.Exec(uint64(volume.PoolID), day, volume.VolumeUSD, volume.SwapCount); err != nil {
+				return fmt.Errorf("failed to save swap volume for pool %d on %s: %w", volume.PoolID, day, err)
+			}
+		}
+	}
+
+	_, err = tx.Exec(`
+		INSERT INTO swap_volume_indexer_state (id, first_height, first_block_time, last_height, last_block_time, updated_at)
+		VALUES (1, $1, $2, $3, $4, CURRENT_TIMESTAMP)
+		ON CONFLICT (id) DO UPDATE
+		SET first_height = EXCLUDED.first_height,
+			first_block_time = EXCLUDED.first_block_time,
+			last_height = EXCLUDED.last_height,
+			last_block_time = EXCLUDED.last_block_time,
+			updated_at = EXCLUDED.updated_at;
+	`, indexerState.FirstHeight, indexerState.FirstBlockTime.UTC(), indexerState.LastHeight, indexerState.LastBlockTime.UTC())
+	if err != nil {
+		return fmt.Errorf("failed to save swap volume indexer state: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit swap volume batch: %w", err)
+	}
+
+	log.Debug().
+		Int("poolDays", len(volumes)).
+		Int64("lastHeight", indexerState.LastHeight).
+		Msg("Saved swap volume batch")
+	return nil
+}
+
+// ResetSwapVolumeIndex deletes all indexed volume and the indexer's progress, so the next run
+// starts a fresh backfill.
+func ResetSwapVolumeIndex() error {
+	if DB == nil {
+		return fmt.Errorf("database not initialized")
+	}
+
+	tx, err := DB.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback() // No-op after a successful commit
+
+	if _, err := tx.Exec(`DELETE FROM pool_swap_volume_daily`); err != nil {
+		return fmt.Errorf("failed to delete swap volume: %w", err)
+	}
+	if _, err := tx.Exec(`DELETE FROM swap_volume_indexer_state`); err != nil {
+		return fmt.Errorf("failed to delete swap volume indexer state: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit swap volume reset: %w", err)
+	}
+
+	log.Info().Msg("Reset swap volume index")
+	return nil
+}
+
+// GetSwapVolumeByPool returns the total USD swap volume of each pool for the days in [from, to).
+func GetSwapVolumeByPool(from, to time.Time) (map[types.PoolID]float64, error) {
+	if DB == nil {
+		return nil, fmt.Errorf("database not initialized")
+	}
+
+	query := `
+		SELECT pool_id, SUM(volume_usd)
+		FROM pool_swap_volume_daily
+		WHERE day >= $1 AND day < $2
+		GROUP BY pool_id
+	`
+
+	rows, err := DB.Query(query, from.UTC().Format("2006-01-02"), to.UTC().Format("2006-01-02"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query swap volume: %w", err)
+	}
+	defer rows.Close()
+
+	volumes := make(map[types.PoolID]float64)
+	for rows.Next() {
+		var poolID uint64
+		var volumeUSD float64
+		if err := rows.Scan(&poolID, &volumeUSD); err != nil {
+			return nil, fmt.Errorf("failed to scan swap volume row: %w", err)
+		}
+		volumes[types.PoolID(poolID)] = volumeUSD
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error during swap volume iteration: %w", err)
+	}
+
+	return volumes, nil
+}
@@ -0,0 +1,51 @@
+/*
+
+This file manages the per-provider symbol mapping for each token.
+A token's symbol on Elys does not always match the identifier a price provider uses
+(e.g., WETH is "ETH" on CryptoCompare and "weth" on CoinGecko).
+
+*/
+
+package state
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+)
+
+// LoadSymbolMappings returns all token symbol mappings as provider -> token symbol -> provider symbol.
+// Token symbols are upper-cased so lookups are case-insensitive.
+func LoadSymbolMappings() (map[string]map[string]string, error) {
+	if DB == nil {
+		return nil, fmt.Errorf("database not initialized")
+	}
+
+	rows, err := DB.Query(`SELECT token_symbol, provider, provider_symbol FROM token_symbol_mappings`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query token symbol mappings: %w", err)
+	}
+	defer rows.Close()
+
+	mappings := make(map[string]map[string]string)
+	count := 0
+	for rows.Next() {
+		var tokenSymbol, provider, providerSymbol string
+		if err := rows.Scan(&tokenSymbol, &provider, &providerSymbol); err != nil {
+			return nil, fmt.Errorf("failed to scan token symbol mapping: %w", err)
+		}
+		provider = strings.ToLower(provider)
+		if mappings[provider] == nil {
+			mappings[provider] = make(map[string]string)
+		}
+		mappings[provider][strings.ToUpper(tokenSymbol)] = providerSymbol
+		count++
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error during token symbol mapping iteration: %w", err)
+	}
+
+	log.Debug().Int("count", count).Msg("Loaded token symbol mappings")
+	return mappings, nil
+}
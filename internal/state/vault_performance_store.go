@@ -0,0 +1,117 @@
+/*
+
+This file manages the vault performance time series: one NAV per share point per cycle, with the
+returns computed up to it. The series is not pruned with the cycle snapshots, since the returns
+since inception need its first point.
+
+*/
+
+package state
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/elys-network/avm/internal/types"
+	"github.com/rs/zerolog/log"
+)
+
+// SaveVaultPerformance records a cycle's NAV point and returns, replacing any earlier record of the cycle
+func (s *sqlStore) SaveVaultPerformance(performance types.VaultPerformance) error {
+	_, err := s.db.Exec(`
+		INSERT INTO vault_performance (
+			cycle_number, recorded_at, block_height, vault_value_usd, share_supply, nav_per_share, net_flow_usd,
+			twr_inception, mwr_annualized, apy_7d, apy_30d, apy_inception
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+		ON CONFLICT (cycle_number) DO UPDATE SET
+			recorded_at = EXCLUDED.recorded_at,
+			block_height = EXCLUDED.block_height,
+			vault_value_usd = EXCLUDED.vault_value_usd,
+			share_supply = EXCLUDED.share_supply,
+			nav_per_share = EXCLUDED.nav_per_share,
+			net_flow_usd = EXCLUDED.net_flow_usd,
+			twr_inception = EXCLUDED.twr_inception,
+			mwr_annualized = EXCLUDED.mwr_annualized,
+			apy_7d = EXCLUDED.apy_7d,
+			apy_30d = EXCLUDED.apy_30d,
+			apy_inception = EXCLUDED.apy_inception;
+	`,
+		performance.CycleNumber, performance.Timestamp.UTC(), performance.BlockHeight,
+		performance.VaultValueUSD, performance.ShareSupply, performance.NAVPerShare, performance.NetFlowUSD,
+		performance.TWRInception, performance.MWRAnnualized, performance.APY7d, performance.APY30d, performance.APYInception,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to save vault performance of cycle %d: %w", performance.CycleNumber, err)
+	}
+
+	log.Debug().Int("cycleNumber", performance.CycleNumber).Float64("navPerShare", performance.NAVPerShare).Msg("Saved vault performance")
+	return nil
+}
+
+// GetNAVHistory returns every recorded NAV point, oldest first
+func (s *sqlStore) GetNAVHistory() ([]types.NAVPoint, error) {
+	rows, err := s.db.Query(`
+		SELECT cycle_number, recorded_at, block_height, vault_value_usd, share_supply, nav_per_share, net_flow_usd
+		FROM vault_performance
+		ORDER BY recorded_at ASC, cycle_number ASC
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query NAV history: %w", err)
+	}
+	defer rows.Close()
+
+	history := make([]types.NAVPoint, 0)
+	for rows.Next() {
+		var point types.NAVPoint
+		if err := rows.Scan(
+			&point.CycleNumber, &point.Timestamp, &point.BlockHeight,
+			&point.VaultValueUSD, &point.ShareSupply, &point.NAVPerShare, &point.NetFlowUSD,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan NAV point: %w", err)
+		}
+		history = append(history, point)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read NAV history: %w", err)
+	}
+	return history, nil
+}
+
+// GetLatestVaultPerformance returns the most recent point of the vault performance time series,
+// or nil if none has been recorded
+func (s *sqlStore) GetLatestVaultPerformance() (*types.VaultPerformance, error) {
+	var performance types.VaultPerformance
+	var mwrAnnualized, apy7d, apy30d, apyInception sql.NullFloat64
+
+	err := s.db.QueryRow(`
+		SELECT cycle_number, recorded_at, block_height, vault_value_usd, share_supply, nav_per_share, net_flow_usd,
+			twr_inception, mwr_annualized, apy_7d, apy_30d, apy_inception
+		FROM vault_performance
+		ORDER BY recorded_at DESC, cycle_number DESC
+		LIMIT 1
+	`).Scan(
+		&performance.CycleNumber, &performance.Timestamp, &performance.BlockHeight,
+		&performance.VaultValueUSD, &performance.ShareSupply, &performance.NAVPerShare, &performance.NetFlowUSD,
+		&performance.TWRInception, &mwrAnnualized, &apy7d, &apy30d, &apyInception,
+	)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get latest vault performance: %w", err)
+	}
+
+	performance.MWRAnnualized = nullFloatPointer(mwrAnnualized)
+	performance.APY7d = nullFloatPointer(apy7d)
+	performance.APY30d = nullFloatPointer(apy30d)
+	performance.APYInception = nullFloatPointer(apyInception)
+	return &performance, nil
+}
+
+// nullFloatPointer returns a pointer to the value of a nullable column, or nil if it is NULL
+func nullFloatPointer(value sql.NullFloat64) *float64 {
+	if !value.Valid {
+		return nil
+	}
+	return &value.Float64
+}
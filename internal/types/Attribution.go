@@ -0,0 +1,52 @@
+/*
+
+This file contains the types of the per-pool PnL attribution, which splits the change in value of
+each pool position between two consecutive cycles into its sources.
+
+*/
+
+package types
+
+import "time"
+
+// PoolAttribution is one pool's PnL over one cycle: the holding period since the previous cycle
+// and the cycle's own execution. All amounts are in USD; costs are positive.
+//
+// PriceReturnUSD + ImpermanentLossUSD + LPFeesUSD is the change in value of the LP shares held
+// through the period, and TotalPnLUSD adds the rewards and subtracts the costs.
+type PoolAttribution struct {
+	CycleNumber   int       `json:"cycle_number"`
+	Timestamp     time.Time `json:"timestamp"` // Cycle start, the end of the holding period
+	PoolID        PoolID    `json:"pool_id"`   // 0 for execution costs not tied to a pool
+	PeriodSeconds float64   `json:"period_seconds"`
+
+	StartValueUSD float64 `json:"start_value_usd"` // Position at the end of the previous cycle
+	EndValueUSD   float64 `json:"end_value_usd"`   // Same LP shares at the start of this cycle
+
+	LPFeesUSD          float64 `json:"lp_fees_usd"`          // Fees accrued to the pool, estimated from its price impact APR
+	EdenRewardsUSD     float64 `json:"eden_rewards_usd"`     // Estimated from the Eden rewards APR
+	UsdcRewardsUSD     float64 `json:"usdc_rewards_usd"`     // Estimated from the USDC fees APR
+	PriceReturnUSD     float64 `json:"price_return_usd"`     // Change in value of the pool tokens had they been held instead
+	ImpermanentLossUSD float64 `json:"impermanent_loss_usd"` // LP position versus holding, net of fees; usually negative
+	SlippageUSD        float64 `json:"slippage_usd"`         // Execution slippage of this cycle's actions in the pool
+	GasUSD             float64 `json:"gas_usd"`              // Gas of this cycle's actions in the pool
+	TotalPnLUSD        float64 `json:"total_pnl_usd"`
+
+	// MarketDataComplete is false when the pool's metrics were missing at either end of the
+	// period. The price return is then 0 and the impermanent loss holds the whole price effect.
+	MarketDataComplete bool `json:"market_data_complete"`
+}
+
+// PoolAttributionSummary is the sum of a pool's attributions over a time range
+type PoolAttributionSummary struct {
+	PoolID             PoolID  `json:"pool_id"`
+	Cycles             int     `json:"cycles"`
+	LPFeesUSD          float64 `json:"lp_fees_usd"`
+	EdenRewardsUSD     float64 `json:"eden_rewards_usd"`
+	UsdcRewardsUSD     float64 `json:"usdc_rewards_usd"`
+	PriceReturnUSD     float64 `json:"price_return_usd"`
+	ImpermanentLossUSD float64 `json:"impermanent_loss_usd"`
+	SlippageUSD        float64 `json:"slippage_usd"`
+	GasUSD             float64 `json:"gas_usd"`
+	TotalPnLUSD        float64 `json:"total_pnl_usd"`
+}
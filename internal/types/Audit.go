@@ -0,0 +1,61 @@
+/*
+
+This file contains the types of the audit log, the append-only record of every change an operator
+or the AVM makes to how the vault is run.
+
+*/
+
+package types
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// AuditAction identifies the kind of change an audit event records
+type AuditAction string
+
+// Audited actions
+const (
+	AuditActionParamsSave     AuditAction = "params.save"     // A new version of the scoring parameters was stored
+	AuditActionParamsActivate AuditAction = "params.activate" // A stored version was made active
+	AuditActionParamsRollback AuditAction = "params.rollback" // The previously active version was reactivated
+	AuditActionAPIKeyCreate   AuditAction = "apikey.create"   // A web API key was created
+	AuditActionAPIKeyRevoke   AuditAction = "apikey.revoke"   // A web API key was revoked
+
+	AuditActionControlPause         AuditAction = "control.pause"          // Execution was paused
+	AuditActionControlResume        AuditAction = "control.resume"         // Execution was resumed
+	AuditActionControlEmergencyStop AuditAction = "control.emergency_stop" // The running cycle was cancelled and cycles stopped
+	AuditActionControlTrigger       AuditAction = "control.trigger"        // A cycle was triggered ahead of the loop interval
+)
+
+// AuditActorAVM is the actor of the changes the AVM makes on its own
+const AuditActorAVM = "avm"
+
+// AuditInfo says who made a change and why
+type AuditInfo struct {
+	Actor  string `json:"actor"`  // Operator name, or "avm" for changes the AVM makes itself
+	Reason string `json:"reason"` // Free text given by the actor
+}
+
+// AuditEvent is one entry of the audit log
+type AuditEvent struct {
+	ID        int64       `json:"id"`
+	Timestamp time.Time   `json:"timestamp"`
+	Action    AuditAction `json:"action"`
+	Target    string      `json:"target"` // What was changed, e.g. "scoring_parameters/default/v3"
+	AuditInfo
+	// Diff describes what changed. For scoring parameters it is a list of ScoringParameterChange
+	// from the previously active version; other actions store an object with their details.
+	Diff json.RawMessage `json:"diff,omitempty"`
+}
+
+// AuditEventFilter selects audit events. Empty fields match every event.
+type AuditEventFilter struct {
+	From   time.Time
+	To     time.Time
+	Action AuditAction
+	Actor  string
+	Target string // Matches the target and everything below it, e.g. "scoring_parameters/default"
+	Limit  int    // Maximum number of events, newest first
+}
@@ -0,0 +1,58 @@
+/*
+
+This file contains the types of the web API's authentication: roles and API keys.
+
+*/
+
+package types
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// APIRole is what an API caller may do. Each role includes the ones below it.
+type APIRole string
+
+// API roles, from least to most privileged
+const (
+	APIRoleViewer   APIRole = "viewer"   // Reads vault data
+	APIRoleOperator APIRole = "operator" // Also runs operator actions and reads the audit log
+	APIRoleAdmin    APIRole = "admin"    // Also manages API keys
+)
+
+// apiRoleRanks orders the roles
+var apiRoleRanks = map[APIRole]int{
+	APIRoleViewer:   1,
+	APIRoleOperator: 2,
+	APIRoleAdmin:    3,
+}
+
+// ParseAPIRole reads a role name
+func ParseAPIRole(name string) (APIRole, error) {
+	role := APIRole(strings.ToLower(strings.TrimSpace(name)))
+	if _, ok := apiRoleRanks[role]; !ok {
+		return "", fmt.Errorf("unknown role %q, must be viewer, operator or admin", name)
+	}
+	return role, nil
+}
+
+// Allows reports whether the role includes required
+func (r APIRole) Allows(required APIRole) bool {
+	rank, ok := apiRoleRanks[r]
+	return ok && rank >= apiRoleRanks[required]
+}
+
+// APIKey is a stored API key. The key itself is only shown when it is created; the database
+// keeps its hash.
+type APIKey struct {
+	ID         int64      `json:"id"`
+	Name       string     `json:"name"` // Unique; the actor of the key's changes in the audit log
+	Role       APIRole    `json:"role"`
+	Prefix     string     `json:"prefix"` // First characters of the key, to recognize it
+	CreatedAt  time.Time  `json:"created_at"`
+	CreatedBy  string     `json:"created_by"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+}
@@ -0,0 +1,48 @@
+/*
+
+This file contains the types of the operator controls: whether the AVM executes its plans, and
+the status the control API reports.
+
+*/
+
+package types
+
+import (
+	"errors"
+	"time"
+)
+
+// ControlMode says what the AVM's cycles do
+type ControlMode string
+
+// Control modes
+const (
+	ControlModeRunning          ControlMode = "running"           // Cycles run and execute their plans
+	ControlModePaused           ControlMode = "paused"            // Cycles fetch, score and plan, but skip execution
+	ControlModeEmergencyStopped ControlMode = "emergency_stopped" // The running cycle was cancelled and no cycles run
+)
+
+// Errors of the control actions
+var (
+	// ErrControlModeUnchanged is returned when the AVM is already in the requested mode.
+	ErrControlModeUnchanged = errors.New("the AVM is already in this mode")
+	// ErrEmergencyStopped is returned when triggering a cycle during an emergency stop.
+	ErrEmergencyStopped = errors.New("the AVM is emergency stopped, resume it first")
+	// ErrCycleAlreadyTriggered is returned when a triggered cycle has not started yet.
+	ErrCycleAlreadyTriggered = errors.New("a cycle is already triggered")
+)
+
+// ControlState is the persisted control mode and the change that set it
+type ControlState struct {
+	Mode      ControlMode `json:"mode"`
+	UpdatedAt time.Time   `json:"updated_at"` // Zero if the mode was never changed
+	AuditInfo             // Who set the mode and why
+}
+
+// ControlStatus is the control state with what the AVM is doing at the moment
+type ControlStatus struct {
+	ControlState
+	CycleRunning   bool       `json:"cycle_running"`
+	CycleStartedAt *time.Time `json:"cycle_started_at,omitempty"` // Start of the running cycle
+	TriggerPending bool       `json:"trigger_pending"`            // A triggered cycle waits for the running one
+}
@@ -0,0 +1,103 @@
+/*
+
+This file contains the types of the cycle diff, which compares two cycle snapshots to show why the
+allocations shifted between them: the target allocations, the positions each cycle left, the
+scoring parameters used and each pool's score components.
+
+*/
+
+package types
+
+import "time"
+
+// PoolChange says how a pool's allocation, position or score changed between two cycles
+type PoolChange string
+
+// Pool changes
+const (
+	PoolChangeAdded     PoolChange = "added"     // Only in the later cycle
+	PoolChangeRemoved   PoolChange = "removed"   // Only in the earlier cycle
+	PoolChangeChanged   PoolChange = "changed"   // In both, with a different value
+	PoolChangeUnchanged PoolChange = "unchanged" // In both, with the same value
+)
+
+// CycleDiffSide identifies one of the compared snapshots
+type CycleDiffSide struct {
+	SnapshotID         int64     `json:"snapshot_id"`
+	CycleNumber        int       `json:"cycle_number"`
+	Timestamp          time.Time `json:"timestamp"`
+	BlockHeight        int64     `json:"block_height"`
+	ScoringParamsID    *int64    `json:"scoring_params_id,omitempty"`
+	FinalVaultValueUSD float64   `json:"final_vault_value_usd"`
+	FinalLiquidUSDC    float64   `json:"final_liquid_usdc"`
+	ScoresRecorded     bool      `json:"scores_recorded"` // False if saved before pool scores were kept, or by a cycle halted before scoring
+}
+
+// ScoringParamsDiff compares the scoring parameters the two cycles used
+type ScoringParamsDiff struct {
+	FromParamsID *int64                   `json:"from_params_id,omitempty"`
+	ToParamsID   *int64                   `json:"to_params_id,omitempty"`
+	FromVersion  int                      `json:"from_version,omitempty"` // 0 if the version is unknown
+	ToVersion    int                      `json:"to_version,omitempty"`
+	Changed      bool                     `json:"changed"`           // The cycles used different parameter versions
+	Changes      []ScoringParameterChange `json:"changes,omitempty"` // Parameters whose values differ; empty if a version could not be loaded
+}
+
+// AllocationDiff is the change in a pool's target allocation, as a fraction of the vault value
+type AllocationDiff struct {
+	PoolID PoolID     `json:"pool_id"`
+	From   float64    `json:"from"`
+	To     float64    `json:"to"`
+	Delta  float64    `json:"delta"`
+	Change PoolChange `json:"change"`
+}
+
+// PositionDiff is the change in a position the two cycles left the vault with
+type PositionDiff struct {
+	PoolID                 PoolID     `json:"pool_id"`
+	PoolAssets             []string   `json:"pool_assets"`
+	FromLPShares           string     `json:"from_lp_shares"`
+	ToLPShares             string     `json:"to_lp_shares"`
+	FromValueUSD           float64    `json:"from_value_usd"`
+	ToValueUSD             float64    `json:"to_value_usd"`
+	ValueDeltaUSD          float64    `json:"value_delta_usd"`
+	FromAllocationPercent  float64    `json:"from_allocation_percent"`
+	ToAllocationPercent    float64    `json:"to_allocation_percent"`
+	AllocationDeltaPercent float64    `json:"allocation_delta_percent"`
+	Change                 PoolChange `json:"change"` // Compares the LP shares
+}
+
+// ScoreComponentDeltas is the change in each score component of a PoolScoreResult, under its JSON name
+type ScoreComponentDeltas struct {
+	WeightedAPR          float64 `json:"weighted_apr"`
+	ILRisk               float64 `json:"il_risk"`
+	AnnualizedVolatility float64 `json:"annualized_volatility"`
+	RewardScoreComponent float64 `json:"reward_score_component"`
+	RiskScoreComponent   float64 `json:"risk_score_component"`
+	TvlScoreComponent    float64 `json:"tvl_score_component"`
+	BonusScoreComponent  float64 `json:"bonus_score_component"`
+}
+
+// PoolScoreDiff is the change in a pool's score and its components. Deltas are only set for
+// pools scored in both cycles.
+type PoolScoreDiff struct {
+	PoolID          PoolID                `json:"pool_id"`
+	From            *PoolScoreResult      `json:"from,omitempty"` // Nil if the pool was not scored in the earlier cycle
+	To              *PoolScoreResult      `json:"to,omitempty"`   // Nil if the pool was not scored in the later cycle
+	FromRank        int                   `json:"from_rank,omitempty"`
+	ToRank          int                   `json:"to_rank,omitempty"`
+	ScoreDelta      float64               `json:"score_delta"`
+	ComponentDeltas *ScoreComponentDeltas `json:"component_deltas,omitempty"`
+	Change          PoolChange            `json:"change"`
+}
+
+// CycleDiff compares two cycle snapshots. Pools are ordered by ID in each section.
+type CycleDiff struct {
+	From               CycleDiffSide     `json:"from"`
+	To                 CycleDiffSide     `json:"to"`
+	VaultValueDeltaUSD float64           `json:"vault_value_delta_usd"`
+	ScoringParams      ScoringParamsDiff `json:"scoring_params"`
+	TargetAllocations  []AllocationDiff  `json:"target_allocations"`
+	Positions          []PositionDiff    `json:"positions"`
+	PoolScores         []PoolScoreDiff   `json:"pool_scores"` // Empty unless both snapshots recorded their pool scores
+}
@@ -0,0 +1,16 @@
+/*
+
+This file contains the types for tracking the vault's exposure to individual tokens across pools.
+
+*/
+
+package types
+
+// TokenExposure describes how much of the vault is exposed to a single underlying token,
+// aggregated across every pool that holds it.
+type TokenExposure struct {
+	Denom           string   `json:"denom"`
+	Symbol          string   `json:"symbol"`
+	ExposurePercent float64  `json:"exposure_percent"` // Fraction of total vault value (0.0 to 1.0)
+	Pools           []PoolID `json:"pools"`            // Pools contributing to this exposure
+}
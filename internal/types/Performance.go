@@ -0,0 +1,43 @@
+/*
+
+This file contains the types of the vault performance time series, recorded once per cycle from
+the vault's value and share supply.
+
+*/
+
+package types
+
+import "time"
+
+// NAVPoint is the vault's net asset value per share at the start of a cycle
+type NAVPoint struct {
+	CycleNumber   int       `json:"cycle_number"`
+	Timestamp     time.Time `json:"timestamp"`    // Cycle start
+	BlockHeight   int64     `json:"block_height"` // Height the value and share supply were read at
+	VaultValueUSD float64   `json:"vault_value_usd"`
+	ShareSupply   string    `json:"share_supply"` // Raw on-chain amount of vault shares outstanding
+	NAVPerShare   float64   `json:"nav_per_share"`
+	// NetFlowUSD is the value of the shares minted minus burned since the previous point, at
+	// this point's NAV per share: deposits are positive, withdrawals negative.
+	NetFlowUSD float64 `json:"net_flow_usd"`
+}
+
+// VaultReturns are the returns of the vault up to a NAV point. Fields that need more history
+// than is available are nil.
+type VaultReturns struct {
+	// TWRInception is the time-weighted return since the first point, the growth of the NAV per
+	// share. It is unaffected by deposits and withdrawals.
+	TWRInception float64 `json:"twr_inception"`
+	// MWRAnnualized is the money-weighted return since the first point: the annual rate at which
+	// the starting value and every net flow grow into the current value (internal rate of return).
+	MWRAnnualized *float64 `json:"mwr_annualized"`
+	APY7d         *float64 `json:"apy_7d"`        // NAV per share growth over the last 7 days, annualized
+	APY30d        *float64 `json:"apy_30d"`       // NAV per share growth over the last 30 days, annualized
+	APYInception  *float64 `json:"apy_inception"` // TWRInception, annualized
+}
+
+// VaultPerformance is one point of the vault performance time series
+type VaultPerformance struct {
+	NAVPoint
+	VaultReturns
+}
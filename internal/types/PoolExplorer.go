@@ -0,0 +1,97 @@
+/*
+
+This file contains the types of the pool explorer: every pool the latest cycle considered, how it
+was scored and ranked, why it was or was not selected, and how each score term was derived from
+the ScoringParameters.
+
+*/
+
+package types
+
+import (
+	"errors"
+	"time"
+)
+
+// Errors of the pool explorer
+var (
+	// ErrPoolUniverseUnavailable is returned until a cycle has scored the pools since the AVM started.
+	ErrPoolUniverseUnavailable = errors.New("no cycle has scored the pools since the AVM started")
+	// ErrPoolNotFound is returned for a pool the latest scoring cycle did not consider.
+	ErrPoolNotFound = errors.New("pool not found in the latest scoring cycle")
+	// ErrPoolNotScored is returned when explaining a pool that was excluded before scoring.
+	ErrPoolNotScored = errors.New("pool was not scored in the latest scoring cycle")
+)
+
+// PoolSelectionReason says why a pool was or was not selected in a cycle
+type PoolSelectionReason string
+
+// Pool selection reasons
+const (
+	PoolSelectionTopScore       PoolSelectionReason = "top_score"        // Selected: among the MaxPools best scores
+	PoolSelectionForcedElys     PoolSelectionReason = "forced_elys"      // Selected: the best ELYS pool, forced in from below MaxPools
+	PoolSelectionBelowMaxPools  PoolSelectionReason = "below_max_pools"  // Scored, but not among the MaxPools best
+	PoolSelectionReplacedByElys PoolSelectionReason = "replaced_by_elys" // Among the MaxPools best, but swapped out for the ELYS pool
+	PoolSelectionInvalidData    PoolSelectionReason = "invalid_data"     // Quarantined: its data, or a token's, failed to fetch or validate
+	PoolSelectionPriceFlagged   PoolSelectionReason = "price_flagged"    // Banned for the cycle: holds a token that failed the price integrity check
+)
+
+// PoolExplorerEntry is a pool of the universe with how the latest scoring cycle treated it
+type PoolExplorerEntry struct {
+	PoolID          PoolID   `json:"pool_id"`
+	Assets          []string `json:"assets,omitempty"` // Symbols; unknown for quarantined pools
+	TvlUSD          float64  `json:"tvl_usd"`
+	Volume7dUSD     float64  `json:"volume_7d_usd"`
+	EdenRewardsAPR  float64  `json:"eden_rewards_apr"`
+	UsdcFeesAPR     float64  `json:"usdc_fees_apr"`
+	PriceImpactAPR  float64  `json:"price_impact_apr"`
+	IsSmartShielded bool     `json:"is_smart_shielded"`
+	AgeInDays       int      `json:"age_in_days"`
+
+	Score        *PoolScoreResult    `json:"score,omitempty"` // Nil if the pool was excluded before scoring
+	Rank         int                 `json:"rank,omitempty"`  // 1 for the best score; 0 if not scored
+	Selected     bool                `json:"selected"`
+	Reason       PoolSelectionReason `json:"reason"`
+	ReasonDetail string              `json:"reason_detail,omitempty"` // Why the pool was quarantined or flagged
+
+	TargetAllocation       float64 `json:"target_allocation"`        // Fraction of the vault value, 0 if not selected
+	HoldingUSD             float64 `json:"holding_usd"`              // Vault position at the start of the cycle
+	HoldingAllocationRatio float64 `json:"holding_allocation_ratio"` // Fraction of the vault value held at the start of the cycle
+}
+
+// PoolUniverse is every pool the latest scoring cycle considered, best ranked first
+type PoolUniverse struct {
+	CycleNumber     int                 `json:"cycle_number"`
+	Timestamp       time.Time           `json:"timestamp"`
+	BlockHeight     int64               `json:"block_height"`
+	ScoringParamsID *int64              `json:"scoring_params_id,omitempty"`
+	MaxPools        int                 `json:"max_pools"`
+	ElysPoolID      PoolID              `json:"elys_pool_id,omitempty"` // The ELYS pool forced into the selection; 0 if none
+	Pools           []PoolExplorerEntry `json:"pools"`
+}
+
+// ScoreTerm is one additive term of a score component and how it was derived
+type ScoreTerm struct {
+	Name    string                 `json:"name"`
+	Formula string                 `json:"formula"`
+	Inputs  map[string]interface{} `json:"inputs"` // Pool data and scoring parameters used, by their JSON names
+	Value   float64                `json:"value"`
+}
+
+// ScoreComponentExplanation is a score component as the sum of its terms
+type ScoreComponentExplanation struct {
+	Name  string      `json:"name"`  // reward, risk, liquidity or bonus
+	Value float64     `json:"value"` // The component in PoolScoreResult
+	Terms []ScoreTerm `json:"terms"`
+}
+
+// PoolScoreExplanation shows how a pool's score was derived from the ScoringParameters. The score
+// is the sum of the components.
+type PoolScoreExplanation struct {
+	PoolID          PoolID                      `json:"pool_id"`
+	Assets          []string                    `json:"assets"`
+	CycleNumber     int                         `json:"cycle_number"`
+	ScoringParamsID *int64                      `json:"scoring_params_id,omitempty"`
+	Score           float64                     `json:"score"`
+	Components      []ScoreComponentExplanation `json:"components"`
+}
@@ -0,0 +1,75 @@
+/*
+
+This file contains the types of the pool metrics time series, recorded for every pool each cycle.
+
+*/
+
+package types
+
+import "time"
+
+// PoolMetricsResolution is how much time one stored pool metrics point covers.
+// Raw points are downsampled to hourly and then daily points as they age.
+type PoolMetricsResolution string
+
+const (
+	PoolMetricsRaw    PoolMetricsResolution = "raw"    // One sample per cycle
+	PoolMetricsHourly PoolMetricsResolution = "hourly" // Average of the samples in one UTC hour
+	PoolMetricsDaily  PoolMetricsResolution = "daily"  // Average of the samples in one UTC day
+)
+
+// PoolMetricsAsset is the balance of one pool asset at the time of a sample
+type PoolMetricsAsset struct {
+	Symbol string  `json:"symbol"`
+	Denom  string  `json:"denom"`
+	Amount string  `json:"amount"` // Raw on-chain amount
+	Weight float64 `json:"weight"` // Share of the pool's USD value
+}
+
+// PoolMetricsPoint is one point of a pool's metrics time series. For downsampled points, the
+// USD values and APRs are averages over the bucket, and the shares and balances are the last sample's.
+type PoolMetricsPoint struct {
+	PoolID         PoolID                `json:"pool_id"`
+	Timestamp      time.Time             `json:"timestamp"` // Cycle start for raw points, bucket start otherwise
+	Resolution     PoolMetricsResolution `json:"resolution"`
+	SampleCount    int                   `json:"sample_count"` // Number of cycles the point covers
+	CycleNumber    int                   `json:"cycle_number"` // Last cycle in the point
+	BlockHeight    int64                 `json:"block_height"` // Block height of the last cycle in the point
+	TvlUSD         float64               `json:"tvl_usd"`
+	Volume7dUSD    float64               `json:"volume_7d_usd"`
+	EdenRewardsAPR float64               `json:"eden_rewards_apr"`
+	UsdcFeesAPR    float64               `json:"usdc_fees_apr"`
+	PriceImpactAPR float64               `json:"price_impact_apr"`
+	TotalAPR       float64               `json:"total_apr"` // Sum of the three APR components
+	SwapFee        float64               `json:"swap_fee"`
+	TotalShares    string                `json:"total_shares"`
+	Assets         []PoolMetricsAsset    `json:"assets"`
+}
+
+// MetricSummary describes the spread of one metric over a time range
+type MetricSummary struct {
+	First  float64 `json:"first"`
+	Last   float64 `json:"last"`
+	Min    float64 `json:"min"`
+	Max    float64 `json:"max"`
+	Mean   float64 `json:"mean"`
+	StdDev float64 `json:"std_dev"`
+	// ChangePercent is the change from First to Last in percent; 0 if First is 0
+	ChangePercent float64 `json:"change_percent"`
+	// CoefficientOfVariation is StdDev / Mean; lower means more stable. 0 if Mean is 0
+	CoefficientOfVariation float64 `json:"coefficient_of_variation"`
+}
+
+// PoolMetricsStats summarizes a pool's metrics over a time range, for judging APR stability and TVL trends
+type PoolMetricsStats struct {
+	PoolID      PoolID        `json:"pool_id"`
+	From        time.Time     `json:"from"`
+	To          time.Time     `json:"to"`
+	PointCount  int           `json:"point_count"`
+	SampleCount int           `json:"sample_count"`
+	TvlUSD      MetricSummary `json:"tvl_usd"`
+	Volume7dUSD MetricSummary `json:"volume_7d_usd"`
+	TotalAPR    MetricSummary `json:"total_apr"`
+	UsdcFeesAPR MetricSummary `json:"usdc_fees_apr"`
+	EdenAPR     MetricSummary `json:"eden_rewards_apr"`
+}
@@ -7,29 +7,43 @@ This is a custom type for pools which contains all the state needed for scoring
 package types
 
 import (
+	"strings"
+
 	"cosmossdk.io/math"
 )
 
 type PoolID uint64
 
+// PoolType is the kind of AMM pool, which decides how its price moves with trades
+type PoolType string
+
+const (
+	PoolTypeWeighted PoolType = "weighted" // Weighted constant-product pool, priced by its own reserves
+	PoolTypeOracle   PoolType = "oracle"   // Oracle pool (smart shielded), priced by the Elys oracle
+)
+
+// PoolAsset is one asset of a pool
+type PoolAsset struct {
+	Token        Token    `json:"token"`
+	Balance      math.Int `json:"balance"`       // Amount of the token held by the pool
+	Weight       float64  `json:"weight"`        // Share of the pool's USD value (normalized, 0.0 to 1.0)
+	TargetWeight float64  `json:"target_weight"` // On-chain pool weight (normalized, 0.0 to 1.0)
+}
+
 type Pool struct {
-	ID              PoolID   `json:"id"`               // e.g., "ATOM-USDC"
-	TokenA          Token    `json:"token_a"`          // e.g., ATOM (non-USDC)
-	TokenB          Token    `json:"token_b"`          // e.g., Likely USDC
-	BalanceA        math.Int `json:"balance_a"`        // Amount of TokenA (T)
-	BalanceB        math.Int `json:"balance_b"`        // Amount of TokenB (USDC)
-	WeightA         float64  `json:"weight_a"`         // Weight of TokenA (normalized, 0.0 to 1.0)
-	WeightB         float64  `json:"weight_b"`         // Weight of TokenB (normalized, 0.0 to 1.0)
-	TvlUSD          float64  `json:"tvl_usd"`          // Total Value Locked in USD
-	Volume7dUSD     float64  `json:"volume_7d_usd"`    // 7-day Trading Volume in USD
-	EdenRewardsAPR  float64  `json:"eden_rewards_apr"` // EDEN rewards component
-	UsdcFeesAPR     float64  `json:"usdc_fees_apr"`    // USDC fees component
-	PriceImpactAPR  float64  `json:"price_impact_apr"` // Price impact fee component
-	IsSmartShielded bool     `json:"is_smart_shielded"`
-	AgeInDays       int      `json:"age_in_days"`
-	SwapFee         float64  `json:"swap_fee"`
-	SentimentScore  float64  `json:"sentiment_score,omitempty"` // -1 to +1, optional
-	TotalShares     math.Int `json:"total_shares"`              // Total number of shares in the pool
+	ID              PoolID      `json:"id"`               // e.g., "ATOM-USDC"
+	Type            PoolType    `json:"type"`             // e.g., "oracle"
+	Assets          []PoolAsset `json:"assets"`           // Two or more assets; USDC, if present, is last
+	TvlUSD          float64     `json:"tvl_usd"`          // Total Value Locked in USD
+	Volume7dUSD     float64     `json:"volume_7d_usd"`    // 7-day Trading Volume in USD
+	EdenRewardsAPR  float64     `json:"eden_rewards_apr"` // EDEN rewards component
+	UsdcFeesAPR     float64     `json:"usdc_fees_apr"`    // USDC fees component
+	PriceImpactAPR  float64     `json:"price_impact_apr"` // Price impact fee component
+	IsSmartShielded bool        `json:"is_smart_shielded"`
+	AgeInDays       int         `json:"age_in_days"`
+	SwapFee         float64     `json:"swap_fee"`
+	SentimentScore  float64     `json:"sentiment_score,omitempty"` // -1 to +1, optional
+	TotalShares     math.Int    `json:"total_shares"`              // Total number of shares in the pool
 
 	Score PoolScoreResult `json:"score"`
 
@@ -38,3 +52,22 @@ type Pool struct {
 	CurrentPositionAgeDays int     `json:"-"`
 	EstimatedPositionValue float64 `json:"-"` // Current value of the vault's position in this pool
 }
+
+// Symbols returns the pool's asset symbols joined by "/", e.g. "ATOM/USDC", for logging
+func (p Pool) Symbols() string {
+	symbols := make([]string, len(p.Assets))
+	for i, asset := range p.Assets {
+		symbols[i] = asset.Token.Symbol
+	}
+	return strings.Join(symbols, "/")
+}
+
+// HasDenom reports whether one of the pool's assets has the given denom or IBC denom
+func (p Pool) HasDenom(denom string) bool {
+	for _, asset := range p.Assets {
+		if asset.Token.Denom == denom || asset.Token.IBCDenom == denom {
+			return true
+		}
+	}
+	return false
+}
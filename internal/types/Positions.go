@@ -65,6 +65,21 @@ type SubAction struct {
 	SlippageTolerancePct float64         `json:"slippage_tolerance_pct,omitempty"` // Maximum acceptable slippage (e.g., 0.05 for 5%)
 }
 
+// PoolID returns the pool the sub-action deposits to, withdraws from or swaps in; 0 for a swap
+// routed through any pool
+func (a SubAction) PoolID() PoolID {
+	switch a.Type {
+	case SubActionSwap:
+		return a.PoolIDForSwap
+	case SubActionDepositLP:
+		return a.PoolIDToDeposit
+	case SubActionWithdrawLP:
+		return a.PoolIDToWithdraw
+	default:
+		return 0
+	}
+}
+
 // ActionPlan holds a sequence of SubActions to achieve a rebalancing goal.
 type ActionPlan struct {
 	GoalDescription       string      `json:"goal_description"` // e.g., "Rebalance to target allocations"
@@ -0,0 +1,18 @@
+/*
+
+This file contains the types describing the persistent hourly price history cache.
+
+*/
+
+package types
+
+import "time"
+
+// PriceHistoryFreshness describes how up to date the cached hourly price history is for one symbol.
+type PriceHistoryFreshness struct {
+	Symbol        string    `json:"symbol"`
+	LatestBar     time.Time `json:"latest_bar"`      // Timestamp of the newest cached hourly bar
+	LastFetchedAt time.Time `json:"last_fetched_at"` // When a bar for this symbol was last written
+	BarCount      int       `json:"bar_count"`       // Bars cached within the volatility window
+	AgeHours      float64   `json:"age_hours"`       // Hours since LatestBar
+}
@@ -0,0 +1,29 @@
+/*
+
+This file contains the types for the price integrity guard, which cross-checks on-chain and
+off-chain prices before the AVM is allowed to act on them.
+
+*/
+
+package types
+
+// PriceIntegrityFlag records a single token whose price sources disagree beyond the configured threshold.
+type PriceIntegrityFlag struct {
+	Denom             string  `json:"denom"`
+	IBCDenom          string  `json:"ibc_denom"`
+	Symbol            string  `json:"symbol"`
+	Reason            string  `json:"reason"`
+	OraclePriceUSD    float64 `json:"oracle_price_usd"`
+	AmmPriceUSD       float64 `json:"amm_price_usd"`
+	ReferencePriceUSD float64 `json:"reference_price_usd"`
+	DivergencePercent float64 `json:"divergence_percent"` // Largest observed divergence (e.g., 7.5 for 7.5%)
+}
+
+// PriceIntegrityReport summarizes the outcome of the price integrity checks for a cycle.
+type PriceIntegrityReport struct {
+	Flags         []PriceIntegrityFlag `json:"flags"`
+	ExcludedPools []PoolID             `json:"excluded_pools"` // Pools removed from selection because they hold a flagged token
+	USDCDepegged  bool                 `json:"usdc_depegged"`
+	HaltExecution bool                 `json:"halt_execution"`
+	HaltReason    string               `json:"halt_reason,omitempty"`
+}
@@ -0,0 +1,39 @@
+/*
+
+This file contains the types for data quarantine, which lets a cycle continue without the tokens
+and pools whose data could not be fetched or validated.
+
+*/
+
+package types
+
+// QuarantinedToken records a token excluded from the cycle because its data failed to fetch or validate.
+type QuarantinedToken struct {
+	Denom    string `json:"denom"`
+	IBCDenom string `json:"ibc_denom"`
+	Symbol   string `json:"symbol"`
+	Reason   string `json:"reason"`
+}
+
+// QuarantinedPool records a pool excluded from selection because its data, or the data of one
+// of its tokens, failed to fetch or validate.
+type QuarantinedPool struct {
+	PoolID PoolID `json:"pool_id"`
+	Reason string `json:"reason"`
+}
+
+// FrozenPosition is an existing position in a quarantined pool. It is neither increased nor
+// withdrawn until the pool's data is healthy again.
+type FrozenPosition struct {
+	PoolID            PoolID  `json:"pool_id"`
+	EstimatedValueUSD float64 `json:"estimated_value_usd"`
+}
+
+// QuarantineReport summarizes the tokens and pools quarantined during a cycle.
+type QuarantineReport struct {
+	Tokens            []QuarantinedToken `json:"tokens"`
+	Pools             []QuarantinedPool  `json:"pools"`
+	FrozenPositions   []FrozenPosition   `json:"frozen_positions"`
+	TokenFailureRatio float64            `json:"token_failure_ratio"` // Quarantined tokens / tokens processed
+	PoolFailureRatio  float64            `json:"pool_failure_ratio"`  // Quarantined pools / supported pools
+}
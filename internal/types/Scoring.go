@@ -21,6 +21,7 @@ type ScoringParameters struct {
 	NormalPoolSlippagePercent  float64 `json:"normal_pool_slippage_percent"`  // Maximum price impact (as a percentage, e.g., 3.0 for 3%) allowed for normal pools.
 	ViableSwapReductionFactor  float64 `json:"viable_swap_reduction_factor"`  // Factor by which to reduce the swap amount when trying to find a viable swap.
 	ViableDepositReductionFactor float64 `json:"viable_deposit_reduction_factor"` // Factor by which to reduce the deposit amount when trying to find a viable deposit.
+	MaxTokenExposure           float64 `json:"max_token_exposure"`            // Maximum fraction of total vault value exposed to any single non-USDC token, summed across all pools holding it.
 
 	// --- Reward Score Components ---
 	AprCoefficient           float64 `json:"apr_coefficient"`            // Coefficient for the weighted APR's impact on the reward score.
@@ -0,0 +1,95 @@
+/*
+
+This file contains the types for managing stored versions of the scoring parameters.
+
+*/
+
+package types
+
+import (
+	"reflect"
+	"strings"
+	"time"
+)
+
+// ScoringParametersVersion is one stored version of the scoring parameters for a config
+type ScoringParametersVersion struct {
+	ParamsID    int64             `json:"params_id"`
+	ConfigName  string            `json:"config_name"`
+	Version     int               `json:"version"`
+	IsActive    bool              `json:"is_active"`
+	ActivatedAt time.Time         `json:"activated_at"`
+	CreatedAt   time.Time         `json:"created_at"`
+	Parameters  ScoringParameters `json:"parameters"`
+}
+
+// ScoringParameterChange is a scoring parameter whose value differs between two sets of parameters
+type ScoringParameterChange struct {
+	Field string  `json:"field"` // JSON name of the parameter, e.g. "max_pools"
+	From  float64 `json:"from"`
+	To    float64 `json:"to"`
+}
+
+// ScoringParamsReload records a newly activated version of the scoring parameters seen by the AVM
+// at the start of a cycle, and whether it was applied or rejected
+type ScoringParamsReload struct {
+	FromParamsID *int64                   `json:"from_params_id,omitempty"` // Parameters in use before the reload; nil if unknown
+	ToParamsID   int64                    `json:"to_params_id"`
+	ToVersion    int                      `json:"to_version"`
+	Applied      bool                     `json:"applied"`
+	RejectReason string                   `json:"reject_reason,omitempty"` // Validation error when not applied
+	Changes      []ScoringParameterChange `json:"changes"`
+}
+
+// ScoringParameterFields returns the JSON names of all scoring parameters, in declaration order
+func ScoringParameterFields() []string {
+	t := reflect.TypeOf(ScoringParameters{})
+	fields := make([]string, 0, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		fields = append(fields, scoringParameterName(t.Field(i)))
+	}
+	return fields
+}
+
+// DiffScoringParameters returns every parameter whose value differs between from and to,
+// in declaration order
+func DiffScoringParameters(from, to ScoringParameters) []ScoringParameterChange {
+	fromValue := reflect.ValueOf(from)
+	toValue := reflect.ValueOf(to)
+	t := fromValue.Type()
+
+	changes := make([]ScoringParameterChange, 0)
+	for i := 0; i < t.NumField(); i++ {
+		a := numericValue(fromValue.Field(i))
+		b := numericValue(toValue.Field(i))
+		if a != b {
+			changes = append(changes, ScoringParameterChange{
+				Field: scoringParameterName(t.Field(i)),
+				From:  a,
+				To:    b,
+			})
+		}
+	}
+	return changes
+}
+
+// scoringParameterName returns the JSON name of a ScoringParameters field
+func scoringParameterName(field reflect.StructField) string {
+	name := strings.Split(field.Tag.Get("json"), ",")[0]
+	if name == "" {
+		return field.Name
+	}
+	return name
+}
+
+// numericValue returns an int or float field as a float64; ScoringParameters only has these kinds
+func numericValue(v reflect.Value) float64 {
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int())
+	case reflect.Float32, reflect.Float64:
+		return v.Float()
+	default:
+		panic("unsupported scoring parameter kind " + v.Kind().String())
+	}
+}
@@ -11,7 +11,10 @@ type CycleSnapshot struct {
 	SnapshotID      int64     `json:"snapshot_id,omitempty"` // Auto-incremented by DB
 	CycleNumber     int       `json:"cycle_number"`
 	Timestamp       time.Time `json:"timestamp"`
-	ScoringParamsID *int64    `json:"scoring_params_id,omitempty"` // Foreign key to the active scoring_parameters
+	ScoringParamsID *int64    `json:"scoring_params_id,omitempty"` // Foreign key to the scoring_parameters used by this cycle
+	BlockHeight     int64     `json:"block_height"`                // Block height all pre-execution chain queries were pinned to
+
+	ScoringParamsReload *ScoringParamsReload `json:"scoring_params_reload,omitempty"` // Set when a newly activated version was picked up or rejected
 
 	// --- Pre-Action State ---
 	InitialVaultValueUSD float64            `json:"initial_vault_value_usd"`
@@ -19,7 +22,9 @@ type CycleSnapshot struct {
 	InitialPositions     []PositionSnapshot `json:"initial_positions"` // State of positions before actions
 
 	// --- The Plan ---
+	PoolScores        []PoolScoreResult  `json:"pool_scores"`        // Score and score components of every pool scored, ordered by pool ID
 	TargetAllocations map[PoolID]float64 `json:"target_allocations"` // The ideal portfolio from the analyzer
+	UnallocatedTarget float64            `json:"unallocated_target"` // Fraction left in liquid USDC because the token exposure cap left no pool room for it
 	ActionPlan        ActionPlan         `json:"action_plan"`        // The full plan generated by the planner
 
 	// --- The Outcome ---
@@ -34,6 +39,15 @@ type CycleSnapshot struct {
 	NetReturnUSD                float64 `json:"net_return_usd"`                // FinalValue - InitialValue
 	TotalSlippageUSD            float64 `json:"total_slippage_usd"`            // Sum of value lost to slippage
 	TotalGasFeeUSD              float64 `json:"total_gas_fee_usd"`             // Sum of gas fees paid
+
+	// --- Risk Reporting ---
+	TargetTokenExposures []TokenExposure       `json:"target_token_exposures"`    // Per-token exposure implied by the target allocations
+	TokenExposures       []TokenExposure       `json:"token_exposures"`           // Per-token exposure of the final positions
+	PriceIntegrity       *PriceIntegrityReport `json:"price_integrity,omitempty"` // Outcome of the price integrity guard
+	Quarantine           *QuarantineReport     `json:"quarantine,omitempty"`      // Tokens and pools skipped because of bad data
+
+	// --- Diagnostics ---
+	StepTimings []StepTiming `json:"step_timings"` // Duration of each cycle step, in completion order
 }
 
 // PositionSnapshot is a detailed record of a single LP position at a point in time,
@@ -46,8 +60,7 @@ type PositionSnapshot struct {
 	AgeDays           int     `json:"age_days"`
 
 	// Contextual Pool Data (at the time of the snapshot)
-	PoolTokenA string  `json:"pool_token_a"` // e.g., "ATOM"
-	PoolTokenB string  `json:"pool_token_b"` // e.g., "USDC"
-	PoolTVL    float64 `json:"pool_tvl"`
-	PoolScore  float64 `json:"pool_score"`
+	PoolAssets []string `json:"pool_assets"` // e.g., ["ATOM", "USDC"]
+	PoolTVL    float64  `json:"pool_tvl"`
+	PoolScore  float64  `json:"pool_score"`
 }
@@ -0,0 +1,7 @@
+package types
+
+// StepTiming records how long one step of a cycle took.
+type StepTiming struct {
+	Step       string  `json:"step"`
+	DurationMs float64 `json:"duration_ms"`
+}
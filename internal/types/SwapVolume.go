@@ -0,0 +1,27 @@
+/*
+
+This file contains the types used by the on-chain swap volume indexer.
+
+*/
+
+package types
+
+import "time"
+
+// PoolDailyVolume is the USD value swapped through one pool on one UTC day.
+type PoolDailyVolume struct {
+	PoolID    PoolID    `json:"pool_id"`
+	Day       time.Time `json:"day"`        // Midnight UTC of the day
+	VolumeUSD float64   `json:"volume_usd"` // Sum of the USD value of every swap
+	SwapCount int       `json:"swap_count"`
+}
+
+// SwapVolumeIndexerState records how far the swap volume indexer has walked the chain.
+// Blocks from FirstHeight to LastHeight have all been indexed without gaps.
+type SwapVolumeIndexerState struct {
+	FirstHeight    int64     `json:"first_height"`
+	FirstBlockTime time.Time `json:"first_block_time"`
+	LastHeight     int64     `json:"last_height"`
+	LastBlockTime  time.Time `json:"last_block_time"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
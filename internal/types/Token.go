@@ -9,14 +9,25 @@ package types
 import "time"
 
 type Token struct {
-	Symbol        string      `json:"symbol"`         // e.g., "atom"
-	Denom         string      `json:"denom"`          // e.g., "uatom"
-	IBCDenom      string      `json:"ibc_denom"`      // e.g., "ibc/273...A8"
-	Precision     int         `json:"precision"`      // e.g., 1000000 = 1 Token (6 decimal precision)
-	PriceUSD      float64     `json:"price_usd"`      // e.g., 1.0
-	OracleSourced bool        `json:"oracle_sourced"` // e.g., Meaning if the price in USD is sourced from the Elys oracle
-	PriceData     []PriceData `json:"price_data"`     // e.g., historical price data
-	Volatility    float64     `json:"volatility"`     // Using the above data, each token gets a volatility score
+	Symbol            string      `json:"symbol"`              // e.g., "atom"
+	Denom             string      `json:"denom"`               // e.g., "uatom"
+	IBCDenom          string      `json:"ibc_denom"`           // e.g., "ibc/273...A8"
+	Precision         int         `json:"precision"`           // e.g., 1000000 = 1 Token (6 decimal precision)
+	PriceUSD          float64     `json:"price_usd"`           // e.g., 1.0
+	OracleSourced     bool        `json:"oracle_sourced"`      // e.g., Meaning if the price in USD is sourced from the Elys oracle
+	OraclePriceUSD    float64     `json:"oracle_price_usd"`    // Raw Elys oracle price (0 if unavailable)
+	AmmPriceUSD       float64     `json:"amm_price_usd"`       // Raw Elys AMM price (0 if unavailable)
+	ReferencePriceUSD float64     `json:"reference_price_usd"` // Off-chain CryptoCompare spot price (0 if unavailable)
+	PriceData         []PriceData `json:"price_data"`          // e.g., historical price data
+	Volatility        float64     `json:"volatility"`          // Using the above data, each token gets a volatility score
+}
+
+// USDCDenom is the base denom of USDC, the vault's numeraire
+const USDCDenom = "uusdc"
+
+// IsUSDC reports whether the token is USDC, by its base denom
+func (t Token) IsUSDC() bool {
+	return t.Denom == USDCDenom
 }
 
 // PriceData holds historical price info
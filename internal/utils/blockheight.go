@@ -0,0 +1,31 @@
+/*
+This file contains helpers for pinning chain queries to one block height.
+
+A context carrying a height makes every gRPC query made with it read state at that height
+(through the x-cosmos-block-height metadata), and lets ABCI queries set their height parameter,
+so all the data used in a cycle comes from the same block.
+*/
+
+package utils
+
+import (
+	"context"
+	"strconv"
+
+	grpctypes "github.com/cosmos/cosmos-sdk/types/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+type blockHeightKey struct{}
+
+// WithBlockHeight returns a context whose chain queries read state at the given height
+func WithBlockHeight(ctx context.Context, height int64) context.Context {
+	ctx = context.WithValue(ctx, blockHeightKey{}, height)
+	return metadata.AppendToOutgoingContext(ctx, grpctypes.GRPCBlockHeightHeader, strconv.FormatInt(height, 10))
+}
+
+// BlockHeightFromContext returns the height set by WithBlockHeight, if any
+func BlockHeightFromContext(ctx context.Context) (int64, bool) {
+	height, ok := ctx.Value(blockHeightKey{}).(int64)
+	return height, ok && height > 0
+}
@@ -0,0 +1,154 @@
+/*
+This file contains helpers for bounded-concurrency work, per-host request rate limiting,
+and context-aware sleeping used by the data fetchers.
+*/
+
+package utils
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// ErrInvalidConcurrency is returned when a worker limit is not positive
+var ErrInvalidConcurrency = errors.New("concurrency limit must be positive")
+
+// ForEachLimited calls fn for every index in [0, n) using at most limit concurrent workers.
+// The first error cancels the context passed to the remaining calls and is returned once all workers stop.
+func ForEachLimited(ctx context.Context, limit, n int, fn func(ctx context.Context, i int) error) error {
+	if limit <= 0 {
+		return ErrInvalidConcurrency
+	}
+	if n <= 0 {
+		return nil
+	}
+	if limit > n {
+		limit = n
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	indexes := make(chan int)
+	var (
+		wg       sync.WaitGroup
+		once     sync.Once
+		firstErr error
+	)
+
+	for w := 0; w < limit; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				if err := fn(ctx, i); err != nil {
+					once.Do(func() {
+						firstErr = err
+						cancel()
+					})
+				}
+			}
+		}()
+	}
+
+feed:
+	for i := 0; i < n; i++ {
+		select {
+		case indexes <- i:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(indexes)
+	wg.Wait()
+
+	if firstErr != nil {
+		return firstErr
+	}
+	return ctx.Err()
+}
+
+// RunConcurrently runs all tasks at the same time. The first error cancels the context passed
+// to the other tasks and is returned once every task has finished.
+func RunConcurrently(ctx context.Context, tasks ...func(ctx context.Context) error) error {
+	return ForEachLimited(ctx, len(tasks), len(tasks), func(ctx context.Context, i int) error {
+		return tasks[i](ctx)
+	})
+}
+
+// SleepContext waits for d, returning early with the context's error if it is cancelled
+func SleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// HostRateLimiter spaces out requests to each host so that no host receives more than its
+// configured number of requests per second, regardless of how many workers share it.
+type HostRateLimiter struct {
+	mu              sync.Mutex
+	defaultInterval time.Duration
+	intervals       map[string]time.Duration // Host -> minimum spacing between requests
+	next            map[string]time.Time     // Host -> earliest time the next request may start
+}
+
+// NewHostRateLimiter creates a limiter allowing defaultPerSecond requests per second to any host,
+// overridden per host by perHost. A rate of zero or less disables limiting for that host.
+func NewHostRateLimiter(defaultPerSecond float64, perHost map[string]float64) *HostRateLimiter {
+	limiter := &HostRateLimiter{
+		defaultInterval: rateToInterval(defaultPerSecond),
+		intervals:       make(map[string]time.Duration, len(perHost)),
+		next:            make(map[string]time.Time),
+	}
+	for host, perSecond := range perHost {
+		limiter.intervals[host] = rateToInterval(perSecond)
+	}
+	return limiter
+}
+
+// Wait blocks until a request to the host of rawURL is allowed, or the context is cancelled
+func (l *HostRateLimiter) Wait(ctx context.Context, rawURL string) error {
+	if l == nil {
+		return ctx.Err()
+	}
+
+	host := rawURL
+	if parsed, err := url.Parse(rawURL); err == nil && parsed.Host != "" {
+		host = parsed.Hostname()
+	}
+
+	l.mu.Lock()
+	interval, exists := l.intervals[host]
+	if !exists {
+		interval = l.defaultInterval
+	}
+	now := time.Now()
+	start := l.next[host]
+	if start.Before(now) {
+		start = now
+	}
+	l.next[host] = start.Add(interval)
+	l.mu.Unlock()
+
+	if wait := time.Until(start); wait > 0 {
+		return SleepContext(ctx, wait)
+	}
+	return ctx.Err()
+}
+
+// rateToInterval converts requests per second into the minimum spacing between requests
+func rateToInterval(perSecond float64) time.Duration {
+	if perSecond <= 0 {
+		return 0
+	}
+	return time.Duration(float64(time.Second) / perSecond)
+}
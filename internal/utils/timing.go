@@ -0,0 +1,59 @@
+/*
+This file contains a small recorder for per-step durations within an AVM cycle.
+*/
+
+package utils
+
+import (
+	"sync"
+	"time"
+
+	"github.com/elys-network/avm/internal/types"
+)
+
+// StepTimer records the duration of named steps. It is safe for concurrent use,
+// and a nil *StepTimer silently records nothing.
+type StepTimer struct {
+	mu      sync.Mutex
+	timings []types.StepTiming
+}
+
+// NewStepTimer creates an empty StepTimer
+func NewStepTimer() *StepTimer {
+	return &StepTimer{timings: make([]types.StepTiming, 0)}
+}
+
+// Track starts timing a step and returns the function that stops it. Only the first call
+// of the returned function records the step. Typical use: `defer timer.Track("fetch_pools")()`.
+func (t *StepTimer) Track(step string) func() {
+	start := time.Now()
+	var once sync.Once
+	return func() {
+		if t == nil {
+			return
+		}
+		once.Do(func() {
+			duration := time.Since(start)
+
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			t.timings = append(t.timings, types.StepTiming{
+				Step:       step,
+				DurationMs: float64(duration.Microseconds()) / 1000.0,
+			})
+		})
+	}
+}
+
+// Timings returns a copy of the recorded step timings in completion order
+func (t *StepTimer) Timings() []types.StepTiming {
+	if t == nil {
+		return []types.StepTiming{}
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	timings := make([]types.StepTiming, len(t.timings))
+	copy(timings, t.timings)
+	return timings
+}
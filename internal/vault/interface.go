@@ -1,28 +1,35 @@
 package vault
 
 import (
+	"context"
+
+	sdkmath "cosmossdk.io/math"
 	"github.com/elys-network/avm/internal/types"
 )
 
 // VaultManager defines the interface for interacting with the vault system.
 // This interface abstracts away the specific implementation details of vault operations,
 // allowing for different vault implementations (live, simulation, etc.).
+// The getters read the vault at the block height pinned on ctx, if any (see utils.WithBlockHeight).
 type VaultManager interface {
 	// GetLiquidUSDC returns the current amount of liquid USDC available in the vault.
-	GetLiquidUSDC() (float64, error)
+	GetLiquidUSDC(ctx context.Context) (float64, error)
 
 	// GetPoolPositions returns all current LP positions in pools.
-	GetPoolPositions() ([]types.Position, error)
+	GetPoolPositions(ctx context.Context) ([]types.Position, error)
 
 	// GetNonPoolPositions returns all non-pool token positions (e.g., liquid tokens other than USDC).
-	GetNonPoolPositions() ([]types.TokenPosition, error)
+	GetNonPoolPositions(ctx context.Context) ([]types.TokenPosition, error)
 
 	// GetTotalVaultValue returns the total USD value of all assets in the vault.
-	GetTotalVaultValue() (float64, error)
+	GetTotalVaultValue(ctx context.Context) (float64, error)
 
 	// GetTradableDenoms returns all tradable token denoms in the vault.
 	// The vault has permission only to trade certain tokens decided by governance.
-	GetTradableDenoms() ([]string, error)
+	GetTradableDenoms(ctx context.Context) ([]string, error)
+
+	// GetShareSupply returns the total amount of vault shares outstanding, in raw on-chain units.
+	GetShareSupply(ctx context.Context) (sdkmath.Int, error)
 
 	// ExecuteActionPlan executes a list of SubActions and returns transaction details.
 	// This is the main method for implementing rebalancing decisions.
@@ -15,12 +15,17 @@ import (
 	"strings"
 	"time"
 
+	sdkmath "cosmossdk.io/math"
 	sdk "github.com/cosmos/cosmos-sdk/types"
+	banktypes "github.com/cosmos/cosmos-sdk/x/bank/types"
 	"google.golang.org/grpc"
 
 	"github.com/elys-network/avm/internal/config"
+	"github.com/elys-network/avm/internal/fixtures"
 	"github.com/elys-network/avm/internal/logger"
+	"github.com/elys-network/avm/internal/metrics"
 	"github.com/elys-network/avm/internal/types"
+	"github.com/elys-network/avm/internal/utils"
 	"github.com/elys-network/avm/internal/wallet"
 	vaulttypes "github.com/elys-network/elys/v6/x/vaults/types"
 	"github.com/gogo/protobuf/proto"
@@ -247,7 +252,7 @@ func validateVaultClient(client *VaultClient) error {
 }
 
 // GetLiquidUSDC fetches USDC with comprehensive validation and mathematical safety
-func (v *VaultClient) GetLiquidUSDC() (float64, error) {
+func (v *VaultClient) GetLiquidUSDC(ctx context.Context) (float64, error) {
 	// Validate client state
 	if err := v.validateClientState(); err != nil {
 		return 0, err
@@ -260,7 +265,7 @@ func (v *VaultClient) GetLiquidUSDC() (float64, error) {
 	}
 
 	// Create timeout context with validation
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
 	defer cancel()
 	if ctx == nil {
 		return 0, errors.New("failed to create timeout context")
@@ -365,7 +370,7 @@ func validatePosition(position vaulttypes.PositionToken, index int) error {
 func (v *VaultClient) findAndValidateUSDCToken() (*types.Token, error) {
 	var usdcToken *types.Token
 	for _, token := range v.Tokens {
-		if token.Denom == "uusdc" || token.Symbol == "USDC" {
+		if token.IsUSDC() {
 			tokenCopy := token
 			usdcToken = &tokenCopy
 			break
@@ -452,7 +457,7 @@ func (v *VaultClient) calculateUSDCAmount(position vaulttypes.PositionToken, usd
 }
 
 // GetPoolPositions fetches pool positions with comprehensive validation
-func (v *VaultClient) GetPoolPositions() ([]types.Position, error) {
+func (v *VaultClient) GetPoolPositions(ctx context.Context) ([]types.Position, error) {
 	// Validate client state
 	if err := v.validateClientState(); err != nil {
 		return nil, err
@@ -465,7 +470,7 @@ func (v *VaultClient) GetPoolPositions() ([]types.Position, error) {
 	}
 
 	// Create timeout context
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
 	defer cancel()
 	if ctx == nil {
 		return nil, errors.New("failed to create timeout context")
@@ -571,7 +576,7 @@ func extractPoolID(denom string) (uint64, error) {
 }
 
 // GetNonPoolPositions fetches non-pool positions with comprehensive validation
-func (v *VaultClient) GetNonPoolPositions() ([]types.TokenPosition, error) {
+func (v *VaultClient) GetNonPoolPositions(ctx context.Context) ([]types.TokenPosition, error) {
 	// Validate client state
 	if err := v.validateClientState(); err != nil {
 		return nil, err
@@ -584,7 +589,7 @@ func (v *VaultClient) GetNonPoolPositions() ([]types.TokenPosition, error) {
 	}
 
 	// Create timeout context
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
 	defer cancel()
 	if ctx == nil {
 		return nil, errors.New("failed to create timeout context")
@@ -659,7 +664,7 @@ func (v *VaultClient) processTokenPosition(position vaulttypes.PositionToken, in
 }
 
 // GetTotalVaultValue fetches total vault value with comprehensive RPC validation
-func (v *VaultClient) GetTotalVaultValue() (float64, error) {
+func (v *VaultClient) GetTotalVaultValue(ctx context.Context) (float64, error) {
 	// Validate client state
 	if err := v.validateClientState(); err != nil {
 		return 0, err
@@ -686,11 +691,11 @@ func (v *VaultClient) GetTotalVaultValue() (float64, error) {
 	}
 
 	// Execute RPC call with comprehensive validation
-	return v.executeVaultValueRPC(protoBytes)
+	return v.executeVaultValueRPC(ctx, protoBytes)
 }
 
 // GetTradableTokens returns all tradable token denoms in the vault.
-func (v *VaultClient) GetTradableDenoms() ([]string, error) {
+func (v *VaultClient) GetTradableDenoms(ctx context.Context) ([]string, error) {
 	// Validate client state
 	if err := v.validateClientState(); err != nil {
 		return nil, err
@@ -703,7 +708,7 @@ func (v *VaultClient) GetTradableDenoms() ([]string, error) {
 	}
 
 	// Create timeout context
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
 	defer cancel()
 	if ctx == nil {
 		return nil, errors.New("failed to create timeout context")
@@ -725,6 +730,47 @@ func (v *VaultClient) GetTradableDenoms() ([]string, error) {
 	return vaultResponse.Vault.AllowedCoins, nil
 }
 
+// GetShareSupply fetches the total supply of the vault's share denom from the bank module
+func (v *VaultClient) GetShareSupply(ctx context.Context) (sdkmath.Int, error) {
+	// Validate client state
+	if err := v.validateClientState(); err != nil {
+		return sdkmath.Int{}, err
+	}
+
+	// Ensure connection
+	if err := v.ensureConnection(); err != nil {
+		vaultLogger.Error().Err(err).Msg("Failed to ensure gRPC connection for GetShareSupply")
+		return sdkmath.Int{}, errors.Join(ErrConnectionFailed, fmt.Errorf("failed to ensure gRPC connection: %w", err))
+	}
+
+	// Create timeout context
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	// Shares are minted and burned by the vaults module under a denom derived from the vault ID
+	shareDenom := vaulttypes.GetShareDenomForVault(v.vaultId)
+	supplyResponse, err := banktypes.NewQueryClient(v.grpcConn).SupplyOf(ctx, &banktypes.QuerySupplyOfRequest{
+		Denom: shareDenom,
+	})
+	if err != nil {
+		return sdkmath.Int{}, errors.Join(ErrRPCRequestFailed, fmt.Errorf("failed to query supply of %s: %w", shareDenom, err))
+	}
+
+	// Validate response
+	supply := supplyResponse.Amount.Amount
+	if supply.IsNil() || supply.IsNegative() {
+		return sdkmath.Int{}, errors.Join(ErrInvalidResponse, fmt.Errorf("invalid supply of %s: %s", shareDenom, supply))
+	}
+
+	vaultLogger.Debug().
+		Uint64("vaultId", v.vaultId).
+		Str("shareDenom", shareDenom).
+		Str("shareSupply", supply.String()).
+		Msg("Fetched vault share supply")
+
+	return supply, nil
+}
+
 
 
 // validateRPCConfig validates RPC configuration
@@ -736,8 +782,9 @@ func validateRPCConfig() error {
 	return nil
 }
 
-// executeVaultValueRPC executes the RPC call with comprehensive error handling
-func (v *VaultClient) executeVaultValueRPC(protoBytes []byte) (float64, error) {
+// executeVaultValueRPC executes the RPC call with comprehensive error handling.
+// The query runs at the block height pinned on ctx, if any.
+func (v *VaultClient) executeVaultValueRPC(ctx context.Context, protoBytes []byte) (float64, error) {
 	hexEncodedData := hex.EncodeToString(protoBytes)
 	if hexEncodedData == "" {
 		return 0, errors.Join(ErrRPCRequestFailed, errors.New("hex encoding failed"))
@@ -754,6 +801,9 @@ func (v *VaultClient) executeVaultValueRPC(protoBytes []byte) (float64, error) {
 			Data: hexEncodedData,
 		},
 	}
+	if height, ok := utils.BlockHeightFromContext(ctx); ok {
+		jsonRPCReq.Params.Height = strconv.FormatInt(height, 10)
+	}
 
 	// Marshal JSON request
 	jsonData, err := json.Marshal(jsonRPCReq)
@@ -767,18 +817,19 @@ func (v *VaultClient) executeVaultValueRPC(protoBytes []byte) (float64, error) {
 	}
 
 	// Execute HTTP request with timeout and validation
-	return v.executeHTTPRequest(jsonData, abciPath, hexEncodedData)
+	return v.executeHTTPRequest(ctx, jsonData, abciPath, hexEncodedData)
 }
 
 // executeHTTPRequest executes the HTTP request with comprehensive validation
-func (v *VaultClient) executeHTTPRequest(jsonData []byte, abciPath, hexData string) (float64, error) {
+func (v *VaultClient) executeHTTPRequest(ctx context.Context, jsonData []byte, abciPath, hexData string) (float64, error) {
 	// Create HTTP client with timeout
 	httpClient := http.Client{
-		Timeout: 20 * time.Second,
+		Timeout:   20 * time.Second,
+		Transport: fixtures.Transport(),
 	}
 
 	// Create request with validation
-	req, err := http.NewRequest("POST", config.NodeRPC, bytes.NewBuffer(jsonData))
+	req, err := http.NewRequestWithContext(ctx, "POST", config.NodeRPC, bytes.NewBuffer(jsonData))
 	if err != nil {
 		vaultLogger.Error().Err(err).Str("endpoint", config.NodeRPC).Msg("Failed to create HTTP request")
 		return 0, errors.Join(ErrRPCRequestFailed, fmt.Errorf("failed to create HTTP request: %w", err))
@@ -919,6 +970,7 @@ func (v *VaultClient) connect() error {
 		config.NodeRPC,
 		grpc.WithTransportCredentials(insecure.NewCredentials()),
 		grpc.WithBlock(),
+		fixtures.GRPCDialOption(),
 	)
 	if err != nil {
 		vaultLogger.Error().Err(err).Str("endpoint", config.NodeRPC).Msg("Failed to connect to gRPC endpoint")
@@ -1032,6 +1084,9 @@ func (v *VaultClient) ExecuteActionPlan(subActions []types.SubAction) (*types.Tr
 	txResponse, err := txBuilder.ProcessSubActions(subActions, v.vaultId)
 	if err != nil {
 		vaultLogger.Error().Err(err).Msg("ExecuteActionPlan: Failed to process SubActions")
+		if errors.Is(err, wallet.ErrTxBroadcastFailed) {
+			metrics.CountTransaction(metrics.TxResultBroadcastFailed)
+		}
 		return &types.TransactionResult{
 			Success:      false,
 			ErrorMessage: err.Error(),
@@ -1045,6 +1100,7 @@ func (v *VaultClient) ExecuteActionPlan(subActions []types.SubAction) (*types.Tr
 	// Validate transaction response
 	if err := v.validateTransactionResponse(txResponse); err != nil {
 		vaultLogger.Error().Err(err).Msg("ExecuteActionPlan: Transaction response validation failed")
+		metrics.CountTransaction(metrics.TxResultBroadcastFailed)
 		return &types.TransactionResult{
 			TxHash:       txResponse.TxHash,
 			Success:      false,
@@ -1110,6 +1166,7 @@ func (v *VaultClient) waitForTransactionInclusion(signingClient *wallet.SigningC
 	vaultLogger.Info().
 		Str("txHash", txHash).
 		Msg("Waiting for transaction to be included in block...")
+	broadcastAt := time.Now()
 
 	// Wait with exponential backoff and timeout
 	maxAttempts := 30            // Maximum number of attempts
@@ -1159,6 +1216,8 @@ func (v *VaultClient) waitForTransactionInclusion(signingClient *wallet.SigningC
 				Int("eventCount", len(txResponse.Events)).
 				Msg("Transaction found in block with complete data")
 
+			// Includes up to one polling delay, since inclusion is only seen when polling
+			metrics.ObserveTxInclusion(time.Since(broadcastAt))
 			return txResponse, nil
 		}
 
@@ -1169,6 +1228,7 @@ func (v *VaultClient) waitForTransactionInclusion(signingClient *wallet.SigningC
 	}
 
 	// If we get here, we've exhausted all attempts
+	metrics.CountTransaction(metrics.TxResultNotIncluded)
 	return nil, fmt.Errorf("transaction %s was not found in block after %d attempts", txHash, maxAttempts)
 }
 
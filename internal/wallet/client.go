@@ -5,8 +5,10 @@ import (
 	"errors"
 	"fmt"
 	"math"
+	"net/http"
 	"os"
 	"sync"
+	"time"
 
 	rpchttp "github.com/cometbft/cometbft/rpc/client/http"
 	"github.com/cosmos/cosmos-sdk/client"
@@ -24,7 +26,9 @@ import (
 	"google.golang.org/grpc"
 
 	"github.com/elys-network/avm/internal/config"
+	"github.com/elys-network/avm/internal/fixtures"
 	"github.com/elys-network/avm/internal/logger"
+	"github.com/elys-network/avm/internal/metrics"
 	vaulttypes "github.com/elys-network/elys/v6/x/vaults/types"
 )
 
@@ -272,7 +276,14 @@ func getAndValidateKey(kr keyring.Keyring) (sdk.AccAddress, error) {
 
 // createRPCClient creates and validates RPC client
 func createRPCClient() (*rpchttp.HTTP, error) {
-	rpcClient, err := rpchttp.New(config.NodeRPC, "/websocket")
+	var rpcClient *rpchttp.HTTP
+	var err error
+	if fixtures.Enabled() {
+		// Route broadcasts and queries through the recorder/replayer
+		rpcClient, err = rpchttp.NewWithClient(config.NodeRPC, "/websocket", &http.Client{Transport: fixtures.Transport()})
+	} else {
+		rpcClient, err = rpchttp.New(config.NodeRPC, "/websocket")
+	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to create RPC client: %w", err)
 	}
@@ -786,7 +797,9 @@ func (s *SigningClient) CalculateGas(ctx context.Context, msgs ...sdk.Msg) (uint
 
 	// Execute simulation
 	walletLogger.Info().Msg("CalculateGas: Executing gas simulation...")
+	start := time.Now()
 	simRes, err := txSvcClient.Simulate(ctx, simRequest)
+	metrics.ObserveSimulation(metrics.SimulationGas, start, err)
 	if err != nil {
 		walletLogger.Error().Err(err).Msg("CalculateGas: Gas simulation failed")
 		return 0, fmt.Errorf("gas simulation failed: %w", err)
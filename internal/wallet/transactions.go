@@ -5,12 +5,14 @@ import (
 	"errors"
 	"fmt"
 	"math"
+	"time"
 
 	sdkmath "cosmossdk.io/math"
 	sdk "github.com/cosmos/cosmos-sdk/types"
 	txtypes "github.com/cosmos/cosmos-sdk/types/tx"
 	"github.com/elys-network/avm/internal/config"
 	"github.com/elys-network/avm/internal/logger"
+	"github.com/elys-network/avm/internal/metrics"
 	"github.com/elys-network/avm/internal/types"
 	vaulttypes "github.com/elys-network/elys/v6/x/vaults/types"
 )
@@ -140,7 +142,9 @@ func (tb *TransactionBuilder) simulateGas(ctx context.Context, msgs ...sdk.Msg)
 		TxBytes: txBytes,
 	}
 
+	start := time.Now()
 	simRes, err := txSvcClient.Simulate(ctx, simRequest)
+	metrics.ObserveSimulation(metrics.SimulationGas, start, err)
 	if err != nil {
 		return 0, fmt.Errorf("gas simulation failed: %w", err)
 	}
@@ -0,0 +1,297 @@
+/*
+
+This file implements the web API's access control: API key authentication, per-route roles,
+the API key management endpoints and the CORS allowlist.
+
+Callers send their key as "Authorization: Bearer <key>" or "X-API-Key: <key>". authMiddleware
+identifies the caller of every request, and requireRole wraps each API route with the role it
+needs. Requests without a key get AuthConfig.AnonymousRole, if any; a key that is unknown or
+revoked is always rejected rather than treated as anonymous.
+
+*/
+
+package web
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/elys-network/avm/internal/auth"
+	"github.com/elys-network/avm/internal/state"
+	"github.com/elys-network/avm/internal/types"
+	"github.com/gorilla/mux"
+)
+
+const (
+	// apiKeyTouchInterval limits how often a key's last use is written to the database
+	apiKeyTouchInterval = time.Minute
+	// maxRequestBodyBytes caps the JSON bodies of write endpoints
+	maxRequestBodyBytes = 1 << 20
+)
+
+// AuthConfig configures the web API's access control
+type AuthConfig struct {
+	AnonymousRole  types.APIRole // Role of requests without an API key; empty requires a key
+	AllowedOrigins []string      // CORS allowlist; "*" allows every origin, empty none
+}
+
+// principal is the caller of a request
+type principal struct {
+	Name  string        `json:"name"`
+	Role  types.APIRole `json:"role"`
+	KeyID int64         `json:"-"` // 0 for anonymous callers
+}
+
+// auditActor names the caller in the audit log
+func (p *principal) auditActor() string {
+	if p.KeyID == 0 {
+		return p.Name
+	}
+	return "apikey:" + p.Name
+}
+
+type contextKey int
+
+const principalContextKey contextKey = iota
+
+// principalFromContext returns the caller identified by authMiddleware, or nil if there is none
+func principalFromContext(ctx context.Context) *principal {
+	p, _ := ctx.Value(principalContextKey).(*principal)
+	return p
+}
+
+// authMiddleware identifies the caller of a request from its API key
+func (ws *WebServer) authMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := requestAPIKey(r)
+		if key == "" {
+			if ws.auth.AnonymousRole != "" {
+				p := &principal{Name: "anonymous", Role: ws.auth.AnonymousRole}
+				r = r.WithContext(context.WithValue(r.Context(), principalContextKey, p))
+			}
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		apiKey, err := ws.store.GetAPIKeyByHash(auth.HashAPIKey(key))
+		if err != nil {
+			webLogger.Error().Err(err).Msg("Failed to look up API key")
+			ws.writeErrorResponse(w, http.StatusInternalServerError, "Failed to authenticate")
+			return
+		}
+		if apiKey == nil || apiKey.RevokedAt != nil {
+			webLogger.Warn().Str("prefix", auth.DisplayPrefix(key)).Str("remote_addr", r.RemoteAddr).Msg("Rejected unknown or revoked API key")
+			w.Header().Set("WWW-Authenticate", `Bearer realm="avm"`)
+			ws.writeErrorResponse(w, http.StatusUnauthorized, "Invalid API key")
+			return
+		}
+
+		now := time.Now()
+		if apiKey.LastUsedAt == nil || now.Sub(*apiKey.LastUsedAt) >= apiKeyTouchInterval {
+			if err := ws.store.TouchAPIKey(apiKey.ID, now); err != nil {
+				webLogger.Warn().Err(err).Str("name", apiKey.Name).Msg("Failed to record API key use")
+			}
+		}
+
+		p := &principal{Name: apiKey.Name, Role: apiKey.Role, KeyID: apiKey.ID}
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), principalContextKey, p)))
+	})
+}
+
+// requestAPIKey returns the API key a request carries, or "" if it has none
+func requestAPIKey(r *http.Request) string {
+	if header := r.Header.Get("Authorization"); header != "" {
+		if scheme, token, found := strings.Cut(header, " "); found && strings.EqualFold(scheme, "Bearer") {
+			return strings.TrimSpace(token)
+		}
+	}
+	return strings.TrimSpace(r.Header.Get("X-API-Key"))
+}
+
+// requireRole only lets callers with at least role through to handler
+func (ws *WebServer) requireRole(role types.APIRole, handler http.HandlerFunc) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		p := principalFromContext(r.Context())
+		if p == nil {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="avm"`)
+			ws.writeErrorResponse(w, http.StatusUnauthorized, "API key required")
+			return
+		}
+		if !p.Role.Allows(role) {
+			ws.writeErrorResponse(w, http.StatusForbidden, "This endpoint requires the "+string(role)+" role")
+			return
+		}
+		handler(w, r)
+	})
+}
+
+// corsMiddleware adds CORS headers for the origins in the allowlist. It wraps the router, so it
+// also answers preflight requests, which match no route.
+func (ws *WebServer) corsMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		origin := r.Header.Get("Origin")
+		allowed := origin != "" && ws.originAllowed(origin)
+		if allowed {
+			w.Header().Set("Access-Control-Allow-Origin", origin)
+			w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
+			w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, X-API-Key")
+			w.Header().Set("Access-Control-Max-Age", "600")
+		}
+		if origin != "" {
+			w.Header().Add("Vary", "Origin")
+		}
+
+		if r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != "" {
+			if !allowed {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// originAllowed reports whether a browser origin is in the CORS allowlist
+func (ws *WebServer) originAllowed(origin string) bool {
+	origin = strings.ToLower(origin)
+	for _, allowed := range ws.auth.AllowedOrigins {
+		if allowed == "*" || allowed == origin {
+			return true
+		}
+	}
+	return false
+}
+
+// handleGetAuthMe returns the caller's name and role
+func (ws *WebServer) handleGetAuthMe(w http.ResponseWriter, r *http.Request) {
+	ws.writeJSONResponse(w, http.StatusOK, principalFromContext(r.Context()))
+}
+
+// handleListAPIKeys returns every API key, without the keys themselves
+func (ws *WebServer) handleListAPIKeys(w http.ResponseWriter, r *http.Request) {
+	keys, err := ws.store.ListAPIKeys()
+	if err != nil {
+		webLogger.Error().Err(err).Msg("Failed to list API keys")
+		ws.writeErrorResponse(w, http.StatusInternalServerError, "Failed to retrieve API keys")
+		return
+	}
+
+	response := map[string]interface{}{
+		"keys":  keys,
+		"count": len(keys),
+	}
+
+	ws.writeJSONResponse(w, http.StatusOK, response)
+}
+
+// handleCreateAPIKey creates an API key and returns it. The key is not shown again.
+func (ws *WebServer) handleCreateAPIKey(w http.ResponseWriter, r *http.Request) {
+	var request struct {
+		Name   string `json:"name"`
+		Role   string `json:"role"`
+		Reason string `json:"reason"`
+	}
+	if !ws.decodeJSONBody(w, r, &request) {
+		return
+	}
+	request.Name = strings.TrimSpace(request.Name)
+	if request.Name == "" {
+		ws.writeErrorResponse(w, http.StatusBadRequest, "name is required")
+		return
+	}
+	role, err := types.ParseAPIRole(request.Role)
+	if err != nil {
+		ws.writeErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	audit, ok := ws.requestAuditInfo(w, r, request.Reason)
+	if !ok {
+		return
+	}
+
+	key, err := auth.GenerateAPIKey()
+	if err != nil {
+		webLogger.Error().Err(err).Msg("Failed to generate API key")
+		ws.writeErrorResponse(w, http.StatusInternalServerError, "Failed to create API key")
+		return
+	}
+	apiKey, err := ws.store.CreateAPIKey(request.Name, role, auth.HashAPIKey(key), auth.DisplayPrefix(key), audit)
+	if errors.Is(err, state.ErrAPIKeyExists) {
+		ws.writeErrorResponse(w, http.StatusConflict, "An API key with this name already exists")
+		return
+	}
+	if err != nil {
+		webLogger.Error().Err(err).Str("name", request.Name).Msg("Failed to create API key")
+		ws.writeErrorResponse(w, http.StatusInternalServerError, "Failed to create API key")
+		return
+	}
+
+	response := map[string]interface{}{
+		"api_key": apiKey,
+		"key":     key,
+	}
+
+	ws.writeJSONResponse(w, http.StatusCreated, response)
+}
+
+// handleRevokeAPIKey revokes an API key by name
+func (ws *WebServer) handleRevokeAPIKey(w http.ResponseWriter, r *http.Request) {
+	var request struct {
+		Reason string `json:"reason"`
+	}
+	if !ws.decodeJSONBody(w, r, &request) {
+		return
+	}
+	audit, ok := ws.requestAuditInfo(w, r, request.Reason)
+	if !ok {
+		return
+	}
+
+	name := mux.Vars(r)["name"]
+	apiKey, err := ws.store.RevokeAPIKey(name, audit)
+	if errors.Is(err, state.ErrAPIKeyNotFound) {
+		ws.writeErrorResponse(w, http.StatusNotFound, "API key not found")
+		return
+	}
+	if errors.Is(err, state.ErrAPIKeyRevoked) {
+		ws.writeErrorResponse(w, http.StatusConflict, "API key already revoked")
+		return
+	}
+	if err != nil {
+		webLogger.Error().Err(err).Str("name", name).Msg("Failed to revoke API key")
+		ws.writeErrorResponse(w, http.StatusInternalServerError, "Failed to revoke API key")
+		return
+	}
+
+	ws.writeJSONResponse(w, http.StatusOK, map[string]interface{}{"api_key": apiKey})
+}
+
+// decodeJSONBody reads a request's JSON body into dest, rejecting unknown fields. It writes an
+// error response and reports false if the body is invalid.
+func (ws *WebServer) decodeJSONBody(w http.ResponseWriter, r *http.Request, dest interface{}) bool {
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestBodyBytes))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(dest); err != nil {
+		ws.writeErrorResponse(w, http.StatusBadRequest, "Invalid JSON body: "+err.Error())
+		return false
+	}
+	return true
+}
+
+// requestAuditInfo returns the caller and reason of a change for the audit log. It writes an
+// error response and reports false if the reason is missing.
+func (ws *WebServer) requestAuditInfo(w http.ResponseWriter, r *http.Request, reason string) (types.AuditInfo, bool) {
+	reason = strings.TrimSpace(reason)
+	if reason == "" {
+		ws.writeErrorResponse(w, http.StatusBadRequest, "reason is required")
+		return types.AuditInfo{}, false
+	}
+	return types.AuditInfo{Actor: principalFromContext(r.Context()).auditActor(), Reason: reason}, true
+}
@@ -0,0 +1,104 @@
+/*
+
+This file implements the operator control endpoints under /api/control: pausing and resuming
+execution, triggering a cycle and the emergency stop. They act on the running AVM through a
+Controller, which cmd/avm sets once the AVM is created; until then they answer 503.
+
+*/
+
+package web
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/elys-network/avm/internal/types"
+)
+
+// Controller runs the operator control actions on the AVM. *avm.AVM implements it.
+type Controller interface {
+	ControlStatus() types.ControlStatus
+	Pause(audit types.AuditInfo) (types.ControlStatus, error)
+	Resume(audit types.AuditInfo) (types.ControlStatus, error)
+	EmergencyStop(audit types.AuditInfo) (types.ControlStatus, error)
+	TriggerCycle(audit types.AuditInfo) (types.ControlStatus, error)
+}
+
+// SetController connects the control endpoints to the AVM
+func (ws *WebServer) SetController(controller Controller) {
+	ws.controllerMu.Lock()
+	defer ws.controllerMu.Unlock()
+	ws.controller = controller
+}
+
+// getController returns the AVM's Controller, writing a 503 response if it is not set yet
+func (ws *WebServer) getController(w http.ResponseWriter) (Controller, bool) {
+	ws.controllerMu.RLock()
+	defer ws.controllerMu.RUnlock()
+	if ws.controller == nil {
+		ws.writeErrorResponse(w, http.StatusServiceUnavailable, "The AVM is starting")
+		return nil, false
+	}
+	return ws.controller, true
+}
+
+// handleGetControlStatus returns the control mode and whether a cycle is running or triggered
+func (ws *WebServer) handleGetControlStatus(w http.ResponseWriter, r *http.Request) {
+	controller, ok := ws.getController(w)
+	if !ok {
+		return
+	}
+	ws.writeJSONResponse(w, http.StatusOK, controller.ControlStatus())
+}
+
+// handlePause makes cycles skip execution
+func (ws *WebServer) handlePause(w http.ResponseWriter, r *http.Request) {
+	ws.handleControlAction(w, r, Controller.Pause)
+}
+
+// handleResume makes cycles execute their plans again
+func (ws *WebServer) handleResume(w http.ResponseWriter, r *http.Request) {
+	ws.handleControlAction(w, r, Controller.Resume)
+}
+
+// handleEmergencyStop cancels the running cycle and stops cycles until resumed
+func (ws *WebServer) handleEmergencyStop(w http.ResponseWriter, r *http.Request) {
+	ws.handleControlAction(w, r, Controller.EmergencyStop)
+}
+
+// handleTriggerCycle runs a cycle now, or right after the running one
+func (ws *WebServer) handleTriggerCycle(w http.ResponseWriter, r *http.Request) {
+	ws.handleControlAction(w, r, Controller.TriggerCycle)
+}
+
+// handleControlAction runs a control action with the reason from the request body and returns the
+// resulting status. Actions that conflict with the current state answer 409.
+func (ws *WebServer) handleControlAction(w http.ResponseWriter, r *http.Request, action func(Controller, types.AuditInfo) (types.ControlStatus, error)) {
+	var request struct {
+		Reason string `json:"reason"`
+	}
+	if !ws.decodeJSONBody(w, r, &request) {
+		return
+	}
+	audit, ok := ws.requestAuditInfo(w, r, request.Reason)
+	if !ok {
+		return
+	}
+	controller, ok := ws.getController(w)
+	if !ok {
+		return
+	}
+
+	status, err := action(controller, audit)
+	if errors.Is(err, types.ErrControlModeUnchanged) || errors.Is(err, types.ErrEmergencyStopped) || errors.Is(err, types.ErrCycleAlreadyTriggered) {
+		ws.writeErrorResponse(w, http.StatusConflict, err.Error())
+		return
+	}
+	if err != nil {
+		webLogger.Error().Err(err).Str("path", r.URL.Path).Msg("Control action failed")
+		ws.writeErrorResponse(w, http.StatusInternalServerError, "Control action failed")
+		return
+	}
+
+	ws.writeJSONResponse(w, http.StatusOK, status)
+}
@@ -0,0 +1,66 @@
+/*
+
+This file implements GET /api/cycles/diff, which compares two cycle snapshots with the
+cyclediff package: the changes in target allocations and positions, the scoring parameter
+versions used and each pool's score components.
+
+*/
+
+package web
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/elys-network/avm/internal/cyclediff"
+	"github.com/elys-network/avm/internal/state"
+	"github.com/elys-network/avm/internal/types"
+)
+
+// handleGetCycleDiff compares the snapshots ?from= and ?to=, both snapshot IDs
+func (ws *WebServer) handleGetCycleDiff(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	fromID, err := strconv.ParseInt(query.Get("from"), 10, 64)
+	if err != nil {
+		ws.writeErrorResponse(w, http.StatusBadRequest, "Invalid from, must be a cycle ID")
+		return
+	}
+	toID, err := strconv.ParseInt(query.Get("to"), 10, 64)
+	if err != nil {
+		ws.writeErrorResponse(w, http.StatusBadRequest, "Invalid to, must be a cycle ID")
+		return
+	}
+
+	from, err := ws.store.GetCycleByID(fromID)
+	if err != nil {
+		webLogger.Error().Err(err).Int64("cycleId", fromID).Msg("Failed to get cycle")
+		ws.writeErrorResponse(w, http.StatusNotFound, "From cycle not found")
+		return
+	}
+	to, err := ws.store.GetCycleByID(toID)
+	if err != nil {
+		webLogger.Error().Err(err).Int64("cycleId", toID).Msg("Failed to get cycle")
+		ws.writeErrorResponse(w, http.StatusNotFound, "To cycle not found")
+		return
+	}
+
+	diff := cyclediff.Compare(*from, *to, ws.loadScoringParams(from.ScoringParamsID), ws.loadScoringParams(to.ScoringParamsID))
+	ws.writeJSONResponse(w, http.StatusOK, diff)
+}
+
+// loadScoringParams loads the scoring parameters a snapshot references. Returns nil if it
+// references none or they cannot be loaded; the diff then lists no parameter changes.
+func (ws *WebServer) loadScoringParams(paramsID *int64) *types.ScoringParametersVersion {
+	if paramsID == nil {
+		return nil
+	}
+	params, err := ws.store.LoadScoringParametersByID(*paramsID)
+	if err != nil {
+		if !errors.Is(err, state.ErrScoringParametersNotFound) {
+			webLogger.Warn().Err(err).Int64("paramsId", *paramsID).Msg("Failed to load scoring parameters for cycle diff")
+		}
+		return nil
+	}
+	return params
+}
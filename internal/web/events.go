@@ -0,0 +1,107 @@
+/*
+
+This file implements GET /api/events, which streams the AVM's cycle events (see internal/events) as
+Server-Sent Events. A new stream first replays the current cycle, or the last one between cycles, so
+the dashboard can show a cycle it connected to midway. A client that reconnects with the
+Last-Event-ID header, or ?last_event_id=, is only replayed what it missed.
+
+*/
+
+package web
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/elys-network/avm/internal/events"
+)
+
+// eventStreamKeepAlive is how often an idle stream sends a comment, so proxies keep it open
+const eventStreamKeepAlive = 15 * time.Second
+
+// handleEventStream streams the cycle events until the client disconnects
+func (ws *WebServer) handleEventStream(w http.ResponseWriter, r *http.Request) {
+	if ws.events == nil {
+		ws.writeErrorResponse(w, http.StatusServiceUnavailable, "Cycle events are not available")
+		return
+	}
+
+	lastEventID := r.Header.Get("Last-Event-ID")
+	if lastEventID == "" {
+		lastEventID = r.URL.Query().Get("last_event_id")
+	}
+	var afterID uint64
+	if lastEventID != "" {
+		var err error
+		afterID, err = strconv.ParseUint(lastEventID, 10, 64)
+		if err != nil {
+			ws.writeErrorResponse(w, http.StatusBadRequest, "Invalid last event ID")
+			return
+		}
+	}
+
+	// The stream outlives the server's write timeout
+	rc := http.NewResponseController(w)
+	if err := rc.SetWriteDeadline(time.Time{}); err != nil {
+		webLogger.Error().Err(err).Msg("Event stream is not supported by the response writer")
+		ws.writeErrorResponse(w, http.StatusInternalServerError, "Streaming not supported")
+		return
+	}
+
+	replay, stream, unsubscribe := ws.events.Subscribe(afterID)
+	defer unsubscribe()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no") // Disables response buffering in nginx
+	w.WriteHeader(http.StatusOK)
+
+	for _, event := range replay {
+		if err := writeEvent(w, event); err != nil {
+			return
+		}
+	}
+	if err := rc.Flush(); err != nil {
+		return
+	}
+
+	keepAlive := time.NewTicker(eventStreamKeepAlive)
+	defer keepAlive.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case event, ok := <-stream:
+			if !ok {
+				// Dropped for falling behind; the client reconnects with its last event ID
+				webLogger.Warn().Str("remote_addr", r.RemoteAddr).Msg("Closed slow event stream")
+				return
+			}
+			if err := writeEvent(w, event); err != nil {
+				return
+			}
+		case <-keepAlive.C:
+			if _, err := fmt.Fprint(w, ": keep-alive\n\n"); err != nil {
+				return
+			}
+		}
+		if err := rc.Flush(); err != nil {
+			return
+		}
+	}
+}
+
+// writeEvent writes an event in the Server-Sent Events format, named after its type
+func writeEvent(w http.ResponseWriter, event events.Event) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		webLogger.Error().Err(err).Uint64("id", event.ID).Msg("Failed to encode cycle event")
+		return nil
+	}
+	_, err = fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", event.ID, event.Type, data)
+	return err
+}
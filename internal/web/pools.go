@@ -0,0 +1,124 @@
+/*
+
+This file implements the pool explorer endpoints: GET /api/pools lists every pool the latest
+scoring cycle considered, with its score breakdown, rank, selection and target allocation, and
+GET /api/pools/{id}/explain shows how each score term was derived from the scoring parameters.
+They read the AVM through a PoolExplorer, which cmd/avm sets once the AVM is created; until then,
+and until a cycle has scored the pools, they answer 503.
+
+*/
+
+package web
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/elys-network/avm/internal/types"
+	"github.com/gorilla/mux"
+)
+
+// PoolExplorer returns the pools of the AVM's latest scoring cycle. *avm.AVM implements it.
+type PoolExplorer interface {
+	PoolUniverse() (*types.PoolUniverse, error)
+	ExplainPool(poolID types.PoolID) (*types.PoolScoreExplanation, error)
+}
+
+// SetPoolExplorer connects the pool explorer endpoints to the AVM
+func (ws *WebServer) SetPoolExplorer(explorer PoolExplorer) {
+	ws.controllerMu.Lock()
+	defer ws.controllerMu.Unlock()
+	ws.poolExplorer = explorer
+}
+
+// getPoolExplorer returns the AVM's PoolExplorer, writing a 503 response if it is not set yet
+func (ws *WebServer) getPoolExplorer(w http.ResponseWriter) (PoolExplorer, bool) {
+	ws.controllerMu.RLock()
+	defer ws.controllerMu.RUnlock()
+	if ws.poolExplorer == nil {
+		ws.writeErrorResponse(w, http.StatusServiceUnavailable, "The AVM is starting")
+		return nil, false
+	}
+	return ws.poolExplorer, true
+}
+
+// handleGetPools returns the pool universe of the latest scoring cycle, optionally only the
+// selected (?selected=true) or not selected (?selected=false) pools
+func (ws *WebServer) handleGetPools(w http.ResponseWriter, r *http.Request) {
+	var selectedFilter *bool
+	if value := r.URL.Query().Get("selected"); value != "" {
+		selected, err := strconv.ParseBool(value)
+		if err != nil {
+			ws.writeErrorResponse(w, http.StatusBadRequest, "Invalid selected, must be true or false")
+			return
+		}
+		selectedFilter = &selected
+	}
+
+	explorer, ok := ws.getPoolExplorer(w)
+	if !ok {
+		return
+	}
+	universe, err := explorer.PoolUniverse()
+	if errors.Is(err, types.ErrPoolUniverseUnavailable) {
+		ws.writeErrorResponse(w, http.StatusServiceUnavailable, err.Error())
+		return
+	}
+	if err != nil {
+		webLogger.Error().Err(err).Msg("Failed to get pool universe")
+		ws.writeErrorResponse(w, http.StatusInternalServerError, "Failed to retrieve pools")
+		return
+	}
+
+	if selectedFilter != nil {
+		pools := make([]types.PoolExplorerEntry, 0, len(universe.Pools))
+		for _, pool := range universe.Pools {
+			if pool.Selected == *selectedFilter {
+				pools = append(pools, pool)
+			}
+		}
+		universe.Pools = pools
+	}
+
+	response := map[string]interface{}{
+		"cycle_number":      universe.CycleNumber,
+		"timestamp":         universe.Timestamp,
+		"block_height":      universe.BlockHeight,
+		"scoring_params_id": universe.ScoringParamsID,
+		"max_pools":         universe.MaxPools,
+		"elys_pool_id":      universe.ElysPoolID,
+		"pools":             universe.Pools,
+		"count":             len(universe.Pools),
+	}
+	ws.writeJSONResponse(w, http.StatusOK, response)
+}
+
+// handleExplainPool returns how a pool's score in the latest scoring cycle was derived
+func (ws *WebServer) handleExplainPool(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		ws.writeErrorResponse(w, http.StatusBadRequest, "Invalid pool ID")
+		return
+	}
+
+	explorer, ok := ws.getPoolExplorer(w)
+	if !ok {
+		return
+	}
+	explanation, err := explorer.ExplainPool(types.PoolID(id))
+	switch {
+	case errors.Is(err, types.ErrPoolUniverseUnavailable):
+		ws.writeErrorResponse(w, http.StatusServiceUnavailable, err.Error())
+		return
+	case errors.Is(err, types.ErrPoolNotFound), errors.Is(err, types.ErrPoolNotScored):
+		ws.writeErrorResponse(w, http.StatusNotFound, err.Error())
+		return
+	case err != nil:
+		webLogger.Error().Err(err).Uint64("poolId", id).Msg("Failed to explain pool score")
+		ws.writeErrorResponse(w, http.StatusInternalServerError, "Failed to explain the pool's score")
+		return
+	}
+
+	ws.writeJSONResponse(w, http.StatusOK, explanation)
+}
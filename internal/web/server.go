@@ -1,20 +1,41 @@
 package web
 
 import (
+	"bytes"
 	"embed"
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"runtime"
 	"strconv"
+	"sync"
 	"time"
 
+	"github.com/elys-network/avm/internal/datafetcher"
+	"github.com/elys-network/avm/internal/events"
+	"github.com/elys-network/avm/internal/export"
 	"github.com/elys-network/avm/internal/logger"
+	"github.com/elys-network/avm/internal/metrics"
 	"github.com/elys-network/avm/internal/state"
+	"github.com/elys-network/avm/internal/types"
 	"github.com/gorilla/mux"
 )
 
 var webLogger = logger.GetForComponent("web_server")
 
+const (
+	priceCacheWindow      = datafetcher.REQUIRED_HOURS * time.Hour // Volatility window
+	priceCacheMaxAgeHours = datafetcher.MAX_CACHE_STALENESS_HOURS  // Served stale no further behind than this
+	priceCacheInactiveAge = 24 * time.Hour                         // Symbols not written for this long are no longer tracked
+
+	defaultPoolHistoryWindow = 7 * 24 * time.Hour // Range of pool history requests without "from"
+	defaultExportWindow      = 7 * 24 * time.Hour // Range of snapshot exports without "from"
+	defaultAttributionWindow = 30 * 24 * time.Hour // Range of PnL attribution summaries without "from"
+	defaultAuditWindow       = 90 * 24 * time.Hour // Range of audit log requests without "from"
+	defaultAuditLimit        = 100                 // Audit events returned without "limit"
+	maxAuditLimit            = 1000
+)
+
 //go:embed static/*
 var staticFiles embed.FS
 
@@ -25,10 +46,19 @@ var dashboardHTML []byte
 type WebServer struct {
 	router *mux.Router
 	port   string
+	store  state.Store
+	auth   AuthConfig
+
+	controllerMu sync.RWMutex // Guards controller and poolExplorer
+	controller   Controller   // Set with SetController once the AVM is created
+	poolExplorer PoolExplorer // Set with SetPoolExplorer once the AVM is created
+
+	events *events.Bus // Cycle events streamed at /api/events; nil if unavailable
 }
 
-// NewWebServer creates a new web server instance
-func NewWebServer(port string) *WebServer {
+// NewWebServer creates a new web server instance serving data from store, with the access
+// control of authConfig, and streaming the cycle events of eventBus
+func NewWebServer(port string, store state.Store, authConfig AuthConfig, eventBus *events.Bus) *WebServer {
 	if port == "" {
 		port = "8080"
 	}
@@ -36,6 +66,9 @@ func NewWebServer(port string) *WebServer {
 	server := &WebServer{
 		router: mux.NewRouter(),
 		port:   port,
+		store:  store,
+		auth:   authConfig,
+		events: eventBus,
 	}
 
 	server.setupRoutes()
@@ -55,19 +88,42 @@ func (ws *WebServer) setupRoutes() {
 	// Health endpoint (direct route)
 	ws.router.HandleFunc("/health", ws.handleHealth).Methods("GET")
 
-	// API endpoints
+	// Prometheus metrics (see internal/metrics); scrapers authenticate with a viewer API key
+	ws.router.Handle("/metrics", ws.requireRole(types.APIRoleViewer, metrics.Handler().ServeHTTP)).Methods("GET")
+
+	// API endpoints, each with the role it requires (see auth.go)
 	api := ws.router.PathPrefix("/api").Subrouter()
 	api.HandleFunc("/health", ws.handleHealth).Methods("GET")
-	api.HandleFunc("/cycles", ws.handleGetCycles).Methods("GET")
-	api.HandleFunc("/cycles/{id}", ws.handleGetCycle).Methods("GET")
-	api.HandleFunc("/cycles/latest", ws.handleGetLatestCycle).Methods("GET")
-	api.HandleFunc("/scoring-parameters", ws.handleGetScoringParameters).Methods("GET")
-	api.HandleFunc("/vault/summary", ws.handleGetVaultSummary).Methods("GET")
-	api.HandleFunc("/performance", ws.handleGetPerformanceMetrics).Methods("GET")
-
-	// Add CORS middleware
-	ws.router.Use(ws.corsMiddleware)
+	api.Handle("/cycles", ws.requireRole(types.APIRoleViewer, ws.handleGetCycles)).Methods("GET")
+	api.Handle("/cycles/export", ws.requireRole(types.APIRoleViewer, ws.handleExportCycles)).Methods("GET")
+	api.Handle("/cycles/diff", ws.requireRole(types.APIRoleViewer, ws.handleGetCycleDiff)).Methods("GET")
+	api.Handle("/cycles/{id}", ws.requireRole(types.APIRoleViewer, ws.handleGetCycle)).Methods("GET")
+	api.Handle("/cycles/latest", ws.requireRole(types.APIRoleViewer, ws.handleGetLatestCycle)).Methods("GET")
+	api.Handle("/scoring-parameters", ws.requireRole(types.APIRoleViewer, ws.handleGetScoringParameters)).Methods("GET")
+	api.Handle("/vault/summary", ws.requireRole(types.APIRoleViewer, ws.handleGetVaultSummary)).Methods("GET")
+	api.Handle("/performance", ws.requireRole(types.APIRoleViewer, ws.handleGetPerformanceMetrics)).Methods("GET")
+	api.Handle("/exposure/tokens", ws.requireRole(types.APIRoleViewer, ws.handleGetTokenExposures)).Methods("GET")
+	api.Handle("/price-integrity", ws.requireRole(types.APIRoleViewer, ws.handleGetPriceIntegrity)).Methods("GET")
+	api.Handle("/pools", ws.requireRole(types.APIRoleViewer, ws.handleGetPools)).Methods("GET")
+	api.Handle("/pools/{id}/explain", ws.requireRole(types.APIRoleViewer, ws.handleExplainPool)).Methods("GET")
+	api.Handle("/pools/{id}/history", ws.requireRole(types.APIRoleViewer, ws.handleGetPoolHistory)).Methods("GET")
+	api.Handle("/pools/{id}/history/stats", ws.requireRole(types.APIRoleViewer, ws.handleGetPoolHistoryStats)).Methods("GET")
+	api.Handle("/attribution", ws.requireRole(types.APIRoleViewer, ws.handleGetAttributionSummary)).Methods("GET")
+	api.Handle("/attribution/cycles/{cycle}", ws.requireRole(types.APIRoleViewer, ws.handleGetCycleAttribution)).Methods("GET")
+	api.Handle("/audit", ws.requireRole(types.APIRoleOperator, ws.handleGetAuditEvents)).Methods("GET")
+	api.Handle("/events", ws.requireRole(types.APIRoleViewer, ws.handleEventStream)).Methods("GET")
+	api.Handle("/control", ws.requireRole(types.APIRoleViewer, ws.handleGetControlStatus)).Methods("GET")
+	api.Handle("/control/pause", ws.requireRole(types.APIRoleOperator, ws.handlePause)).Methods("POST")
+	api.Handle("/control/resume", ws.requireRole(types.APIRoleOperator, ws.handleResume)).Methods("POST")
+	api.Handle("/control/trigger", ws.requireRole(types.APIRoleOperator, ws.handleTriggerCycle)).Methods("POST")
+	api.Handle("/control/emergency-stop", ws.requireRole(types.APIRoleOperator, ws.handleEmergencyStop)).Methods("POST")
+	api.Handle("/auth/me", ws.requireRole(types.APIRoleViewer, ws.handleGetAuthMe)).Methods("GET")
+	api.Handle("/auth/keys", ws.requireRole(types.APIRoleAdmin, ws.handleListAPIKeys)).Methods("GET")
+	api.Handle("/auth/keys", ws.requireRole(types.APIRoleAdmin, ws.handleCreateAPIKey)).Methods("POST")
+	api.Handle("/auth/keys/{name}/revoke", ws.requireRole(types.APIRoleAdmin, ws.handleRevokeAPIKey)).Methods("POST")
+
 	ws.router.Use(ws.loggingMiddleware)
+	ws.router.Use(ws.authMiddleware)
 }
 
 // Start starts the web server
@@ -76,7 +132,7 @@ func (ws *WebServer) Start() error {
 
 	server := &http.Server{
 		Addr:         ":" + ws.port,
-		Handler:      ws.router,
+		Handler:      ws.corsMiddleware(ws.router),
 		ReadTimeout:  15 * time.Second,
 		WriteTimeout: 15 * time.Second,
 		IdleTimeout:  60 * time.Second,
@@ -92,7 +148,7 @@ func (ws *WebServer) handleHealth(w http.ResponseWriter, r *http.Request) {
 	runtime.ReadMemStats(&memStats)
 	
 	// Get latest cycle information
-	latestCycle, cycleErr := state.GetRecentCycles(1)
+	latestCycle, cycleErr := ws.store.GetRecentCycles(1)
 	var cycleInfo map[string]interface{}
 	var hasErrors bool
 	var lastCycleTime *time.Time
@@ -122,12 +178,18 @@ func (ws *WebServer) handleHealth(w http.ResponseWriter, r *http.Request) {
 	
 	// Get database connection status
 	dbHealthy := true
-	dbErr := state.TestDBConnection()
+	dbErr := ws.store.Ping()
 	if dbErr != nil {
 		dbHealthy = false
 		hasErrors = true
 	}
 	
+	// Get price history cache freshness
+	priceCacheInfo := ws.priceCacheHealth()
+	if priceCacheInfo["status"] == "stale" {
+		hasErrors = true
+	}
+
 	// Determine overall status
 	overallStatus := "OK"
 	if hasErrors {
@@ -161,6 +223,7 @@ func (ws *WebServer) handleHealth(w http.ResponseWriter, r *http.Request) {
 			"database_healthy":    dbHealthy,
 			"has_recent_errors":   hasErrors,
 			"cycle_info":          cycleInfo,
+			"price_cache":         priceCacheInfo,
 		},
 	}
 
@@ -173,6 +236,57 @@ func (ws *WebServer) handleHealth(w http.ResponseWriter, r *http.Request) {
 	ws.writeJSONResponse(w, statusCode, response)
 }
 
+// priceCacheHealth summarizes the freshness of the hourly price history cache.
+// The cache is "stale" if any actively tracked symbol's newest bar is too old or its volatility window
+// is incomplete. Symbols that have not been written recently (e.g. delisted tokens) are reported as inactive.
+// The cache lives in PostgreSQL, so it is "disabled" with the SQLite store.
+func (ws *WebServer) priceCacheHealth() map[string]interface{} {
+	if state.DB == nil {
+		return map[string]interface{}{
+			"status": "disabled",
+		}
+	}
+
+	freshness, err := state.GetPriceHistoryFreshness(priceCacheWindow)
+	if err != nil {
+		webLogger.Error().Err(err).Msg("Failed to get price history cache freshness")
+		return map[string]interface{}{
+			"status": "unavailable",
+		}
+	}
+
+	if len(freshness) == 0 {
+		return map[string]interface{}{
+			"status":  "empty",
+			"symbols": freshness,
+		}
+	}
+
+	staleSymbols := make([]string, 0)
+	inactiveSymbols := make([]string, 0)
+	for _, f := range freshness {
+		if time.Since(f.LastFetchedAt) > priceCacheInactiveAge {
+			inactiveSymbols = append(inactiveSymbols, f.Symbol)
+			continue
+		}
+		if f.AgeHours > priceCacheMaxAgeHours || f.BarCount < int(priceCacheWindow/time.Hour) {
+			staleSymbols = append(staleSymbols, f.Symbol)
+		}
+	}
+
+	status := "fresh"
+	if len(staleSymbols) > 0 {
+		status = "stale"
+	}
+
+	return map[string]interface{}{
+		"status":           status,
+		"stale_symbols":    staleSymbols,
+		"inactive_symbols": inactiveSymbols,
+		"symbols":          freshness,
+	}
+}
+
 // handleDashboard serves the main dashboard HTML
 func (ws *WebServer) handleDashboard(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "text/html")
@@ -192,7 +306,7 @@ func (ws *WebServer) handleGetCycles(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	cycles, err := state.GetRecentCycles(limit)
+	cycles, err := ws.store.GetRecentCycles(limit)
 	if err != nil {
 		webLogger.Error().Err(err).Msg("Failed to get recent cycles")
 		ws.writeErrorResponse(w, http.StatusInternalServerError, "Failed to retrieve cycles")
@@ -219,7 +333,7 @@ func (ws *WebServer) handleGetCycle(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	cycle, err := state.GetCycleByID(id)
+	cycle, err := ws.store.GetCycleByID(id)
 	if err != nil {
 		webLogger.Error().Err(err).Int64("cycleId", id).Msg("Failed to get cycle")
 		ws.writeErrorResponse(w, http.StatusNotFound, "Cycle not found")
@@ -231,7 +345,7 @@ func (ws *WebServer) handleGetCycle(w http.ResponseWriter, r *http.Request) {
 
 // handleGetLatestCycle returns the most recent cycle
 func (ws *WebServer) handleGetLatestCycle(w http.ResponseWriter, r *http.Request) {
-	cycles, err := state.GetRecentCycles(1)
+	cycles, err := ws.store.GetRecentCycles(1)
 	if err != nil || len(cycles) == 0 {
 		webLogger.Error().Err(err).Msg("Failed to get latest cycle")
 		ws.writeErrorResponse(w, http.StatusNotFound, "No cycles found")
@@ -243,7 +357,7 @@ func (ws *WebServer) handleGetLatestCycle(w http.ResponseWriter, r *http.Request
 
 // handleGetScoringParameters returns current scoring parameters
 func (ws *WebServer) handleGetScoringParameters(w http.ResponseWriter, r *http.Request) {
-	params, err := state.LoadActiveScoringParameters("default_avm_strategy")
+	params, err := ws.store.LoadActiveScoringParameters("default_avm_strategy")
 	if err != nil {
 		webLogger.Error().Err(err).Msg("Failed to get scoring parameters")
 		ws.writeErrorResponse(w, http.StatusInternalServerError, "Failed to retrieve scoring parameters")
@@ -260,7 +374,7 @@ func (ws *WebServer) handleGetScoringParameters(w http.ResponseWriter, r *http.R
 
 // handleGetVaultSummary returns vault summary statistics
 func (ws *WebServer) handleGetVaultSummary(w http.ResponseWriter, r *http.Request) {
-	summary, err := state.GetVaultSummary()
+	summary, err := ws.store.GetVaultSummary()
 	if err != nil {
 		webLogger.Error().Err(err).Msg("Failed to get vault summary")
 		ws.writeErrorResponse(w, http.StatusInternalServerError, "Failed to retrieve vault summary")
@@ -272,7 +386,7 @@ func (ws *WebServer) handleGetVaultSummary(w http.ResponseWriter, r *http.Reques
 
 // handleGetPerformanceMetrics returns performance metrics
 func (ws *WebServer) handleGetPerformanceMetrics(w http.ResponseWriter, r *http.Request) {
-	metrics, err := state.GetPerformanceMetrics()
+	metrics, err := ws.store.GetPerformanceMetrics()
 	if err != nil {
 		webLogger.Error().Err(err).Msg("Failed to get performance metrics")
 		ws.writeErrorResponse(w, http.StatusInternalServerError, "Failed to retrieve performance metrics")
@@ -282,6 +396,300 @@ func (ws *WebServer) handleGetPerformanceMetrics(w http.ResponseWriter, r *http.
 	ws.writeJSONResponse(w, http.StatusOK, metrics)
 }
 
+// handleGetTokenExposures returns the per-token exposure recorded in the most recent cycle
+func (ws *WebServer) handleGetTokenExposures(w http.ResponseWriter, r *http.Request) {
+	cycles, err := ws.store.GetRecentCycles(1)
+	if err != nil || len(cycles) == 0 {
+		webLogger.Error().Err(err).Msg("Failed to get latest cycle for token exposures")
+		ws.writeErrorResponse(w, http.StatusNotFound, "No cycles found")
+		return
+	}
+
+	cycle := cycles[0]
+	response := map[string]interface{}{
+		"cycle_number":           cycle.CycleNumber,
+		"timestamp":              cycle.Timestamp,
+		"token_exposures":        cycle.TokenExposures,
+		"target_token_exposures": cycle.TargetTokenExposures,
+		"unallocated_target":     cycle.UnallocatedTarget,
+	}
+
+	ws.writeJSONResponse(w, http.StatusOK, response)
+}
+
+// handleGetPriceIntegrity returns the price integrity report recorded in the most recent cycle
+func (ws *WebServer) handleGetPriceIntegrity(w http.ResponseWriter, r *http.Request) {
+	cycles, err := ws.store.GetRecentCycles(1)
+	if err != nil || len(cycles) == 0 {
+		webLogger.Error().Err(err).Msg("Failed to get latest cycle for price integrity")
+		ws.writeErrorResponse(w, http.StatusNotFound, "No cycles found")
+		return
+	}
+
+	cycle := cycles[0]
+	response := map[string]interface{}{
+		"cycle_number":    cycle.CycleNumber,
+		"timestamp":       cycle.Timestamp,
+		"price_integrity": cycle.PriceIntegrity,
+	}
+
+	ws.writeJSONResponse(w, http.StatusOK, response)
+}
+
+// handleGetPoolHistory returns a pool's metrics time series
+func (ws *WebServer) handleGetPoolHistory(w http.ResponseWriter, r *http.Request) {
+	poolID, from, to, ok := ws.parsePoolHistoryRequest(w, r)
+	if !ok {
+		return
+	}
+
+	interval := r.URL.Query().Get("interval")
+	if interval != "" && interval != "hour" && interval != "day" {
+		ws.writeErrorResponse(w, http.StatusBadRequest, "Invalid interval, must be hour or day")
+		return
+	}
+
+	points, err := ws.store.GetPoolMetricsHistory(poolID, from, to, interval)
+	if err != nil {
+		webLogger.Error().Err(err).Uint64("poolId", uint64(poolID)).Msg("Failed to get pool history")
+		ws.writeErrorResponse(w, http.StatusInternalServerError, "Failed to retrieve pool history")
+		return
+	}
+
+	response := map[string]interface{}{
+		"pool_id":  poolID,
+		"from":     from,
+		"to":       to,
+		"interval": interval,
+		"points":   points,
+		"count":    len(points),
+	}
+
+	ws.writeJSONResponse(w, http.StatusOK, response)
+}
+
+// handleGetPoolHistoryStats returns summary statistics of a pool's TVL, volume and APRs
+func (ws *WebServer) handleGetPoolHistoryStats(w http.ResponseWriter, r *http.Request) {
+	poolID, from, to, ok := ws.parsePoolHistoryRequest(w, r)
+	if !ok {
+		return
+	}
+
+	stats, err := ws.store.GetPoolMetricsStats(poolID, from, to)
+	if err != nil {
+		webLogger.Error().Err(err).Uint64("poolId", uint64(poolID)).Msg("Failed to get pool history stats")
+		ws.writeErrorResponse(w, http.StatusInternalServerError, "Failed to retrieve pool history stats")
+		return
+	}
+
+	ws.writeJSONResponse(w, http.StatusOK, stats)
+}
+
+// parsePoolHistoryRequest reads the pool ID and the RFC 3339 "from" and "to" parameters of a pool
+// history request, defaulting to the last 7 days. It writes an error response and reports false
+// if they are invalid.
+func (ws *WebServer) parsePoolHistoryRequest(w http.ResponseWriter, r *http.Request) (types.PoolID, time.Time, time.Time, bool) {
+	id, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		ws.writeErrorResponse(w, http.StatusBadRequest, "Invalid pool ID")
+		return 0, time.Time{}, time.Time{}, false
+	}
+
+	from, to, ok := ws.parseTimeRange(w, r, defaultPoolHistoryWindow)
+	if !ok {
+		return 0, time.Time{}, time.Time{}, false
+	}
+	return types.PoolID(id), from, to, true
+}
+
+// handleGetAttributionSummary returns each pool's PnL attribution summed over a time range, and the total
+func (ws *WebServer) handleGetAttributionSummary(w http.ResponseWriter, r *http.Request) {
+	from, to, ok := ws.parseTimeRange(w, r, defaultAttributionWindow)
+	if !ok {
+		return
+	}
+
+	pools, err := ws.store.GetPoolAttributionSummary(from, to)
+	if err != nil {
+		webLogger.Error().Err(err).Msg("Failed to get PnL attribution summary")
+		ws.writeErrorResponse(w, http.StatusInternalServerError, "Failed to retrieve PnL attribution")
+		return
+	}
+
+	var total types.PoolAttributionSummary
+	for _, pool := range pools {
+		total.LPFeesUSD += pool.LPFeesUSD
+		total.EdenRewardsUSD += pool.EdenRewardsUSD
+		total.UsdcRewardsUSD += pool.UsdcRewardsUSD
+		total.PriceReturnUSD += pool.PriceReturnUSD
+		total.ImpermanentLossUSD += pool.ImpermanentLossUSD
+		total.SlippageUSD += pool.SlippageUSD
+		total.GasUSD += pool.GasUSD
+		total.TotalPnLUSD += pool.TotalPnLUSD
+	}
+
+	response := map[string]interface{}{
+		"from":  from,
+		"to":    to,
+		"pools": pools,
+		"total": total,
+	}
+
+	ws.writeJSONResponse(w, http.StatusOK, response)
+}
+
+// handleGetCycleAttribution returns the PnL attribution of one cycle, by cycle number
+func (ws *WebServer) handleGetCycleAttribution(w http.ResponseWriter, r *http.Request) {
+	cycleNumber, err := strconv.Atoi(mux.Vars(r)["cycle"])
+	if err != nil {
+		ws.writeErrorResponse(w, http.StatusBadRequest, "Invalid cycle number")
+		return
+	}
+
+	attributions, err := ws.store.GetPoolAttributions(cycleNumber)
+	if err != nil {
+		webLogger.Error().Err(err).Int("cycleNumber", cycleNumber).Msg("Failed to get cycle PnL attribution")
+		ws.writeErrorResponse(w, http.StatusInternalServerError, "Failed to retrieve PnL attribution")
+		return
+	}
+	if len(attributions) == 0 {
+		ws.writeErrorResponse(w, http.StatusNotFound, "No PnL attribution for this cycle")
+		return
+	}
+
+	response := map[string]interface{}{
+		"cycle_number": cycleNumber,
+		"pools":        attributions,
+	}
+
+	ws.writeJSONResponse(w, http.StatusOK, response)
+}
+
+// handleGetAuditEvents returns the audit log within a time range, newest first, optionally
+// filtered by action, actor and target
+func (ws *WebServer) handleGetAuditEvents(w http.ResponseWriter, r *http.Request) {
+	from, to, ok := ws.parseTimeRange(w, r, defaultAuditWindow)
+	if !ok {
+		return
+	}
+
+	limit := defaultAuditLimit
+	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+		parsedLimit, err := strconv.Atoi(limitStr)
+		if err != nil || parsedLimit <= 0 || parsedLimit > maxAuditLimit {
+			ws.writeErrorResponse(w, http.StatusBadRequest, fmt.Sprintf("Invalid limit, must be between 1 and %d", maxAuditLimit))
+			return
+		}
+		limit = parsedLimit
+	}
+
+	filter := types.AuditEventFilter{
+		From:   from,
+		To:     to,
+		Action: types.AuditAction(r.URL.Query().Get("action")),
+		Actor:  r.URL.Query().Get("actor"),
+		Target: r.URL.Query().Get("target"),
+		Limit:  limit,
+	}
+	events, err := ws.store.GetAuditEvents(filter)
+	if err != nil {
+		webLogger.Error().Err(err).Msg("Failed to get audit events")
+		ws.writeErrorResponse(w, http.StatusInternalServerError, "Failed to retrieve audit events")
+		return
+	}
+
+	response := map[string]interface{}{
+		"from":   from,
+		"to":     to,
+		"events": events,
+		"count":  len(events),
+		"limit":  limit,
+	}
+
+	ws.writeJSONResponse(w, http.StatusOK, response)
+}
+
+// parseTimeRange reads the RFC 3339 "from" and "to" parameters of a request. "to" defaults to now
+// and "from" to defaultWindow before "to". It writes an error response and reports false if they
+// are invalid.
+func (ws *WebServer) parseTimeRange(w http.ResponseWriter, r *http.Request, defaultWindow time.Duration) (time.Time, time.Time, bool) {
+	var err error
+	to := time.Now().UTC()
+	if toStr := r.URL.Query().Get("to"); toStr != "" {
+		to, err = time.Parse(time.RFC3339, toStr)
+		if err != nil {
+			ws.writeErrorResponse(w, http.StatusBadRequest, "Invalid to, must be an RFC 3339 timestamp")
+			return time.Time{}, time.Time{}, false
+		}
+	}
+
+	from := to.Add(-defaultWindow)
+	if fromStr := r.URL.Query().Get("from"); fromStr != "" {
+		from, err = time.Parse(time.RFC3339, fromStr)
+		if err != nil {
+			ws.writeErrorResponse(w, http.StatusBadRequest, "Invalid from, must be an RFC 3339 timestamp")
+			return time.Time{}, time.Time{}, false
+		}
+	}
+
+	if !from.Before(to) {
+		ws.writeErrorResponse(w, http.StatusBadRequest, "from must be before to")
+		return time.Time{}, time.Time{}, false
+	}
+
+	return from.UTC(), to.UTC(), true
+}
+
+// handleExportCycles returns one flattened table of the snapshots taken within [from, to) as a
+// file download. "table" defaults to snapshots and "format" to csv.
+func (ws *WebServer) handleExportCycles(w http.ResponseWriter, r *http.Request) {
+	from, to, ok := ws.parseTimeRange(w, r, defaultExportWindow)
+	if !ok {
+		return
+	}
+
+	query := r.URL.Query()
+	table := export.TableSnapshots
+	if tableStr := query.Get("table"); tableStr != "" {
+		parsed, err := export.ParseTable(tableStr)
+		if err != nil {
+			ws.writeErrorResponse(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		table = parsed
+	}
+	format := export.FormatCSV
+	if formatStr := query.Get("format"); formatStr != "" {
+		parsed, err := export.ParseFormat(formatStr)
+		if err != nil {
+			ws.writeErrorResponse(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		format = parsed
+	}
+
+	cycles, err := ws.store.GetCyclesInRange(from, to)
+	if err != nil {
+		webLogger.Error().Err(err).Msg("Failed to get cycles for export")
+		ws.writeErrorResponse(w, http.StatusInternalServerError, "Failed to retrieve cycles")
+		return
+	}
+
+	// Written to a buffer first, so a failure can still be reported as an error response
+	var body bytes.Buffer
+	if err := export.Flatten(cycles).WriteTable(&body, table, format); err != nil {
+		webLogger.Error().Err(err).Str("table", string(table)).Str("format", string(format)).Msg("Failed to export cycles")
+		ws.writeErrorResponse(w, http.StatusInternalServerError, "Failed to export cycles")
+		return
+	}
+
+	filename := fmt.Sprintf("%s_%s_%s.%s", table, from.Format("20060102T150405Z"), to.Format("20060102T150405Z"), format.Extension())
+	w.Header().Set("Content-Type", format.ContentType())
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	w.WriteHeader(http.StatusOK)
+	w.Write(body.Bytes())
+}
+
 // writeJSONResponse writes a JSON response
 func (ws *WebServer) writeJSONResponse(w http.ResponseWriter, statusCode int, data interface{}) {
 	w.Header().Set("Content-Type", "application/json")
@@ -303,22 +711,6 @@ func (ws *WebServer) writeErrorResponse(w http.ResponseWriter, statusCode int, m
 	ws.writeJSONResponse(w, statusCode, response)
 }
 
-// corsMiddleware adds CORS headers
-func (ws *WebServer) corsMiddleware(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Access-Control-Allow-Origin", "*")
-		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
-
-		if r.Method == "OPTIONS" {
-			w.WriteHeader(http.StatusOK)
-			return
-		}
-
-		next.ServeHTTP(w, r)
-	})
-}
-
 // loggingMiddleware logs HTTP requests
 func (ws *WebServer) loggingMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -351,3 +743,8 @@ func (w *responseWriterWrapper) WriteHeader(statusCode int) {
 	w.statusCode = statusCode
 	w.ResponseWriter.WriteHeader(statusCode)
 }
+
+// Unwrap returns the wrapped writer, so http.ResponseController can flush the event stream
+func (w *responseWriterWrapper) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
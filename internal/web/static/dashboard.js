@@ -1,8 +1,56 @@
-async function fetchAPI(endpoint) {
+// The API key is kept in the browser's local storage and asked for when the API requires one
+const API_KEY_STORAGE = 'avmApiKey';
+let apiKeyPrompt = null;
+let apiKeyDeclined = false;
+
+function requestAPIKey() {
+    // Concurrent requests share a single prompt, and a declined prompt is not repeated until reload
+    if (apiKeyDeclined) {
+        return Promise.resolve(null);
+    }
+    if (!apiKeyPrompt) {
+        apiKeyPrompt = Promise.resolve().then(() => {
+            const key = window.prompt('This dashboard requires an API key:');
+            if (key) {
+                localStorage.setItem(API_KEY_STORAGE, key.trim());
+            } else {
+                apiKeyDeclined = true;
+            }
+            apiKeyPrompt = null;
+            return key;
+        });
+    }
+    return apiKeyPrompt;
+}
+
+// fetchAPI calls an API endpoint; body, if given, is sent as JSON with a POST
+async function fetchAPI(endpoint, body = null, retried = false) {
     try {
-        const response = await fetch('/api' + endpoint);
+        const options = { headers: {} };
+        const apiKey = localStorage.getItem(API_KEY_STORAGE);
+        if (apiKey) {
+            options.headers['Authorization'] = 'Bearer ' + apiKey;
+        }
+        if (body !== null) {
+            options.method = 'POST';
+            options.headers['Content-Type'] = 'application/json';
+            options.body = JSON.stringify(body);
+        }
+        const response = await fetch('/api' + endpoint, options);
+        if (response.status === 401 && !retried) {
+            // Another request may already have asked for a new key
+            if (localStorage.getItem(API_KEY_STORAGE) !== apiKey) {
+                return fetchAPI(endpoint, body, true);
+            }
+            localStorage.removeItem(API_KEY_STORAGE);
+            if (await requestAPIKey()) {
+                return fetchAPI(endpoint, body, true);
+            }
+        }
         if (!response.ok) {
-            throw new Error('HTTP error! status: ' + response.status);
+            // Error responses carry a message, e.g. why a control action conflicts
+            const data = await response.json().catch(() => null);
+            throw new Error(data && data.message ? data.message : 'HTTP error! status: ' + response.status);
         }
         return await response.json();
     } catch (error) {
@@ -43,8 +91,26 @@ async function loadVaultSummary() {
 async function loadPerformanceMetrics() {
     try {
         const data = await fetchAPI('/performance');
+        const nav = data.nav || {};
+        const formatReturn = value => (value === null || value === undefined) ? 'N/A' : `${(value * 100).toFixed(2)}%`;
         const html = `
             <div class="grid">
+                <div class="metric">
+                    <div class="metric-value">${nav.nav_per_share ? '$' + nav.nav_per_share.toFixed(4) : 'N/A'}</div>
+                    <div class="metric-label">NAV per Share</div>
+                </div>
+                <div class="metric">
+                    <div class="metric-value">${formatReturn(nav.twr_inception)}</div>
+                    <div class="metric-label">Time-Weighted Return</div>
+                </div>
+                <div class="metric">
+                    <div class="metric-value">${formatReturn(nav.apy_7d)} / ${formatReturn(nav.apy_30d)}</div>
+                    <div class="metric-label">APY 7d / 30d</div>
+                </div>
+                <div class="metric">
+                    <div class="metric-value">${formatReturn(nav.apy_inception)}</div>
+                    <div class="metric-label">APY since Inception</div>
+                </div>
                 <div class="metric">
                     <div class="metric-value status-error">
                         $${data.total_slippage ? Math.abs(data.total_slippage).toLocaleString() : '0'}
@@ -73,6 +139,229 @@ async function loadPerformanceMetrics() {
     }
 }
 
+async function loadPnLAttribution() {
+    try {
+        const data = await fetchAPI('/attribution');
+        if (!data.pools || data.pools.length === 0) {
+            document.getElementById('pnl-attribution').innerHTML = '<p>No PnL attribution recorded yet</p>';
+            return;
+        }
+
+        const usd = value => {
+            const cls = value < 0 ? 'status-error' : 'status-good';
+            return `<td class="${cls}">$${(value || 0).toFixed(2)}</td>`;
+        };
+        const attributionRow = (label, row) => `
+            <tr>
+                <td>${label}</td>
+                ${usd(row.lp_fees_usd)}
+                ${usd(row.eden_rewards_usd)}
+                ${usd(row.usdc_rewards_usd)}
+                ${usd(row.price_return_usd)}
+                ${usd(row.impermanent_loss_usd)}
+                ${usd(-row.slippage_usd)}
+                ${usd(-row.gas_usd)}
+                ${usd(row.total_pnl_usd)}
+            </tr>
+        `;
+
+        let html = `
+            <table>
+                <thead>
+                    <tr>
+                        <th>Pool</th>
+                        <th>LP Fees</th>
+                        <th>Eden Rewards</th>
+                        <th>USDC Rewards</th>
+                        <th>Price Return</th>
+                        <th>Impermanent Loss</th>
+                        <th>Slippage</th>
+                        <th>Gas</th>
+                        <th>Total PnL</th>
+                    </tr>
+                </thead>
+                <tbody>
+        `;
+        data.pools.forEach(pool => {
+            html += attributionRow(pool.pool_id === 0 ? 'Unassigned' : pool.pool_id, pool);
+        });
+        html += attributionRow('<strong>Total</strong>', data.total);
+        html += '</tbody></table>';
+
+        document.getElementById('pnl-attribution').innerHTML = html;
+    } catch (error) {
+        document.getElementById('pnl-attribution').innerHTML = '<div class="error">Failed to load PnL attribution</div>';
+    }
+}
+
+// The cycle diff compares the latest two cycles unless the user picked others
+let cycleDiffSelection = null;
+
+async function loadCycleDiff() {
+    try {
+        let selection = cycleDiffSelection;
+        if (!selection) {
+            const recent = await fetchAPI('/cycles?limit=2');
+            if (!recent.cycles || recent.cycles.length < 2) {
+                document.getElementById('cycle-diff').innerHTML = '<p>Two cycles are needed to compare</p>';
+                return;
+            }
+            selection = { from: recent.cycles[1].snapshot_id, to: recent.cycles[0].snapshot_id };
+        }
+        const diff = await fetchAPI(`/cycles/diff?from=${selection.from}&to=${selection.to}`);
+        document.getElementById('cycle-diff').innerHTML = renderCycleDiff(diff);
+    } catch (error) {
+        document.getElementById('cycle-diff').innerHTML = `<div class="error">Failed to load cycle diff: ${escapeHTML(error.message)}</div>`;
+    }
+}
+
+// selectCycleDiff asks for the snapshot IDs to compare; an empty answer goes back to the latest two
+async function selectCycleDiff() {
+    const answer = window.prompt('Cycle IDs to compare, as from,to (empty for the latest two):');
+    if (answer === null) {
+        return;
+    }
+    const ids = answer.split(',').map(id => parseInt(id.trim(), 10));
+    cycleDiffSelection = ids.length === 2 && ids.every(id => id > 0) ? { from: ids[0], to: ids[1] } : null;
+    await loadCycleDiff();
+}
+
+function renderCycleDiff(diff) {
+    const signed = (value, digits) => `${value > 0 ? '+' : ''}${(value || 0).toFixed(digits)}`;
+    const deltaCell = (value, digits) => {
+        const cls = value < 0 ? 'status-error' : value > 0 ? 'status-good' : '';
+        return `<td class="${cls}">${signed(value, digits)}</td>`;
+    };
+    const percent = value => `${((value || 0) * 100).toFixed(1)}%`;
+
+    let html = `
+        <p>
+            Cycle ${diff.from.cycle_number} (#${diff.from.snapshot_id}, ${new Date(diff.from.timestamp).toLocaleString()})
+            → cycle ${diff.to.cycle_number} (#${diff.to.snapshot_id}, ${new Date(diff.to.timestamp).toLocaleString()}),
+            vault value ${signed(diff.vault_value_delta_usd, 2)} USD
+        </p>
+        <button class="refresh-btn" onclick="selectCycleDiff()">🔀 Compare Other Cycles</button>
+    `;
+
+    const params = diff.scoring_params;
+    if (!params.changed) {
+        html += '<p>Same scoring parameters</p>';
+    } else {
+        html += `<p>Scoring parameters changed from version ${params.from_version || '?'} to ${params.to_version || '?'}</p>`;
+        if (params.changes && params.changes.length > 0) {
+            html += '<p>' + params.changes.map(c => `${escapeHTML(c.field)}: ${c.from} → ${c.to}`).join('<br>') + '</p>';
+        }
+    }
+
+    const allocations = diff.target_allocations.filter(a => a.change !== 'unchanged');
+    html += '<h4>Target Allocations</h4>';
+    if (allocations.length === 0) {
+        html += '<p>No changes</p>';
+    } else {
+        html += '<table><thead><tr><th>Pool</th><th>Change</th><th>From</th><th>To</th><th>Delta (pp)</th></tr></thead><tbody>';
+        allocations.forEach(a => {
+            html += `<tr><td>${a.pool_id}</td><td>${a.change}</td><td>${percent(a.from)}</td><td>${percent(a.to)}</td>${deltaCell(a.delta * 100, 1)}</tr>`;
+        });
+        html += '</tbody></table>';
+    }
+
+    const positions = diff.positions.filter(p => p.change !== 'unchanged');
+    html += '<h4>Positions</h4>';
+    if (positions.length === 0) {
+        html += '<p>No changes</p>';
+    } else {
+        html += '<table><thead><tr><th>Pool</th><th>Assets</th><th>Change</th><th>Value</th><th>Value Delta</th><th>Allocation Delta (pp)</th></tr></thead><tbody>';
+        positions.forEach(p => {
+            html += `
+                <tr>
+                    <td>${p.pool_id}</td>
+                    <td>${escapeHTML((p.pool_assets || []).join('/'))}</td>
+                    <td>${p.change}</td>
+                    <td>$${p.from_value_usd.toFixed(2)} → $${p.to_value_usd.toFixed(2)}</td>
+                    ${deltaCell(p.value_delta_usd, 2)}
+                    ${deltaCell(p.allocation_delta_percent, 1)}
+                </tr>
+            `;
+        });
+        html += '</tbody></table>';
+    }
+
+    html += '<h4>Pool Scores</h4>';
+    if (!diff.from.scores_recorded || !diff.to.scores_recorded) {
+        html += '<p>Pool scores were not recorded for both cycles</p>';
+    } else {
+        html += '<table><thead><tr><th>Pool</th><th>Change</th><th>Rank</th><th>Score Delta</th><th>Reward</th><th>Risk</th><th>TVL</th><th>Bonus</th></tr></thead><tbody>';
+        diff.pool_scores.filter(p => p.change !== 'unchanged').forEach(p => {
+            const c = p.component_deltas;
+            html += `
+                <tr>
+                    <td>${p.pool_id}</td>
+                    <td>${p.change}</td>
+                    <td>${p.from_rank || '-'} → ${p.to_rank || '-'}</td>
+                    ${c ? deltaCell(p.score_delta, 4) : '<td>-</td>'}
+                    ${c ? deltaCell(c.reward_score_component, 4) : '<td>-</td>'}
+                    ${c ? deltaCell(c.risk_score_component, 4) : '<td>-</td>'}
+                    ${c ? deltaCell(c.tvl_score_component, 4) : '<td>-</td>'}
+                    ${c ? deltaCell(c.bonus_score_component, 4) : '<td>-</td>'}
+                </tr>
+            `;
+        });
+        html += '</tbody></table>';
+    }
+    return html;
+}
+
+async function loadPoolExplorer() {
+    try {
+        const data = await fetchAPI('/pools');
+        if (!data.pools || data.pools.length === 0) {
+            document.getElementById('pool-explorer').innerHTML = '<p>No pools scored yet</p>';
+            return;
+        }
+
+        const percent = value => `${((value || 0) * 100).toFixed(1)}%`;
+        let html = `
+            <p>Cycle ${data.cycle_number}, at most ${data.max_pools} pools</p>
+            <table>
+                <thead>
+                    <tr>
+                        <th>Rank</th>
+                        <th>Pool</th>
+                        <th>Assets</th>
+                        <th>Score</th>
+                        <th>Selected</th>
+                        <th>Reason</th>
+                        <th>Target</th>
+                        <th>Held</th>
+                    </tr>
+                </thead>
+                <tbody>
+        `;
+        data.pools.forEach(pool => {
+            const reason = pool.reason_detail
+                ? `${pool.reason}: ${escapeHTML(pool.reason_detail)}`
+                : pool.reason;
+            html += `
+                <tr>
+                    <td>${pool.rank || '-'}</td>
+                    <td>${pool.pool_id}</td>
+                    <td>${escapeHTML((pool.assets || []).join('/'))}</td>
+                    <td>${pool.score ? pool.score.final_score.toFixed(4) : '-'}</td>
+                    <td class="${pool.selected ? 'status-good' : ''}">${pool.selected ? 'Yes' : 'No'}</td>
+                    <td>${reason}</td>
+                    <td>${percent(pool.target_allocation)}</td>
+                    <td>${percent(pool.holding_allocation_ratio)}</td>
+                </tr>
+            `;
+        });
+        html += '</tbody></table>';
+
+        document.getElementById('pool-explorer').innerHTML = html;
+    } catch (error) {
+        document.getElementById('pool-explorer').innerHTML = `<div class="error">Failed to load pools: ${escapeHTML(error.message)}</div>`;
+    }
+}
+
 async function loadRecentCycles() {
     try {
         const data = await fetchAPI('/cycles?limit=10');
@@ -218,6 +507,7 @@ async function loadScoringParameters() {
                     <h4>Allocation Limits</h4>
                     <p><strong>Min Allocation:</strong> ${(params.min_allocation * 100).toFixed(2)}%</p>
                     <p><strong>Max Allocation:</strong> ${(params.max_allocation * 100).toFixed(2)}%</p>
+                    <p><strong>Max Token Exposure:</strong> ${params.max_token_exposure ? (params.max_token_exposure * 100).toFixed(2) + '%' : 'N/A'}</p>
                     <p><strong>Rebalance Threshold:</strong> ${params.rebalance_threshold_amount ? params.rebalance_threshold_amount.toFixed(2) : 'N/A'}%</p>
                     <p><strong>Max Withdrawal Per Cycle:</strong> ${params.max_rebalance_percent_per_cycle ? params.max_rebalance_percent_per_cycle.toFixed(2) : 'N/A'}%</p>
                 </div>
@@ -241,17 +531,252 @@ async function loadScoringParameters() {
     }
 }
 
+async function loadAuditLog() {
+    try {
+        const data = await fetchAPI('/audit?limit=10');
+        if (!data.events || data.events.length === 0) {
+            document.getElementById('audit-log').innerHTML = '<p>No audit events in the last 90 days</p>';
+            return;
+        }
+
+        let html = `
+            <table>
+                <thead>
+                    <tr>
+                        <th>Time</th>
+                        <th>Action</th>
+                        <th>Target</th>
+                        <th>Actor</th>
+                        <th>Reason</th>
+                        <th>Changes</th>
+                    </tr>
+                </thead>
+                <tbody>
+        `;
+        data.events.forEach(event => {
+            // Parameter changes are a list of {field, from, to}; other actions an object
+            let changes = '';
+            if (Array.isArray(event.diff)) {
+                changes = event.diff.map(c => `${escapeHTML(c.field)}: ${c.from} → ${c.to}`).join('<br>');
+            } else if (event.diff) {
+                changes = escapeHTML(JSON.stringify(event.diff));
+            }
+            html += `
+                <tr>
+                    <td>${new Date(event.timestamp).toLocaleString()}</td>
+                    <td>${escapeHTML(event.action)}</td>
+                    <td>${escapeHTML(event.target)}</td>
+                    <td>${escapeHTML(event.actor)}</td>
+                    <td>${escapeHTML(event.reason)}</td>
+                    <td>${changes}</td>
+                </tr>
+            `;
+        });
+        html += '</tbody></table>';
+
+        document.getElementById('audit-log').innerHTML = html;
+    } catch (error) {
+        document.getElementById('audit-log').innerHTML = '<div class="error">Failed to load audit log (requires the operator role)</div>';
+    }
+}
+
+async function loadControlStatus() {
+    try {
+        const data = await fetchAPI('/control');
+        const modes = {
+            running: '🟢 Running',
+            paused: '⏸️ Paused - plans are not executed',
+            emergency_stopped: '🛑 Emergency stopped - no cycles run',
+        };
+        let html = `
+            <div class="grid">
+                <div class="metric">
+                    <div class="metric-value">${escapeHTML(modes[data.mode] || data.mode)}</div>
+                    <div class="metric-label">Control Mode</div>
+                </div>
+                <div class="metric">
+                    <div class="metric-value">${data.cycle_running ? 'Cycle running' : 'Idle'}${data.trigger_pending ? ', cycle triggered' : ''}</div>
+                    <div class="metric-label">Activity</div>
+                </div>
+            </div>
+        `;
+        if (data.actor) {
+            html += `<p>Set by ${escapeHTML(data.actor)} on ${new Date(data.updated_at).toLocaleString()}: ${escapeHTML(data.reason)}</p>`;
+        }
+        html += `
+            <button class="refresh-btn" onclick="runControlAction('pause')">⏸️ Pause</button>
+            <button class="refresh-btn" onclick="runControlAction('resume')">▶️ Resume</button>
+            <button class="refresh-btn" onclick="runControlAction('trigger')">⏩ Run Cycle Now</button>
+            <button class="refresh-btn" onclick="runControlAction('emergency-stop')">🛑 Emergency Stop</button>
+        `;
+        document.getElementById('control-status').innerHTML = html;
+    } catch (error) {
+        document.getElementById('control-status').innerHTML = '<div class="error">Failed to load control status</div>';
+    }
+}
+
+// runControlAction runs a control action with a reason asked from the operator
+async function runControlAction(action) {
+    const reason = window.prompt(`Reason for ${action.replace('-', ' ')}:`);
+    if (!reason || !reason.trim()) {
+        return;
+    }
+    try {
+        await fetchAPI('/control/' + action, { reason: reason.trim() });
+    } catch (error) {
+        window.alert(`Failed to ${action.replace('-', ' ')}: ${error.message}`);
+    }
+    await Promise.all([loadControlStatus(), loadAuditLog()]);
+}
+
+// The live cycle view follows /api/events. EventSource cannot send the API key, so the stream is
+// read with fetch; after a disconnect it reconnects with the last event ID and gets what it missed.
+const CYCLE_STEPS = ['fetch', 'assess', 'score', 'plan', 'withdraw', 'deposit', 'finalize'];
+const liveCycle = { events: [], lastEventId: null };
+
+async function followCycleEvents() {
+    while (true) {
+        try {
+            const headers = {};
+            const apiKey = localStorage.getItem(API_KEY_STORAGE);
+            if (apiKey) {
+                headers['Authorization'] = 'Bearer ' + apiKey;
+            }
+            if (liveCycle.lastEventId !== null) {
+                headers['Last-Event-ID'] = liveCycle.lastEventId;
+            }
+            const response = await fetch('/api/events', { headers });
+            if (!response.ok) {
+                throw new Error('HTTP error! status: ' + response.status);
+            }
+            renderLiveCycle();
+            const reader = response.body.pipeThrough(new TextDecoderStream()).getReader();
+            let buffer = '';
+            while (true) {
+                const { value, done } = await reader.read();
+                if (done) {
+                    break;
+                }
+                // Events end with a blank line; keep-alive comments carry no data
+                buffer += value;
+                let end;
+                while ((end = buffer.indexOf('\n\n')) >= 0) {
+                    const data = buffer.slice(0, end).split('\n').find(line => line.startsWith('data: '));
+                    buffer = buffer.slice(end + 2);
+                    if (data) {
+                        handleCycleEvent(JSON.parse(data.slice('data: '.length)));
+                    }
+                }
+            }
+        } catch (error) {
+            console.error('Cycle event stream failed:', error);
+        }
+        await new Promise(resolve => setTimeout(resolve, 5000));
+    }
+}
+
+function handleCycleEvent(event) {
+    liveCycle.lastEventId = event.id;
+    if (event.type === 'cycle_started') {
+        liveCycle.events = [];
+    }
+    liveCycle.events.push(event);
+    renderLiveCycle();
+}
+
+function renderLiveCycle() {
+    const events = liveCycle.events;
+    if (events.length === 0) {
+        document.getElementById('live-cycle').innerHTML = '<p>Waiting for the next cycle...</p>';
+        return;
+    }
+
+    const steps = {};
+    const transactions = [];
+    let plan = null;
+    let finished = null;
+    events.forEach(event => {
+        if (event.type === 'step_started') {
+            steps[event.step] = { status: 'running' };
+        } else if (event.type === 'step_finished') {
+            steps[event.step] = event.data;
+        } else if (event.type === 'plan') {
+            plan = event.data;
+        } else if (event.type === 'transaction') {
+            transactions.push(event.data);
+        } else if (event.type === 'cycle_finished') {
+            finished = event.data;
+        }
+    });
+
+    const status = finished
+        ? `${escapeHTML(finished.outcome.replace(/_/g, ' '))} in ${(finished.duration_ms / 1000).toFixed(1)}s`
+        : 'running';
+    let html = `<p><strong>Cycle #${events[0].cycle_number}</strong> - ${status}</p>`;
+    if (finished && finished.goal) {
+        html += `<p>${escapeHTML(finished.goal)}</p>`;
+    }
+    html += `
+        <table>
+            <thead>
+                <tr>
+                    <th>Step</th>
+                    <th>Status</th>
+                    <th>Duration</th>
+                </tr>
+            </thead>
+            <tbody>
+    `;
+    const icons = { running: '⏳', completed: '✅', failed: '❌' };
+    CYCLE_STEPS.forEach(step => {
+        const state = steps[step];
+        if (!state) {
+            return;
+        }
+        const detail = state.error ? ` - ${escapeHTML(state.error)}` : '';
+        html += `
+            <tr>
+                <td>${step}</td>
+                <td>${icons[state.status] || ''} ${state.status}${detail}</td>
+                <td>${state.duration_ms !== undefined ? (state.duration_ms / 1000).toFixed(2) + 's' : ''}</td>
+            </tr>
+        `;
+    });
+    html += '</tbody></table>';
+    if (plan) {
+        html += `<p>Plan: ${(plan.withdrawals || []).length} withdrawals, ${(plan.deposits || []).length} deposits</p>`;
+    }
+    transactions.forEach(tx => {
+        html += `<p>Transaction ${escapeHTML(tx.tx_hash)} (gas $${tx.gas_fee_usd.toFixed(4)})</p>`;
+    });
+
+    document.getElementById('live-cycle').innerHTML = html;
+}
+
+// escapeHTML escapes free text, such as operator-supplied reasons, for use in innerHTML
+function escapeHTML(text) {
+    const div = document.createElement('div');
+    div.textContent = text == null ? '' : String(text);
+    return div.innerHTML;
+}
+
 async function loadDashboard() {
     await Promise.all([
         loadVaultSummary(),
+        loadControlStatus(),
         loadPerformanceMetrics(),
+        loadCycleDiff(),
+        loadPoolExplorer(),
+        loadPnLAttribution(),
         loadRecentCycles(),
-        loadScoringParameters()
+        loadScoringParameters(),
+        loadAuditLog()
     ]);
 }
 
-// Load dashboard on page load
+// Load dashboard and follow the cycle events on page load
 document.addEventListener('DOMContentLoaded', loadDashboard);
+document.addEventListener('DOMContentLoaded', followCycleEvents);
 
 // Auto-refresh every 30 seconds
 setInterval(loadDashboard, 30000); 
\ No newline at end of file
@@ -88,6 +88,19 @@ func main() {
 		DROP TABLE IF EXISTS performance_snapshots CASCADE;
 		DROP TABLE IF EXISTS scoring_parameters CASCADE;
 		DROP TABLE IF EXISTS cycle_counter CASCADE;
+		DROP TABLE IF EXISTS price_history CASCADE;
+		DROP TABLE IF EXISTS token_symbol_mappings CASCADE;
+		DROP TABLE IF EXISTS pool_swap_volume_daily CASCADE;
+		DROP TABLE IF EXISTS swap_volume_indexer_state CASCADE;
+		DROP TABLE IF EXISTS pool_metrics CASCADE;
+		DROP TABLE IF EXISTS vault_performance CASCADE;
+		DROP TABLE IF EXISTS pool_pnl_attribution CASCADE;
+		DROP TABLE IF EXISTS audit_events CASCADE;
+		DROP TABLE IF EXISTS api_keys CASCADE;
+		DROP TABLE IF EXISTS avm_control_state CASCADE;
+		DROP FUNCTION IF EXISTS audit_events_append_only();
+		DROP TABLE IF EXISTS scoring_parameter_activations CASCADE;
+		DROP TABLE IF EXISTS schema_migrations CASCADE;
 	`
 
 	_, err = state.DB.Exec(dropTablesQuery)